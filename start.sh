@@ -1,4 +1,5 @@
 #!/bin/sh
 
 # El backend ahora sirve también el frontend (./dist) y escucha en $PORT (Render)
-exec ./backend-app
+# Los argumentos pasan al binario: ./start.sh crear-admin nombre
+exec ./backend-app "$@"
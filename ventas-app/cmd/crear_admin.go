@@ -0,0 +1,67 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"os"
+	"strings"
+	"ventas-app/config"
+	"ventas-app/database"
+	"ventas-app/services"
+)
+
+// crearAdmin implementa `server crear-admin nombre`: da de alta un usuario
+// admin en la base del entorno. POST /usuarios exige ser admin, así que el
+// primero se crea por acá. La clave se toma de ADMIN_CLAVE o, si no está, de
+// la primera línea de la entrada estándar (para que no quede en el historial
+// del shell). En la imagen: `docker run -it IMAGEN ./start.sh crear-admin root`.
+func crearAdmin(cfg *config.Config, args []string) int {
+	if len(args) != 1 || strings.TrimSpace(args[0]) == "" {
+		fmt.Fprintln(os.Stderr, "uso: crear-admin nombre (clave en ADMIN_CLAVE o por stdin)")
+		return 2
+	}
+	nombre := strings.TrimSpace(args[0])
+
+	clave := os.Getenv("ADMIN_CLAVE")
+	if clave == "" {
+		fmt.Fprint(os.Stderr, "Clave: ")
+		linea, err := bufio.NewReader(os.Stdin).ReadString('\n')
+		if err != nil && linea == "" {
+			fmt.Fprintln(os.Stderr, "no se pudo leer la clave:", err)
+			return 1
+		}
+		clave = strings.TrimRight(linea, "\r\n")
+	}
+
+	services.Politica = services.PoliticaClave{
+		MinLongitud:       cfg.Clave.MinLongitud,
+		RequerirMayuscula: cfg.Clave.RequerirMayuscula,
+		RequerirMinuscula: cfg.Clave.RequerirMinuscula,
+		RequerirDigito:    cfg.Clave.RequerirDigito,
+		RequerirSimbolo:   cfg.Clave.RequerirSimbolo,
+	}
+	services.CostoBcrypt = cfg.Clave.CostoBcrypt
+	if cfg.Clave.ListaNegra != "" {
+		if err := services.CargarListaNegra(cfg.Clave.ListaNegra); err != nil {
+			fmt.Fprintln(os.Stderr, "no se pudo leer la lista negra de claves:", err)
+			return 1
+		}
+	}
+
+	database.Connect(cfg.Env, cfg.Database)
+	defer database.Close(context.Background())
+	db := &database.GormDB{DB: database.DBs[cfg.Env], QueryTimeout: cfg.Database.QueryTimeout}
+
+	usuario, err := services.CrearUsuario(db.WithContext(context.Background()), nombre, clave, "admin")
+	if problemas, debil := services.ProblemasClave(err); debil {
+		fmt.Fprintln(os.Stderr, "la clave no cumple la política:", strings.Join(problemas, "; "))
+		return 1
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	fmt.Printf("Admin %q creado (id %d)\n", usuario.Nombre, usuario.ID)
+	return 0
+}
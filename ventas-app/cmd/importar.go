@@ -0,0 +1,74 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"ventas-app/config"
+	"ventas-app/database"
+	"ventas-app/services"
+)
+
+// importarProductos implementa `server importar-productos [flags] archivo`:
+// la misma importación que POST /productos/importar, contra la base del
+// entorno. Imprime el reporte en JSON y devuelve el código de salida (1 si
+// hubo errores).
+func importarProductos(cfg *config.Config, args []string) int {
+	flags := flag.NewFlagSet("importar-productos", flag.ContinueOnError)
+	dryRun := flags.Bool("dry-run", false, "solo validar, sin escribir")
+	clave := flags.String("clave", "nombre", "campo para decidir si crear o actualizar (nombre|sku)")
+	mapeo := flags.String("mapeo", "", "columnas del archivo: campo=Columna,...")
+	formato := flags.String("formato", "", "csv|xlsx (por defecto, la extensión del archivo)")
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+	if flags.NArg() != 1 {
+		fmt.Fprintln(os.Stderr, "uso: importar-productos [-dry-run] [-clave nombre|sku] [-mapeo campo=Columna,...] archivo")
+		return 2
+	}
+	ruta := flags.Arg(0)
+	if *formato == "" {
+		*formato = strings.TrimPrefix(strings.ToLower(filepath.Ext(ruta)), ".")
+	}
+	campos, err := services.ParsearMapeo(*mapeo)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 2
+	}
+
+	archivo, err := os.Open(ruta)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	defer archivo.Close()
+	filas, err := services.LeerArchivo(archivo, *formato)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+
+	database.Connect(cfg.Env, cfg.Database)
+	defer database.Close(context.Background())
+	db := &database.GormDB{DB: database.DBs[cfg.Env], QueryTimeout: cfg.Database.QueryTimeout}
+
+	res, err := services.ImportarProductos(db.WithContext(context.Background()), filas, services.OpcionesImportacion{
+		Mapeo:  campos,
+		Clave:  *clave,
+		DryRun: *dryRun,
+	})
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	salida, _ := json.MarshalIndent(res, "", "  ")
+	fmt.Println(string(salida))
+	if len(res.Errores) > 0 {
+		return 1
+	}
+	return 0
+}
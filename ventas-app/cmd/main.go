@@ -1,73 +1,136 @@
 package main
 
 import (
+	"context"
 	"fmt"
+	"log"
 	"os"
+	"os/signal"
 	"path/filepath"
+	"strings"
+	"syscall"
+	"time"
 	"ventas-app/config"
+	"ventas-app/controllers"
 	"ventas-app/database"
+	"ventas-app/jobs"
+	"ventas-app/middleware"
 	"ventas-app/routes"
+	"ventas-app/server"
+	"ventas-app/services"
+	"ventas-app/utils"
 
-	"github.com/gin-contrib/cors"
 	"github.com/gin-gonic/gin"
 )
 
 func main() {
 
-	// Detectar entorno
-	env := os.Getenv("APP_ENV")
+	// Configuración tipada: defaults → CONFIG_FILE → .env.<APP_ENV> → variables de entorno
+	cfg, err := config.Load()
+	if err != nil {
+		log.Fatal("Configuración inválida:\n", err)
+	}
+
+	// `server config` imprime la configuración efectiva (sin secretos) y sale
+	if len(os.Args) > 1 && os.Args[1] == "config" {
+		dump, err := cfg.Dump()
+		if err != nil {
+			log.Fatal(err)
+		}
+		fmt.Print(dump)
+		return
+	}
 
-	// Si es CI → usar entorno CI
-	if os.Getenv("CI") == "true" {
-		env = "ci"
+	// `server importar-productos archivo` carga productos desde CSV/XLSX y sale
+	if len(os.Args) > 1 && os.Args[1] == "importar-productos" {
+		os.Exit(importarProductos(cfg, os.Args[2:]))
 	}
 
-	// Si no viene nada (Render), usar QA como default
-	if env == "" {
-		env = "qa"
+	// `server crear-admin nombre` da de alta el primer admin y sale
+	if len(os.Args) > 1 && os.Args[1] == "crear-admin" {
+		os.Exit(crearAdmin(cfg, os.Args[2:]))
 	}
 
-	fmt.Println("Iniciando backend en entorno:", env)
+	fmt.Println("Iniciando backend en entorno:", cfg.Env)
+
+	// Claves JWT: JWT_SECRET o JWT_KEYS (con rotación por kid)
+	llavero, err := utils.CargarLlavero(cfg.JWT.Secret, cfg.JWT.Keys, cfg.JWT.ActiveKid)
+	if err != nil {
+		log.Fatal("Claves JWT inválidas: ", err)
+	}
+	utils.SetLlavero(llavero)
+	utils.AccessTTL = cfg.JWT.AccessTTL
+	utils.Issuer = cfg.JWT.Issuer
+	utils.Audience = cfg.JWT.Audience
+	services.RefreshTTL = cfg.JWT.RefreshTTL
+
+	// Bloqueo por intentos fallidos de login
+	services.MaxIntentosUsuario = cfg.Login.MaxIntentosUsuario
+	services.BloqueoUsuario = cfg.Login.Bloqueo
+	controllers.LimitadorLogin = services.NuevoLimitadorIP(cfg.Login.MaxIntentosIP, cfg.Login.Ventana, cfg.Login.Bloqueo)
+
+	// Política de claves y costo de bcrypt
+	services.Politica = services.PoliticaClave{
+		MinLongitud:       cfg.Clave.MinLongitud,
+		RequerirMayuscula: cfg.Clave.RequerirMayuscula,
+		RequerirMinuscula: cfg.Clave.RequerirMinuscula,
+		RequerirDigito:    cfg.Clave.RequerirDigito,
+		RequerirSimbolo:   cfg.Clave.RequerirSimbolo,
+	}
+	services.CostoBcrypt = cfg.Clave.CostoBcrypt
+	if cfg.Clave.ListaNegra != "" {
+		if err := services.CargarListaNegra(cfg.Clave.ListaNegra); err != nil {
+			log.Fatal("No se pudo leer la lista negra de claves: ", err)
+		}
+	}
 
-	// Cargar variables desde env.<APP_ENV>
-	config.LoadEnv(env)
+	// Doble factor: roles obligados, emisor y vida del token intermedio
+	services.RolesMFA = map[string]bool{}
+	for _, rol := range cfg.MFA.Roles {
+		services.RolesMFA[strings.ToLower(rol)] = true
+	}
+	services.EmisorTOTP = cfg.MFA.Emisor
+	services.MFATTL = cfg.MFA.TokenTTL
+	services.MargenMinimo = cfg.Reportes.MargenMinimo
 
 	// Conectar BD según entorno
-	database.Connect()
+	database.Connect(cfg.Env, cfg.Database)
 
 	r := gin.Default()
 
-	fmt.Println("Conexión establecida para entorno:", env)
+	// Solo se cree el X-Forwarded-For de los proxies configurados; si no,
+	// cualquiera elegiría su IP para ClientIP (límite de login, auditoría)
+	if err := r.SetTrustedProxies(cfg.Server.TrustedProxies); err != nil {
+		log.Fatal("TRUSTED_PROXIES inválido: ", err)
+	}
+
+	// Timeout por request: las consultas a la BD se cortan cuando vence
+	// (REQUEST_TIMEOUT, ej. "30s"; "0" lo desactiva)
+	r.Use(middleware.Timeout(cfg.Server.RequestTimeout))
+
+	fmt.Println("Conexión establecida para entorno:", cfg.Env)
+
+	// Request ID y actor para la auditoría de cambios (GET /auditoria)
+	r.Use(middleware.Auditoria())
 
 	// ===========================
 	//        🔥 CORS FINAL
 	// ===========================
-	allowedOrigins := []string{
-		// Localhost para Vite y Cypress
-		"http://localhost:5173",
-		"http://localhost:5174",
+	// Orígenes, headers y max-age por entorno (CORS_ALLOWED_ORIGINS, ...)
+	r.Use(middleware.CORS(cfg.CORS))
 
-		// QA
-		"https://frontqa-t0a9.onrender.com",
+	// Tiempo máximo de /readyz por base de datos
+	controllers.ReadinessTimeout = cfg.Server.ReadinessTimeout
 
-		// PROD (OJO: ESTA es la correcta según Render)
-		"https://frontqa.onrender.com",
-	}
-
-	r.Use(cors.New(cors.Config{
-		AllowOrigins:     allowedOrigins,
-		AllowMethods:     []string{"GET", "POST", "PUT", "DELETE", "OPTIONS"},
-		AllowHeaders:     []string{"Origin", "Content-Type", "Authorization"},
-		ExposeHeaders:    []string{"Content-Length"},
-		AllowCredentials: true,
-	}))
+	// Las exportaciones tienen su propio límite (EXPORT_TIMEOUT)
+	controllers.ExportacionTimeout = cfg.Server.ExportTimeout
 
 	// Registrar rutas de API
 	routes.Setup(r)
 
 	// Servir frontend estático desde ./dist si existe
 	// Esto permite que un único servicio de Render atienda frontend y backend.
-	distPath := filepath.Join(".", "dist")
+	distPath := filepath.Join(".", cfg.Server.StaticDir)
 	r.Static("/assets", filepath.Join(distPath, "assets"))
 	// Exponer config.json para que axios pueda leerlo en runtime
 	r.StaticFile("/config.json", filepath.Join(distPath, "config.json"))
@@ -76,11 +139,42 @@ func main() {
 		c.File(filepath.Join(distPath, "index.html"))
 	})
 
-	// Puerto: usar PORT de Render si está definido, si no 8080
-	port := os.Getenv("PORT")
-	if port == "" {
-		port = "8080"
+	// Puerto: PORT de Render si está definido, si no 8080
+	srvCfg := server.Config{
+		Addr:              ":" + cfg.Server.Port,
+		ReadTimeout:       cfg.Server.ReadTimeout,
+		ReadHeaderTimeout: cfg.Server.ReadHeaderTimeout,
+		WriteTimeout:      cfg.Server.WriteTimeout,
+		IdleTimeout:       cfg.Server.IdleTimeout,
+		MaxHeaderBytes:    cfg.Server.MaxHeaderBytes,
+		ShutdownTimeout:   cfg.Server.ShutdownTimeout,
 	}
 
-	r.Run(":" + port)
+	// SIGTERM (deploy en Render) o Ctrl+C inician el apagado ordenado
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	// Jobs en segundo plano: se detienen antes de cerrar la BD
+	jobsRunner := jobs.NewRunner(ctx)
+	jobsRunner.Every("purgar-tokens", time.Hour, func(ctx context.Context) error {
+		db := &database.GormDB{DB: database.DBs[cfg.Env], QueryTimeout: cfg.Database.QueryTimeout}
+		return services.PurgarVencidos(db.WithContext(ctx))
+	})
+	// Snapshot diario del inventario para valuar a fechas pasadas: corre cada
+	// hora y la primera vez de cada día guarda el cierre del anterior
+	jobsRunner.Every("snapshot-inventario", time.Hour, func(ctx context.Context) error {
+		db := &database.GormDB{DB: database.DBs[cfg.Env], QueryTimeout: cfg.Database.QueryTimeout}
+		_, err := services.TomarSnapshotInventario(db.WithContext(ctx), time.Now())
+		return err
+	})
+
+	fmt.Println("Escuchando en", srvCfg.Addr)
+	err = server.Run(ctx, server.New(r, srvCfg), srvCfg.ShutdownTimeout,
+		jobsRunner.Stop,
+		database.Close,
+	)
+	if err != nil {
+		log.Fatal("Error en el servidor:", err)
+	}
+	fmt.Println("Servidor detenido")
 }
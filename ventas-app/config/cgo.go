@@ -0,0 +1,7 @@
+//go:build cgo
+
+package config
+
+// conCgo indica si el binario se compiló con cgo, que el driver de SQLite
+// (mattn/go-sqlite3) necesita.
+const conCgo = true
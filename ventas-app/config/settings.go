@@ -0,0 +1,563 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pelletier/go-toml/v2"
+	"golang.org/x/crypto/bcrypt"
+	"gopkg.in/yaml.v3"
+)
+
+// Config es la configuración tipada de la aplicación. Se arma en este orden
+// (cada paso pisa al anterior): valores por defecto, archivo YAML/TOML
+// indicado en CONFIG_FILE y variables de entorno (incluidas las de .env).
+//
+// Cada campo declara su clave en el archivo (tag yaml) y su variable de
+// entorno (tag env). Los marcados con secret:"true" se ocultan en Redacted.
+type Config struct {
+	// Env es el entorno detectado (qa, prod, ci, ...). No se lee del archivo.
+	Env string `yaml:"-"`
+
+	Server   ServerConfig   `yaml:"server"`
+	Database DatabaseConfig `yaml:"database"`
+	JWT      JWTConfig      `yaml:"jwt"`
+	Login    LoginConfig    `yaml:"login"`
+	Clave    ClaveConfig    `yaml:"clave"`
+	MFA      MFAConfig      `yaml:"mfa"`
+	Reportes ReportesConfig `yaml:"reportes"`
+	CORS     CORSConfig     `yaml:"cors"`
+}
+
+type ServerConfig struct {
+	Port              string        `yaml:"port" env:"PORT"`
+	StaticDir         string        `yaml:"static_dir" env:"STATIC_DIR"`
+	ReadTimeout       time.Duration `yaml:"read_timeout" env:"HTTP_READ_TIMEOUT"`
+	ReadHeaderTimeout time.Duration `yaml:"read_header_timeout" env:"HTTP_READ_HEADER_TIMEOUT"`
+	WriteTimeout      time.Duration `yaml:"write_timeout" env:"HTTP_WRITE_TIMEOUT"`
+	IdleTimeout       time.Duration `yaml:"idle_timeout" env:"HTTP_IDLE_TIMEOUT"`
+	MaxHeaderBytes    int           `yaml:"max_header_bytes" env:"HTTP_MAX_HEADER_BYTES"`
+	ShutdownTimeout   time.Duration `yaml:"shutdown_timeout" env:"SHUTDOWN_TIMEOUT"`
+	RequestTimeout    time.Duration `yaml:"request_timeout" env:"REQUEST_TIMEOUT"`
+	ReadinessTimeout  time.Duration `yaml:"readiness_timeout" env:"READINESS_TIMEOUT"`
+	// ExportTimeout reemplaza a RequestTimeout y WriteTimeout en las
+	// exportaciones, que recorren tablas enteras.
+	ExportTimeout time.Duration `yaml:"export_timeout" env:"EXPORT_TIMEOUT"`
+	// TrustedProxies son las IPs o CIDR de los proxies cuyo X-Forwarded-For
+	// se cree. En qa y prod por defecto son las redes privadas, de donde llega
+	// el balanceador de Render; en el resto ninguno y la IP del cliente es la
+	// de la conexión.
+	TrustedProxies []string `yaml:"trusted_proxies" env:"TRUSTED_PROXIES"`
+}
+
+type DatabaseConfig struct {
+	Driver       string        `yaml:"driver" env:"DB_DRIVER"`
+	Host         string        `yaml:"host" env:"DB_HOST"`
+	Port         string        `yaml:"port" env:"DB_PORT"`
+	User         string        `yaml:"user" env:"DB_USER"`
+	Password     string        `yaml:"password" env:"DB_PASS" secret:"true"`
+	Name         string        `yaml:"name" env:"DB_NAME"`
+	SSLMode      string        `yaml:"ssl_mode" env:"DB_SSL_MODE"`
+	CACertFile   string        `yaml:"ca_cert_file" env:"DB_CA_CERT"`
+	QueryTimeout time.Duration `yaml:"query_timeout" env:"DB_QUERY_TIMEOUT"`
+}
+
+// JWTConfig define las claves de firma. Secret es la forma simple (una clave
+// HS256); Keys permite varias claves "kid:ALG:valor" para rotarlas, y
+// ActiveKid elige con cuál se firma (por defecto la primera).
+type JWTConfig struct {
+	Secret     string        `yaml:"secret" env:"JWT_SECRET" secret:"true"`
+	Keys       []string      `yaml:"keys" env:"JWT_KEYS" secret:"true"`
+	ActiveKid  string        `yaml:"active_kid" env:"JWT_ACTIVE_KID"`
+	Issuer     string        `yaml:"issuer" env:"JWT_ISSUER"`
+	Audience   string        `yaml:"audience" env:"JWT_AUDIENCE"`
+	AccessTTL  time.Duration `yaml:"access_ttl" env:"JWT_ACCESS_TTL"`
+	RefreshTTL time.Duration `yaml:"refresh_ttl" env:"JWT_REFRESH_TTL"`
+}
+
+// LoginConfig limita los intentos de login fallidos. Al llegar a
+// MaxIntentosUsuario seguidos la cuenta se bloquea durante Bloqueo; cada IP
+// puede fallar MaxIntentosIP veces por Ventana antes de quedar bloqueada.
+type LoginConfig struct {
+	MaxIntentosUsuario int           `yaml:"max_intentos_usuario" env:"LOGIN_MAX_INTENTOS_USUARIO"`
+	MaxIntentosIP      int           `yaml:"max_intentos_ip" env:"LOGIN_MAX_INTENTOS_IP"`
+	Ventana            time.Duration `yaml:"ventana" env:"LOGIN_VENTANA"`
+	Bloqueo            time.Duration `yaml:"bloqueo" env:"LOGIN_BLOQUEO"`
+}
+
+// ClaveConfig es la política de claves de usuario, que se aplica al crearlas
+// y al cambiarlas. ListaNegra es un archivo opcional con claves prohibidas
+// (una por línea) que se suma a la lista de claves comunes incluida. Si se
+// sube CostoBcrypt, los hashes viejos se rehashean en el siguiente login.
+type ClaveConfig struct {
+	MinLongitud       int    `yaml:"min_longitud" env:"CLAVE_MIN_LONGITUD"`
+	RequerirMayuscula bool   `yaml:"requerir_mayuscula" env:"CLAVE_REQUERIR_MAYUSCULA"`
+	RequerirMinuscula bool   `yaml:"requerir_minuscula" env:"CLAVE_REQUERIR_MINUSCULA"`
+	RequerirDigito    bool   `yaml:"requerir_digito" env:"CLAVE_REQUERIR_DIGITO"`
+	RequerirSimbolo   bool   `yaml:"requerir_simbolo" env:"CLAVE_REQUERIR_SIMBOLO"`
+	ListaNegra        string `yaml:"lista_negra" env:"CLAVE_LISTA_NEGRA"`
+	CostoBcrypt       int    `yaml:"costo_bcrypt" env:"CLAVE_COSTO_BCRYPT"`
+}
+
+// MFAConfig configura el doble factor TOTP. Cualquier usuario puede
+// activarlo; los de Roles están obligados (por defecto ninguno). TokenTTL es
+// cuánto tiene el usuario para ingresar el código después de la clave.
+type MFAConfig struct {
+	Roles    []string      `yaml:"roles" env:"MFA_ROLES"`
+	Emisor   string        `yaml:"emisor" env:"MFA_EMISOR"`
+	TokenTTL time.Duration `yaml:"token_ttl" env:"MFA_TOKEN_TTL"`
+}
+
+// ReportesConfig configura los reportes. MargenMinimo es el margen (en % sobre
+// el neto) por debajo del cual el reporte de márgenes marca una alerta.
+type ReportesConfig struct {
+	MargenMinimo float64 `yaml:"margen_minimo" env:"REPORTES_MARGEN_MINIMO"`
+}
+
+// CORSConfig es la política CORS. AllowOrigins acepta orígenes exactos
+// ("https://front.example.com"), patrones de subdominio
+// ("https://*.onrender.com") o "*" para cualquiera (sin credenciales).
+type CORSConfig struct {
+	AllowOrigins     []string      `yaml:"allow_origins" env:"CORS_ALLOWED_ORIGINS"`
+	AllowHeaders     []string      `yaml:"allow_headers" env:"CORS_ALLOWED_HEADERS"`
+	ExposeHeaders    []string      `yaml:"expose_headers" env:"CORS_EXPOSE_HEADERS"`
+	AllowCredentials bool          `yaml:"allow_credentials" env:"CORS_ALLOW_CREDENTIALS"`
+	MaxAge           time.Duration `yaml:"max_age" env:"CORS_MAX_AGE"`
+}
+
+// Default devuelve la configuración por defecto para el entorno env.
+func Default(env string) *Config {
+	return &Config{
+		Env: env,
+		Server: ServerConfig{
+			Port:              "8080",
+			StaticDir:         "dist",
+			ReadTimeout:       15 * time.Second,
+			ReadHeaderTimeout: 5 * time.Second,
+			WriteTimeout:      40 * time.Second,
+			IdleTimeout:       60 * time.Second,
+			MaxHeaderBytes:    1 << 20,
+			ShutdownTimeout:   25 * time.Second,
+			RequestTimeout:    30 * time.Second,
+			ReadinessTimeout:  2 * time.Second,
+			ExportTimeout:     10 * time.Minute,
+			TrustedProxies:    proxiesPorDefecto(env),
+		},
+		Database: DatabaseConfig{
+			Driver:       "mysql",
+			CACertFile:   "BaltimoreCyberTrustRoot.crt.pem",
+			QueryTimeout: 10 * time.Second,
+		},
+		JWT: JWTConfig{
+			Issuer:     "ventas-app",
+			Audience:   "ventas-app",
+			AccessTTL:  15 * time.Minute,
+			RefreshTTL: 7 * 24 * time.Hour,
+		},
+		Login: LoginConfig{
+			MaxIntentosUsuario: 5,
+			MaxIntentosIP:      20,
+			Ventana:            15 * time.Minute,
+			Bloqueo:            15 * time.Minute,
+		},
+		Clave: ClaveConfig{
+			MinLongitud:       10,
+			RequerirMayuscula: true,
+			RequerirMinuscula: true,
+			RequerirDigito:    true,
+			CostoBcrypt:       bcrypt.DefaultCost,
+		},
+		MFA: MFAConfig{
+			Emisor:   "ventas-app",
+			TokenTTL: 5 * time.Minute,
+		},
+		Reportes: ReportesConfig{
+			MargenMinimo: 15,
+		},
+		CORS: CORSConfig{
+			AllowOrigins:     origenesPorDefecto(env),
+			AllowHeaders:     []string{"Origin", "Content-Type", "Authorization", "X-Env", "X-API-Key"},
+			ExposeHeaders:    []string{"Content-Length", "X-Request-ID"},
+			AllowCredentials: true,
+			MaxAge:           12 * time.Hour,
+		},
+	}
+}
+
+// origenesPorDefecto son los frontends conocidos de cada entorno. Un deploy
+// nuevo se agrega con CORS_ALLOWED_ORIGINS o en el archivo de configuración.
+func origenesPorDefecto(env string) []string {
+	// Localhost para Vite y Cypress
+	locales := []string{"http://localhost:5173", "http://localhost:5174"}
+
+	switch env {
+	case "prod":
+		return []string{"https://frontqa.onrender.com"}
+	case "qa":
+		// Es el entorno de Render cuando no hay APP_ENV: mantiene la lista
+		// histórica, con el frontend de QA y el de producción
+		return append(locales, "https://frontqa-t0a9.onrender.com", "https://frontqa.onrender.com")
+	default:
+		return locales
+	}
+}
+
+// proxiesPorDefecto son los proxies de confianza de cada entorno. Detrás del
+// balanceador de Render sin ellos todos los requests tendrían la IP del
+// balanceador, y el límite de login por IP bloquearía a todos juntos.
+func proxiesPorDefecto(env string) []string {
+	switch env {
+	case "qa", "prod":
+		return []string{"10.0.0.0/8", "172.16.0.0/12", "192.168.0.0/16"}
+	default:
+		return nil
+	}
+}
+
+// DetectEnv determina el entorno: APP_ENV, "ci" si CI=true, y "qa" si no viene nada (Render).
+func DetectEnv() string {
+	env := os.Getenv("APP_ENV")
+	if os.Getenv("CI") == "true" {
+		env = "ci"
+	}
+	if env == "" {
+		env = "qa"
+	}
+	return env
+}
+
+// Load detecta el entorno, carga su archivo .env, el archivo CONFIG_FILE (si
+// está definido) y las variables de entorno, y valida el resultado.
+func Load() (*Config, error) {
+	env := DetectEnv()
+	LoadEnv(env)
+
+	cfg := Default(env)
+	if archivo := os.Getenv("CONFIG_FILE"); archivo != "" {
+		if err := cfg.loadFile(archivo); err != nil {
+			return nil, err
+		}
+	}
+	if err := cfg.loadEnvVars(); err != nil {
+		return nil, err
+	}
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+	return cfg, nil
+}
+
+// sqliteDisponible es falso en binarios sin cgo: ahí DB_DRIVER=sqlite se
+// rechaza al validar y no al primer acceso a la base.
+var sqliteDisponible = conCgo
+
+// Validate revisa que la configuración permita arrancar. Devuelve todos los
+// problemas juntos para no tener que corregirlos de a uno.
+func (c *Config) Validate() error {
+	var errs []error
+
+	switch strings.ToLower(c.Database.Driver) {
+	case "mysql", "postgres", "postgresql":
+		if c.Database.Host == "" {
+			errs = append(errs, errors.New("database.host (DB_HOST) es obligatorio"))
+		}
+		if c.Database.Name == "" {
+			errs = append(errs, errors.New("database.name (DB_NAME) es obligatorio"))
+		}
+		if c.Database.Port != "" && !puertoValido(c.Database.Port) {
+			errs = append(errs, fmt.Errorf("database.port (DB_PORT) inválido: %q", c.Database.Port))
+		}
+	case "sqlite", "sqlite3":
+		if !sqliteDisponible {
+			errs = append(errs, errors.New("database.driver (DB_DRIVER) sqlite requiere un binario compilado con CGO_ENABLED=1"))
+		}
+	default:
+		errs = append(errs, fmt.Errorf("database.driver (DB_DRIVER) no soportado: %q", c.Database.Driver))
+	}
+
+	if !puertoValido(c.Server.Port) {
+		errs = append(errs, fmt.Errorf("server.port (PORT) inválido: %q", c.Server.Port))
+	}
+	for _, proxy := range c.Server.TrustedProxies {
+		if !proxyValido(proxy) {
+			errs = append(errs, fmt.Errorf("server.trusted_proxies (TRUSTED_PROXIES) IP o CIDR inválido: %q", proxy))
+		}
+	}
+	if c.Server.MaxHeaderBytes <= 0 {
+		errs = append(errs, errors.New("server.max_header_bytes (HTTP_MAX_HEADER_BYTES) debe ser mayor a 0"))
+	}
+	if c.JWT.Secret == "" && len(c.JWT.Keys) == 0 {
+		errs = append(errs, errors.New("jwt.secret (JWT_SECRET) o jwt.keys (JWT_KEYS) es obligatorio"))
+	}
+	if c.JWT.Issuer == "" || c.JWT.Audience == "" {
+		errs = append(errs, errors.New("jwt.issuer (JWT_ISSUER) y jwt.audience (JWT_AUDIENCE) son obligatorios"))
+	}
+	if c.JWT.AccessTTL <= 0 || c.JWT.RefreshTTL <= c.JWT.AccessTTL {
+		errs = append(errs, errors.New("jwt.refresh_ttl (JWT_REFRESH_TTL) debe ser mayor que jwt.access_ttl (JWT_ACCESS_TTL) y ambos positivos"))
+	}
+	if c.Login.MaxIntentosUsuario <= 0 || c.Login.MaxIntentosIP <= 0 {
+		errs = append(errs, errors.New("login.max_intentos_usuario (LOGIN_MAX_INTENTOS_USUARIO) y login.max_intentos_ip (LOGIN_MAX_INTENTOS_IP) deben ser mayores a 0"))
+	}
+	if c.Clave.MinLongitud < 1 || c.Clave.MinLongitud > 72 {
+		errs = append(errs, errors.New("clave.min_longitud (CLAVE_MIN_LONGITUD) debe estar entre 1 y 72"))
+	}
+	if c.Clave.CostoBcrypt < bcrypt.MinCost || c.Clave.CostoBcrypt > bcrypt.MaxCost {
+		errs = append(errs, fmt.Errorf("clave.costo_bcrypt (CLAVE_COSTO_BCRYPT) debe estar entre %d y %d", bcrypt.MinCost, bcrypt.MaxCost))
+	}
+	if c.MFA.Emisor == "" || c.MFA.TokenTTL <= 0 {
+		errs = append(errs, errors.New("mfa.emisor (MFA_EMISOR) es obligatorio y mfa.token_ttl (MFA_TOKEN_TTL) debe ser mayor a 0"))
+	}
+	if c.Reportes.MargenMinimo < 0 || c.Reportes.MargenMinimo > 100 {
+		errs = append(errs, errors.New("reportes.margen_minimo (REPORTES_MARGEN_MINIMO) debe estar entre 0 y 100"))
+	}
+	if len(c.CORS.AllowOrigins) == 0 {
+		errs = append(errs, errors.New("cors.allow_origins (CORS_ALLOWED_ORIGINS) no puede estar vacío"))
+	}
+	for _, origen := range c.CORS.AllowOrigins {
+		if origen == "*" {
+			if c.CORS.AllowCredentials {
+				errs = append(errs, errors.New("cors.allow_origins (CORS_ALLOWED_ORIGINS) no admite \"*\" con allow_credentials"))
+			}
+			continue
+		}
+		if !origenValido(origen) {
+			errs = append(errs, fmt.Errorf("cors.allow_origins (CORS_ALLOWED_ORIGINS) origen inválido: %q", origen))
+		}
+	}
+	for _, f := range c.campos() {
+		if d, ok := f.valor.Interface().(time.Duration); ok && d < 0 {
+			errs = append(errs, fmt.Errorf("%s (%s) no puede ser negativo", f.clave, f.env))
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+// Redacted devuelve la configuración como mapa anidado (mismas claves que el
+// archivo) con los secretos ocultos, para loguearla o imprimirla.
+func (c *Config) Redacted() map[string]interface{} {
+	raiz := map[string]interface{}{"env": c.Env}
+	for _, f := range c.campos() {
+		var valor interface{} = f.valor.Interface()
+		switch v := valor.(type) {
+		case time.Duration:
+			valor = v.String()
+		case string:
+			if f.secret && v != "" {
+				valor = "****"
+			}
+		case []string:
+			if f.secret && len(v) > 0 {
+				valor = "****"
+			}
+		}
+
+		nodo := raiz
+		partes := strings.Split(f.clave, ".")
+		for _, p := range partes[:len(partes)-1] {
+			hijo, ok := nodo[p].(map[string]interface{})
+			if !ok {
+				hijo = map[string]interface{}{}
+				nodo[p] = hijo
+			}
+			nodo = hijo
+		}
+		nodo[partes[len(partes)-1]] = valor
+	}
+	return raiz
+}
+
+// Dump devuelve Redacted en YAML.
+func (c *Config) Dump() (string, error) {
+	out, err := yaml.Marshal(c.Redacted())
+	return string(out), err
+}
+
+// campo es un valor configurable hoja de Config.
+type campo struct {
+	clave  string // ruta en el archivo, ej. "database.host"
+	env    string
+	secret bool
+	valor  reflect.Value
+}
+
+func (c *Config) campos() []campo {
+	return recorrer(reflect.ValueOf(c).Elem(), "")
+}
+
+func recorrer(v reflect.Value, prefijo string) []campo {
+	var campos []campo
+	for i := 0; i < v.NumField(); i++ {
+		sf := v.Type().Field(i)
+		nombre := sf.Tag.Get("yaml")
+		if nombre == "" || nombre == "-" {
+			continue
+		}
+		clave := prefijo + nombre
+		fv := v.Field(i)
+		if fv.Kind() == reflect.Struct {
+			campos = append(campos, recorrer(fv, clave+".")...)
+			continue
+		}
+		campos = append(campos, campo{
+			clave:  clave,
+			env:    sf.Tag.Get("env"),
+			secret: sf.Tag.Get("secret") == "true",
+			valor:  fv,
+		})
+	}
+	return campos
+}
+
+func (c *Config) loadFile(archivo string) error {
+	data, err := os.ReadFile(archivo)
+	if err != nil {
+		return fmt.Errorf("no se pudo leer CONFIG_FILE: %w", err)
+	}
+
+	var crudo map[string]interface{}
+	switch strings.ToLower(filepath.Ext(archivo)) {
+	case ".yaml", ".yml":
+		err = yaml.Unmarshal(data, &crudo)
+	case ".toml":
+		err = toml.Unmarshal(data, &crudo)
+	default:
+		return fmt.Errorf("CONFIG_FILE debe ser .yaml, .yml o .toml: %s", archivo)
+	}
+	if err != nil {
+		return fmt.Errorf("error leyendo %s: %w", archivo, err)
+	}
+
+	planos := map[string]interface{}{}
+	aplanar(crudo, "", planos)
+
+	for _, f := range c.campos() {
+		crudo, ok := planos[f.clave]
+		if !ok {
+			continue
+		}
+		if err := asignarValor(f.valor, crudo); err != nil {
+			return fmt.Errorf("%s en %s: %w", f.clave, archivo, err)
+		}
+		delete(planos, f.clave)
+	}
+	for clave := range planos {
+		return fmt.Errorf("clave desconocida en %s: %s", archivo, clave)
+	}
+	return nil
+}
+
+func (c *Config) loadEnvVars() error {
+	for _, f := range c.campos() {
+		texto, ok := os.LookupEnv(f.env)
+		if !ok || f.env == "" {
+			continue
+		}
+		if err := asignar(f.valor, texto); err != nil {
+			return fmt.Errorf("%s: %w", f.env, err)
+		}
+	}
+	return nil
+}
+
+func aplanar(m map[string]interface{}, prefijo string, out map[string]interface{}) {
+	for k, v := range m {
+		if hijo, ok := v.(map[string]interface{}); ok {
+			aplanar(hijo, prefijo+k+".", out)
+			continue
+		}
+		out[prefijo+k] = v
+	}
+}
+
+// asignarValor asigna un valor leído de YAML/TOML usando la misma conversión
+// que para las variables de entorno.
+func asignarValor(v reflect.Value, crudo interface{}) error {
+	if lista, ok := crudo.([]interface{}); ok {
+		if v.Kind() != reflect.Slice {
+			return errors.New("no se esperaba una lista")
+		}
+		items := make([]string, len(lista))
+		for i, item := range lista {
+			items[i] = fmt.Sprint(item)
+		}
+		v.Set(reflect.ValueOf(items))
+		return nil
+	}
+	return asignar(v, fmt.Sprint(crudo))
+}
+
+// asignar convierte texto al tipo del campo: string, []string (separado por
+// comas), int, float64, bool o time.Duration ("30s", "500ms").
+func asignar(v reflect.Value, texto string) error {
+	texto = strings.TrimSpace(texto)
+	switch v.Interface().(type) {
+	case time.Duration:
+		d, err := time.ParseDuration(texto)
+		if err != nil {
+			return fmt.Errorf("duración inválida %q", texto)
+		}
+		v.SetInt(int64(d))
+	case string:
+		v.SetString(texto)
+	case []string:
+		var items []string
+		for _, item := range strings.Split(texto, ",") {
+			if item = strings.TrimSpace(item); item != "" {
+				items = append(items, item)
+			}
+		}
+		v.Set(reflect.ValueOf(items))
+	case int:
+		n, err := strconv.Atoi(texto)
+		if err != nil {
+			return fmt.Errorf("número inválido %q", texto)
+		}
+		v.SetInt(int64(n))
+	case float64:
+		n, err := strconv.ParseFloat(texto, 64)
+		if err != nil {
+			return fmt.Errorf("número inválido %q", texto)
+		}
+		v.SetFloat(n)
+	case bool:
+		b, err := strconv.ParseBool(texto)
+		if err != nil {
+			return fmt.Errorf("booleano inválido %q", texto)
+		}
+		v.SetBool(b)
+	default:
+		return fmt.Errorf("tipo no soportado %s", v.Type())
+	}
+	return nil
+}
+
+// origenValido acepta esquema://host[:puerto], donde el host puede empezar
+// con "*." para cualquier subdominio. No se permiten rutas ni otros "*".
+func origenValido(origen string) bool {
+	u, err := url.Parse(strings.Replace(origen, "://*.", "://comodin.", 1))
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return false
+	}
+	return u.Path == "" && u.RawQuery == "" && u.Fragment == "" && u.User == nil && !strings.Contains(u.Host, "*")
+}
+
+func proxyValido(p string) bool {
+	if _, _, err := net.ParseCIDR(p); err == nil {
+		return true
+	}
+	return net.ParseIP(p) != nil
+}
+
+func puertoValido(p string) bool {
+	n, err := strconv.Atoi(p)
+	return err == nil && n > 0 && n < 65536
+}
@@ -0,0 +1,232 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// entornoLimpio deja en blanco las variables que lee Load para que los tests
+// no dependan del entorno de la máquina.
+func entornoLimpio(t *testing.T) {
+	t.Helper()
+	t.Setenv("APP_ENV", "test")
+	t.Setenv("CI", "")
+	t.Setenv("CONFIG_FILE", "")
+	for _, f := range Default("test").campos() {
+		if f.env != "" {
+			t.Setenv(f.env, "")
+			os.Unsetenv(f.env)
+		}
+	}
+	t.Setenv("DB_DRIVER", "sqlite")
+	t.Setenv("JWT_SECRET", "secreto-de-test")
+}
+
+func escribir(t *testing.T, nombre, contenido string) string {
+	t.Helper()
+	archivo := filepath.Join(t.TempDir(), nombre)
+	require.NoError(t, os.WriteFile(archivo, []byte(contenido), 0o600))
+	return archivo
+}
+
+func TestLoad_Defaults(t *testing.T) {
+	entornoLimpio(t)
+
+	cfg, err := Load()
+
+	require.NoError(t, err)
+	assert.Equal(t, "test", cfg.Env)
+	assert.Equal(t, "8080", cfg.Server.Port)
+	assert.Equal(t, 30*time.Second, cfg.Server.RequestTimeout)
+	assert.Equal(t, 10*time.Second, cfg.Database.QueryTimeout)
+	assert.Contains(t, cfg.CORS.AllowOrigins, "http://localhost:5173")
+}
+
+func TestLoad_ArchivoYAML(t *testing.T) {
+	entornoLimpio(t)
+	t.Setenv("CONFIG_FILE", escribir(t, "config.yaml", `
+server:
+  port: "9090"
+  request_timeout: 5s
+database:
+  query_timeout: 2s
+cors:
+  allow_origins:
+    - https://a.example.com
+    - https://b.example.com
+`))
+
+	cfg, err := Load()
+
+	require.NoError(t, err)
+	assert.Equal(t, "9090", cfg.Server.Port)
+	assert.Equal(t, 5*time.Second, cfg.Server.RequestTimeout)
+	assert.Equal(t, 2*time.Second, cfg.Database.QueryTimeout)
+	assert.Equal(t, []string{"https://a.example.com", "https://b.example.com"}, cfg.CORS.AllowOrigins)
+	assert.Equal(t, 15*time.Second, cfg.Server.ReadTimeout, "lo que no está en el archivo conserva el default")
+}
+
+func TestLoad_ArchivoTOML(t *testing.T) {
+	entornoLimpio(t)
+	t.Setenv("CONFIG_FILE", escribir(t, "config.toml", `
+[server]
+port = 9191
+max_header_bytes = 4096
+
+[jwt]
+secret = "desde-archivo"
+`))
+	os.Unsetenv("JWT_SECRET")
+
+	cfg, err := Load()
+
+	require.NoError(t, err)
+	assert.Equal(t, "9191", cfg.Server.Port)
+	assert.Equal(t, 4096, cfg.Server.MaxHeaderBytes)
+	assert.Equal(t, "desde-archivo", cfg.JWT.Secret)
+}
+
+func TestLoad_EnvPisaAlArchivo(t *testing.T) {
+	entornoLimpio(t)
+	t.Setenv("CONFIG_FILE", escribir(t, "config.yaml", "server:\n  port: \"9090\"\n"))
+	t.Setenv("PORT", "7070")
+	t.Setenv("CORS_ALLOWED_ORIGINS", "https://x.example.com, https://y.example.com")
+	t.Setenv("REPORTES_MARGEN_MINIMO", "12.5")
+
+	cfg, err := Load()
+
+	require.NoError(t, err)
+	assert.Equal(t, "7070", cfg.Server.Port)
+	assert.Equal(t, 12.5, cfg.Reportes.MargenMinimo)
+	assert.Equal(t, []string{"https://x.example.com", "https://y.example.com"}, cfg.CORS.AllowOrigins)
+}
+
+func TestLoad_DuracionInvalida(t *testing.T) {
+	entornoLimpio(t)
+	t.Setenv("REQUEST_TIMEOUT", "treinta")
+
+	_, err := Load()
+
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "REQUEST_TIMEOUT")
+}
+
+func TestLoad_ClaveDesconocidaEnArchivo(t *testing.T) {
+	entornoLimpio(t)
+	t.Setenv("CONFIG_FILE", escribir(t, "config.yaml", "server:\n  puerto: 1\n"))
+
+	_, err := Load()
+
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "server.puerto")
+}
+
+func TestValidate_JuntaTodosLosErrores(t *testing.T) {
+	cfg := Default("prod")
+	cfg.Server.Port = "0"
+	cfg.Server.ShutdownTimeout = -time.Second
+	cfg.Clave.CostoBcrypt = 3
+	cfg.Reportes.MargenMinimo = 150
+
+	err := cfg.Validate()
+
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "DB_HOST")
+	assert.Contains(t, err.Error(), "DB_NAME")
+	assert.Contains(t, err.Error(), "PORT")
+	assert.Contains(t, err.Error(), "SHUTDOWN_TIMEOUT")
+	assert.Contains(t, err.Error(), "JWT_SECRET")
+	assert.Contains(t, err.Error(), "CLAVE_COSTO_BCRYPT")
+	assert.Contains(t, err.Error(), "REPORTES_MARGEN_MINIMO")
+}
+
+func TestRedacted_OcultaSecretos(t *testing.T) {
+	cfg := Default("qa")
+	cfg.Database.Password = "super-secreta"
+	cfg.JWT.Secret = "firma"
+	cfg.JWT.Keys = []string{"k1:HS256:otra-firma"}
+
+	dump, err := cfg.Dump()
+
+	require.NoError(t, err)
+	assert.NotContains(t, dump, "super-secreta")
+	assert.NotContains(t, dump, "firma")
+	assert.NotContains(t, dump, "k1:HS256")
+	assert.Contains(t, dump, "****")
+	assert.Contains(t, dump, "request_timeout: 30s")
+
+	red := cfg.Redacted()
+	assert.Equal(t, "qa", red["env"])
+	assert.Equal(t, "****", red["database"].(map[string]interface{})["password"])
+}
+
+func TestDetectEnv(t *testing.T) {
+	t.Setenv("APP_ENV", "")
+	t.Setenv("CI", "")
+	assert.Equal(t, "qa", DetectEnv())
+
+	t.Setenv("APP_ENV", "prod")
+	assert.Equal(t, "prod", DetectEnv())
+
+	t.Setenv("CI", "true")
+	assert.Equal(t, "ci", DetectEnv())
+}
+
+func TestDefault_OrigenesPorEntorno(t *testing.T) {
+	assert.Equal(t, []string{"https://frontqa.onrender.com"}, Default("prod").CORS.AllowOrigins)
+	assert.Contains(t, Default("qa").CORS.AllowOrigins, "https://frontqa-t0a9.onrender.com")
+	assert.Contains(t, Default("qa").CORS.AllowOrigins, "http://localhost:5173")
+	assert.Contains(t, Default("qa").CORS.AllowOrigins, "https://frontqa.onrender.com", "sin APP_ENV Render es qa y sirve al frontend de producción")
+	assert.NotContains(t, Default("ci").CORS.AllowOrigins, "https://frontqa-t0a9.onrender.com")
+	assert.Contains(t, Default("ci").CORS.AllowHeaders, "X-Env")
+}
+
+func TestValidate_OrigenesCORS(t *testing.T) {
+	cfg := Default("test")
+	cfg.Database.Driver = "sqlite"
+	cfg.JWT.Secret = "x"
+	cfg.CORS.AllowOrigins = []string{"https://*.onrender.com", "http://localhost:5173"}
+	assert.NoError(t, cfg.Validate())
+
+	for _, origen := range []string{"frontqa.onrender.com", "https://front.com/app", "https://a.*.com", "*"} {
+		cfg.CORS.AllowOrigins = []string{origen}
+		assert.Error(t, cfg.Validate(), origen)
+	}
+
+	cfg.CORS.AllowCredentials = false
+	cfg.CORS.AllowOrigins = []string{"*"}
+	assert.NoError(t, cfg.Validate())
+}
+
+func TestValidate_SQLiteSinCgo(t *testing.T) {
+	cfg := Default("test")
+	cfg.Database.Driver = "sqlite"
+	cfg.JWT.Secret = "x"
+	require.NoError(t, cfg.Validate())
+
+	sqliteDisponible = false
+	defer func() { sqliteDisponible = conCgo }()
+	err := cfg.Validate()
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "CGO_ENABLED=1")
+}
+
+func TestValidate_TrustedProxies(t *testing.T) {
+	cfg := Default("test")
+	cfg.Database.Driver = "sqlite"
+	cfg.JWT.Secret = "x"
+	assert.Empty(t, cfg.Server.TrustedProxies, "fuera de Render no se cree ningún X-Forwarded-For")
+	assert.Contains(t, Default("qa").Server.TrustedProxies, "10.0.0.0/8")
+	assert.Equal(t, Default("qa").Server.TrustedProxies, Default("prod").Server.TrustedProxies)
+
+	cfg.Server.TrustedProxies = []string{"10.0.0.0/8", "192.168.1.10", "::1"}
+	assert.NoError(t, cfg.Validate())
+
+	cfg.Server.TrustedProxies = []string{"render.com"}
+	assert.ErrorContains(t, cfg.Validate(), "TRUSTED_PROXIES")
+}
@@ -0,0 +1,8 @@
+//go:build !cgo
+
+package config
+
+// conCgo indica si el binario se compiló con cgo, que el driver de SQLite
+// (mattn/go-sqlite3) necesita. Con CGO_ENABLED=0 el driver compila igual
+// pero falla recién al abrir la base.
+const conCgo = false
@@ -12,6 +12,7 @@ import (
 
 	"github.com/gin-gonic/gin"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 	"gorm.io/gorm"
 )
 
@@ -111,9 +112,9 @@ func TestCrearProducto_CreateError(t *testing.T) {
 
 // Test happy path: Registrar compra actualiza stock y crea compra
 func TestRegistrarCompra_HappyPath(t *testing.T) {
-	// Arrange: producto con stock 5
-	prod := models.Producto{Model: gorm.Model{ID: 1}, Nombre: "P1", Precio: 10.0, Stock: 5}
-	mock := &mocks.MockDB{Productos: []models.Producto{prod}}
+	// Arrange: producto con stock 5 (MemoryDB: el stock se actualiza por ID)
+	mock := mocks.NewMemoryDB()
+	require.NoError(t, mock.Create(&models.Producto{Model: gorm.Model{ID: 1}, Nombre: "P1", Precio: 10.0, Stock: 5}))
 	database.GetDB = func(c *gin.Context) database.DBHandler { return mock }
 
 	gin.SetMode(gin.TestMode)
@@ -130,21 +131,21 @@ func TestRegistrarCompra_HappyPath(t *testing.T) {
 
 	// Assert: creado y stock incrementado a 8
 	assert.Equal(t, http.StatusCreated, resp.Code)
-	if len(mock.Productos) > 0 {
-		assert.Equal(t, 8, mock.Productos[0].Stock)
-	}
+	var producto models.Producto
+	require.NoError(t, mock.First(&producto, uint(1)))
+	assert.Equal(t, 8, producto.Stock)
 }
 
 // Test happy path: Registrar venta calcula precio y decrementa stock
 func TestRegistrarVenta_HappyPath(t *testing.T) {
 	// Arrange: producto con stock 10, precio 20
-	prod := models.Producto{Model: gorm.Model{ID: 2}, Nombre: "P2", Precio: 20.0, Stock: 10}
-	mock := &mocks.MockDB{Productos: []models.Producto{prod}}
+	mock := mocks.NewMemoryDB()
+	require.NoError(t, mock.Create(&models.Producto{Model: gorm.Model{ID: 2}, Nombre: "P2", Precio: 20.0, Stock: 10}))
 	database.GetDB = func(c *gin.Context) database.DBHandler { return mock }
 
 	gin.SetMode(gin.TestMode)
 	router := gin.Default()
-	router.POST("/ventas", RegistrarVenta)
+	router.POST("/ventas", comoUsuario(vendedorDePrueba), RegistrarVenta)
 
 	body := `{"producto_id":2,"cantidad":2,"descuento":10}`
 	req, _ := http.NewRequest("POST", "/ventas", bytes.NewBufferString(body))
@@ -156,7 +157,7 @@ func TestRegistrarVenta_HappyPath(t *testing.T) {
 
 	// Assert: creado y stock decrementado a 8
 	assert.Equal(t, http.StatusCreated, resp.Code)
-	if len(mock.Productos) > 0 {
-		assert.Equal(t, 8, mock.Productos[0].Stock)
-	}
+	var producto models.Producto
+	require.NoError(t, mock.First(&producto, uint(2)))
+	assert.Equal(t, 8, producto.Stock)
 }
@@ -0,0 +1,109 @@
+package controllers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+	"ventas-app/database"
+	"ventas-app/middleware"
+	"ventas-app/models"
+	"ventas-app/services"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+type CrearAPIKeyInput struct {
+	Nombre    string     `json:"nombre" binding:"required"`
+	Scopes    []string   `json:"scopes" binding:"required"`
+	ExpiraEn  *time.Time `json:"expira_en"`
+	UsuarioID *uint      `json:"usuario_id"` // propietario de la key; por defecto quien la crea
+}
+
+// CrearAPIKey genera una API key (uso de admin). La clave solo se muestra en
+// esta respuesta.
+func CrearAPIKey(c *gin.Context) {
+	db := database.GetDB(c)
+
+	var input CrearAPIKeyInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Datos inválidos"})
+		return
+	}
+	if input.ExpiraEn != nil && !input.ExpiraEn.After(time.Now()) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "La fecha de vencimiento ya pasó"})
+		return
+	}
+
+	propietario, ok := middleware.CurrentUser(c)
+	if input.UsuarioID != nil {
+		if err := db.First(&propietario, *input.UsuarioID); err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				c.JSON(http.StatusNotFound, gin.H{"error": "Usuario no encontrado"})
+				return
+			}
+			responderErrorDB(c, err, http.StatusInternalServerError, "Error al buscar el usuario")
+			return
+		}
+		ok = true
+	}
+	if !ok {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Falta el usuario propietario de la API key"})
+		return
+	}
+
+	key, clave, err := services.CrearAPIKey(db, propietario, input.Nombre, input.Scopes, input.ExpiraEn)
+	if errors.Is(err, services.ErrScopeInvalido) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Scope inválido", "detalle": err.Error()})
+		return
+	}
+	if err != nil {
+		responderErrorDB(c, err, http.StatusInternalServerError, "No se pudo crear la API key")
+		return
+	}
+
+	c.Header("Cache-Control", "no-store")
+	c.JSON(http.StatusCreated, gin.H{"clave": clave, "api_key": key})
+}
+
+// ListarAPIKeys devuelve las API keys (sin el hash), incluidas las revocadas.
+func ListarAPIKeys(c *gin.Context) {
+	db := database.GetDB(c)
+
+	var keys []models.APIKey
+	if err := db.Find(&keys); err != nil {
+		responderErrorDB(c, err, http.StatusInternalServerError, "Error al listar las API keys")
+		return
+	}
+
+	c.JSON(http.StatusOK, keys)
+}
+
+// RevocarAPIKey invalida una API key (uso de admin).
+func RevocarAPIKey(c *gin.Context) {
+	db := database.GetDB(c)
+
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "ID inválido"})
+		return
+	}
+
+	var key models.APIKey
+	if err := db.First(&key, uint(id)); err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "API key no encontrada"})
+			return
+		}
+		responderErrorDB(c, err, http.StatusInternalServerError, "Error al buscar la API key")
+		return
+	}
+
+	if err := services.RevocarAPIKey(db, &key); err != nil {
+		responderErrorDB(c, err, http.StatusInternalServerError, "No se pudo revocar la API key")
+		return
+	}
+
+	c.JSON(http.StatusOK, key)
+}
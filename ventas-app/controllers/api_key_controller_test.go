@@ -0,0 +1,60 @@
+package controllers
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+	"ventas-app/mocks"
+	"ventas-app/models"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+// Test: alta, listado y revocación de API keys
+func TestAPIKeys(t *testing.T) {
+	mock := mocks.NewMemoryDB()
+	mock.Create(&models.Usuario{Nombre: "ecommerce", Clave: "x", Rol: "vendedor"})
+
+	usarDB(t, mock)
+
+	gin.SetMode(gin.TestMode)
+	router := gin.Default()
+	router.POST("/api-keys", CrearAPIKey)
+	router.GET("/api-keys", ListarAPIKeys)
+	router.DELETE("/api-keys/:id", RevocarAPIKey)
+
+	for _, caso := range []struct {
+		body     string
+		esperado int
+	}{
+		{`{"nombre": "sync", "scopes": ["usuarios:write"], "usuario_id": 1}`, http.StatusBadRequest},
+		{`{"nombre": "sync", "scopes": ["ventas:write"], "usuario_id": 1, "expira_en": "2000-01-01T00:00:00Z"}`, http.StatusBadRequest},
+		{`{"nombre": "sync", "scopes": ["ventas:write"], "usuario_id": 99}`, http.StatusNotFound},
+		{`{"nombre": "sync", "scopes": ["ventas:write"]}`, http.StatusBadRequest},
+	} {
+		assert.Equal(t, caso.esperado, pedir(router, "POST", "/api-keys", caso.body).Code, caso.body)
+	}
+
+	resp := pedir(router, "POST", "/api-keys", `{"nombre": "sync", "scopes": ["ventas:write", "productos:read"], "usuario_id": 1}`)
+	assert.Equal(t, http.StatusCreated, resp.Code)
+	var creada struct {
+		Clave  string        `json:"clave"`
+		APIKey models.APIKey `json:"api_key"`
+	}
+	json.Unmarshal(resp.Body.Bytes(), &creada)
+	assert.NotEmpty(t, creada.Clave)
+	assert.Equal(t, "productos:read ventas:write", creada.APIKey.Scopes)
+
+	resp = pedir(router, "GET", "/api-keys", "")
+	assert.Equal(t, http.StatusOK, resp.Code)
+	assert.NotContains(t, resp.Body.String(), creada.Clave)
+	assert.NotContains(t, resp.Body.String(), `"hash"`)
+
+	assert.Equal(t, http.StatusNotFound, pedir(router, "DELETE", "/api-keys/99", "").Code)
+	assert.Equal(t, http.StatusOK, pedir(router, "DELETE", "/api-keys/1", "").Code)
+
+	var key models.APIKey
+	assert.NoError(t, mock.First(&key, uint(1)))
+	assert.NotNil(t, key.RevocadaEn)
+}
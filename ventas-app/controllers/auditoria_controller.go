@@ -0,0 +1,72 @@
+package controllers
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+	"ventas-app/database"
+	"ventas-app/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	limiteAuditoria    = 100
+	maxLimiteAuditoria = 1000
+)
+
+// ListarAuditoria devuelve los eventos de auditoría más recientes primero
+// (uso de admin). Filtros opcionales: entidad, entidad_id, usuario_id,
+// operacion, request_id, desde y hasta (RFC 3339) y limite (100 por defecto,
+// 1000 como máximo).
+func ListarAuditoria(c *gin.Context) {
+	db := database.GetDB(c)
+
+	for _, campo := range []string{"entidad", "operacion", "request_id"} {
+		if v := c.Query(campo); v != "" {
+			db = db.Where(campo+" = ?", v)
+		}
+	}
+	for _, campo := range []string{"entidad_id", "usuario_id"} {
+		v := c.Query(campo)
+		if v == "" {
+			continue
+		}
+		id, err := strconv.ParseUint(v, 10, 64)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": campo + " inválido"})
+			return
+		}
+		db = db.Where(campo+" = ?", uint(id))
+	}
+	for campo, op := range map[string]string{"desde": ">=", "hasta": "<="} {
+		v := c.Query(campo)
+		if v == "" {
+			continue
+		}
+		t, err := time.Parse(time.RFC3339, v)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Fecha '" + campo + "' inválida, usar RFC 3339"})
+			return
+		}
+		db = db.Where("created_at "+op+" ?", t)
+	}
+
+	limite := limiteAuditoria
+	if v := c.Query("limite"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 || n > maxLimiteAuditoria {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "limite debe estar entre 1 y 1000"})
+			return
+		}
+		limite = n
+	}
+
+	eventos := []models.AuditoriaEvento{}
+	if err := db.Order("id DESC").Limit(limite).Find(&eventos); err != nil {
+		responderErrorDB(c, err, http.StatusInternalServerError, "Error al listar la auditoría")
+		return
+	}
+
+	c.JSON(http.StatusOK, eventos)
+}
@@ -0,0 +1,70 @@
+package controllers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"ventas-app/database"
+	"ventas-app/mocks"
+	"ventas-app/models"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+// Test: filtros, orden y límite del listado de auditoría
+func TestListarAuditoria(t *testing.T) {
+	mock := mocks.NewMemoryDB()
+	ana := uint(1)
+	for _, ev := range []models.AuditoriaEvento{
+		{Entidad: "Producto", EntidadID: 1, Operacion: models.AuditoriaCrear, UsuarioID: &ana, RequestID: "r1", Cambios: `{"precio":10}`},
+		{Entidad: "Producto", EntidadID: 1, Operacion: models.AuditoriaActualizar, RequestID: "r2", Cambios: `{"precio":{"antes":10,"despues":12}}`},
+		{Entidad: "Venta", EntidadID: 1, Operacion: models.AuditoriaCrear, UsuarioID: &ana, RequestID: "r2"},
+	} {
+		ev := ev
+		mock.Create(&ev)
+	}
+
+	database.GetDB = func(c *gin.Context) database.DBHandler {
+		return mock
+	}
+
+	gin.SetMode(gin.TestMode)
+	router := gin.Default()
+	router.GET("/auditoria", ListarAuditoria)
+	listar := func(query string) (int, []models.AuditoriaEvento) {
+		req, _ := http.NewRequest("GET", "/auditoria"+query, nil)
+		resp := httptest.NewRecorder()
+		router.ServeHTTP(resp, req)
+		var eventos []models.AuditoriaEvento
+		json.Unmarshal(resp.Body.Bytes(), &eventos)
+		return resp.Code, eventos
+	}
+
+	code, eventos := listar("")
+	assert.Equal(t, http.StatusOK, code)
+	if assert.Len(t, eventos, 3) {
+		assert.Equal(t, uint(3), eventos[0].ID, "más recientes primero")
+		assert.JSONEq(t, `{"precio":{"antes":10,"despues":12}}`, string(eventos[1].Cambios))
+	}
+
+	for query, esperados := range map[string]int{
+		"?entidad=Producto":                      2,
+		"?entidad=Producto&operacion=actualizar": 1,
+		"?usuario_id=1":                          2,
+		"?request_id=r2":                         2,
+		"?entidad_id=1&limite=1":                 1,
+		"?desde=2000-01-01T00:00:00Z":            3,
+		"?hasta=2000-01-01T00:00:00Z":            0,
+	} {
+		code, eventos := listar(query)
+		assert.Equal(t, http.StatusOK, code, query)
+		assert.Len(t, eventos, esperados, query)
+	}
+
+	for _, query := range []string{"?usuario_id=x", "?desde=ayer", "?limite=0", "?limite=5000"} {
+		code, _ := listar(query)
+		assert.Equal(t, http.StatusBadRequest, code, query)
+	}
+}
@@ -1,14 +1,22 @@
 package controllers
 
 import (
+	"errors"
+	"log"
 	"net/http"
+	"strconv"
+	"sync"
+	"time"
+	"ventas-app/middleware"
 	"ventas-app/models"
+	"ventas-app/services"
 	"ventas-app/utils"
 
 	"ventas-app/database"
 
 	"github.com/gin-gonic/gin"
 	"golang.org/x/crypto/bcrypt"
+	"gorm.io/gorm"
 )
 
 type LoginInput struct {
@@ -16,6 +24,32 @@ type LoginInput struct {
 	Clave  string `json:"clave"`
 }
 
+// LimitadorLogin frena los logins fallidos por IP. main lo reemplaza con
+// los valores de config.LoginConfig.
+var LimitadorLogin = services.NuevoLimitadorIP(20, 15*time.Minute, 15*time.Minute)
+
+// mensajeCredenciales es la única respuesta ante un login fallido: no revela
+// si el usuario existe, si la clave es incorrecta o si la cuenta está bloqueada.
+const mensajeCredenciales = "Credenciales inválidas"
+
+var (
+	hashFicticioOnce sync.Once
+	hashFicticio     []byte
+)
+
+// compararClave corre bcrypt aunque el usuario no exista, para que el tiempo
+// de respuesta no delate qué nombres son válidos.
+func compararClave(hash, clave string) bool {
+	if hash == "" {
+		hashFicticioOnce.Do(func() {
+			hashFicticio, _ = bcrypt.GenerateFromPassword([]byte("clave-ficticia"), services.CostoBcrypt)
+		})
+		bcrypt.CompareHashAndPassword(hashFicticio, []byte(clave))
+		return false
+	}
+	return bcrypt.CompareHashAndPassword([]byte(hash), []byte(clave)) == nil
+}
+
 func Login(c *gin.Context) {
 	db := database.GetDB(c) // ← función centralizada en database/db_selector.go
 
@@ -25,26 +59,205 @@ func Login(c *gin.Context) {
 		return
 	}
 
+	ip := c.ClientIP()
+	if espera, bloqueada := LimitadorLogin.Bloqueado(ip); bloqueada {
+		c.Header("Retry-After", strconv.Itoa(int(espera.Seconds())+1))
+		c.JSON(http.StatusTooManyRequests, gin.H{"error": "Demasiados intentos, reintente más tarde"})
+		return
+	}
+
 	var user models.Usuario
-	if err := db.Where("nombre = ?", input.Nombre).First(&user); err != nil {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "Usuario no encontrado"})
+	err := db.Where("nombre = ?", input.Nombre).First(&user)
+	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		responderErrorDB(c, err, http.StatusInternalServerError, "Error al verificar el usuario")
 		return
 	}
+	existe := err == nil
+
+	claveOK := compararClave(user.Clave, input.Clave)
 
-	if err := bcrypt.CompareHashAndPassword([]byte(user.Clave), []byte(input.Clave)); err != nil {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "Clave incorrecta"})
+	var motivo string
+	switch {
+	case !existe:
+		motivo = models.LoginUsuarioInexistente
+	case user.Bloqueado(time.Now()):
+		motivo = models.LoginBloqueado
+	case !claveOK:
+		motivo = models.LoginClaveIncorrecta
+	case !user.Habilitado():
+		motivo = models.LoginDeshabilitado
+	default:
+		motivo = models.LoginOK
+	}
+
+	var usuario *models.Usuario
+	if existe {
+		usuario = &user
+	}
+
+	if motivo != models.LoginOK {
+		if motivo != models.LoginDeshabilitado {
+			LimitadorLogin.Fallo(ip)
+		}
+		// Si no se puede auditar el fallo igual se rechaza el login
+		if err := services.RegistrarLogin(db, usuario, input.Nombre, ip, motivo); err != nil {
+			log.Printf("No se pudo registrar el login fallido de %q: %v", input.Nombre, err)
+		}
+		if motivo == models.LoginDeshabilitado {
+			c.JSON(http.StatusForbidden, gin.H{"error": "Usuario deshabilitado"})
+			return
+		}
+		c.JSON(http.StatusUnauthorized, gin.H{"error": mensajeCredenciales})
 		return
 	}
 
-	token, err := utils.GenerateToken(user.ID, user.Rol)
-	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "No se pudo generar el token"})
+	// Con doble factor la sesión se entrega recién en /login/mfa
+	if services.RequiereMFA(user) {
+		if err := services.ActualizarHash(db, &user, input.Clave); err != nil {
+			responderErrorDB(c, err, http.StatusInternalServerError, "No se pudo generar el token")
+			return
+		}
+		token, err := services.IniciarMFA(user, database.EnvSeleccionado(c))
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "No se pudo generar el token"})
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{
+			"mfa_requerido": true,
+			"mfa_token":     token,
+			"enrolar":       !user.TOTPActivo(),
+			"expira_en":     int(services.MFATTL.Seconds()),
+		})
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{
-		"token": token,
-		"rol":   user.Rol, // ← esto depende de tu modelo
+	var sesion services.Sesion
+	err = db.Transaction(func(tx database.DBHandler) error {
+		if err := services.RegistrarLogin(tx, usuario, input.Nombre, ip, motivo); err != nil {
+			return err
+		}
+		// Si subió el costo de bcrypt, aprovechar que se tiene la clave en claro
+		if err := services.ActualizarHash(tx, &user, input.Clave); err != nil {
+			return err
+		}
+		var err error
+		sesion, err = services.IniciarSesion(tx, user, database.EnvSeleccionado(c))
+		return err
 	})
+	if err != nil {
+		responderErrorDB(c, err, http.StatusInternalServerError, "No se pudo generar el token")
+		return
+	}
+
+	responderSesion(c, sesion)
+}
+
+type RefreshInput struct {
+	RefreshToken string `json:"refresh_token" binding:"required"`
+}
+
+// Refresh canjea un refresh token por un par nuevo. El token usado deja de
+// servir; reusarlo revoca todas las sesiones que salieron de él.
+func Refresh(c *gin.Context) {
+	db := database.GetDB(c)
+
+	var input RefreshInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Datos inválidos"})
+		return
+	}
 
+	sesion, err := services.RenovarSesion(db, input.RefreshToken, database.EnvSeleccionado(c))
+	if errors.Is(err, services.ErrRefreshInvalido) {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Refresh token inválido"})
+		return
+	}
+	if err != nil {
+		responderErrorDB(c, err, http.StatusInternalServerError, "No se pudo renovar la sesión")
+		return
+	}
+
+	responderSesion(c, sesion)
+}
+
+type LogoutInput struct {
+	RefreshToken string `json:"refresh_token"`
+}
+
+// Logout revoca el access token del request y, si se envía, el refresh token.
+// Requiere AuthRequired antes.
+func Logout(c *gin.Context) {
+	db := database.GetDB(c)
+
+	var input LogoutInput
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&input); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Datos inválidos"})
+			return
+		}
+	}
+
+	claims, _ := middleware.CurrentClaims(c)
+	if err := services.CerrarSesion(db, claims, input.RefreshToken); err != nil {
+		responderErrorDB(c, err, http.StatusInternalServerError, "No se pudo cerrar la sesión")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"mensaje": "Sesión cerrada"})
+}
+
+// RevocarSesiones invalida todos los tokens de un usuario (uso de admin, por
+// ejemplo al dar de baja a un empleado).
+func RevocarSesiones(c *gin.Context) {
+	db := database.GetDB(c)
+
+	usuario, ok := buscarUsuarioParam(c, db)
+	if !ok {
+		return
+	}
+
+	if err := services.RevocarSesiones(db, &usuario); err != nil {
+		responderErrorDB(c, err, http.StatusInternalServerError, "No se pudieron revocar las sesiones")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"mensaje": "Sesiones revocadas"})
+}
+
+// JWKS publica las claves públicas de firma. Con claves HMAC la lista queda vacía.
+func JWKS(c *gin.Context) {
+	c.Header("Cache-Control", "public, max-age=300")
+	c.JSON(http.StatusOK, gin.H{"keys": utils.JWKS()})
+}
+
+// DesbloquearUsuario quita el bloqueo por intentos fallidos (uso de admin).
+func DesbloquearUsuario(c *gin.Context) {
+	db := database.GetDB(c)
+
+	usuario, ok := buscarUsuarioParam(c, db)
+	if !ok {
+		return
+	}
+
+	if err := services.DesbloquearUsuario(db, &usuario); err != nil {
+		responderErrorDB(c, err, http.StatusInternalServerError, "No se pudo desbloquear el usuario")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"mensaje": "Usuario desbloqueado"})
+}
+
+func responderSesion(c *gin.Context, sesion services.Sesion) {
+	c.JSON(http.StatusOK, datosSesion(sesion))
+}
+
+func datosSesion(sesion services.Sesion) gin.H {
+	return gin.H{
+		"token":         sesion.AccessToken,
+		"refresh_token": sesion.RefreshToken,
+		"expira_en":     int(utils.AccessTTL.Seconds()),
+		"rol":           sesion.Usuario.Rol, // ← esto depende de tu modelo
+
+		"debe_cambiar_clave": sesion.Usuario.DebeCambiarClave,
+	}
 }
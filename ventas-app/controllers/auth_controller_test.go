@@ -5,15 +5,19 @@ import (
 	"encoding/json"
 	"net/http"
 	"net/http/httptest"
+	"strconv"
 	"testing"
+	"time"
+	"ventas-app/config"
 	"ventas-app/database"
 	"ventas-app/mocks"
 	"ventas-app/models"
+	"ventas-app/services"
+	"ventas-app/utils"
 
 	"github.com/gin-gonic/gin"
 	"github.com/stretchr/testify/assert"
 	"golang.org/x/crypto/bcrypt"
-	"gorm.io/gorm"
 )
 
 func TestLogin_InvalidBody(t *testing.T) {
@@ -57,20 +61,18 @@ func TestLogin_MalformedJSON(t *testing.T) {
 
 // Test: Login exitoso
 func TestLogin_Success(t *testing.T) {
+	utils.SetSecret("test-secret-key")
+
 	// Crear clave hasheada para el test
 	hashedPassword, _ := bcrypt.GenerateFromPassword([]byte("password123"), bcrypt.DefaultCost)
 	
-	mock := &mocks.MockDB{
-		Usuarios: []models.Usuario{
-			{
-				Model:  gorm.Model{ID: 1},
-				Nombre: "testuser",
-				Clave:  string(hashedPassword),
-				Rol:    "vendedor",
-			},
-		},
-		ShouldErr: false,
-	}
+	otraClave, _ := bcrypt.GenerateFromPassword([]byte("otra"), bcrypt.DefaultCost)
+
+	// MemoryDB evalúa el Where de verdad: "otro" va primero para que un
+	// First que ignore el filtro haga fallar el test.
+	mock := mocks.NewMemoryDB()
+	mock.Create(&models.Usuario{Nombre: "otro", Clave: string(otraClave), Rol: "comprador"})
+	mock.Create(&models.Usuario{Nombre: "testuser", Clave: string(hashedPassword), Rol: "vendedor"})
 
 	database.GetDB = func(c *gin.Context) database.DBHandler {
 		return mock
@@ -98,10 +100,11 @@ func TestLogin_Success(t *testing.T) {
 
 // Test: Usuario no encontrado
 func TestLogin_UserNotFound(t *testing.T) {
-	mock := &mocks.MockDB{
-		Usuarios:  []models.Usuario{}, // Sin usuarios
-		ShouldErr: false,
-	}
+	hashedPassword, _ := bcrypt.GenerateFromPassword([]byte("password123"), bcrypt.DefaultCost)
+
+	// Hay un usuario con la misma clave, pero no con el nombre buscado
+	mock := mocks.NewMemoryDB()
+	mock.Create(&models.Usuario{Nombre: "testuser", Clave: string(hashedPassword), Rol: "vendedor"})
 
 	database.GetDB = func(c *gin.Context) database.DBHandler {
 		return mock
@@ -125,17 +128,13 @@ func TestLogin_UserNotFound(t *testing.T) {
 func TestLogin_WrongPassword(t *testing.T) {
 	hashedPassword, _ := bcrypt.GenerateFromPassword([]byte("correctpassword"), bcrypt.DefaultCost)
 	
-	mock := &mocks.MockDB{
-		Usuarios: []models.Usuario{
-			{
-				Model:  gorm.Model{ID: 1},
-				Nombre: "testuser",
-				Clave:  string(hashedPassword),
-				Rol:    "vendedor",
-			},
-		},
-		ShouldErr: false,
-	}
+	otraClave, _ := bcrypt.GenerateFromPassword([]byte("otra"), bcrypt.DefaultCost)
+
+	// MemoryDB evalúa el Where de verdad: "otro" va primero para que un
+	// First que ignore el filtro haga fallar el test.
+	mock := mocks.NewMemoryDB()
+	mock.Create(&models.Usuario{Nombre: "otro", Clave: string(otraClave), Rol: "comprador"})
+	mock.Create(&models.Usuario{Nombre: "testuser", Clave: string(hashedPassword), Rol: "vendedor"})
 
 	database.GetDB = func(c *gin.Context) database.DBHandler {
 		return mock
@@ -154,3 +153,255 @@ func TestLogin_WrongPassword(t *testing.T) {
 
 	assert.Equal(t, http.StatusUnauthorized, resp.Code)
 }
+
+// Test: revocar sesiones de un usuario
+func TestRevocarSesiones(t *testing.T) {
+	mock := mocks.NewMemoryDB()
+	mock.Create(&models.Usuario{Nombre: "ana", Clave: "x", Rol: "vendedor"})
+
+	database.GetDB = func(c *gin.Context) database.DBHandler {
+		return mock
+	}
+
+	gin.SetMode(gin.TestMode)
+	router := gin.Default()
+	router.POST("/usuarios/:id/revocar-sesiones", RevocarSesiones)
+
+	for _, caso := range []struct {
+		path     string
+		esperado int
+	}{
+		{"/usuarios/1/revocar-sesiones", http.StatusOK},
+		{"/usuarios/99/revocar-sesiones", http.StatusNotFound},
+		{"/usuarios/abc/revocar-sesiones", http.StatusBadRequest},
+	} {
+		req, _ := http.NewRequest("POST", caso.path, nil)
+		resp := httptest.NewRecorder()
+		router.ServeHTTP(resp, req)
+
+		assert.Equal(t, caso.esperado, resp.Code, caso.path)
+	}
+
+	var usuario models.Usuario
+	assert.NoError(t, mock.First(&usuario, uint(1)))
+	assert.NotNil(t, usuario.SesionesRevocadasEn)
+}
+
+// Test: refresh con token inexistente
+func TestRefresh_TokenInvalido(t *testing.T) {
+	database.GetDB = func(c *gin.Context) database.DBHandler {
+		return mocks.NewMemoryDB()
+	}
+
+	gin.SetMode(gin.TestMode)
+	router := gin.Default()
+	router.POST("/refresh", Refresh)
+
+	req, _ := http.NewRequest("POST", "/refresh", bytes.NewBufferString(`{"refresh_token": "inventado"}`))
+	req.Header.Set("Content-Type", "application/json")
+	resp := httptest.NewRecorder()
+	router.ServeHTTP(resp, req)
+
+	assert.Equal(t, http.StatusUnauthorized, resp.Code)
+	assert.Contains(t, resp.Body.String(), "Refresh token inválido")
+}
+
+// Test: usuario deshabilitado no puede iniciar sesión
+func TestLogin_UsuarioDeshabilitado(t *testing.T) {
+	utils.SetSecret("test-secret-key")
+	hashedPassword, _ := bcrypt.GenerateFromPassword([]byte("password123"), bcrypt.MinCost)
+
+	ahora := time.Now()
+	mock := mocks.NewMemoryDB()
+	mock.Create(&models.Usuario{Nombre: "testuser", Clave: string(hashedPassword), Rol: "vendedor", DeshabilitadoEn: &ahora})
+
+	database.GetDB = func(c *gin.Context) database.DBHandler {
+		return mock
+	}
+
+	gin.SetMode(gin.TestMode)
+	router := gin.Default()
+	router.POST("/login", Login)
+
+	body := `{"nombre": "testuser", "clave": "password123"}`
+	req, _ := http.NewRequest("POST", "/login", bytes.NewBufferString(body))
+	req.Header.Set("Content-Type", "application/json")
+	resp := httptest.NewRecorder()
+	router.ServeHTTP(resp, req)
+
+	assert.Equal(t, http.StatusForbidden, resp.Code)
+	assert.NotContains(t, resp.Body.String(), "token")
+}
+
+// Test: mismo mensaje para usuario inexistente y clave incorrecta, con bloqueo
+func TestLogin_MensajeUniformeYBloqueo(t *testing.T) {
+	utils.SetSecret("test-secret-key")
+	LimitadorLogin = services.NuevoLimitadorIP(100, time.Minute, time.Minute)
+	hashedPassword, _ := bcrypt.GenerateFromPassword([]byte("password123"), bcrypt.MinCost)
+
+	mock := mocks.NewMemoryDB()
+	mock.Create(&models.Usuario{Nombre: "testuser", Clave: string(hashedPassword), Rol: "vendedor"})
+
+	database.GetDB = func(c *gin.Context) database.DBHandler {
+		return mock
+	}
+
+	gin.SetMode(gin.TestMode)
+	router := gin.Default()
+	router.POST("/login", Login)
+	login := func(body string) *httptest.ResponseRecorder {
+		req, _ := http.NewRequest("POST", "/login", bytes.NewBufferString(body))
+		req.Header.Set("Content-Type", "application/json")
+		resp := httptest.NewRecorder()
+		router.ServeHTTP(resp, req)
+		return resp
+	}
+
+	inexistente := login(`{"nombre": "nadie", "clave": "password123"}`)
+	incorrecta := login(`{"nombre": "testuser", "clave": "otra"}`)
+	assert.Equal(t, http.StatusUnauthorized, inexistente.Code)
+	assert.Equal(t, inexistente.Body.String(), incorrecta.Body.String())
+
+	for i := 1; i < services.MaxIntentosUsuario; i++ {
+		login(`{"nombre": "testuser", "clave": "otra"}`)
+	}
+
+	// Bloqueado: ni con la clave correcta, y con el mismo mensaje
+	bloqueado := login(`{"nombre": "testuser", "clave": "password123"}`)
+	assert.Equal(t, http.StatusUnauthorized, bloqueado.Code)
+	assert.Equal(t, inexistente.Body.String(), bloqueado.Body.String())
+
+	var eventos []models.LoginEvento
+	assert.NoError(t, mock.Find(&eventos))
+	assert.Len(t, eventos, services.MaxIntentosUsuario+2)
+	assert.Equal(t, models.LoginBloqueado, eventos[len(eventos)-1].Motivo)
+
+	// Un admin lo desbloquea
+	router.POST("/usuarios/:id/desbloquear", DesbloquearUsuario)
+	req, _ := http.NewRequest("POST", "/usuarios/1/desbloquear", nil)
+	resp := httptest.NewRecorder()
+	router.ServeHTTP(resp, req)
+	assert.Equal(t, http.StatusOK, resp.Code)
+
+	assert.Equal(t, http.StatusOK, login(`{"nombre": "testuser", "clave": "password123"}`).Code)
+	assert.NoError(t, mock.Where("exito = ?", true).Find(&eventos))
+	assert.Len(t, eventos, 1)
+}
+
+// Test: demasiados fallos desde la misma IP
+func TestLogin_LimitePorIP(t *testing.T) {
+	LimitadorLogin = services.NuevoLimitadorIP(3, time.Minute, time.Minute)
+	defer func() { LimitadorLogin = services.NuevoLimitadorIP(20, 15*time.Minute, 15*time.Minute) }()
+
+	database.GetDB = func(c *gin.Context) database.DBHandler {
+		return mocks.NewMemoryDB()
+	}
+
+	gin.SetMode(gin.TestMode)
+	router := gin.Default()
+	router.POST("/login", Login)
+
+	var resp *httptest.ResponseRecorder
+	for i := 0; i < 4; i++ {
+		req, _ := http.NewRequest("POST", "/login", bytes.NewBufferString(`{"nombre": "usuario`+strconv.Itoa(i)+`", "clave": "x"}`))
+		req.Header.Set("Content-Type", "application/json")
+		req.RemoteAddr = "203.0.113.7:5000"
+		resp = httptest.NewRecorder()
+		router.ServeHTTP(resp, req)
+	}
+
+	assert.Equal(t, http.StatusTooManyRequests, resp.Code)
+	assert.NotEmpty(t, resp.Header().Get("Retry-After"))
+}
+
+// Test: sin proxies de confianza, un X-Forwarded-For distinto en cada intento
+// no esquiva el límite por IP
+func TestLogin_LimitePorIPIgnoraForwardedFor(t *testing.T) {
+	LimitadorLogin = services.NuevoLimitadorIP(3, time.Minute, time.Minute)
+	defer func() { LimitadorLogin = services.NuevoLimitadorIP(20, 15*time.Minute, 15*time.Minute) }()
+
+	database.GetDB = func(c *gin.Context) database.DBHandler {
+		return mocks.NewMemoryDB()
+	}
+
+	gin.SetMode(gin.TestMode)
+	router := gin.Default()
+	assert.NoError(t, router.SetTrustedProxies(nil))
+	router.POST("/login", Login)
+
+	var resp *httptest.ResponseRecorder
+	for i := 0; i < 4; i++ {
+		req, _ := http.NewRequest("POST", "/login", bytes.NewBufferString(`{"nombre": "usuario", "clave": "x"}`))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("X-Forwarded-For", "198.51.100."+strconv.Itoa(i))
+		req.RemoteAddr = "203.0.113.7:5000"
+		resp = httptest.NewRecorder()
+		router.ServeHTTP(resp, req)
+	}
+
+	assert.Equal(t, http.StatusTooManyRequests, resp.Code)
+}
+
+// Test: detrás del balanceador (proxies por defecto de qa) cada cliente
+// cuenta por su IP y no por la del proxy
+func TestLogin_LimitePorIPDetrasDelProxy(t *testing.T) {
+	LimitadorLogin = services.NuevoLimitadorIP(3, time.Minute, time.Minute)
+	defer func() { LimitadorLogin = services.NuevoLimitadorIP(20, 15*time.Minute, 15*time.Minute) }()
+	usarDB(t, mocks.NewMemoryDB())
+
+	gin.SetMode(gin.TestMode)
+	router := gin.Default()
+	assert.NoError(t, router.SetTrustedProxies(config.Default("qa").Server.TrustedProxies))
+	router.POST("/login", Login)
+
+	intentar := func(cliente string) *httptest.ResponseRecorder {
+		req, _ := http.NewRequest("POST", "/login", bytes.NewBufferString(`{"nombre": "usuario", "clave": "x"}`))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("X-Forwarded-For", cliente)
+		req.RemoteAddr = "10.20.0.5:443"
+		resp := httptest.NewRecorder()
+		router.ServeHTTP(resp, req)
+		return resp
+	}
+
+	for i := 0; i < 3; i++ {
+		intentar("198.51.100.1")
+	}
+	assert.Equal(t, http.StatusTooManyRequests, intentar("198.51.100.1").Code)
+	assert.Equal(t, http.StatusUnauthorized, intentar("198.51.100.2").Code, "otro cliente no queda bloqueado")
+	assert.Equal(t, http.StatusTooManyRequests, intentar("198.51.100.2, 198.51.100.1").Code, "lo que agrega el cliente al header no cuenta")
+}
+
+// Test: un login correcto rehashea la clave si subió el costo de bcrypt
+func TestLogin_RehasheaClave(t *testing.T) {
+	utils.SetSecret("test-secret-key")
+	costo := services.CostoBcrypt
+	defer func() { services.CostoBcrypt = costo }()
+
+	viejo, _ := bcrypt.GenerateFromPassword([]byte("password123"), bcrypt.MinCost)
+	mock := mocks.NewMemoryDB()
+	mock.Create(&models.Usuario{Nombre: "testuser", Clave: string(viejo), Rol: "vendedor"})
+
+	database.GetDB = func(c *gin.Context) database.DBHandler {
+		return mock
+	}
+
+	gin.SetMode(gin.TestMode)
+	router := gin.Default()
+	router.POST("/login", Login)
+
+	services.CostoBcrypt = bcrypt.MinCost + 1
+	body := `{"nombre": "testuser", "clave": "password123"}`
+	req, _ := http.NewRequest("POST", "/login", bytes.NewBufferString(body))
+	req.Header.Set("Content-Type", "application/json")
+	resp := httptest.NewRecorder()
+	router.ServeHTTP(resp, req)
+
+	assert.Equal(t, http.StatusOK, resp.Code)
+
+	var usuario models.Usuario
+	assert.NoError(t, mock.First(&usuario, uint(1)))
+	nuevoCosto, _ := bcrypt.Cost([]byte(usuario.Clave))
+	assert.Equal(t, bcrypt.MinCost+1, nuevoCosto)
+	assert.NoError(t, bcrypt.CompareHashAndPassword([]byte(usuario.Clave), []byte("password123")))
+}
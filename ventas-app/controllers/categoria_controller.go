@@ -0,0 +1,221 @@
+package controllers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"ventas-app/database"
+	"ventas-app/models"
+	"ventas-app/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+type CategoriaInput struct {
+	Nombre  string `json:"nombre" binding:"required"`
+	PadreID *uint  `json:"padre_id"`
+}
+
+type MarcaInput struct {
+	Nombre string `json:"nombre" binding:"required"`
+}
+
+type ClasificacionInput struct {
+	CategoriaID *uint `json:"categoria_id"`
+	MarcaID     *uint `json:"marca_id"`
+}
+
+// ListarCategorias devuelve el árbol de categorías (las raíces con sus hijas).
+func ListarCategorias(c *gin.Context) {
+	db := database.GetDB(c)
+
+	arbol, err := services.ArbolCategorias(db)
+	if err != nil {
+		responderErrorDB(c, err, http.StatusInternalServerError, "Error al listar las categorías")
+		return
+	}
+
+	c.JSON(http.StatusOK, arbol)
+}
+
+// CrearCategoria da de alta una categoría; sin padre_id es raíz.
+func CrearCategoria(c *gin.Context) {
+	guardarCategoria(c, models.Categoria{}, http.StatusCreated)
+}
+
+// ActualizarCategoria renombra o mueve una categoría con toda su rama.
+func ActualizarCategoria(c *gin.Context) {
+	cat, ok := buscarCategoriaParam(c, database.GetDB(c))
+	if !ok {
+		return
+	}
+	guardarCategoria(c, cat, http.StatusOK)
+}
+
+// BorrarCategoria elimina una categoría vacía (sin hijas ni productos).
+func BorrarCategoria(c *gin.Context) {
+	db := database.GetDB(c)
+
+	cat, ok := buscarCategoriaParam(c, db)
+	if !ok {
+		return
+	}
+	switch err := services.BorrarCategoria(db, cat); {
+	case errors.Is(err, services.ErrCategoriaEnUso):
+		c.JSON(http.StatusConflict, gin.H{"error": "La categoría tiene subcategorías o productos"})
+	case err != nil:
+		responderErrorDB(c, err, http.StatusInternalServerError, "Error al borrar la categoría")
+	default:
+		c.JSON(http.StatusOK, gin.H{"mensaje": "Categoría borrada"})
+	}
+}
+
+func guardarCategoria(c *gin.Context, cat models.Categoria, status int) {
+	db := database.GetDB(c)
+
+	var input CategoriaInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Datos inválidos"})
+		return
+	}
+	cat.Nombre = input.Nombre
+	cat.PadreID = input.PadreID
+
+	switch err := services.GuardarCategoria(db, &cat); {
+	case errors.Is(err, services.ErrCategoriaInvalida):
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Falta el nombre o la categoría padre no existe"})
+	case errors.Is(err, services.ErrCategoriaCiclo):
+		c.JSON(http.StatusBadRequest, gin.H{"error": "La categoría no puede quedar debajo de sí misma"})
+	case errors.Is(err, services.ErrCategoriaDuplicada):
+		c.JSON(http.StatusConflict, gin.H{"error": "Ya existe una categoría con ese nombre en el mismo nivel"})
+	case err != nil:
+		responderErrorDB(c, err, http.StatusInternalServerError, "Error al guardar la categoría")
+	default:
+		c.JSON(status, cat)
+	}
+}
+
+func buscarCategoriaParam(c *gin.Context, db database.DBHandler) (models.Categoria, bool) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "ID inválido"})
+		return models.Categoria{}, false
+	}
+
+	var cat models.Categoria
+	if err := db.First(&cat, uint(id)); err != nil {
+		responderErrorDB(c, err, http.StatusNotFound, "Categoría no encontrada")
+		return models.Categoria{}, false
+	}
+	return cat, true
+}
+
+// ListarMarcas devuelve las marcas por nombre.
+func ListarMarcas(c *gin.Context) {
+	db := database.GetDB(c)
+
+	marcas := []models.Marca{}
+	if err := db.Order("nombre").Find(&marcas); err != nil {
+		responderErrorDB(c, err, http.StatusInternalServerError, "Error al listar las marcas")
+		return
+	}
+
+	c.JSON(http.StatusOK, marcas)
+}
+
+// CrearMarca da de alta una marca.
+func CrearMarca(c *gin.Context) {
+	guardarMarca(c, models.Marca{}, http.StatusCreated)
+}
+
+// ActualizarMarca renombra una marca.
+func ActualizarMarca(c *gin.Context) {
+	marca, ok := buscarMarcaParam(c, database.GetDB(c))
+	if !ok {
+		return
+	}
+	guardarMarca(c, marca, http.StatusOK)
+}
+
+// BorrarMarca elimina una marca sin productos.
+func BorrarMarca(c *gin.Context) {
+	db := database.GetDB(c)
+
+	marca, ok := buscarMarcaParam(c, db)
+	if !ok {
+		return
+	}
+	switch err := services.BorrarMarca(db, marca); {
+	case errors.Is(err, services.ErrMarcaEnUso):
+		c.JSON(http.StatusConflict, gin.H{"error": "La marca tiene productos"})
+	case err != nil:
+		responderErrorDB(c, err, http.StatusInternalServerError, "Error al borrar la marca")
+	default:
+		c.JSON(http.StatusOK, gin.H{"mensaje": "Marca borrada"})
+	}
+}
+
+func guardarMarca(c *gin.Context, marca models.Marca, status int) {
+	db := database.GetDB(c)
+
+	var input MarcaInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Datos inválidos"})
+		return
+	}
+	marca.Nombre = input.Nombre
+
+	switch err := services.GuardarMarca(db, &marca); {
+	case errors.Is(err, services.ErrMarcaInvalida):
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Falta el nombre"})
+	case errors.Is(err, services.ErrMarcaDuplicada):
+		c.JSON(http.StatusConflict, gin.H{"error": "Ya existe una marca con ese nombre"})
+	case err != nil:
+		responderErrorDB(c, err, http.StatusInternalServerError, "Error al guardar la marca")
+	default:
+		c.JSON(status, marca)
+	}
+}
+
+func buscarMarcaParam(c *gin.Context, db database.DBHandler) (models.Marca, bool) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "ID inválido"})
+		return models.Marca{}, false
+	}
+
+	var marca models.Marca
+	if err := db.First(&marca, uint(id)); err != nil {
+		responderErrorDB(c, err, http.StatusNotFound, "Marca no encontrada")
+		return models.Marca{}, false
+	}
+	return marca, true
+}
+
+// ClasificarProducto asigna categoría y marca a un producto; null las quita.
+func ClasificarProducto(c *gin.Context) {
+	db := database.GetDB(c)
+
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "ID inválido"})
+		return
+	}
+	var input ClasificacionInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Datos inválidos"})
+		return
+	}
+	var producto models.Producto
+	if err := db.First(&producto, uint(id)); err != nil {
+		responderErrorDB(c, err, http.StatusNotFound, "Producto no encontrado")
+		return
+	}
+
+	if err := services.ClasificarProducto(db, &producto, input.CategoriaID, input.MarcaID); err != nil {
+		responderErrorProducto(c, err, "Error al clasificar el producto")
+		return
+	}
+
+	c.JSON(http.StatusOK, producto)
+}
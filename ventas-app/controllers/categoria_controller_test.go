@@ -0,0 +1,82 @@
+package controllers
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+	"ventas-app/mocks"
+	"ventas-app/models"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// Test: árbol de categorías, marcas, clasificación y filtro de productos
+func TestCategoriasYMarcas(t *testing.T) {
+	db := mocks.NewMemoryDB()
+	require.NoError(t, db.Create(&models.Producto{Nombre: "Yerba"}))
+	require.NoError(t, db.Create(&models.Producto{Nombre: "Arroz"}))
+	usarDB(t, db)
+
+	gin.SetMode(gin.TestMode)
+	router := gin.Default()
+	router.GET("/categorias", ListarCategorias)
+	router.POST("/categorias", CrearCategoria)
+	router.PUT("/categorias/:id", ActualizarCategoria)
+	router.DELETE("/categorias/:id", BorrarCategoria)
+	router.GET("/marcas", ListarMarcas)
+	router.POST("/marcas", CrearMarca)
+	router.DELETE("/marcas/:id", BorrarMarca)
+	router.PUT("/productos/:id/clasificacion", ClasificarProducto)
+	router.GET("/productos", ListarProductos)
+	nombres := func(path string) []string {
+		resp := pedir(router, "GET", path, "")
+		require.Equal(t, http.StatusOK, resp.Code, resp.Body.String())
+		var productos []models.Producto
+		require.NoError(t, json.Unmarshal(resp.Body.Bytes(), &productos))
+		var n []string
+		for _, p := range productos {
+			n = append(n, p.Nombre)
+		}
+		return n
+	}
+
+	assert.Equal(t, http.StatusCreated, pedir(router, "POST", "/categorias", `{"nombre": "Almacén"}`).Code)
+	assert.Equal(t, http.StatusCreated, pedir(router, "POST", "/categorias", `{"nombre": "Infusiones", "padre_id": 1}`).Code)
+	assert.Equal(t, http.StatusCreated, pedir(router, "POST", "/categorias", `{"nombre": "Yerbas", "padre_id": 2}`).Code)
+	assert.Equal(t, http.StatusCreated, pedir(router, "POST", "/categorias", `{"nombre": "Bebidas"}`).Code)
+	assert.Equal(t, http.StatusConflict, pedir(router, "POST", "/categorias", `{"nombre": "yerbas", "padre_id": 2}`).Code)
+	assert.Equal(t, http.StatusBadRequest, pedir(router, "POST", "/categorias", `{"nombre": "X", "padre_id": 9}`).Code)
+	assert.Equal(t, http.StatusBadRequest, pedir(router, "PUT", "/categorias/1", `{"nombre": "Almacén", "padre_id": 3}`).Code)
+	assert.Equal(t, http.StatusCreated, pedir(router, "POST", "/marcas", `{"nombre": "Taragüí"}`).Code)
+	assert.Equal(t, http.StatusConflict, pedir(router, "POST", "/marcas", `{"nombre": "Taragüí"}`).Code)
+
+	resp := pedir(router, "PUT", "/productos/1/clasificacion", `{"categoria_id": 3, "marca_id": 1}`)
+	require.Equal(t, http.StatusOK, resp.Code, resp.Body.String())
+	assert.Equal(t, http.StatusBadRequest, pedir(router, "PUT", "/productos/1/clasificacion", `{"categoria_id": 9}`).Code)
+	assert.Equal(t, http.StatusNotFound, pedir(router, "PUT", "/productos/9/clasificacion", `{}`).Code)
+
+	assert.Equal(t, []string{"Yerba"}, nombres("/productos?categoria=1"))
+	assert.Equal(t, []string{"Yerba"}, nombres("/productos?marca=1"))
+	assert.Empty(t, nombres("/productos?categoria=4"))
+	assert.Len(t, nombres("/productos"), 2)
+	assert.Equal(t, http.StatusNotFound, pedir(router, "GET", "/productos?categoria=9", "").Code)
+
+	// Mover Infusiones a Bebidas: el producto sigue en Yerbas y aparece bajo Bebidas
+	assert.Equal(t, http.StatusOK, pedir(router, "PUT", "/categorias/2", `{"nombre": "Infusiones", "padre_id": 4}`).Code)
+	assert.Equal(t, []string{"Yerba"}, nombres("/productos?categoria=4"))
+	assert.Empty(t, nombres("/productos?categoria=1"))
+
+	resp = pedir(router, "GET", "/categorias", "")
+	require.Equal(t, http.StatusOK, resp.Code)
+	var arbol []map[string]interface{}
+	require.NoError(t, json.Unmarshal(resp.Body.Bytes(), &arbol))
+	require.Len(t, arbol, 2)
+	assert.Equal(t, "Almacén", arbol[0]["nombre"])
+	assert.Len(t, arbol[1]["hijas"], 1)
+
+	assert.Equal(t, http.StatusConflict, pedir(router, "DELETE", "/categorias/3", "").Code)
+	assert.Equal(t, http.StatusConflict, pedir(router, "DELETE", "/marcas/1", "").Code)
+	assert.Equal(t, http.StatusOK, pedir(router, "DELETE", "/categorias/1", "").Code)
+}
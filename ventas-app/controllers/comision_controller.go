@@ -0,0 +1,231 @@
+package controllers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+	"ventas-app/database"
+	"ventas-app/middleware"
+	"ventas-app/models"
+	"ventas-app/services"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+type EsquemaComisionInput struct {
+	Nombre       string                 `json:"nombre" binding:"required"`
+	UsuarioID    *uint                  `json:"usuario_id"`
+	Porcentaje   float64                `json:"porcentaje"`
+	Tramos       []models.TramoComision `json:"tramos"`
+	PorCategoria map[uint]float64       `json:"por_categoria"`
+}
+
+// ListarEsquemasComision devuelve todos los esquemas (uso de admin).
+func ListarEsquemasComision(c *gin.Context) {
+	db := database.GetDB(c)
+
+	esquemas := []models.EsquemaComision{}
+	if err := db.Order("id").Find(&esquemas); err != nil {
+		responderErrorDB(c, err, http.StatusInternalServerError, "Error al listar los esquemas")
+		return
+	}
+
+	c.JSON(http.StatusOK, esquemas)
+}
+
+// CrearEsquemaComision da de alta un esquema para un vendedor o, sin
+// usuario_id, el esquema por defecto.
+func CrearEsquemaComision(c *gin.Context) {
+	guardarEsquemaComision(c, models.EsquemaComision{}, http.StatusCreated)
+}
+
+// ActualizarEsquemaComision reemplaza un esquema. Los períodos ya cerrados
+// no cambian.
+func ActualizarEsquemaComision(c *gin.Context) {
+	esquema, ok := buscarEsquemaParam(c, database.GetDB(c))
+	if !ok {
+		return
+	}
+	guardarEsquemaComision(c, esquema, http.StatusOK)
+}
+
+// BorrarEsquemaComision elimina un esquema; sus vendedores pasan al esquema
+// por defecto.
+func BorrarEsquemaComision(c *gin.Context) {
+	db := database.GetDB(c)
+
+	esquema, ok := buscarEsquemaParam(c, db)
+	if !ok {
+		return
+	}
+	if err := db.Delete(&esquema); err != nil {
+		responderErrorDB(c, err, http.StatusInternalServerError, "Error al borrar el esquema")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"mensaje": "Esquema borrado"})
+}
+
+func guardarEsquemaComision(c *gin.Context, esquema models.EsquemaComision, status int) {
+	db := database.GetDB(c)
+
+	var input EsquemaComisionInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Datos inválidos"})
+		return
+	}
+	if input.UsuarioID != nil {
+		var usuario models.Usuario
+		if err := db.First(&usuario, *input.UsuarioID); err != nil {
+			responderErrorDB(c, err, http.StatusNotFound, "Usuario no encontrado")
+			return
+		}
+	}
+
+	esquema.Nombre = input.Nombre
+	esquema.UsuarioID = input.UsuarioID
+	esquema.Porcentaje = input.Porcentaje
+	esquema.Tramos = input.Tramos
+	esquema.PorCategoria = input.PorCategoria
+
+	switch err := services.GuardarEsquema(db, &esquema); {
+	case errors.Is(err, services.ErrEsquemaInvalido):
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Los porcentajes van de 0 a 100 y los tramos en orden creciente"})
+	case errors.Is(err, services.ErrClasificacionInvalida):
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Categoría inexistente"})
+	case errors.Is(err, services.ErrEsquemaDuplicado):
+		c.JSON(http.StatusConflict, gin.H{"error": "Ya existe un esquema para ese vendedor"})
+	case err != nil:
+		responderErrorDB(c, err, http.StatusInternalServerError, "Error al guardar el esquema")
+	default:
+		c.JSON(status, esquema)
+	}
+}
+
+func buscarEsquemaParam(c *gin.Context, db database.DBHandler) (models.EsquemaComision, bool) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "ID inválido"})
+		return models.EsquemaComision{}, false
+	}
+
+	var esquema models.EsquemaComision
+	if err := db.First(&esquema, uint(id)); err != nil {
+		responderErrorDB(c, err, http.StatusNotFound, "Esquema no encontrado")
+		return models.EsquemaComision{}, false
+	}
+	return esquema, true
+}
+
+// ReporteComisiones liquida las comisiones de desde a hasta ("2006-01-02",
+// ambos días incluidos). Si ese período está cerrado devuelve lo congelado;
+// si no, un cálculo provisorio.
+func ReporteComisiones(c *gin.Context) {
+	db := database.GetDB(c)
+
+	desde, hasta, ok := periodoComision(c, c.Query("desde"), c.Query("hasta"))
+	if !ok {
+		return
+	}
+
+	cierre, err := services.BuscarCierre(db, desde, hasta)
+	if err == nil {
+		c.JSON(http.StatusOK, gin.H{"cerrado": true, "cierre_id": cierre.ID, "comisiones": cierre.Comisiones})
+		return
+	}
+	if !errors.Is(err, gorm.ErrRecordNotFound) {
+		responderErrorDB(c, err, http.StatusInternalServerError, "Error al buscar el cierre")
+		return
+	}
+
+	comisiones, err := services.CalcularComisiones(db, desde, hasta)
+	if err != nil {
+		responderErrorDB(c, err, http.StatusInternalServerError, "Error al calcular las comisiones")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"cerrado": false, "comisiones": comisiones})
+}
+
+type CerrarComisionesInput struct {
+	Desde string `json:"desde" binding:"required"`
+	Hasta string `json:"hasta" binding:"required"`
+}
+
+// CerrarComisiones congela las comisiones de un período ya terminado.
+func CerrarComisiones(c *gin.Context) {
+	db := database.GetDB(c)
+
+	var input CerrarComisionesInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Datos inválidos"})
+		return
+	}
+	desde, hasta, ok := periodoComision(c, input.Desde, input.Hasta)
+	if !ok {
+		return
+	}
+
+	usuario, _ := middleware.CurrentUser(c)
+	cierre, err := services.CerrarPeriodo(db, desde, hasta, usuario.ID, time.Now())
+	switch {
+	case errors.Is(err, services.ErrPeriodoInvalido):
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Solo se pueden cerrar períodos ya terminados"})
+	case errors.Is(err, services.ErrPeriodoCerrado):
+		c.JSON(http.StatusConflict, gin.H{"error": "El período se superpone con uno ya cerrado"})
+	case err != nil:
+		responderErrorDB(c, err, http.StatusInternalServerError, "Error al cerrar el período")
+	default:
+		c.JSON(http.StatusCreated, cierre)
+	}
+}
+
+// ListarCierresComision devuelve los cierres, el más reciente primero.
+func ListarCierresComision(c *gin.Context) {
+	db := database.GetDB(c)
+
+	cierres := []models.CierreComision{}
+	if err := db.Order("desde DESC").Find(&cierres); err != nil {
+		responderErrorDB(c, err, http.StatusInternalServerError, "Error al listar los cierres")
+		return
+	}
+
+	c.JSON(http.StatusOK, cierres)
+}
+
+// ObtenerCierreComision devuelve un cierre con sus comisiones.
+func ObtenerCierreComision(c *gin.Context) {
+	db := database.GetDB(c)
+
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "ID inválido"})
+		return
+	}
+
+	var cierre models.CierreComision
+	if err := db.First(&cierre, uint(id)); err != nil {
+		responderErrorDB(c, err, http.StatusNotFound, "Cierre no encontrado")
+		return
+	}
+	if err := services.CargarComisiones(db, &cierre); err != nil {
+		responderErrorDB(c, err, http.StatusInternalServerError, "Error al cargar las comisiones")
+		return
+	}
+
+	c.JSON(http.StatusOK, cierre)
+}
+
+// periodoComision convierte desde y hasta ("2006-01-02", hasta incluido) en
+// [desde, hasta + 1 día). Si son inválidos responde 400 y devuelve false.
+func periodoComision(c *gin.Context, desdeTexto, hastaTexto string) (time.Time, time.Time, bool) {
+	desde, errDesde := time.ParseInLocation(time.DateOnly, desdeTexto, time.Local)
+	hasta, errHasta := time.ParseInLocation(time.DateOnly, hastaTexto, time.Local)
+	if errDesde != nil || errHasta != nil || hasta.Before(desde) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "desde y hasta son obligatorios (2006-01-02) y desde no puede ser posterior"})
+		return time.Time{}, time.Time{}, false
+	}
+	return desde, hasta.AddDate(0, 0, 1), true
+}
@@ -0,0 +1,96 @@
+package controllers
+
+import (
+	"net/http"
+	"testing"
+	"time"
+	"ventas-app/config"
+	"ventas-app/database"
+	"ventas-app/models"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+)
+
+// Test: esquemas, devolución, reporte y cierre de comisiones sobre SQLite
+func TestComisiones(t *testing.T) {
+	gdb, err := database.Open(config.DatabaseConfig{Driver: database.DriverSQLite})
+	require.NoError(t, err)
+	require.NoError(t, database.Migrate(gdb))
+	db := &database.GormDB{DB: gdb}
+	require.NoError(t, db.Create(&models.Usuario{Nombre: "ana", Clave: "x", Rol: "vendedor"}))
+	require.NoError(t, db.Create(&models.Producto{Nombre: "Mate", Precio: 100, Stock: 5}))
+	ayer := time.Now().AddDate(0, 0, -1)
+	require.NoError(t, db.Create(&models.Venta{Model: gorm.Model{CreatedAt: ayer}, UsuarioID: 1, ProductoID: 1, Cantidad: 4, Neto: 400, IVA: 84, PrecioFinal: 484}))
+
+	usarDB(t, db)
+
+	gin.SetMode(gin.TestMode)
+	router := gin.Default()
+	router.POST("/comisiones/esquemas", CrearEsquemaComision)
+	router.PUT("/comisiones/esquemas/:id", ActualizarEsquemaComision)
+	router.GET("/comisiones/esquemas", ListarEsquemasComision)
+	router.DELETE("/comisiones/esquemas/:id", BorrarEsquemaComision)
+	router.POST("/ventas/:id/devoluciones", RegistrarDevolucion)
+	router.GET("/reportes/comisiones", ReporteComisiones)
+	router.POST("/comisiones/cierres", CerrarComisiones)
+	router.GET("/comisiones/cierres/:id", ObtenerCierreComision)
+
+	resp, _ := pedirDatos(router, "POST", "/comisiones/esquemas", `{"nombre": "General", "porcentaje": 5}`)
+	require.Equal(t, http.StatusCreated, resp.Code, resp.Body.String())
+	resp, _ = pedirDatos(router, "POST", "/comisiones/esquemas", `{"nombre": "Otro", "porcentaje": 1}`)
+	assert.Equal(t, http.StatusConflict, resp.Code)
+	resp, _ = pedirDatos(router, "POST", "/comisiones/esquemas", `{"nombre": "Ana", "usuario_id": 1, "tramos": [{"desde": 100, "porcentaje": 3}, {"desde": 50, "porcentaje": 1}]}`)
+	assert.Equal(t, http.StatusBadRequest, resp.Code)
+	resp, _ = pedirDatos(router, "POST", "/comisiones/esquemas", `{"nombre": "Ana", "usuario_id": 9, "porcentaje": 1}`)
+	assert.Equal(t, http.StatusNotFound, resp.Code)
+	resp, _ = pedirDatos(router, "PUT", "/comisiones/esquemas/1", `{"nombre": "General", "porcentaje": 10}`)
+	assert.Equal(t, http.StatusOK, resp.Code)
+
+	resp, _ = pedirDatos(router, "POST", "/ventas/1/devoluciones", `{"cantidad": 5}`)
+	assert.Equal(t, http.StatusBadRequest, resp.Code)
+	resp, datos := pedirDatos(router, "POST", "/ventas/1/devoluciones", `{"cantidad": 1, "motivo": "falla"}`)
+	require.Equal(t, http.StatusCreated, resp.Code, resp.Body.String())
+	assert.Equal(t, 100.0, datos["neto"])
+	resp, _ = pedirDatos(router, "POST", "/ventas/99/devoluciones", `{"cantidad": 1}`)
+	assert.Equal(t, http.StatusNotFound, resp.Code)
+
+	periodo := "desde=" + ayer.Format(time.DateOnly) + "&hasta=" + time.Now().Format(time.DateOnly)
+	resp, datos = pedirDatos(router, "GET", "/reportes/comisiones?"+periodo, "")
+	require.Equal(t, http.StatusOK, resp.Code)
+	assert.Equal(t, false, datos["cerrado"])
+	comision := datos["comisiones"].([]interface{})[0].(map[string]interface{})
+	assert.Equal(t, 300.0, comision["base"])
+	assert.Equal(t, 30.0, comision["comision"])
+
+	resp, _ = pedirDatos(router, "GET", "/reportes/comisiones?desde=2026-02-01", "")
+	assert.Equal(t, http.StatusBadRequest, resp.Code)
+
+	// Hoy no terminó: solo se cierra hasta ayer
+	body := `{"desde": "` + ayer.Format(time.DateOnly) + `", "hasta": "` + time.Now().Format(time.DateOnly) + `"}`
+	resp, _ = pedirDatos(router, "POST", "/comisiones/cierres", body)
+	assert.Equal(t, http.StatusBadRequest, resp.Code)
+	body = `{"desde": "` + ayer.Format(time.DateOnly) + `", "hasta": "` + ayer.Format(time.DateOnly) + `"}`
+	resp, _ = pedirDatos(router, "POST", "/comisiones/cierres", body)
+	require.Equal(t, http.StatusCreated, resp.Code, resp.Body.String())
+	resp, _ = pedirDatos(router, "POST", "/comisiones/cierres", body)
+	assert.Equal(t, http.StatusConflict, resp.Code)
+
+	resp, datos = pedirDatos(router, "GET", "/reportes/comisiones?desde="+ayer.Format(time.DateOnly)+"&hasta="+ayer.Format(time.DateOnly), "")
+	require.Equal(t, http.StatusOK, resp.Code)
+	assert.Equal(t, true, datos["cerrado"])
+	comision = datos["comisiones"].([]interface{})[0].(map[string]interface{})
+	assert.Equal(t, 40.0, comision["comision"], "la devolución de hoy no entra en el período de ayer")
+
+	resp, datos = pedirDatos(router, "GET", "/comisiones/cierres/1", "")
+	require.Equal(t, http.StatusOK, resp.Code)
+	assert.Len(t, datos["comisiones"], 1)
+
+	resp, _ = pedirDatos(router, "DELETE", "/comisiones/esquemas/1", "")
+	assert.Equal(t, http.StatusOK, resp.Code)
+	resp, _ = pedirDatos(router, "GET", "/comisiones/esquemas", "")
+	assert.Equal(t, http.StatusOK, resp.Code)
+	assert.JSONEq(t, "[]", resp.Body.String())
+}
@@ -4,6 +4,7 @@ import (
 	"net/http"
 	"ventas-app/database"
 	"ventas-app/models"
+	"ventas-app/services"
 
 	"github.com/gin-gonic/gin"
 )
@@ -25,19 +26,35 @@ func RegistrarCompra(c *gin.Context) {
 
 	var producto models.Producto
 	if err := db.First(&producto, compra.ProductoID); err != nil {
-		c.JSON(http.StatusNotFound, gin.H{"error": "Producto no encontrado"})
+		responderErrorDB(c, err, http.StatusNotFound, "Producto no encontrado")
 		return
 	}
 
-	producto.Stock += compra.Cantidad
-
-	if err := db.Save(&producto); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error al actualizar el stock"})
+	variante, err := services.ResolverVariante(db, producto, compra.VarianteID)
+	if err != nil {
+		responderErrorProducto(c, err, "Error al buscar la variante")
 		return
 	}
 
-	if err := db.Create(&compra); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error al registrar la compra"})
+	// Stock y compra se guardan juntos: si falla uno no queda el otro a medias
+	var mensajeError string
+	err = db.Transaction(func(tx database.DBHandler) error {
+		var varianteID *uint
+		if variante != nil {
+			varianteID = &variante.ID
+		}
+		if err := services.MoverStock(tx, producto.ID, varianteID, compra.Cantidad); err != nil {
+			mensajeError = "Error al actualizar el stock"
+			return err
+		}
+		if err := tx.Create(&compra); err != nil {
+			mensajeError = "Error al registrar la compra"
+			return err
+		}
+		return nil
+	})
+	if err != nil {
+		responderErrorDB(c, err, http.StatusInternalServerError, mensajeError)
 		return
 	}
 
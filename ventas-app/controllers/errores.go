@@ -0,0 +1,24 @@
+package controllers
+
+import (
+	"context"
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// responderErrorDB responde status/mensaje, salvo que el error venga de un
+// timeout o cancelación: si venció el request entero → 504, si solo venció la
+// consulta (o se canceló) → 503, para que el cliente sepa que puede reintentar.
+func responderErrorDB(c *gin.Context, err error, status int, mensaje string) {
+	if errors.Is(err, context.DeadlineExceeded) || errors.Is(err, context.Canceled) {
+		if c.Request != nil && errors.Is(c.Request.Context().Err(), context.DeadlineExceeded) {
+			c.JSON(http.StatusGatewayTimeout, gin.H{"error": "La operación excedió el tiempo máximo"})
+			return
+		}
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "La base de datos no respondió a tiempo"})
+		return
+	}
+	c.JSON(status, gin.H{"error": mensaje})
+}
@@ -0,0 +1,71 @@
+package controllers
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+	"ventas-app/database"
+	"ventas-app/mocks"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+// Test: si venció el request completo se responde 504
+func TestListarProductos_RequestTimeout(t *testing.T) {
+	mock := mocks.NewMemoryDB()
+	database.GetDB = func(c *gin.Context) database.DBHandler {
+		return mock.WithContext(c.Request.Context())
+	}
+
+	gin.SetMode(gin.TestMode)
+	router := gin.Default()
+	router.GET("/productos", ListarProductos)
+
+	ctx, cancel := context.WithTimeout(context.Background(), -time.Second)
+	defer cancel()
+	req, _ := http.NewRequestWithContext(ctx, "GET", "/productos", nil)
+	resp := httptest.NewRecorder()
+	router.ServeHTTP(resp, req)
+
+	assert.Equal(t, http.StatusGatewayTimeout, resp.Code)
+}
+
+// Test: si solo venció la consulta se responde 503
+func TestRegistrarVenta_QueryTimeout(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), -time.Second)
+	defer cancel()
+	mock := mocks.NewMemoryDB().WithContext(ctx)
+	database.GetDB = func(c *gin.Context) database.DBHandler {
+		return mock
+	}
+
+	gin.SetMode(gin.TestMode)
+	router := gin.Default()
+	router.POST("/ventas", comoUsuario(vendedorDePrueba), RegistrarVenta)
+
+	body := `{"producto_id": 1, "cantidad": 1}`
+	req, _ := http.NewRequest("POST", "/ventas", bytes.NewBufferString(body))
+	req.Header.Set("Content-Type", "application/json")
+
+	resp := httptest.NewRecorder()
+	router.ServeHTTP(resp, req)
+
+	assert.Equal(t, http.StatusServiceUnavailable, resp.Code)
+}
+
+// Test: un error común conserva el status del controller
+func TestResponderErrorDB_ErrorComun(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	resp := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(resp)
+	c.Request = httptest.NewRequest("GET", "/", nil)
+
+	responderErrorDB(c, assert.AnError, http.StatusNotFound, "Producto no encontrado")
+
+	assert.Equal(t, http.StatusNotFound, resp.Code)
+	assert.Contains(t, resp.Body.String(), "Producto no encontrado")
+}
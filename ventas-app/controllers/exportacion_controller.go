@@ -0,0 +1,78 @@
+package controllers
+
+import (
+	"errors"
+	"net/http"
+	"time"
+	"ventas-app/database"
+	"ventas-app/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ExportacionTimeout es el límite propio de las rutas de exportación, que
+// reemplaza al timeout general del request y al WriteTimeout del servidor.
+var ExportacionTimeout = 10 * time.Minute
+
+// Tipos de contenido de cada formato de exportación.
+var tiposExportacion = map[string]string{
+	services.FormatoCSV:       "text/csv; charset=utf-8",
+	services.FormatoXLSX:      "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet",
+	services.FormatoJSONLines: "application/x-ndjson",
+}
+
+// ExportarProductos descarga los productos. Ver exportar.
+func ExportarProductos(c *gin.Context) { exportar(c, "productos") }
+
+// ExportarVentas descarga las ventas. Ver exportar.
+func ExportarVentas(c *gin.Context) { exportar(c, "ventas") }
+
+// ExportarCompras descarga las compras. Ver exportar.
+func ExportarCompras(c *gin.Context) { exportar(c, "compras") }
+
+// exportar escribe la entidad en formato (csv por defecto, xlsx o
+// json-lines), filtrada por fecha de alta con desde/hasta como los reportes.
+// CSV y JSON lines salen a medida que se leen los lotes; si la base falla a
+// mitad de camino la respuesta queda cortada y el error se registra.
+func exportar(c *gin.Context, entidad string) {
+	db := database.GetDB(c)
+
+	filtro, ok := filtroReporte(c, "")
+	if !ok {
+		return
+	}
+	formato := c.DefaultQuery("formato", services.FormatoCSV)
+	tipo, ok := tiposExportacion[formato]
+	if !ok {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "formato debe ser csv, xlsx o json-lines"})
+		return
+	}
+	escritor, err := services.NuevoEscritor(formato, c.Writer)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	extension := formato
+	if formato == services.FormatoJSONLines {
+		extension = "jsonl"
+	}
+	c.Header("Content-Type", tipo)
+	c.Header("Content-Disposition", `attachment; filename="`+entidad+"."+extension+`"`)
+
+	err = services.Exportar(db, entidad, filtro, escritor)
+	if err != nil && !c.Writer.Written() {
+		c.Writer.Header().Del("Content-Type")
+		c.Writer.Header().Del("Content-Disposition")
+		if errors.Is(err, services.ErrExportacionInvalida) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		responderErrorDB(c, err, http.StatusInternalServerError, "Error al exportar "+entidad)
+		return
+	}
+	if err != nil {
+		c.Error(err)
+		c.Abort()
+	}
+}
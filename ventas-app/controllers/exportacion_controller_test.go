@@ -0,0 +1,49 @@
+package controllers
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+	"ventas-app/mocks"
+	"ventas-app/models"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// Test: descarga de productos y ventas en cada formato
+func TestExportar(t *testing.T) {
+	db := mocks.NewMemoryDB()
+	sku := "M-1"
+	require.NoError(t, db.Create(&models.Producto{Nombre: "Mate", SKU: &sku, Costo: 50, Precio: 100, Stock: 3}))
+	require.NoError(t, db.Create(&models.Venta{UsuarioID: 1, ProductoID: 1, Cantidad: 1, Neto: 100, IVA: 21, PrecioFinal: 121}))
+	usarDB(t, db)
+
+	gin.SetMode(gin.TestMode)
+	router := gin.Default()
+	router.GET("/productos/exportar", ExportarProductos)
+	router.GET("/ventas/exportar", ExportarVentas)
+
+	resp := pedir(router, "GET", "/productos/exportar", "")
+	require.Equal(t, http.StatusOK, resp.Code)
+	assert.Equal(t, "text/csv; charset=utf-8", resp.Header().Get("Content-Type"))
+	assert.Equal(t, `attachment; filename="productos.csv"`, resp.Header().Get("Content-Disposition"))
+	lineas := strings.Split(strings.TrimSpace(resp.Body.String()), "\n")
+	require.Len(t, lineas, 2)
+	assert.True(t, strings.HasSuffix(lineas[1], ",Mate,M-1,50,100,3,,"), lineas[1])
+
+	resp = pedir(router, "GET", "/ventas/exportar?formato=json-lines", "")
+	require.Equal(t, http.StatusOK, resp.Code)
+	assert.Equal(t, "application/x-ndjson", resp.Header().Get("Content-Type"))
+	assert.Contains(t, resp.Body.String(), `"precio_final":121`)
+
+	resp = pedir(router, "GET", "/ventas/exportar?formato=xlsx", "")
+	require.Equal(t, http.StatusOK, resp.Code)
+	assert.True(t, strings.HasPrefix(resp.Body.String(), "PK"), "un xlsx es un zip")
+
+	resp = pedir(router, "GET", "/ventas/exportar?formato=pdf", "")
+	assert.Equal(t, http.StatusBadRequest, resp.Code)
+	resp = pedir(router, "GET", "/ventas/exportar?desde=ayer", "")
+	assert.Equal(t, http.StatusBadRequest, resp.Code)
+}
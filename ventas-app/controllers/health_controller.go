@@ -0,0 +1,109 @@
+package controllers
+
+import (
+	"context"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+	"ventas-app/database"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// ReadinessTimeout limita cuánto espera /readyz a cada base de datos.
+var ReadinessTimeout = 2 * time.Second
+
+type estadoDependencia struct {
+	Estado           string  `json:"estado"`
+	LatenciaMs       float64 `json:"latencia_ms"`
+	VersionMigracion int     `json:"version_migracion"`
+	Error            string  `json:"error,omitempty"`
+}
+
+// Livez indica que el proceso está vivo. No toca dependencias externas: si
+// la base se cae no queremos que Render reinicie el contenedor.
+func Livez(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{"estado": "ok"})
+}
+
+// Readyz hace ping a cada conexión de database.DBs y verifica que tenga el
+// esquema esperado. Responde 503 si alguna falla, para sacar la instancia del
+// balanceo hasta que se recupere.
+func Readyz(c *gin.Context) {
+	nombres := make([]string, 0, len(database.DBs))
+	for nombre := range database.DBs {
+		nombres = append(nombres, nombre)
+	}
+	sort.Strings(nombres)
+
+	resultados := make([]estadoDependencia, len(nombres))
+	var wg sync.WaitGroup
+	for i, nombre := range nombres {
+		wg.Add(1)
+		go func(i int, db *gorm.DB) {
+			defer wg.Done()
+			resultados[i] = chequearDB(c.Request.Context(), db)
+		}(i, database.DBs[nombre])
+	}
+	wg.Wait()
+
+	listo := len(nombres) > 0
+	dependencias := gin.H{}
+	for i, nombre := range nombres {
+		dependencias["db:"+nombre] = resultados[i]
+		if resultados[i].Estado != "ok" {
+			listo = false
+		}
+	}
+
+	status, estado := http.StatusOK, "ok"
+	if !listo {
+		status, estado = http.StatusServiceUnavailable, "error"
+	}
+	c.JSON(status, gin.H{
+		"estado":            estado,
+		"version_migracion": database.SchemaVersion,
+		"dependencias":      dependencias,
+	})
+}
+
+func chequearDB(ctx context.Context, db *gorm.DB) (resultado estadoDependencia) {
+	ctx, cancel := context.WithTimeout(ctx, ReadinessTimeout)
+	defer cancel()
+
+	inicio := time.Now()
+	resultado.Estado = "error"
+	defer func() {
+		resultado.LatenciaMs = float64(time.Since(inicio).Microseconds()) / 1000
+	}()
+
+	if db == nil {
+		resultado.Error = "conexión no inicializada"
+		return resultado
+	}
+	sqlDB, err := db.DB()
+	if err != nil {
+		resultado.Error = err.Error()
+		return resultado
+	}
+	if err := sqlDB.PingContext(ctx); err != nil {
+		resultado.Error = err.Error()
+		return resultado
+	}
+
+	version, err := database.AppliedVersion(db.WithContext(ctx))
+	if err != nil {
+		resultado.Error = err.Error()
+		return resultado
+	}
+	resultado.VersionMigracion = version
+	if version < database.SchemaVersion {
+		resultado.Error = "migraciones pendientes"
+		return resultado
+	}
+
+	resultado.Estado = "ok"
+	return resultado
+}
@@ -0,0 +1,62 @@
+package controllers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"ventas-app/database"
+	"ventas-app/models"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// vendedorDePrueba es el usuario con el que se registran las ventas en los
+// tests que no pasan por AuthRequired.
+var vendedorDePrueba = models.Usuario{Model: gorm.Model{ID: 1}, Nombre: "ana", Rol: "vendedor"}
+
+// comoUsuario deja usuario en el contexto como lo hace AuthRequired, para
+// probar handlers que usan middleware.CurrentUser sin emitir tokens.
+func comoUsuario(usuario models.Usuario) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Set("usuario", usuario)
+		c.Next()
+	}
+}
+
+// usarDB hace que database.GetDB devuelva db hasta que termine el test.
+func usarDB(t *testing.T, db database.DBHandler) {
+	anterior := database.GetDB
+	database.GetDB = func(c *gin.Context) database.DBHandler {
+		return db
+	}
+	t.Cleanup(func() { database.GetDB = anterior })
+}
+
+// pedir hace un request con cuerpo JSON contra router.
+func pedir(router http.Handler, metodo, path, body string) *httptest.ResponseRecorder {
+	resp, _ := pedirConToken(router, metodo, path, "", body)
+	return resp
+}
+
+// pedirDatos es pedir con la respuesta decodificada como objeto JSON.
+func pedirDatos(router http.Handler, metodo, path, body string) (*httptest.ResponseRecorder, map[string]interface{}) {
+	return pedirConToken(router, metodo, path, "", body)
+}
+
+// pedirConToken es pedirDatos con token, si no es "", como Bearer.
+func pedirConToken(router http.Handler, metodo, path, token, body string) (*httptest.ResponseRecorder, map[string]interface{}) {
+	req := httptest.NewRequest(metodo, path, strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	resp := httptest.NewRecorder()
+	router.ServeHTTP(resp, req)
+
+	var datos map[string]interface{}
+	json.Unmarshal(resp.Body.Bytes(), &datos)
+	return resp, datos
+}
@@ -0,0 +1,87 @@
+package controllers
+
+import (
+	"errors"
+	"net/http"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"ventas-app/database"
+	"ventas-app/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+// MaxArchivoImportacion es el tamaño máximo del archivo que acepta
+// ImportarProductos.
+var MaxArchivoImportacion int64 = 10 << 20
+
+// ImportarProductos crea o actualiza productos desde un CSV o XLSX (campo
+// multipart "archivo"). Opciones: formato (csv|xlsx; por defecto la
+// extensión), clave (nombre|sku), mapeo ("campo=Columna,...") y dry_run.
+// Si alguna fila tiene errores no se aplica ninguna y responde 422 con el
+// reporte; con dry_run solo valida.
+func ImportarProductos(c *gin.Context) {
+	db := database.GetDB(c)
+
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, MaxArchivoImportacion)
+	cabecera, err := c.FormFile("archivo")
+	if err != nil {
+		var demasiado *http.MaxBytesError
+		if errors.As(err, &demasiado) {
+			c.JSON(http.StatusRequestEntityTooLarge, gin.H{"error": "El archivo es demasiado grande"})
+			return
+		}
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Falta el archivo"})
+		return
+	}
+
+	formato := strings.ToLower(c.PostForm("formato"))
+	if formato == "" {
+		formato = strings.TrimPrefix(strings.ToLower(filepath.Ext(cabecera.Filename)), ".")
+	}
+	mapeo, err := services.ParsearMapeo(c.PostForm("mapeo"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	dryRun := false
+	if v := c.PostForm("dry_run"); v != "" {
+		if dryRun, err = strconv.ParseBool(v); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "dry_run debe ser true o false"})
+			return
+		}
+	}
+
+	archivo, err := cabecera.Open()
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "No se pudo leer el archivo"})
+		return
+	}
+	defer archivo.Close()
+
+	filas, err := services.LeerArchivo(archivo, formato)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	res, err := services.ImportarProductos(db, filas, services.OpcionesImportacion{
+		Mapeo:  mapeo,
+		Clave:  c.PostForm("clave"),
+		DryRun: dryRun,
+	})
+	if errors.Is(err, services.ErrMapeoImportacion) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		responderErrorDB(c, err, http.StatusInternalServerError, "Error al importar productos")
+		return
+	}
+	if len(res.Errores) > 0 {
+		c.JSON(http.StatusUnprocessableEntity, res)
+		return
+	}
+	c.JSON(http.StatusOK, res)
+}
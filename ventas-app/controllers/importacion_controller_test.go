@@ -0,0 +1,77 @@
+package controllers
+
+import (
+	"bytes"
+	"encoding/json"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"ventas-app/database"
+	"ventas-app/mocks"
+	"ventas-app/models"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// Test: importación de productos por multipart, con dry run y errores por fila
+func TestImportarProductos(t *testing.T) {
+	db := mocks.NewMemoryDB()
+	require.NoError(t, db.Create(&models.Producto{Nombre: "Mate", Precio: 90, Stock: 3}))
+	database.GetDB = func(c *gin.Context) database.DBHandler {
+		return db
+	}
+
+	gin.SetMode(gin.TestMode)
+	router := gin.Default()
+	router.POST("/productos/importar", ImportarProductos)
+	importar := func(nombreArchivo, contenido string, campos map[string]string) (*httptest.ResponseRecorder, map[string]interface{}) {
+		var body bytes.Buffer
+		form := multipart.NewWriter(&body)
+		if nombreArchivo != "" {
+			parte, _ := form.CreateFormFile("archivo", nombreArchivo)
+			parte.Write([]byte(contenido))
+		}
+		for k, v := range campos {
+			form.WriteField(k, v)
+		}
+		form.Close()
+		req, _ := http.NewRequest("POST", "/productos/importar", &body)
+		req.Header.Set("Content-Type", form.FormDataContentType())
+		resp := httptest.NewRecorder()
+		router.ServeHTTP(resp, req)
+		var datos map[string]interface{}
+		json.Unmarshal(resp.Body.Bytes(), &datos)
+		return resp, datos
+	}
+	csv := "Producto,Precio,Stock\nMate,120,10\nTermo,300,4\n"
+
+	resp, _ := importar("", "", nil)
+	assert.Equal(t, http.StatusBadRequest, resp.Code)
+	resp, _ = importar("productos.ods", csv, nil)
+	assert.Equal(t, http.StatusBadRequest, resp.Code)
+	resp, _ = importar("productos.csv", csv, nil)
+	assert.Equal(t, http.StatusBadRequest, resp.Code, "sin mapeo falta la columna nombre")
+
+	resp, datos := importar("productos.csv", csv, map[string]string{"mapeo": "nombre=Producto", "dry_run": "true"})
+	require.Equal(t, http.StatusOK, resp.Code, resp.Body.String())
+	assert.Equal(t, false, datos["aplicado"])
+	assert.Equal(t, 1.0, datos["creados"])
+	assert.Equal(t, 1.0, datos["actualizados"])
+
+	resp, datos = importar("productos.csv", csv+"Yerba,-5,1\n", map[string]string{"mapeo": "nombre=Producto"})
+	require.Equal(t, http.StatusUnprocessableEntity, resp.Code)
+	errores := datos["errores"].([]interface{})
+	require.Len(t, errores, 1)
+	assert.Equal(t, 4.0, errores[0].(map[string]interface{})["fila"])
+
+	resp, datos = importar("productos.csv", csv, map[string]string{"mapeo": "nombre=Producto"})
+	require.Equal(t, http.StatusOK, resp.Code, resp.Body.String())
+	assert.Equal(t, true, datos["aplicado"])
+	var productos []models.Producto
+	require.NoError(t, db.Find(&productos))
+	require.Len(t, productos, 2)
+	assert.Equal(t, 120.0, productos[0].Precio)
+}
@@ -0,0 +1,286 @@
+package controllers
+
+import (
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+	"ventas-app/database"
+	"ventas-app/middleware"
+	"ventas-app/models"
+	"ventas-app/services"
+	"ventas-app/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+type LoginMFAInput struct {
+	MFAToken string `json:"mfa_token" binding:"required"`
+	Codigo   string `json:"codigo" binding:"required"`
+}
+
+type MFATokenInput struct {
+	MFAToken string `json:"mfa_token" binding:"required"`
+}
+
+type CodigoInput struct {
+	Codigo string `json:"codigo" binding:"required"`
+}
+
+// LoginMFA es el segundo paso del login: canjea el token intermedio y un
+// código TOTP (o de recuperación) por la sesión. Si el usuario estaba
+// obligado pero no enrolado, el código confirma el alta y la respuesta
+// incluye los códigos de recuperación.
+func LoginMFA(c *gin.Context) {
+	db := database.GetDB(c)
+
+	var input LoginMFAInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Datos inválidos"})
+		return
+	}
+
+	ip := c.ClientIP()
+	if espera, bloqueada := LimitadorLogin.Bloqueado(ip); bloqueada {
+		c.Header("Retry-After", strconv.Itoa(int(espera.Seconds())+1))
+		c.JSON(http.StatusTooManyRequests, gin.H{"error": "Demasiados intentos, reintente más tarde"})
+		return
+	}
+
+	claims, usuario, ok := validarTokenMFA(c, db, input.MFAToken)
+	if !ok {
+		return
+	}
+
+	var codigos []string
+	var err error
+	if usuario.TOTPActivo() {
+		err = services.VerificarSegundoFactor(db, &usuario, input.Codigo)
+	} else {
+		codigos, err = services.ConfirmarTOTP(db, &usuario, input.Codigo)
+	}
+	if errors.Is(err, services.ErrTOTPNoIniciado) {
+		c.JSON(http.StatusConflict, gin.H{"error": "Primero debe generar el secreto TOTP"})
+		return
+	}
+	if errors.Is(err, services.ErrCodigoInvalido) {
+		LimitadorLogin.Fallo(ip)
+		if err := services.RegistrarLogin(db, &usuario, usuario.Nombre, ip, models.LoginCodigoIncorrecto); err != nil {
+			log.Printf("No se pudo registrar el código fallido de %q: %v", usuario.Nombre, err)
+		}
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Código inválido"})
+		return
+	}
+	if err != nil {
+		responderErrorDB(c, err, http.StatusInternalServerError, "No se pudo verificar el código")
+		return
+	}
+
+	var sesion services.Sesion
+	err = db.Transaction(func(tx database.DBHandler) error {
+		if err := services.ConsumirTokenMFA(tx, claims); err != nil {
+			return err
+		}
+		if err := services.RegistrarLogin(tx, &usuario, usuario.Nombre, ip, models.LoginOK); err != nil {
+			return err
+		}
+		var err error
+		sesion, err = services.IniciarSesion(tx, usuario, claims.Tenant)
+		return err
+	})
+	if err != nil {
+		responderErrorDB(c, err, http.StatusInternalServerError, "No se pudo generar el token")
+		return
+	}
+
+	respuesta := datosSesion(sesion)
+	if codigos != nil {
+		c.Header("Cache-Control", "no-store")
+		respuesta["codigos_recuperacion"] = codigos
+	}
+	c.JSON(http.StatusOK, respuesta)
+}
+
+// EnrolarLoginMFA genera el secreto TOTP para un usuario cuyo rol exige doble
+// factor y que todavía no lo activó. Se confirma con LoginMFA.
+func EnrolarLoginMFA(c *gin.Context) {
+	db := database.GetDB(c)
+
+	var input MFATokenInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Datos inválidos"})
+		return
+	}
+
+	_, usuario, ok := validarTokenMFA(c, db, input.MFAToken)
+	if !ok {
+		return
+	}
+	responderSecretoTOTP(c, db, &usuario)
+}
+
+// IniciarTOTP genera el secreto TOTP del usuario autenticado. Queda pendiente
+// hasta ConfirmarTOTP.
+func IniciarTOTP(c *gin.Context) {
+	db := database.GetDB(c)
+
+	usuario, ok := middleware.CurrentUser(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Token faltante"})
+		return
+	}
+	responderSecretoTOTP(c, db, &usuario)
+}
+
+// ConfirmarTOTP activa el doble factor con el primer código de la app y
+// devuelve los códigos de recuperación.
+func ConfirmarTOTP(c *gin.Context) {
+	db := database.GetDB(c)
+
+	var input CodigoInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Datos inválidos"})
+		return
+	}
+
+	usuario, ok := middleware.CurrentUser(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Token faltante"})
+		return
+	}
+
+	codigos, err := services.ConfirmarTOTP(db, &usuario, input.Codigo)
+	if !responderErrorTOTP(c, err, "No se pudo activar el doble factor") {
+		return
+	}
+
+	c.Header("Cache-Control", "no-store")
+	c.JSON(http.StatusOK, gin.H{"mensaje": "Doble factor activado", "codigos_recuperacion": codigos})
+}
+
+// DesactivarTOTP quita el doble factor propio; pide un código válido. No se
+// permite si el rol lo exige.
+func DesactivarTOTP(c *gin.Context) {
+	db := database.GetDB(c)
+
+	var input CodigoInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Datos inválidos"})
+		return
+	}
+
+	usuario, ok := middleware.CurrentUser(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Token faltante"})
+		return
+	}
+	if services.RolesMFA[usuario.Rol] {
+		c.JSON(http.StatusConflict, gin.H{"error": "Su rol exige doble factor"})
+		return
+	}
+
+	err := services.VerificarSegundoFactor(db, &usuario, input.Codigo)
+	if err == nil {
+		err = services.DesactivarTOTP(db, &usuario)
+	}
+	if !responderErrorTOTP(c, err, "No se pudo desactivar el doble factor") {
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"mensaje": "Doble factor desactivado"})
+}
+
+// RegenerarCodigosRecuperacion invalida los códigos de recuperación y
+// entrega otros; pide un código válido.
+func RegenerarCodigosRecuperacion(c *gin.Context) {
+	db := database.GetDB(c)
+
+	var input CodigoInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Datos inválidos"})
+		return
+	}
+
+	usuario, ok := middleware.CurrentUser(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Token faltante"})
+		return
+	}
+
+	var codigos []string
+	err := services.VerificarSegundoFactor(db, &usuario, input.Codigo)
+	if err == nil {
+		codigos, err = services.GenerarCodigosRecuperacion(db, &usuario)
+	}
+	if !responderErrorTOTP(c, err, "No se pudieron generar los códigos") {
+		return
+	}
+
+	c.Header("Cache-Control", "no-store")
+	c.JSON(http.StatusOK, gin.H{"codigos_recuperacion": codigos})
+}
+
+// ResetearTOTP quita el doble factor de un usuario que perdió el dispositivo
+// y los códigos (uso de admin). Si su rol lo exige, se le pedirá el alta en
+// el próximo login.
+func ResetearTOTP(c *gin.Context) {
+	db := database.GetDB(c)
+
+	usuario, ok := buscarUsuarioParam(c, db)
+	if !ok {
+		return
+	}
+
+	if err := services.DesactivarTOTP(db, &usuario); err != nil {
+		responderErrorDB(c, err, http.StatusInternalServerError, "No se pudo desactivar el doble factor")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"mensaje": "Doble factor desactivado"})
+}
+
+// validarTokenMFA valida el token intermedio para el entorno del request. Si
+// no es válido ya respondió el error y devuelve false.
+func validarTokenMFA(c *gin.Context, db database.DBHandler, token string) (*utils.Claims, models.Usuario, bool) {
+	claims, usuario, err := services.ValidarTokenMFA(db, token)
+	if err == nil && claims.Tenant != "" && claims.Tenant != database.EnvSeleccionado(c) {
+		err = services.ErrTokenMFAInvalido
+	}
+	if errors.Is(err, services.ErrTokenMFAInvalido) {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Token MFA inválido"})
+		return nil, models.Usuario{}, false
+	}
+	if err != nil {
+		responderErrorDB(c, err, http.StatusInternalServerError, "Error al verificar el usuario")
+		return nil, models.Usuario{}, false
+	}
+	return claims, usuario, true
+}
+
+func responderSecretoTOTP(c *gin.Context, db database.DBHandler, usuario *models.Usuario) {
+	secreto, uri, err := services.GenerarTOTP(db, usuario)
+	if !responderErrorTOTP(c, err, "No se pudo generar el secreto") {
+		return
+	}
+
+	c.Header("Cache-Control", "no-store")
+	c.JSON(http.StatusOK, gin.H{"secreto": secreto, "uri": uri})
+}
+
+// responderErrorTOTP traduce los errores del doble factor. Devuelve true si
+// no hubo error.
+func responderErrorTOTP(c *gin.Context, err error, mensaje string) bool {
+	switch {
+	case err == nil:
+		return true
+	case errors.Is(err, services.ErrCodigoInvalido):
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Código inválido"})
+	case errors.Is(err, services.ErrTOTPActivo):
+		c.JSON(http.StatusConflict, gin.H{"error": "El doble factor ya está activo"})
+	case errors.Is(err, services.ErrTOTPNoIniciado):
+		c.JSON(http.StatusConflict, gin.H{"error": "Primero debe generar el secreto TOTP"})
+	default:
+		responderErrorDB(c, err, http.StatusInternalServerError, mensaje)
+	}
+	return false
+}
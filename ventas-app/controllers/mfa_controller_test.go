@@ -0,0 +1,123 @@
+package controllers
+
+import (
+	"net/http"
+	"testing"
+	"time"
+	"ventas-app/middleware"
+	"ventas-app/mocks"
+	"ventas-app/models"
+	"ventas-app/services"
+	"ventas-app/utils"
+
+	"github.com/gin-gonic/gin"
+	"github.com/pquerna/otp/totp"
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/crypto/bcrypt"
+)
+
+func routerMFA(t *testing.T, mock *mocks.MemoryDB) *gin.Engine {
+	utils.SetSecret("test-secret-key")
+	LimitadorLogin = services.NuevoLimitadorIP(100, time.Minute, time.Minute)
+	usarDB(t, mock)
+
+	gin.SetMode(gin.TestMode)
+	router := gin.Default()
+	router.POST("/login", Login)
+	router.POST("/login/mfa", LoginMFA)
+	router.POST("/login/mfa/enrolar", EnrolarLoginMFA)
+	router.GET("/protegido", middleware.AuthRequired(), func(c *gin.Context) { c.Status(http.StatusOK) })
+	router.POST("/me/totp", middleware.AuthRequired(), IniciarTOTP)
+	router.POST("/me/totp/confirmar", middleware.AuthRequired(), ConfirmarTOTP)
+	router.POST("/me/totp/desactivar", middleware.AuthRequired(), DesactivarTOTP)
+
+	return router
+}
+
+// Test: alta opcional desde /me/totp y login en dos pasos
+func TestLoginMFA_DosPasos(t *testing.T) {
+	hash, _ := bcrypt.GenerateFromPassword([]byte("password123"), bcrypt.MinCost)
+	mock := mocks.NewMemoryDB()
+	mock.Create(&models.Usuario{Nombre: "precio", Clave: string(hash), Rol: "precio"})
+	router := routerMFA(t, mock)
+
+	resp, datos := pedirConToken(router, "POST", "/login", "", `{"nombre": "precio", "clave": "password123"}`)
+	assert.Equal(t, http.StatusOK, resp.Code)
+	token := datos["token"].(string)
+
+	// Alta del doble factor
+	resp, datos = pedirConToken(router, "POST", "/me/totp", token, "")
+	assert.Equal(t, http.StatusOK, resp.Code)
+	secreto := datos["secreto"].(string)
+	assert.Contains(t, datos["uri"], "otpauth://")
+
+	codigo, _ := totp.GenerateCode(secreto, time.Now())
+	resp, datos = pedirConToken(router, "POST", "/me/totp/confirmar", token, `{"codigo": "`+codigo+`"}`)
+	assert.Equal(t, http.StatusOK, resp.Code)
+	recuperacion := datos["codigos_recuperacion"].([]interface{})
+	assert.Len(t, recuperacion, services.CantidadCodigosRecuperacion)
+
+	// Ahora el login pide el código
+	resp, datos = pedirConToken(router, "POST", "/login", "", `{"nombre": "precio", "clave": "password123"}`)
+	assert.Equal(t, http.StatusOK, resp.Code)
+	assert.Equal(t, true, datos["mfa_requerido"])
+	assert.Equal(t, false, datos["enrolar"])
+	assert.Nil(t, datos["token"])
+	mfaToken := datos["mfa_token"].(string)
+
+	resp, _ = pedirConToken(router, "GET", "/protegido", mfaToken, "")
+	assert.Equal(t, http.StatusUnauthorized, resp.Code, "el token intermedio no da acceso")
+
+	resp, _ = pedirConToken(router, "POST", "/login/mfa", "", `{"mfa_token": "`+mfaToken+`", "codigo": "000000"}`)
+	assert.Equal(t, http.StatusUnauthorized, resp.Code)
+
+	resp, datos = pedirConToken(router, "POST", "/login/mfa", "", `{"mfa_token": "`+mfaToken+`", "codigo": "`+recuperacion[0].(string)+`"}`)
+	assert.Equal(t, http.StatusOK, resp.Code)
+	resp, _ = pedirConToken(router, "GET", "/protegido", datos["token"].(string), "")
+	assert.Equal(t, http.StatusOK, resp.Code)
+
+	resp, _ = pedirConToken(router, "POST", "/login/mfa", "", `{"mfa_token": "`+mfaToken+`", "codigo": "`+recuperacion[1].(string)+`"}`)
+	assert.Equal(t, http.StatusUnauthorized, resp.Code, "el token intermedio es de un solo uso")
+
+	var eventos []models.LoginEvento
+	assert.NoError(t, mock.Where("motivo = ?", models.LoginCodigoIncorrecto).Find(&eventos))
+	assert.Len(t, eventos, 1)
+}
+
+// Test: un rol obligado sin TOTP debe enrolarse antes de recibir la sesión
+func TestLoginMFA_RolObligado(t *testing.T) {
+	services.RolesMFA = map[string]bool{"comprador": true}
+	defer func() { services.RolesMFA = map[string]bool{} }()
+
+	hash, _ := bcrypt.GenerateFromPassword([]byte("password123"), bcrypt.MinCost)
+	mock := mocks.NewMemoryDB()
+	mock.Create(&models.Usuario{Nombre: "compras", Clave: string(hash), Rol: "comprador"})
+	router := routerMFA(t, mock)
+
+	resp, datos := pedirConToken(router, "POST", "/login", "", `{"nombre": "compras", "clave": "password123"}`)
+	assert.Equal(t, http.StatusOK, resp.Code)
+	assert.Equal(t, true, datos["enrolar"])
+	mfaToken := datos["mfa_token"].(string)
+
+	resp, _ = pedirConToken(router, "POST", "/login/mfa", "", `{"mfa_token": "`+mfaToken+`", "codigo": "123456"}`)
+	assert.Equal(t, http.StatusConflict, resp.Code, "sin secreto generado")
+
+	resp, datos = pedirConToken(router, "POST", "/login/mfa/enrolar", "", `{"mfa_token": "`+mfaToken+`"}`)
+	assert.Equal(t, http.StatusOK, resp.Code)
+	secreto := datos["secreto"].(string)
+	codigo, _ := totp.GenerateCode(secreto, time.Now())
+
+	resp, datos = pedirConToken(router, "POST", "/login/mfa", "", `{"mfa_token": "`+mfaToken+`", "codigo": "`+codigo+`"}`)
+	assert.Equal(t, http.StatusOK, resp.Code)
+	assert.NotEmpty(t, datos["token"])
+	assert.Len(t, datos["codigos_recuperacion"], services.CantidadCodigosRecuperacion)
+
+	// El rol no puede desactivarlo
+	siguiente, _ := totp.GenerateCode(secreto, time.Now().Add(30*time.Second))
+	resp, _ = pedirConToken(router, "POST", "/me/totp/desactivar", datos["token"].(string), `{"codigo": "`+siguiente+`"}`)
+	assert.Equal(t, http.StatusConflict, resp.Code)
+
+	var usuario models.Usuario
+	assert.NoError(t, mock.First(&usuario, uint(1)))
+	assert.True(t, usuario.TOTPActivo())
+}
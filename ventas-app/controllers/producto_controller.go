@@ -1,11 +1,15 @@
 package controllers
 
 import (
+	"errors"
 	"net/http"
+	"strconv"
 	"ventas-app/database"
 	"ventas-app/models"
+	"ventas-app/services"
 
 	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
 )
 
 func CrearProducto(c *gin.Context) {
@@ -17,8 +21,8 @@ func CrearProducto(c *gin.Context) {
 		return
 	}
 
-	if err := db.Create(&p); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error al guardar"})
+	if err := services.CrearProducto(db, &p); err != nil {
+		responderErrorProducto(c, err, "Error al guardar")
 		return
 	}
 
@@ -28,11 +32,119 @@ func CrearProducto(c *gin.Context) {
 func ListarProductos(c *gin.Context) {
 	db := database.GetDB(c) // ← selecciona la base según el entorno
 
+	// ?categoria=ID incluye las subcategorías; ?marca=ID filtra por marca
+	q := db
+	if v := c.Query("categoria"); v != "" {
+		id, err := strconv.ParseUint(v, 10, 64)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "categoria inválida"})
+			return
+		}
+		ids, err := services.SubarbolCategoria(db, uint(id))
+		if err != nil {
+			responderErrorDB(c, err, http.StatusNotFound, "Categoría no encontrada")
+			return
+		}
+		q = q.Where("categoria_id IN ?", ids)
+	}
+	if v := c.Query("marca"); v != "" {
+		id, err := strconv.ParseUint(v, 10, 64)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "marca inválida"})
+			return
+		}
+		q = q.Where("marca_id = ?", uint(id))
+	}
+
 	var productos []models.Producto
-	if err := db.Find(&productos); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error al listar productos"})
+	if err := q.Find(&productos); err != nil {
+		responderErrorDB(c, err, http.StatusInternalServerError, "Error al listar productos")
+		return
+	}
+	if err := services.CargarCodigosBarras(db, productos); err != nil {
+		responderErrorDB(c, err, http.StatusInternalServerError, "Error al listar productos")
 		return
 	}
 
 	c.JSON(http.StatusOK, productos)
 }
+
+// BuscarProductoPorCodigo devuelve el producto de un código de barras, para
+// el lector del punto de venta.
+func BuscarProductoPorCodigo(c *gin.Context) {
+	db := database.GetDB(c)
+
+	producto, err := services.BuscarPorCodigoBarras(db, c.Param("code"))
+	if err != nil {
+		responderErrorProducto(c, err, "Error al buscar el producto")
+		return
+	}
+
+	c.JSON(http.StatusOK, producto)
+}
+
+type CodigoBarrasInput struct {
+	Codigo string `json:"codigo" binding:"required"`
+}
+
+// AgregarCodigoBarras suma un código de barras a un producto.
+func AgregarCodigoBarras(c *gin.Context) {
+	db := database.GetDB(c)
+
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "ID inválido"})
+		return
+	}
+	var input CodigoBarrasInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Datos inválidos"})
+		return
+	}
+
+	cb, err := services.AgregarCodigoBarras(db, uint(id), input.Codigo)
+	if err != nil {
+		responderErrorProducto(c, err, "Error al guardar el código de barras")
+		return
+	}
+
+	c.JSON(http.StatusCreated, cb)
+}
+
+// QuitarCodigoBarras borra un código de barras de un producto.
+func QuitarCodigoBarras(c *gin.Context) {
+	db := database.GetDB(c)
+
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "ID inválido"})
+		return
+	}
+
+	if err := services.QuitarCodigoBarras(db, uint(id), c.Param("codigo")); err != nil {
+		responderErrorProducto(c, err, "Error al borrar el código de barras")
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+// responderErrorProducto traduce los errores de validación de SKU, códigos
+// de barras, clasificación y variantes; el resto va a responderErrorDB.
+func responderErrorProducto(c *gin.Context, err error, mensaje string) {
+	switch {
+	case errors.Is(err, models.ErrSKUInvalido), errors.Is(err, models.ErrCodigoBarrasInvalido),
+		errors.Is(err, services.ErrClasificacionInvalida), errors.Is(err, services.ErrAtributosInvalidos),
+		errors.Is(err, services.ErrVarianteInvalida), errors.Is(err, services.ErrVarianteRequerida),
+		errors.Is(err, services.ErrVarianteAjena):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	case errors.Is(err, services.ErrSKUDuplicado), errors.Is(err, services.ErrCodigoBarrasDuplicado),
+		errors.Is(err, services.ErrVarianteDuplicada), errors.Is(err, services.ErrStockSinVariante),
+		errors.Is(err, services.ErrVarianteConStock):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "Producto no encontrado"})
+	default:
+		responderErrorDB(c, err, http.StatusInternalServerError, mensaje)
+	}
+}
@@ -60,9 +60,7 @@ func TestCrearProducto_MalformedJSON(t *testing.T) {
 func TestCrearProducto_Success(t *testing.T) {
 	mock := &mocks.MockDB{ShouldErr: false}
 
-	database.GetDB = func(c *gin.Context) database.DBHandler {
-		return mock
-	}
+	usarDB(t, mock)
 
 	gin.SetMode(gin.TestMode)
 	router := gin.Default()
@@ -77,3 +75,78 @@ func TestCrearProducto_Success(t *testing.T) {
 
 	assert.Equal(t, http.StatusCreated, resp.Code)
 }
+
+// Test: códigos de barras al crear, búsqueda por código y venta escaneada
+func TestCodigosBarras(t *testing.T) {
+	db := mocks.NewMemoryDB()
+	usarDB(t, db)
+
+	gin.SetMode(gin.TestMode)
+	router := gin.Default()
+	router.POST("/productos", CrearProducto)
+	router.GET("/productos", ListarProductos)
+	router.GET("/productos/barcode/:code", BuscarProductoPorCodigo)
+	router.POST("/productos/:id/codigos-barras", AgregarCodigoBarras)
+	router.DELETE("/productos/:id/codigos-barras/:codigo", QuitarCodigoBarras)
+	router.POST("/ventas", comoUsuario(vendedorDePrueba), RegistrarVenta)
+
+	resp, _ := pedirDatos(router, "POST", "/productos", `{"nombre": "Mate", "precio": 100, "stock": 5, "codigos_barras": ["7790070410123"]}`)
+	assert.Equal(t, http.StatusBadRequest, resp.Code, "dígito verificador incorrecto")
+	resp, _ = pedirDatos(router, "POST", "/productos", `{"nombre": "Mate", "sku": "MATE 1", "precio": 100}`)
+	assert.Equal(t, http.StatusBadRequest, resp.Code, "SKU con espacios")
+	resp, datos := pedirDatos(router, "POST", "/productos", `{"nombre": "Mate", "sku": "MATE-1", "precio": 100, "stock": 5, "codigos_barras": ["7790070410122"]}`)
+	assert.Equal(t, http.StatusCreated, resp.Code, resp.Body.String())
+	assert.Equal(t, []interface{}{"07790070410122"}, datos["codigos_barras"], "se guarda como GTIN-14")
+	resp, _ = pedirDatos(router, "POST", "/productos", `{"nombre": "Otro", "codigos_barras": ["7790070410122"]}`)
+	assert.Equal(t, http.StatusConflict, resp.Code)
+	resp, _ = pedirDatos(router, "POST", "/productos", `{"nombre": "Otro", "codigos_barras": ["07790070410122"]}`)
+	assert.Equal(t, http.StatusConflict, resp.Code, "el mismo código con otro largo")
+	resp, _ = pedirDatos(router, "POST", "/productos", `{"nombre": "Otro", "sku": "MATE-1"}`)
+	assert.Equal(t, http.StatusConflict, resp.Code)
+
+	resp, _ = pedirDatos(router, "POST", "/productos/1/codigos-barras", `{"codigo": "96385074"}`)
+	assert.Equal(t, http.StatusCreated, resp.Code, resp.Body.String())
+	resp, _ = pedirDatos(router, "POST", "/productos/9/codigos-barras", `{"codigo": "4006381333931"}`)
+	assert.Equal(t, http.StatusNotFound, resp.Code)
+
+	// UPC-A y su EAN-13 son el mismo GTIN
+	resp, _ = pedirDatos(router, "POST", "/productos/1/codigos-barras", `{"codigo": "012345678905"}`)
+	assert.Equal(t, http.StatusCreated, resp.Code, resp.Body.String())
+	resp, _ = pedirDatos(router, "POST", "/productos/1/codigos-barras", `{"codigo": "0012345678905"}`)
+	assert.Equal(t, http.StatusConflict, resp.Code)
+	resp, datos = pedirDatos(router, "GET", "/productos/barcode/0012345678905", "")
+	assert.Equal(t, http.StatusOK, resp.Code)
+	assert.Equal(t, "Mate", datos["nombre"])
+	resp, _ = pedirDatos(router, "DELETE", "/productos/1/codigos-barras/0012345678905", "")
+	assert.Equal(t, http.StatusNoContent, resp.Code)
+
+	resp, datos = pedirDatos(router, "GET", "/productos/barcode/96385074", "")
+	assert.Equal(t, http.StatusOK, resp.Code)
+	assert.Equal(t, "Mate", datos["nombre"])
+	assert.Equal(t, []interface{}{"07790070410122", "00000096385074"}, datos["codigos_barras"])
+	resp, _ = pedirDatos(router, "GET", "/productos/barcode/4006381333931", "")
+	assert.Equal(t, http.StatusNotFound, resp.Code)
+	resp, _ = pedirDatos(router, "GET", "/productos/barcode/123", "")
+	assert.Equal(t, http.StatusBadRequest, resp.Code)
+
+	resp, datos = pedirDatos(router, "POST", "/ventas", `{"usuario_id": 1, "codigo_barras": "96385074", "cantidad": 2}`)
+	assert.Equal(t, http.StatusCreated, resp.Code, resp.Body.String())
+	assert.Equal(t, 1.0, datos["producto_id"])
+	resp, _ = pedirDatos(router, "POST", "/ventas", `{"usuario_id": 1, "codigo_barras": "4006381333931", "cantidad": 1}`)
+	assert.Equal(t, http.StatusNotFound, resp.Code)
+	resp, _ = pedirDatos(router, "POST", "/ventas", `{"usuario_id": 1, "producto_id": 2, "codigo_barras": "96385074", "cantidad": 1}`)
+	assert.Equal(t, http.StatusBadRequest, resp.Code)
+
+	resp, _ = pedirDatos(router, "DELETE", "/productos/1/codigos-barras/96385074", "")
+	assert.Equal(t, http.StatusNoContent, resp.Code)
+	resp, _ = pedirDatos(router, "GET", "/productos/barcode/96385074", "")
+	assert.Equal(t, http.StatusNotFound, resp.Code)
+
+	resp, _ = pedirDatos(router, "GET", "/productos", "")
+	var productos []map[string]interface{}
+	json.Unmarshal(resp.Body.Bytes(), &productos)
+	if assert.Len(t, productos, 1) {
+		assert.Equal(t, []interface{}{"07790070410122"}, productos[0]["codigos_barras"])
+		assert.Equal(t, 3.0, productos[0]["stock"])
+	}
+}
@@ -0,0 +1,197 @@
+package controllers
+
+import (
+	"encoding/csv"
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+	"ventas-app/database"
+	"ventas-app/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ReporteVentas devuelve unidades, neto, IVA y bruto de las ventas agrupadas
+// por agrupar (dia, semana, mes, producto o usuario; dia por defecto), con el
+// total general. desde y hasta aceptan "2006-01-02" (hasta incluye ese día;
+// días en UTC, igual que los períodos) o RFC 3339.
+func ReporteVentas(c *gin.Context) {
+	db := database.GetDB(c)
+
+	filtro, ok := filtroReporte(c, services.AgruparDia)
+	if !ok {
+		return
+	}
+
+	reporte, err := services.GenerarReporteVentas(db, filtro)
+	if errors.Is(err, services.ErrAgrupacionInvalida) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "agrupar debe ser dia, semana, mes, producto o usuario"})
+		return
+	}
+	if err != nil {
+		responderErrorDB(c, err, http.StatusInternalServerError, "Error al generar el reporte")
+		return
+	}
+
+	c.JSON(http.StatusOK, reporte)
+}
+
+// ReporteMargen devuelve neto, costo (el registrado en cada venta), ganancia
+// y margen por producto, usuario o categoría (agrupar, producto por defecto),
+// de menor a mayor margen, y marca las filas por debajo de margen_minimo (en
+// %; por defecto REPORTES_MARGEN_MINIMO). Acepta desde/hasta como
+// ReporteVentas y formato=csv para descargarlo.
+func ReporteMargen(c *gin.Context) {
+	db := database.GetDB(c)
+
+	filtro, ok := filtroReporte(c, services.AgruparProducto)
+	if !ok {
+		return
+	}
+	minimo := -1.0
+	if v := c.Query("margen_minimo"); v != "" {
+		n, err := strconv.ParseFloat(v, 64)
+		if err != nil || n < 0 || n > 100 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "margen_minimo debe estar entre 0 y 100"})
+			return
+		}
+		minimo = n
+	}
+
+	reporte, err := services.GenerarReporteMargen(db, filtro, minimo)
+	if errors.Is(err, services.ErrAgrupacionInvalida) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "agrupar debe ser producto, usuario o categoria"})
+		return
+	}
+	if err != nil {
+		responderErrorDB(c, err, http.StatusInternalServerError, "Error al generar el reporte")
+		return
+	}
+
+	if c.Query("formato") == "csv" {
+		responderMargenCSV(c, reporte)
+		return
+	}
+	c.JSON(http.StatusOK, reporte)
+}
+
+// responderMargenCSV escribe una fila por grupo y al final la de totales.
+func responderMargenCSV(c *gin.Context, reporte services.ReporteMargen) {
+	c.Header("Content-Type", "text/csv; charset=utf-8")
+	c.Header("Content-Disposition", `attachment; filename="margenes.csv"`)
+	c.Status(http.StatusOK)
+
+	w := csv.NewWriter(c.Writer)
+	w.Write([]string{"grupo", "nombre", "ventas", "unidades", "neto", "costo", "ganancia", "margen", "bajo_margen"})
+	fila := func(grupo string, f services.FilaMargen) []string {
+		return []string{
+			grupo,
+			f.Nombre,
+			strconv.FormatInt(f.Ventas, 10),
+			strconv.FormatInt(f.Unidades, 10),
+			strconv.FormatFloat(f.Neto, 'f', 2, 64),
+			strconv.FormatFloat(f.Costo, 'f', 2, 64),
+			strconv.FormatFloat(f.Ganancia, 'f', 2, 64),
+			strconv.FormatFloat(f.Margen, 'f', 2, 64),
+			strconv.FormatBool(f.BajoMargen),
+		}
+	}
+	for _, f := range reporte.Filas {
+		w.Write(fila(f.Grupo, f))
+	}
+	w.Write(fila("total", reporte.Totales))
+	w.Flush()
+}
+
+// filtroReporte lee agrupar (por defecto agrupar), desde y hasta; si algo es
+// inválido responde 400 y devuelve false.
+func filtroReporte(c *gin.Context, agrupar string) (services.FiltroReporte, bool) {
+	filtro := services.FiltroReporte{Agrupar: c.DefaultQuery("agrupar", agrupar)}
+	var ok bool
+	if filtro.Desde, ok = fechaReporte(c, "desde"); !ok {
+		return filtro, false
+	}
+	if filtro.Hasta, ok = fechaReporte(c, "hasta"); !ok {
+		return filtro, false
+	}
+	if !filtro.Desde.IsZero() && !filtro.Hasta.IsZero() && !filtro.Desde.Before(filtro.Hasta) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "'desde' debe ser anterior a 'hasta'"})
+		return filtro, false
+	}
+	return filtro, true
+}
+
+// fechaReporte lee el parámetro campo. Una fecha sin hora es el día en UTC,
+// como los períodos que agrupa la base, y para "hasta" se lleva al día
+// siguiente porque el filtro excluye el límite superior.
+func fechaReporte(c *gin.Context, campo string) (time.Time, bool) {
+	v := c.Query(campo)
+	if v == "" {
+		return time.Time{}, true
+	}
+	if t, err := time.Parse(time.DateOnly, v); err == nil {
+		if campo == "hasta" {
+			t = t.AddDate(0, 0, 1)
+		}
+		return t, true
+	}
+	t, err := time.Parse(time.RFC3339, v)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Fecha '" + campo + "' inválida, usar 2006-01-02 o RFC 3339"})
+		return time.Time{}, false
+	}
+	return t, true
+}
+
+// ValuacionInventario devuelve cantidad × costo de cada producto, el
+// subtotal de cada categoría y el total.
+// Sin fecha (o con hoy) usa el stock actual; con una fecha pasada
+// ("2006-01-02") el stock al cierre de ese día, del último snapshot de ese
+// día o anterior.
+func ValuacionInventario(c *gin.Context) {
+	db := database.GetDB(c)
+
+	var fecha time.Time
+	if v := c.Query("fecha"); v != "" {
+		t, err := time.Parse(time.DateOnly, v)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Fecha inválida, usar 2006-01-02"})
+			return
+		}
+		fecha = t
+	}
+
+	valuacion, err := services.ValorarInventario(db, fecha, time.Now())
+	if errors.Is(err, services.ErrSinSnapshot) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "No hay snapshot de inventario para esa fecha"})
+		return
+	}
+	if err != nil {
+		responderErrorDB(c, err, http.StatusInternalServerError, "Error al valuar el inventario")
+		return
+	}
+
+	c.JSON(http.StatusOK, valuacion)
+}
+
+// TomarSnapshotInventario guarda el cierre de ayer si todavía no existe (el
+// job lo hace solo al empezar el día; esto es por si no corrió, por ejemplo
+// con el servidor apagado).
+func TomarSnapshotInventario(c *gin.Context) {
+	db := database.GetDB(c)
+
+	ahora := time.Now()
+	creados, err := services.TomarSnapshotInventario(db, ahora)
+	if err != nil {
+		responderErrorDB(c, err, http.StatusInternalServerError, "Error al guardar el snapshot")
+		return
+	}
+
+	fecha := services.DiaCierre(ahora).Format(time.DateOnly)
+	if creados == 0 {
+		c.JSON(http.StatusOK, gin.H{"mensaje": "El snapshot del día ya existe", "fecha": fecha})
+		return
+	}
+	c.JSON(http.StatusCreated, gin.H{"mensaje": "Snapshot guardado", "fecha": fecha, "productos": creados})
+}
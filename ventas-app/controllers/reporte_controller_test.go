@@ -0,0 +1,120 @@
+package controllers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+	"ventas-app/config"
+	"ventas-app/database"
+	"ventas-app/mocks"
+	"ventas-app/models"
+	"ventas-app/services"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+)
+
+// Test: reportes de ventas y márgenes sobre SQLite (MemoryDB no agrega)
+func TestReportes(t *testing.T) {
+	gdb, err := database.Open(config.DatabaseConfig{Driver: database.DriverSQLite})
+	require.NoError(t, err)
+	require.NoError(t, database.Migrate(gdb))
+	db := &database.GormDB{DB: gdb}
+	require.NoError(t, db.Create(&models.Producto{Nombre: "Mate", Costo: 60, Precio: 100, Stock: 10}))
+
+	usarDB(t, db)
+
+	gin.SetMode(gin.TestMode)
+	router := gin.Default()
+	router.POST("/ventas", comoUsuario(vendedorDePrueba), RegistrarVenta)
+	router.GET("/reportes/ventas", ReporteVentas)
+	router.GET("/reportes/margenes", ReporteMargen)
+
+	require.Equal(t, http.StatusCreated, pedir(router, "POST", "/ventas", `{"producto_id": 1, "cantidad": 2, "descuento": 10}`).Code)
+	require.Equal(t, http.StatusCreated, pedir(router, "POST", "/ventas", `{"producto_id": 1, "cantidad": 1}`).Code)
+
+	resp := pedir(router, "GET", "/reportes/ventas?agrupar=producto", "")
+	require.Equal(t, http.StatusOK, resp.Code, resp.Body.String())
+	var reporte services.ReporteVentas
+	require.NoError(t, json.Unmarshal(resp.Body.Bytes(), &reporte))
+	require.Len(t, reporte.Filas, 1)
+	assert.Equal(t, "Mate", reporte.Filas[0].Nombre)
+	assert.Equal(t, services.FilaReporte{Ventas: 2, Unidades: 3, Neto: 280, IVA: 58.8, Bruto: 338.8}, reporte.Totales)
+
+	resp = pedir(router, "GET", "/reportes/ventas?desde=2000-01-01&hasta=2000-01-31", "")
+	require.Equal(t, http.StatusOK, resp.Code)
+	require.NoError(t, json.Unmarshal(resp.Body.Bytes(), &reporte))
+	assert.Empty(t, reporte.Filas)
+
+	for _, query := range []string{"?agrupar=anio", "?desde=ayer", "?desde=2026-02-01&hasta=2026-01-01"} {
+		assert.Equal(t, http.StatusBadRequest, pedir(router, "GET", "/reportes/ventas"+query, "").Code, query)
+	}
+
+	// El costo actual ya no cambia el margen de lo vendido
+	require.NoError(t, db.Save(&models.Producto{Model: gorm.Model{ID: 1}, Nombre: "Mate", Costo: 90, Precio: 100}))
+
+	resp = pedir(router, "GET", "/reportes/margenes?margen_minimo=40", "")
+	require.Equal(t, http.StatusOK, resp.Code, resp.Body.String())
+	var margen services.ReporteMargen
+	require.NoError(t, json.Unmarshal(resp.Body.Bytes(), &margen))
+	require.Len(t, margen.Filas, 1)
+	assert.Equal(t, services.FilaMargen{Grupo: "1", Nombre: "Mate", Ventas: 2, Unidades: 3, Neto: 280, Costo: 180, Ganancia: 100, Margen: 35.71, BajoMargen: true}, margen.Filas[0])
+	assert.Equal(t, 1, margen.Alertas)
+
+	resp = pedir(router, "GET", "/reportes/margenes?formato=csv", "")
+	require.Equal(t, http.StatusOK, resp.Code)
+	assert.Equal(t, "text/csv; charset=utf-8", resp.Header().Get("Content-Type"))
+	assert.Equal(t, "grupo,nombre,ventas,unidades,neto,costo,ganancia,margen,bajo_margen\n"+
+		"1,Mate,2,3,280.00,180.00,100.00,35.71,false\n"+
+		"total,,2,3,280.00,180.00,100.00,35.71,false\n", resp.Body.String())
+
+	for _, query := range []string{"?agrupar=dia", "?margen_minimo=-5", "?margen_minimo=x"} {
+		assert.Equal(t, http.StatusBadRequest, pedir(router, "GET", "/reportes/margenes"+query, "").Code, query)
+	}
+}
+
+// Test: las fechas sin hora son días UTC aunque el servidor tenga otra zona
+func TestFiltroReporte_FechasEnUTC(t *testing.T) {
+	local := time.Local
+	time.Local = time.FixedZone("ART", -3*60*60)
+	t.Cleanup(func() { time.Local = local })
+
+	gin.SetMode(gin.TestMode)
+	c, _ := gin.CreateTestContext(httptest.NewRecorder())
+	c.Request = httptest.NewRequest("GET", "/reportes/ventas?desde=2026-01-05&hasta=2026-01-05", nil)
+
+	filtro, ok := filtroReporte(c, services.AgruparDia)
+	require.True(t, ok)
+	assert.Equal(t, time.Date(2026, 1, 5, 0, 0, 0, 0, time.UTC), filtro.Desde)
+	assert.Equal(t, time.Date(2026, 1, 6, 0, 0, 0, 0, time.UTC), filtro.Hasta)
+}
+
+// Test: valuación de inventario y snapshot a pedido
+func TestValuacionInventario(t *testing.T) {
+	mock := mocks.NewMemoryDB()
+	mock.Create(&models.Producto{Nombre: "Mate", Costo: 60, Stock: 10})
+
+	usarDB(t, mock)
+
+	gin.SetMode(gin.TestMode)
+	router := gin.Default()
+	router.GET("/reportes/inventario", ValuacionInventario)
+	router.POST("/inventario/snapshots", TomarSnapshotInventario)
+
+	assert.Equal(t, http.StatusCreated, pedir(router, "POST", "/inventario/snapshots", "").Code)
+	assert.Equal(t, http.StatusOK, pedir(router, "POST", "/inventario/snapshots", "").Code, "ya existía")
+
+	resp := pedir(router, "GET", "/reportes/inventario", "")
+	require.Equal(t, http.StatusOK, resp.Code)
+	var valuacion services.ValuacionInventario
+	require.NoError(t, json.Unmarshal(resp.Body.Bytes(), &valuacion))
+	assert.Equal(t, services.FuenteActual, valuacion.Fuente)
+	assert.Equal(t, 600.0, valuacion.Valor)
+
+	assert.Equal(t, http.StatusNotFound, pedir(router, "GET", "/reportes/inventario?fecha=2000-01-31", "").Code)
+	assert.Equal(t, http.StatusBadRequest, pedir(router, "GET", "/reportes/inventario?fecha=31/01/2000", "").Code)
+}
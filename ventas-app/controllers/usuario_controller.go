@@ -1,16 +1,21 @@
 package controllers
 
 import (
+	"errors"
 	"net/http"
+	"strconv"
 	"strings"
 	"ventas-app/database"
+	"ventas-app/middleware"
 	"ventas-app/models"
+	"ventas-app/services"
 
 	"github.com/gin-gonic/gin"
-	"golang.org/x/crypto/bcrypt"
+	"gorm.io/gorm"
 )
 
 var rolesValidos = map[string]bool{
+	"admin":     true,
 	"vendedor":  true,
 	"comprador": true,
 	"precio":    true,
@@ -37,28 +42,206 @@ func CrearUsuario(c *gin.Context) {
 		return
 	}
 
-	var existente models.Usuario
-	if err := db.Where("nombre = ?", input.Nombre).First(&existente); err == nil {
+	_, err := services.CrearUsuario(db, input.Nombre, input.Clave, input.Rol)
+	if _, debil := services.ProblemasClave(err); debil {
+		responderClaveDebil(c, err)
+		return
+	}
+	if errors.Is(err, services.ErrUsuarioExistente) {
 		c.JSON(http.StatusConflict, gin.H{"error": "El usuario ya existe"})
 		return
 	}
+	if err != nil {
+		responderErrorDB(c, err, http.StatusInternalServerError, "Error al crear el usuario")
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{"mensaje": "Usuario creado correctamente"})
+}
+
+// ListarUsuarios devuelve los usuarios, opcionalmente filtrados por ?rol=.
+// La clave nunca se serializa.
+func ListarUsuarios(c *gin.Context) {
+	db := database.GetDB(c)
+
+	if rol := c.Query("rol"); rol != "" {
+		db = db.Where("rol = ?", strings.ToLower(rol))
+	}
+
+	var usuarios []models.Usuario
+	if err := db.Find(&usuarios); err != nil {
+		responderErrorDB(c, err, http.StatusInternalServerError, "Error al listar usuarios")
+		return
+	}
+
+	c.JSON(http.StatusOK, usuarios)
+}
+
+type CambiarRolInput struct {
+	Rol string `json:"rol" binding:"required"`
+}
+
+// CambiarRol asigna otro rol al usuario y revoca sus sesiones. Un admin no
+// puede cambiarse el rol a sí mismo, para no quedarse sin acceso.
+func CambiarRol(c *gin.Context) {
+	db := database.GetDB(c)
+
+	var input CambiarRolInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Datos inválidos"})
+		return
+	}
+
+	input.Rol = strings.ToLower(input.Rol)
+	if !rolesValidos[input.Rol] {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Rol no permitido"})
+		return
+	}
+
+	usuario, ok := buscarUsuarioParam(c, db)
+	if !ok {
+		return
+	}
+	if esUsuarioActual(c, usuario) {
+		c.JSON(http.StatusConflict, gin.H{"error": "No puede cambiar su propio rol"})
+		return
+	}
+
+	if err := services.CambiarRol(db, &usuario, input.Rol); err != nil {
+		responderErrorDB(c, err, http.StatusInternalServerError, "No se pudo cambiar el rol")
+		return
+	}
+
+	c.JSON(http.StatusOK, usuario)
+}
+
+// DeshabilitarUsuario da de baja el acceso del usuario sin borrarlo.
+func DeshabilitarUsuario(c *gin.Context) {
+	db := database.GetDB(c)
+
+	usuario, ok := buscarUsuarioParam(c, db)
+	if !ok {
+		return
+	}
+	if esUsuarioActual(c, usuario) {
+		c.JSON(http.StatusConflict, gin.H{"error": "No puede deshabilitarse a sí mismo"})
+		return
+	}
 
-	hash, err := bcrypt.GenerateFromPassword([]byte(input.Clave), bcrypt.DefaultCost)
+	if err := services.DeshabilitarUsuario(db, &usuario); err != nil {
+		responderErrorDB(c, err, http.StatusInternalServerError, "No se pudo deshabilitar el usuario")
+		return
+	}
+
+	c.JSON(http.StatusOK, usuario)
+}
+
+// HabilitarUsuario revierte DeshabilitarUsuario.
+func HabilitarUsuario(c *gin.Context) {
+	db := database.GetDB(c)
+
+	usuario, ok := buscarUsuarioParam(c, db)
+	if !ok {
+		return
+	}
+
+	if err := services.HabilitarUsuario(db, &usuario); err != nil {
+		responderErrorDB(c, err, http.StatusInternalServerError, "No se pudo habilitar el usuario")
+		return
+	}
+
+	c.JSON(http.StatusOK, usuario)
+}
+
+// ResetearClave genera una clave temporal para el usuario, que deberá
+// cambiarla al ingresar. La clave temporal solo se muestra en esta respuesta.
+func ResetearClave(c *gin.Context) {
+	db := database.GetDB(c)
+
+	usuario, ok := buscarUsuarioParam(c, db)
+	if !ok {
+		return
+	}
+
+	temporal, err := services.ResetearClave(db, &usuario)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error al encriptar la clave"})
+		responderErrorDB(c, err, http.StatusInternalServerError, "No se pudo resetear la clave")
 		return
 	}
 
-	usuario := models.Usuario{
-		Nombre: input.Nombre,
-		Clave:  string(hash),
-		Rol:    input.Rol,
+	c.Header("Cache-Control", "no-store")
+	c.JSON(http.StatusOK, gin.H{"mensaje": "Clave reseteada", "clave_temporal": temporal})
+}
+
+type CambiarClaveInput struct {
+	ClaveActual string `json:"clave_actual" binding:"required"`
+	ClaveNueva  string `json:"clave_nueva" binding:"required"`
+}
+
+// CambiarMiClave cambia la clave del usuario autenticado. Cierra todas sus
+// sesiones, incluida la actual. Requiere AuthRequired antes.
+func CambiarMiClave(c *gin.Context) {
+	db := database.GetDB(c)
+
+	var input CambiarClaveInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Datos inválidos"})
+		return
 	}
 
-	if err := db.Create(&usuario); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error al crear el usuario"})
+	usuario, ok := middleware.CurrentUser(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Token faltante"})
 		return
 	}
 
-	c.JSON(http.StatusCreated, gin.H{"mensaje": "Usuario creado correctamente"})
+	err := services.CambiarClave(db, &usuario, input.ClaveActual, input.ClaveNueva)
+	if errors.Is(err, services.ErrClaveActualIncorrecta) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "La clave actual no es correcta"})
+		return
+	}
+	if _, debil := services.ProblemasClave(err); debil {
+		responderClaveDebil(c, err)
+		return
+	}
+	if err != nil {
+		responderErrorDB(c, err, http.StatusInternalServerError, "No se pudo cambiar la clave")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"mensaje": "Clave actualizada, inicie sesión nuevamente"})
+}
+
+// responderClaveDebil responde 400 con las reglas de la política que la
+// clave no cumple.
+func responderClaveDebil(c *gin.Context, err error) {
+	problemas, _ := services.ProblemasClave(err)
+	c.JSON(http.StatusBadRequest, gin.H{"error": "La clave no cumple la política", "detalles": problemas})
+}
+
+// buscarUsuarioParam carga el usuario del parámetro :id. Si no puede, ya
+// respondió el error y devuelve false.
+func buscarUsuarioParam(c *gin.Context, db database.DBHandler) (models.Usuario, bool) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "ID inválido"})
+		return models.Usuario{}, false
+	}
+
+	var usuario models.Usuario
+	if err := db.First(&usuario, uint(id)); err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Usuario no encontrado"})
+			return models.Usuario{}, false
+		}
+		responderErrorDB(c, err, http.StatusInternalServerError, "Error al buscar el usuario")
+		return models.Usuario{}, false
+	}
+	return usuario, true
+}
+
+// esUsuarioActual indica si usuario es quien hace el request.
+func esUsuarioActual(c *gin.Context, usuario models.Usuario) bool {
+	actual, ok := middleware.CurrentUser(c)
+	return ok && actual.ID == usuario.ID
 }
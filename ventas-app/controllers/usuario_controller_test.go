@@ -7,12 +7,15 @@ import (
 	"net/http/httptest"
 	"testing"
 	"ventas-app/database"
+	"ventas-app/middleware"
 	"ventas-app/mocks"
 	"ventas-app/models"
+	"ventas-app/services"
+	"ventas-app/utils"
 
 	"github.com/gin-gonic/gin"
 	"github.com/stretchr/testify/assert"
-	"gorm.io/gorm"
+	"golang.org/x/crypto/bcrypt"
 )
 
 func TestCrearUsuario_InvalidData(t *testing.T) {
@@ -65,7 +68,7 @@ func TestCrearUsuario_InvalidRole(t *testing.T) {
 	router := gin.Default()
 	router.POST("/usuarios", CrearUsuario)
 
-	body := `{"nombre": "testuser", "clave": "password123", "rol": "admin"}`
+	body := `{"nombre": "testuser", "clave": "Mostrador-2024", "rol": "superusuario"}`
 	req, _ := http.NewRequest("POST", "/usuarios", bytes.NewBufferString(body))
 	req.Header.Set("Content-Type", "application/json")
 	resp := httptest.NewRecorder()
@@ -81,17 +84,9 @@ func TestCrearUsuario_InvalidRole(t *testing.T) {
 
 // Test: Usuario ya existente
 func TestCrearUsuario_UserExists(t *testing.T) {
-	mock := &mocks.MockDB{
-		Usuarios: []models.Usuario{
-			{
-				Model:  gorm.Model{ID: 1},
-				Nombre: "existing",
-				Clave:  "hashedpassword",
-				Rol:    "vendedor",
-			},
-		},
-		ShouldErr: false,
-	}
+	mock := mocks.NewMemoryDB()
+	mock.Create(&models.Usuario{Nombre: "otro", Clave: "hashedpassword", Rol: "comprador"})
+	mock.Create(&models.Usuario{Nombre: "existing", Clave: "hashedpassword", Rol: "vendedor"})
 
 	database.GetDB = func(c *gin.Context) database.DBHandler {
 		return mock
@@ -101,7 +96,7 @@ func TestCrearUsuario_UserExists(t *testing.T) {
 	router := gin.Default()
 	router.POST("/usuarios", CrearUsuario)
 
-	body := `{"nombre": "existing", "clave": "password123", "rol": "vendedor"}`
+	body := `{"nombre": "existing", "clave": "Mostrador-2024", "rol": "vendedor"}`
 	req, _ := http.NewRequest("POST", "/usuarios", bytes.NewBufferString(body))
 	req.Header.Set("Content-Type", "application/json")
 	resp := httptest.NewRecorder()
@@ -131,7 +126,7 @@ func TestCrearUsuario_DatabaseError(t *testing.T) {
 	router := gin.Default()
 	router.POST("/usuarios", CrearUsuario)
 
-	body := `{"nombre": "newuser", "clave": "password123", "rol": "vendedor"}`
+	body := `{"nombre": "newuser", "clave": "Mostrador-2024", "rol": "vendedor"}`
 	req, _ := http.NewRequest("POST", "/usuarios", bytes.NewBufferString(body))
 	req.Header.Set("Content-Type", "application/json")
 	resp := httptest.NewRecorder()
@@ -147,10 +142,9 @@ func TestCrearUsuario_DatabaseError(t *testing.T) {
 
 // Test: Crear usuario exitoso
 func TestCrearUsuario_Success(t *testing.T) {
-	mock := &mocks.MockDB{
-		Usuarios:  []models.Usuario{}, // Sin usuarios existentes
-		ShouldErr: false,
-	}
+	// Ya existe otro usuario: no debe confundirse con "newuser"
+	mock := mocks.NewMemoryDB()
+	mock.Create(&models.Usuario{Nombre: "otro", Clave: "hashedpassword", Rol: "comprador"})
 
 	database.GetDB = func(c *gin.Context) database.DBHandler {
 		return mock
@@ -160,7 +154,7 @@ func TestCrearUsuario_Success(t *testing.T) {
 	router := gin.Default()
 	router.POST("/usuarios", CrearUsuario)
 
-	body := `{"nombre": "newuser", "clave": "password123", "rol": "VENDEDOR"}`  // Rol en mayúscula para probar conversión
+	body := `{"nombre": "newuser", "clave": "Mostrador-2024", "rol": "VENDEDOR"}`  // Rol en mayúscula para probar conversión
 	req, _ := http.NewRequest("POST", "/usuarios", bytes.NewBufferString(body))
 	req.Header.Set("Content-Type", "application/json")
 	resp := httptest.NewRecorder()
@@ -173,3 +167,224 @@ func TestCrearUsuario_Success(t *testing.T) {
 	json.Unmarshal(resp.Body.Bytes(), &response)
 	assert.Contains(t, response["mensaje"], "Usuario creado correctamente")
 }
+
+// Test: el usuario creado queda guardado con la clave hasheada
+func TestCrearUsuario_GuardaHash(t *testing.T) {
+	mock := mocks.NewMemoryDB()
+	database.GetDB = func(c *gin.Context) database.DBHandler {
+		return mock
+	}
+
+	gin.SetMode(gin.TestMode)
+	router := gin.Default()
+	router.POST("/usuarios", CrearUsuario)
+
+	body := `{"nombre": "nuevo", "clave": "Mostrador-2024", "rol": "comprador"}`
+	req, _ := http.NewRequest("POST", "/usuarios", bytes.NewBufferString(body))
+	req.Header.Set("Content-Type", "application/json")
+	resp := httptest.NewRecorder()
+
+	router.ServeHTTP(resp, req)
+
+	assert.Equal(t, http.StatusCreated, resp.Code)
+
+	var guardado models.Usuario
+	assert.NoError(t, mock.Where("nombre = ?", "nuevo").First(&guardado))
+	assert.Equal(t, "comprador", guardado.Rol)
+	assert.NotEqual(t, "Mostrador-2024", guardado.Clave)
+}
+
+// Test: el listado nunca incluye la clave
+func TestListarUsuarios(t *testing.T) {
+	mock := mocks.NewMemoryDB()
+	mock.Create(&models.Usuario{Nombre: "ana", Clave: "hash-secreto", Rol: "vendedor"})
+	mock.Create(&models.Usuario{Nombre: "beto", Clave: "hash-secreto", Rol: "admin"})
+
+	database.GetDB = func(c *gin.Context) database.DBHandler {
+		return mock
+	}
+
+	gin.SetMode(gin.TestMode)
+	router := gin.Default()
+	router.GET("/usuarios", ListarUsuarios)
+
+	req, _ := http.NewRequest("GET", "/usuarios", nil)
+	resp := httptest.NewRecorder()
+	router.ServeHTTP(resp, req)
+
+	assert.Equal(t, http.StatusOK, resp.Code)
+	assert.NotContains(t, resp.Body.String(), "hash-secreto")
+	assert.NotContains(t, resp.Body.String(), `"clave"`)
+
+	req, _ = http.NewRequest("GET", "/usuarios?rol=ADMIN", nil)
+	resp = httptest.NewRecorder()
+	router.ServeHTTP(resp, req)
+
+	var usuarios []models.Usuario
+	json.Unmarshal(resp.Body.Bytes(), &usuarios)
+	assert.Len(t, usuarios, 1)
+	assert.Equal(t, "beto", usuarios[0].Nombre)
+}
+
+// Test: cambio de rol, baja y alta, con las sesiones revocadas
+func TestAdministrarUsuario(t *testing.T) {
+	mock := mocks.NewMemoryDB()
+	mock.Create(&models.Usuario{Nombre: "ana", Clave: "x", Rol: "vendedor"})
+
+	database.GetDB = func(c *gin.Context) database.DBHandler {
+		return mock
+	}
+
+	gin.SetMode(gin.TestMode)
+	router := gin.Default()
+	router.PUT("/usuarios/:id/rol", CambiarRol)
+	router.POST("/usuarios/:id/deshabilitar", DeshabilitarUsuario)
+	router.POST("/usuarios/:id/habilitar", HabilitarUsuario)
+
+	for _, caso := range []struct {
+		metodo, path, body string
+		esperado           int
+	}{
+		{"PUT", "/usuarios/1/rol", `{"rol": "superusuario"}`, http.StatusBadRequest},
+		{"PUT", "/usuarios/99/rol", `{"rol": "comprador"}`, http.StatusNotFound},
+		{"PUT", "/usuarios/1/rol", `{"rol": "Comprador"}`, http.StatusOK},
+		{"POST", "/usuarios/1/deshabilitar", "", http.StatusOK},
+	} {
+		req, _ := http.NewRequest(caso.metodo, caso.path, bytes.NewBufferString(caso.body))
+		req.Header.Set("Content-Type", "application/json")
+		resp := httptest.NewRecorder()
+		router.ServeHTTP(resp, req)
+
+		assert.Equal(t, caso.esperado, resp.Code, caso.path+" "+caso.body)
+	}
+
+	var usuario models.Usuario
+	assert.NoError(t, mock.First(&usuario, uint(1)))
+	assert.Equal(t, "comprador", usuario.Rol)
+	assert.False(t, usuario.Habilitado())
+	assert.NotNil(t, usuario.SesionesRevocadasEn)
+
+	req, _ := http.NewRequest("POST", "/usuarios/1/habilitar", nil)
+	resp := httptest.NewRecorder()
+	router.ServeHTTP(resp, req)
+
+	assert.Equal(t, http.StatusOK, resp.Code)
+	assert.NoError(t, mock.First(&usuario, uint(1)))
+	assert.True(t, usuario.Habilitado())
+}
+
+// Test: un admin no puede deshabilitarse ni cambiarse el rol a sí mismo
+func TestAdministrarUsuario_SiMismo(t *testing.T) {
+	utils.SetSecret("test-secret-key")
+	mock := mocks.NewMemoryDB()
+	mock.Create(&models.Usuario{Nombre: "admin", Clave: "x", Rol: "admin"})
+
+	database.GetDB = func(c *gin.Context) database.DBHandler {
+		return mock
+	}
+
+	gin.SetMode(gin.TestMode)
+	router := gin.Default()
+	router.PUT("/usuarios/:id/rol", middleware.AuthRequired("admin"), CambiarRol)
+	router.POST("/usuarios/:id/deshabilitar", middleware.AuthRequired("admin"), DeshabilitarUsuario)
+
+	token, _ := utils.GenerateToken(1, "admin")
+	for _, caso := range []struct{ metodo, path, body string }{
+		{"PUT", "/usuarios/1/rol", `{"rol": "vendedor"}`},
+		{"POST", "/usuarios/1/deshabilitar", ""},
+	} {
+		req, _ := http.NewRequest(caso.metodo, caso.path, bytes.NewBufferString(caso.body))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("Authorization", "Bearer "+token)
+		resp := httptest.NewRecorder()
+		router.ServeHTTP(resp, req)
+
+		assert.Equal(t, http.StatusConflict, resp.Code, caso.path)
+	}
+}
+
+// Test: reset de clave por un admin y cambio de la clave propia
+func TestResetearYCambiarClave(t *testing.T) {
+	utils.SetSecret("test-secret-key")
+	mock := mocks.NewMemoryDB()
+	mock.Create(&models.Usuario{Nombre: "ana", Clave: "x", Rol: "vendedor"})
+
+	database.GetDB = func(c *gin.Context) database.DBHandler {
+		return mock
+	}
+
+	gin.SetMode(gin.TestMode)
+	router := gin.Default()
+	router.POST("/usuarios/:id/resetear-clave", ResetearClave)
+	router.PUT("/me/clave", middleware.AuthRequired(), CambiarMiClave)
+
+	req, _ := http.NewRequest("POST", "/usuarios/1/resetear-clave", nil)
+	resp := httptest.NewRecorder()
+	router.ServeHTTP(resp, req)
+
+	assert.Equal(t, http.StatusOK, resp.Code)
+	var reseteo map[string]string
+	json.Unmarshal(resp.Body.Bytes(), &reseteo)
+	temporal := reseteo["clave_temporal"]
+	assert.NotEmpty(t, temporal)
+
+	var usuario models.Usuario
+	assert.NoError(t, mock.First(&usuario, uint(1)))
+	assert.True(t, usuario.DebeCambiarClave)
+
+	// Emitido después del reset, aunque sea en el mismo segundo: sigue vigente
+	sesion, err := services.IniciarSesion(mock, usuario, "")
+	assert.NoError(t, err)
+
+	cambiar := func(body string) *httptest.ResponseRecorder {
+		req, _ := http.NewRequest("PUT", "/me/clave", bytes.NewBufferString(body))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("Authorization", "Bearer "+sesion.AccessToken)
+		resp := httptest.NewRecorder()
+		router.ServeHTTP(resp, req)
+		return resp
+	}
+
+	assert.Equal(t, http.StatusBadRequest, cambiar(`{"clave_actual": "otra", "clave_nueva": "Nueva-Clave-1"}`).Code)
+	assert.Equal(t, http.StatusOK, cambiar(`{"clave_actual": "`+temporal+`", "clave_nueva": "Nueva-Clave-1"}`).Code)
+
+	assert.NoError(t, mock.First(&usuario, uint(1)))
+	assert.False(t, usuario.DebeCambiarClave)
+	assert.NoError(t, bcrypt.CompareHashAndPassword([]byte(usuario.Clave), []byte("Nueva-Clave-1")))
+
+	// El cambio cierra la sesión con la que se hizo
+	assert.Equal(t, http.StatusUnauthorized, cambiar(`{"clave_actual": "Nueva-Clave-1", "clave_nueva": "otra-mas"}`).Code)
+}
+
+// Test: la clave debe cumplir la política
+func TestCrearUsuario_ClaveDebil(t *testing.T) {
+	mock := mocks.NewMemoryDB()
+	database.GetDB = func(c *gin.Context) database.DBHandler {
+		return mock
+	}
+
+	gin.SetMode(gin.TestMode)
+	router := gin.Default()
+	router.POST("/usuarios", CrearUsuario)
+
+	body := `{"nombre": "nuevo", "clave": "1", "rol": "vendedor"}`
+	req, _ := http.NewRequest("POST", "/usuarios", bytes.NewBufferString(body))
+	req.Header.Set("Content-Type", "application/json")
+	resp := httptest.NewRecorder()
+
+	router.ServeHTTP(resp, req)
+
+	assert.Equal(t, http.StatusBadRequest, resp.Code)
+
+	var response struct {
+		Error    string   `json:"error"`
+		Detalles []string `json:"detalles"`
+	}
+	json.Unmarshal(resp.Body.Bytes(), &response)
+	assert.Contains(t, response.Error, "política")
+	assert.NotEmpty(t, response.Detalles)
+
+	var usuarios []models.Usuario
+	assert.NoError(t, mock.Find(&usuarios))
+	assert.Empty(t, usuarios)
+}
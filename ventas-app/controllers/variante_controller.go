@@ -0,0 +1,172 @@
+package controllers
+
+import (
+	"net/http"
+	"strconv"
+	"ventas-app/database"
+	"ventas-app/models"
+	"ventas-app/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+type AtributoInput struct {
+	Nombre  string   `json:"nombre" binding:"required"`
+	Valores []string `json:"valores" binding:"required"`
+}
+
+type VarianteInput struct {
+	SKU       *string           `json:"sku"`
+	Atributos map[string]string `json:"atributos"`
+	Precio    *float64          `json:"precio"`
+	Stock     int               `json:"stock"`
+}
+
+type ActualizarVarianteInput struct {
+	SKU    *string  `json:"sku"`
+	Precio *float64 `json:"precio"`
+}
+
+// ListarVariantes devuelve los atributos y las variantes de un producto.
+func ListarVariantes(c *gin.Context) {
+	db := database.GetDB(c)
+
+	producto, ok := buscarProductoParam(c, db)
+	if !ok {
+		return
+	}
+	variantes, err := services.ListarVariantes(db, producto.ID)
+	if err != nil {
+		responderErrorDB(c, err, http.StatusInternalServerError, "Error al listar las variantes")
+		return
+	}
+
+	c.JSON(http.StatusOK, variantes)
+}
+
+// DefinirAtributos reemplaza los atributos de variante de un producto (ej.
+// talle y color con sus valores posibles).
+func DefinirAtributos(c *gin.Context) {
+	db := database.GetDB(c)
+
+	producto, ok := buscarProductoParam(c, db)
+	if !ok {
+		return
+	}
+	var input []AtributoInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Datos inválidos"})
+		return
+	}
+	atributos := make([]models.AtributoVariante, len(input))
+	for i, a := range input {
+		atributos[i] = models.AtributoVariante{Nombre: a.Nombre, Valores: a.Valores}
+	}
+
+	atributos, err := services.DefinirAtributos(db, producto.ID, atributos)
+	if err != nil {
+		responderErrorProducto(c, err, "Error al guardar los atributos")
+		return
+	}
+
+	c.JSON(http.StatusOK, atributos)
+}
+
+// CrearVariante da de alta una combinación de atributos con su stock
+// inicial, SKU y, opcionalmente, su propio precio.
+func CrearVariante(c *gin.Context) {
+	db := database.GetDB(c)
+
+	producto, ok := buscarProductoParam(c, db)
+	if !ok {
+		return
+	}
+	var input VarianteInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Datos inválidos"})
+		return
+	}
+
+	variante := models.Variante{SKU: input.SKU, Atributos: input.Atributos, Precio: input.Precio, Stock: input.Stock}
+	if err := services.CrearVariante(db, &producto, &variante); err != nil {
+		responderErrorProducto(c, err, "Error al guardar la variante")
+		return
+	}
+
+	c.JSON(http.StatusCreated, variante)
+}
+
+// ActualizarVariante cambia SKU y precio de una variante; sin precio usa el
+// del producto.
+func ActualizarVariante(c *gin.Context) {
+	db := database.GetDB(c)
+
+	variante, ok := buscarVarianteParam(c, db)
+	if !ok {
+		return
+	}
+	var input ActualizarVarianteInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Datos inválidos"})
+		return
+	}
+
+	if err := services.ActualizarVariante(db, &variante, input.SKU, input.Precio); err != nil {
+		responderErrorProducto(c, err, "Error al guardar la variante")
+		return
+	}
+
+	c.JSON(http.StatusOK, variante)
+}
+
+// BorrarVariante da de baja una variante sin stock.
+func BorrarVariante(c *gin.Context) {
+	db := database.GetDB(c)
+
+	variante, ok := buscarVarianteParam(c, db)
+	if !ok {
+		return
+	}
+	if err := services.BorrarVariante(db, variante); err != nil {
+		responderErrorProducto(c, err, "Error al borrar la variante")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"mensaje": "Variante borrada"})
+}
+
+func buscarProductoParam(c *gin.Context, db database.DBHandler) (models.Producto, bool) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "ID inválido"})
+		return models.Producto{}, false
+	}
+
+	var producto models.Producto
+	if err := db.First(&producto, uint(id)); err != nil {
+		responderErrorDB(c, err, http.StatusNotFound, "Producto no encontrado")
+		return models.Producto{}, false
+	}
+	return producto, true
+}
+
+// buscarVarianteParam busca :variante y controla que sea del producto :id.
+func buscarVarianteParam(c *gin.Context, db database.DBHandler) (models.Variante, bool) {
+	productoID, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "ID inválido"})
+		return models.Variante{}, false
+	}
+	id, err := strconv.ParseUint(c.Param("variante"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "ID inválido"})
+		return models.Variante{}, false
+	}
+
+	var variante models.Variante
+	if err := db.First(&variante, uint(id)); err != nil || variante.ProductoID != uint(productoID) {
+		responderErrorDB(c, err, http.StatusNotFound, "Variante no encontrada")
+		return models.Variante{}, false
+	}
+	return variante, true
+}
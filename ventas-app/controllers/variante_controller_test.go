@@ -0,0 +1,85 @@
+package controllers
+
+import (
+	"net/http"
+	"testing"
+	"ventas-app/mocks"
+	"ventas-app/models"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// Test: atributos, variantes y compra, venta y devolución por variante
+func TestVariantes(t *testing.T) {
+	db := mocks.NewMemoryDB()
+	require.NoError(t, db.Create(&models.Producto{Nombre: "Remera", Costo: 40, Precio: 100}))
+	usarDB(t, db)
+
+	gin.SetMode(gin.TestMode)
+	router := gin.Default()
+	router.GET("/productos/:id/variantes", ListarVariantes)
+	router.PUT("/productos/:id/atributos", DefinirAtributos)
+	router.POST("/productos/:id/variantes", CrearVariante)
+	router.PUT("/productos/:id/variantes/:variante", ActualizarVariante)
+	router.DELETE("/productos/:id/variantes/:variante", BorrarVariante)
+	router.POST("/compras", RegistrarCompra)
+	router.POST("/ventas", comoUsuario(vendedorDePrueba), RegistrarVenta)
+	router.POST("/ventas/:id/devoluciones", RegistrarDevolucion)
+	stock := func() (producto, m, s int) {
+		var p models.Producto
+		require.NoError(t, db.First(&p, uint(1)))
+		var vm, vs models.Variante
+		require.NoError(t, db.First(&vm, uint(1)))
+		require.NoError(t, db.First(&vs, uint(2)))
+		return p.Stock, vm.Stock, vs.Stock
+	}
+
+	resp, _ := pedirDatos(router, "PUT", "/productos/1/atributos", `[{"nombre": "Talle", "valores": ["S", "M"]}, {"nombre": "Color", "valores": ["Rojo"]}]`)
+	require.Equal(t, http.StatusOK, resp.Code, resp.Body.String())
+	resp, _ = pedirDatos(router, "POST", "/productos/1/variantes", `{"sku": "REM-M-R", "atributos": {"Talle": "M", "Color": "Rojo"}, "precio": 120, "stock": 2}`)
+	require.Equal(t, http.StatusCreated, resp.Code, resp.Body.String())
+	resp, _ = pedirDatos(router, "POST", "/productos/1/variantes", `{"sku": "REM-S-R", "atributos": {"Talle": "S", "Color": "Rojo"}}`)
+	require.Equal(t, http.StatusCreated, resp.Code, resp.Body.String())
+	resp, _ = pedirDatos(router, "POST", "/productos/1/variantes", `{"sku": "REM-M-R", "atributos": {"Talle": "S", "Color": "Azul"}}`)
+	assert.Equal(t, http.StatusBadRequest, resp.Code)
+	resp, _ = pedirDatos(router, "POST", "/productos/1/variantes", `{"atributos": {"Talle": "M", "Color": "Rojo"}}`)
+	assert.Equal(t, http.StatusConflict, resp.Code)
+
+	resp, _ = pedirDatos(router, "POST", "/compras", `{"usuario_id": 1, "producto_id": 1, "cantidad": 5, "costo_unit": 40}`)
+	assert.Equal(t, http.StatusBadRequest, resp.Code, "falta variante_id")
+	resp, _ = pedirDatos(router, "POST", "/compras", `{"usuario_id": 1, "producto_id": 1, "variante_id": 2, "cantidad": 5, "costo_unit": 40}`)
+	require.Equal(t, http.StatusCreated, resp.Code, resp.Body.String())
+	p, m, s := stock()
+	assert.Equal(t, []int{7, 2, 5}, []int{p, m, s})
+
+	resp, _ = pedirDatos(router, "POST", "/ventas", `{"usuario_id": 1, "producto_id": 1, "variante_id": 1, "cantidad": 3}`)
+	assert.Equal(t, http.StatusBadRequest, resp.Code, "stock de la variante insuficiente")
+	resp, datos := pedirDatos(router, "POST", "/ventas", `{"usuario_id": 1, "producto_id": 1, "variante_id": 1, "cantidad": 2}`)
+	require.Equal(t, http.StatusCreated, resp.Code, resp.Body.String())
+	assert.Equal(t, 240.0, datos["neto"], "usa el precio de la variante")
+	resp, datos = pedirDatos(router, "POST", "/ventas", `{"usuario_id": 1, "producto_id": 1, "variante_id": 2, "cantidad": 1}`)
+	require.Equal(t, http.StatusCreated, resp.Code, resp.Body.String())
+	assert.Equal(t, 100.0, datos["neto"], "sin precio propio usa el del producto")
+	p, m, s = stock()
+	assert.Equal(t, []int{4, 0, 4}, []int{p, m, s})
+
+	resp, _ = pedirDatos(router, "POST", "/ventas/1/devoluciones", `{"cantidad": 1}`)
+	require.Equal(t, http.StatusCreated, resp.Code, resp.Body.String())
+	p, m, s = stock()
+	assert.Equal(t, []int{5, 1, 4}, []int{p, m, s})
+
+	resp, datos = pedirDatos(router, "PUT", "/productos/1/variantes/1", `{"sku": "REM-M-R"}`)
+	require.Equal(t, http.StatusOK, resp.Code, resp.Body.String())
+	assert.Nil(t, datos["precio"])
+	resp, _ = pedirDatos(router, "DELETE", "/productos/1/variantes/1", "")
+	assert.Equal(t, http.StatusConflict, resp.Code)
+	resp, _ = pedirDatos(router, "DELETE", "/productos/2/variantes/1", "")
+	assert.Equal(t, http.StatusNotFound, resp.Code)
+
+	resp, datos = pedirDatos(router, "GET", "/productos/1/variantes", "")
+	require.Equal(t, http.StatusOK, resp.Code)
+	assert.Len(t, datos["atributos"], 2)
+	assert.Len(t, datos["variantes"], 2)
+}
@@ -1,9 +1,13 @@
 package controllers
 
 import (
+	"errors"
 	"net/http"
+	"strconv"
 	"ventas-app/database"
+	"ventas-app/middleware"
 	"ventas-app/models"
+	"ventas-app/services"
 
 	"github.com/gin-gonic/gin"
 )
@@ -17,40 +21,136 @@ func RegistrarVenta(c *gin.Context) {
 		return
 	}
 
-	// Validación adicional: producto_id y cantidad deben ser mayores que 0
-	if venta.ProductoID <= 0 || venta.Cantidad <= 0 {
+	// Validación adicional: producto_id (o codigo_barras) y cantidad deben ser mayores que 0
+	if (venta.ProductoID <= 0 && venta.CodigoBarras == "") || venta.Cantidad <= 0 {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "Datos inválidos"})
 		return
 	}
 
+	// El vendedor (que cobra la comisión) es quien registra la venta, no el
+	// usuario_id que venga en el cuerpo
+	usuario, ok := middleware.CurrentUser(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Token faltante"})
+		return
+	}
+	venta.UsuarioID = usuario.ID
+
 	var producto models.Producto
-	if err := db.First(&producto, venta.ProductoID); err != nil {
-		c.JSON(http.StatusNotFound, gin.H{"error": "Producto no encontrado"})
+	if venta.CodigoBarras != "" {
+		var err error
+		producto, err = services.BuscarPorCodigoBarras(db, venta.CodigoBarras)
+		if errors.Is(err, models.ErrCodigoBarrasInvalido) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Código de barras inválido"})
+			return
+		}
+		if err != nil {
+			responderErrorDB(c, err, http.StatusNotFound, "Producto no encontrado")
+			return
+		}
+		if venta.ProductoID != 0 && venta.ProductoID != producto.ID {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "El código de barras es de otro producto"})
+			return
+		}
+		venta.ProductoID = producto.ID
+	} else if err := db.First(&producto, venta.ProductoID); err != nil {
+		responderErrorDB(c, err, http.StatusNotFound, "Producto no encontrado")
+		return
+	}
+
+	variante, err := services.ResolverVariante(db, producto, venta.VarianteID)
+	if err != nil {
+		responderErrorProducto(c, err, "Error al buscar la variante")
 		return
 	}
 
-	if producto.Stock < venta.Cantidad {
+	// Este control es solo para responder rápido: el que vale es el del
+	// descuento dentro de la transacción
+	stock, precioUnitario := producto.Stock, producto.Precio
+	if variante != nil {
+		stock, precioUnitario = variante.Stock, variante.PrecioPara(producto)
+	}
+	if stock < venta.Cantidad {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "Stock insuficiente"})
 		return
 	}
 
-	precioUnitario := producto.Precio
 	total := float64(venta.Cantidad) * precioUnitario
 	totalConDescuento := total * (1 - venta.Descuento/100)
-	totalConIVA := totalConDescuento * 1.21
+	iva := totalConDescuento * models.AlicuotaIVA
 
-	venta.PrecioFinal = totalConIVA
-	producto.Stock -= venta.Cantidad
+	venta.Neto = totalConDescuento
+	venta.IVA = iva
+	venta.PrecioFinal = totalConDescuento + iva
+	venta.CostoUnit = producto.Costo
 
-	if err := db.Save(&producto); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error al actualizar el stock"})
+	// Stock y venta se guardan juntos: si falla uno no queda el otro a medias.
+	// El descuento exige stock suficiente en la misma sentencia, así dos
+	// ventas simultáneas no pueden llevarse la misma unidad
+	var mensajeError string
+	err = db.Transaction(func(tx database.DBHandler) error {
+		var varianteID *uint
+		if variante != nil {
+			varianteID = &variante.ID
+		}
+		if err := services.MoverStock(tx, producto.ID, varianteID, -venta.Cantidad); err != nil {
+			mensajeError = "Error al actualizar el stock"
+			return err
+		}
+		if err := tx.Create(&venta); err != nil {
+			mensajeError = "Error al registrar la venta"
+			return err
+		}
+		return nil
+	})
+	if errors.Is(err, services.ErrStockInsuficiente) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Stock insuficiente"})
 		return
 	}
-
-	if err := db.Create(&venta); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error al registrar la venta"})
+	if err != nil {
+		responderErrorDB(c, err, http.StatusInternalServerError, mensajeError)
 		return
 	}
 
 	c.JSON(http.StatusCreated, venta)
 }
+
+type DevolucionInput struct {
+	Cantidad int    `json:"cantidad" binding:"required"`
+	Motivo   string `json:"motivo"`
+}
+
+// RegistrarDevolucion devuelve unidades de una venta: repone el stock y
+// descuenta el neto de la comisión del vendedor en el período actual.
+func RegistrarDevolucion(c *gin.Context) {
+	db := database.GetDB(c)
+
+	var input DevolucionInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Datos inválidos"})
+		return
+	}
+
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "ID inválido"})
+		return
+	}
+	var venta models.Venta
+	if err := db.First(&venta, uint(id)); err != nil {
+		responderErrorDB(c, err, http.StatusNotFound, "Venta no encontrada")
+		return
+	}
+
+	devolucion, err := services.RegistrarDevolucion(db, venta, input.Cantidad, input.Motivo)
+	if errors.Is(err, services.ErrCantidadDevolucion) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "La cantidad supera lo vendido o ya devuelto"})
+		return
+	}
+	if err != nil {
+		responderErrorDB(c, err, http.StatusInternalServerError, "Error al registrar la devolución")
+		return
+	}
+
+	c.JSON(http.StatusCreated, devolucion)
+}
@@ -23,7 +23,7 @@ func TestRegistrarVenta_InvalidBody(t *testing.T) {
 	gin.SetMode(gin.TestMode)
 
 	router := gin.Default()
-	router.POST("/ventas", RegistrarVenta)
+	router.POST("/ventas", comoUsuario(vendedorDePrueba), RegistrarVenta)
 
 	// Arrange: sin datos de producto ni cantidad
 	body := `{"producto_id": 0, "cantidad": 0}`
@@ -46,7 +46,7 @@ func TestRegistrarVenta_MalformedJSON(t *testing.T) {
 
 	gin.SetMode(gin.TestMode)
 	router := gin.Default()
-	router.POST("/ventas", RegistrarVenta)
+	router.POST("/ventas", comoUsuario(vendedorDePrueba), RegistrarVenta)
 
 	body := `{"producto_id": 1, "cantidad":}`  // JSON incompleto
 	req, _ := http.NewRequest("POST", "/ventas", bytes.NewBufferString(body))
@@ -75,7 +75,7 @@ func TestRegistrarVenta_ProductNotFound(t *testing.T) {
 
 	gin.SetMode(gin.TestMode)
 	router := gin.Default()
-	router.POST("/ventas", RegistrarVenta)
+	router.POST("/ventas", comoUsuario(vendedorDePrueba), RegistrarVenta)
 
 	body := `{"producto_id": 999, "cantidad": 2}`  // Producto inexistente
 	req, _ := http.NewRequest("POST", "/ventas", bytes.NewBufferString(body))
@@ -105,7 +105,7 @@ func TestRegistrarVenta_InsufficientStock(t *testing.T) {
 
 	gin.SetMode(gin.TestMode)
 	router := gin.Default()
-	router.POST("/ventas", RegistrarVenta)
+	router.POST("/ventas", comoUsuario(vendedorDePrueba), RegistrarVenta)
 
 	body := `{"producto_id": 1, "cantidad": 10}`  // Intentar vender más de lo disponible
 	req, _ := http.NewRequest("POST", "/ventas", bytes.NewBufferString(body))
@@ -136,7 +136,7 @@ func TestRegistrarVenta_SaveProductError(t *testing.T) {
 
 	gin.SetMode(gin.TestMode)
 	router := gin.Default()
-	router.POST("/ventas", RegistrarVenta)
+	router.POST("/ventas", comoUsuario(vendedorDePrueba), RegistrarVenta)
 
 	body := `{"producto_id": 1, "cantidad": 2}`
 	req, _ := http.NewRequest("POST", "/ventas", bytes.NewBufferString(body))
@@ -167,7 +167,7 @@ func TestRegistrarVenta_CreateVentaError(t *testing.T) {
 
 	gin.SetMode(gin.TestMode)
 	router := gin.Default()
-	router.POST("/ventas", RegistrarVenta)
+	router.POST("/ventas", comoUsuario(vendedorDePrueba), RegistrarVenta)
 
 	body := `{"producto_id": 1, "cantidad": 2}`
 	req, _ := http.NewRequest("POST", "/ventas", bytes.NewBufferString(body))
@@ -182,3 +182,66 @@ func TestRegistrarVenta_CreateVentaError(t *testing.T) {
 	json.Unmarshal(resp.Body.Bytes(), &response)
 	assert.Contains(t, response["error"], "Error al registrar la venta")
 }
+
+// Test: si falla el alta de la venta no se descuenta el stock
+func TestRegistrarVenta_RollbackStock(t *testing.T) {
+	mock := mocks.NewMemoryDB()
+	mock.Create(&models.Producto{Nombre: "P1", Precio: 20.0, Stock: 10})
+	mock.Create(&models.Venta{Model: gorm.Model{ID: 7}, ProductoID: 1, Cantidad: 1})
+
+	database.GetDB = func(c *gin.Context) database.DBHandler {
+		return mock
+	}
+
+	gin.SetMode(gin.TestMode)
+	router := gin.Default()
+	router.POST("/ventas", comoUsuario(vendedorDePrueba), RegistrarVenta)
+
+	// ID repetido: el Create falla dentro de la transacción
+	body := `{"ID": 7, "producto_id": 1, "cantidad": 3}`
+	req, _ := http.NewRequest("POST", "/ventas", bytes.NewBufferString(body))
+	req.Header.Set("Content-Type", "application/json")
+
+	resp := httptest.NewRecorder()
+	router.ServeHTTP(resp, req)
+
+	assert.Equal(t, http.StatusInternalServerError, resp.Code)
+
+	var producto models.Producto
+	assert.NoError(t, mock.First(&producto, 1))
+	assert.Equal(t, 10, producto.Stock)
+}
+
+// Test: la venta (y su comisión) queda a nombre de quien la registra, no del
+// usuario_id del cuerpo
+func TestRegistrarVenta_VendedorAutenticado(t *testing.T) {
+	mock := mocks.NewMemoryDB()
+	mock.Create(&models.Producto{Nombre: "P1", Precio: 20.0, Stock: 10})
+
+	database.GetDB = func(c *gin.Context) database.DBHandler {
+		return mock
+	}
+
+	gin.SetMode(gin.TestMode)
+	router := gin.Default()
+	router.POST("/ventas", comoUsuario(models.Usuario{Model: gorm.Model{ID: 5}, Rol: "vendedor"}), RegistrarVenta)
+	router.POST("/sin-usuario", RegistrarVenta)
+
+	body := `{"usuario_id": 9, "producto_id": 1, "cantidad": 1}`
+	req, _ := http.NewRequest("POST", "/ventas", bytes.NewBufferString(body))
+	req.Header.Set("Content-Type", "application/json")
+	resp := httptest.NewRecorder()
+	router.ServeHTTP(resp, req)
+
+	assert.Equal(t, http.StatusCreated, resp.Code)
+	var venta models.Venta
+	assert.NoError(t, mock.First(&venta, 1))
+	assert.Equal(t, uint(5), venta.UsuarioID)
+
+	req, _ = http.NewRequest("POST", "/sin-usuario", bytes.NewBufferString(body))
+	req.Header.Set("Content-Type", "application/json")
+	resp = httptest.NewRecorder()
+	router.ServeHTTP(resp, req)
+
+	assert.Equal(t, http.StatusUnauthorized, resp.Code)
+}
@@ -0,0 +1,331 @@
+package database
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"ventas-app/models"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"gorm.io/gorm/schema"
+)
+
+// InfoAuditoria identifica quién hace un cambio. middleware.Auditoria la pone
+// en el contexto del request y AuthRequired completa el actor; como GetDB
+// pasa ese contexto a gorm, los callbacks de auditoría la leen al registrar
+// cada evento.
+type InfoAuditoria struct {
+	RequestID string
+	Env       string
+	IP        string
+	UsuarioID *uint
+	APIKeyID  *uint
+}
+
+type claveInfoAuditoria struct{}
+
+// ConInfoAuditoria devuelve un contexto que lleva info.
+func ConInfoAuditoria(ctx context.Context, info *InfoAuditoria) context.Context {
+	return context.WithValue(ctx, claveInfoAuditoria{}, info)
+}
+
+// InfoAuditoriaDe devuelve la InfoAuditoria del contexto, o nil si no hay.
+func InfoAuditoriaDe(ctx context.Context) *InfoAuditoria {
+	if ctx == nil {
+		return nil
+	}
+	info, _ := ctx.Value(claveInfoAuditoria{}).(*InfoAuditoria)
+	return info
+}
+
+// EntidadesAuditadas son los modelos cuyas altas, modificaciones y bajas se
+// registran en models.AuditoriaEvento.
+var EntidadesAuditadas = map[string]bool{
+	"Usuario":          true,
+	"Producto":         true,
+	"CodigoBarras":     true,
+	"Categoria":        true,
+	"Marca":            true,
+	"AtributoVariante": true,
+	"Variante":         true,
+	"Compra":           true,
+	"Venta":            true,
+	// Afectan lo que se paga de comisión
+	"Devolucion":      true,
+	"EsquemaComision": true,
+}
+
+const (
+	claveOmitir = "auditoria:omitir"
+	claveAntes  = "auditoria:antes"
+	oculto      = "[oculto]"
+)
+
+// camposIgnorados cambian en cada operación y no aportan al diff. Los
+// contadores del login también: cada intento fallido o código TOTP usado
+// generaría un evento sin nada visible (son columnas ocultas); el bloqueo que
+// provocan sí queda en bloqueado_hasta.
+var camposIgnorados = map[string]bool{
+	"created_at":        true,
+	"updated_at":        true,
+	"intentos_fallidos": true,
+	"totp_ultimo_paso":  true,
+}
+
+// registrarAuditoria agrega los callbacks que auditan create/update/delete.
+// Corren dentro de la transacción de la operación: si no se puede guardar el
+// evento, la operación falla.
+func registrarAuditoria(db *gorm.DB) error {
+	cb := db.Callback()
+	pasos := []error{
+		cb.Create().After("gorm:create").Before("gorm:commit_or_rollback_transaction").
+			Register("auditoria:crear", auditarCreate),
+		cb.Update().After("gorm:begin_transaction").Before("gorm:update").
+			Register("auditoria:antes_actualizar", capturarAntes),
+		cb.Update().After("gorm:update").Before("gorm:commit_or_rollback_transaction").
+			Register("auditoria:actualizar", auditarUpdate),
+		cb.Delete().After("gorm:begin_transaction").Before("gorm:delete").
+			Register("auditoria:antes_borrar", capturarAntes),
+		cb.Delete().After("gorm:delete").Before("gorm:commit_or_rollback_transaction").
+			Register("auditoria:borrar", auditarDelete),
+	}
+	for _, err := range pasos {
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// SinAuditoria devuelve una sesión, reutilizable para varias consultas, cuyas
+// operaciones no se auditan; es para cambios del sistema como las migraciones
+// de datos.
+func SinAuditoria(db *gorm.DB) *gorm.DB {
+	return db.Set(claveOmitir, true).Session(&gorm.Session{})
+}
+
+func auditable(db *gorm.DB) bool {
+	if omitir, _ := db.Get(claveOmitir); omitir == true {
+		return false
+	}
+	return db.Error == nil && db.Statement.Schema != nil && EntidadesAuditadas[db.Statement.Schema.Name]
+}
+
+func auditarCreate(db *gorm.DB) {
+	if !auditable(db) {
+		return
+	}
+	for _, fila := range filasDe(db.Statement.ReflectValue) {
+		registrarEvento(db, models.AuditoriaCrear, fila, foto(db, fila))
+	}
+}
+
+// capturarAntes guarda el estado de las filas afectadas antes de modificarlas
+// o borrarlas.
+func capturarAntes(db *gorm.DB) {
+	if !auditable(db) {
+		return
+	}
+	antes, err := cargarFilas(db, pksDe(db, filasDe(db.Statement.ReflectValue)), true)
+	if err != nil {
+		db.AddError(err)
+		return
+	}
+	db.InstanceSet(claveAntes, antes)
+}
+
+func auditarUpdate(db *gorm.DB) {
+	if !auditable(db) || db.RowsAffected == 0 {
+		return
+	}
+	antes := filasAntes(db)
+	if len(antes) == 0 {
+		return
+	}
+
+	despues, err := cargarFilas(db, pksDe(db, antes), false)
+	if err != nil {
+		db.AddError(err)
+		return
+	}
+	porPK := map[string]reflect.Value{}
+	for _, fila := range despues {
+		porPK[clavePK(db, fila)] = fila
+	}
+
+	for _, previa := range antes {
+		actual, ok := porPK[clavePK(db, previa)]
+		if !ok {
+			continue
+		}
+		if cambios := diff(db, previa, actual); len(cambios) > 0 {
+			registrarEvento(db, models.AuditoriaActualizar, actual, cambios)
+		}
+	}
+}
+
+func auditarDelete(db *gorm.DB) {
+	if !auditable(db) || db.RowsAffected == 0 {
+		return
+	}
+	for _, fila := range filasAntes(db) {
+		registrarEvento(db, models.AuditoriaBorrar, fila, foto(db, fila))
+	}
+}
+
+func filasAntes(db *gorm.DB) []reflect.Value {
+	v, ok := db.InstanceGet(claveAntes)
+	if !ok {
+		return nil
+	}
+	filas, _ := v.([]reflect.Value)
+	return filas
+}
+
+// cargarFilas lee de la base las filas con esas claves primarias y, si
+// conWhere, también las que cumplan el WHERE de la operación (borrados o
+// actualizaciones por condición).
+func cargarFilas(db *gorm.DB, pks []interface{}, conWhere bool) ([]reflect.Value, error) {
+	stmt := db.Statement
+	where, hayWhere := stmt.Clauses["WHERE"]
+	if len(pks) == 0 && !(conWhere && hayWhere) {
+		return nil, nil
+	}
+
+	q := db.Session(&gorm.Session{NewDB: true}).Unscoped().Table(stmt.Table)
+	if len(pks) > 0 {
+		q = q.Where(clause.IN{Column: clause.Column{Name: stmt.Schema.PrioritizedPrimaryField.DBName}, Values: pks})
+	}
+	if conWhere && hayWhere {
+		q = q.Clauses(where.Expression)
+	}
+
+	destino := reflect.New(reflect.SliceOf(stmt.Schema.ModelType))
+	if err := q.Find(destino.Interface()).Error; err != nil {
+		return nil, err
+	}
+	return filasDe(destino.Elem()), nil
+}
+
+// filasDe devuelve los structs de un valor que puede ser struct, puntero o slice.
+func filasDe(v reflect.Value) []reflect.Value {
+	v = reflect.Indirect(v)
+	switch v.Kind() {
+	case reflect.Struct:
+		return []reflect.Value{v}
+	case reflect.Slice, reflect.Array:
+		filas := make([]reflect.Value, 0, v.Len())
+		for i := 0; i < v.Len(); i++ {
+			if fila := reflect.Indirect(v.Index(i)); fila.Kind() == reflect.Struct {
+				filas = append(filas, fila)
+			}
+		}
+		return filas
+	}
+	return nil
+}
+
+func pksDe(db *gorm.DB, filas []reflect.Value) []interface{} {
+	pk := db.Statement.Schema.PrioritizedPrimaryField
+	if pk == nil {
+		return nil
+	}
+	var pks []interface{}
+	for _, fila := range filas {
+		if v, zero := pk.ValueOf(db.Statement.Context, fila); !zero {
+			pks = append(pks, v)
+		}
+	}
+	return pks
+}
+
+func clavePK(db *gorm.DB, fila reflect.Value) string {
+	v, _ := db.Statement.Schema.PrioritizedPrimaryField.ValueOf(db.Statement.Context, fila)
+	return fmt.Sprint(v)
+}
+
+// foto devuelve los valores de la fila por columna, con los secretos ocultos.
+func foto(db *gorm.DB, fila reflect.Value) map[string]interface{} {
+	valores := map[string]interface{}{}
+	for _, f := range db.Statement.Schema.Fields {
+		if f.DBName == "" || camposIgnorados[f.DBName] {
+			continue
+		}
+		v, zero := valorCampo(db, f, fila)
+		if esSecreto(f) {
+			if !zero {
+				valores[f.DBName] = oculto
+			}
+			continue
+		}
+		valores[f.DBName] = v
+	}
+	return valores
+}
+
+// diff devuelve {"columna": {"antes": x, "despues": y}} con lo que cambió.
+func diff(db *gorm.DB, antes, despues reflect.Value) map[string]interface{} {
+	cambios := map[string]interface{}{}
+	for _, f := range db.Statement.Schema.Fields {
+		if f.DBName == "" || camposIgnorados[f.DBName] {
+			continue
+		}
+		a, _ := valorCampo(db, f, antes)
+		d, _ := valorCampo(db, f, despues)
+		if reflect.DeepEqual(a, d) {
+			continue
+		}
+		if esSecreto(f) {
+			cambios[f.DBName] = oculto
+			continue
+		}
+		cambios[f.DBName] = map[string]interface{}{"antes": a, "despues": d}
+	}
+	return cambios
+}
+
+// valorCampo devuelve el valor del campo en el struct. No usa f.ValueOf
+// porque con serializer (ej. serializer:json) devuelve el serializador.
+func valorCampo(db *gorm.DB, f *schema.Field, fila reflect.Value) (interface{}, bool) {
+	v := f.ReflectValueOf(db.Statement.Context, fila)
+	return v.Interface(), v.IsZero()
+}
+
+func esSecreto(f *schema.Field) bool {
+	return f.Tag.Get("json") == "-"
+}
+
+func registrarEvento(db *gorm.DB, operacion string, fila reflect.Value, cambios map[string]interface{}) {
+	datos, err := json.Marshal(cambios)
+	if err != nil {
+		db.AddError(err)
+		return
+	}
+
+	evento := models.AuditoriaEvento{
+		Env:       CurrentEnv,
+		Entidad:   db.Statement.Schema.Name,
+		Operacion: operacion,
+		Cambios:   models.JSONCrudo(datos),
+	}
+	if id, _ := db.Statement.Schema.PrioritizedPrimaryField.ValueOf(db.Statement.Context, fila); id != nil {
+		if n, ok := id.(uint); ok {
+			evento.EntidadID = n
+		}
+	}
+	if info := InfoAuditoriaDe(db.Statement.Context); info != nil {
+		if info.Env != "" {
+			evento.Env = info.Env
+		}
+		evento.RequestID = info.RequestID
+		evento.IP = info.IP
+		evento.UsuarioID = info.UsuarioID
+		evento.APIKeyID = info.APIKeyID
+	}
+
+	if err := db.Session(&gorm.Session{NewDB: true}).Create(&evento).Error; err != nil {
+		db.AddError(err)
+	}
+}
@@ -0,0 +1,146 @@
+package database
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+	"ventas-app/config"
+	"ventas-app/models"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+)
+
+func dbAuditada(t *testing.T) *gorm.DB {
+	t.Helper()
+	db, err := Open(config.DatabaseConfig{Driver: DriverSQLite})
+	require.NoError(t, err)
+	require.NoError(t, Migrate(db))
+	return db
+}
+
+func eventos(t *testing.T, db *gorm.DB) []models.AuditoriaEvento {
+	t.Helper()
+	var evs []models.AuditoriaEvento
+	require.NoError(t, db.Order("id").Find(&evs).Error)
+	return evs
+}
+
+func cambios(t *testing.T, ev models.AuditoriaEvento) map[string]interface{} {
+	t.Helper()
+	var m map[string]interface{}
+	require.NoError(t, json.Unmarshal([]byte(ev.Cambios), &m))
+	return m
+}
+
+func TestAuditoria_CrearActualizarBorrar(t *testing.T) {
+	db := dbAuditada(t)
+	actor := uint(7)
+	ctx := ConInfoAuditoria(context.Background(), &InfoAuditoria{
+		RequestID: "req-1", Env: "qa", IP: "10.0.0.1", UsuarioID: &actor,
+	})
+	tx := db.WithContext(ctx)
+
+	p := models.Producto{Nombre: "Yerba", Costo: 100, Precio: 150, Stock: 3}
+	require.NoError(t, tx.Create(&p).Error)
+	p.Precio = 180
+	p.Stock = 5
+	require.NoError(t, tx.Save(&p).Error)
+	require.NoError(t, tx.Model(&models.Producto{}).Where("id = ?", p.ID).Update("stock", 4).Error)
+	require.NoError(t, tx.Delete(&p).Error)
+
+	evs := eventos(t, db)
+	require.Len(t, evs, 4)
+	for _, ev := range evs {
+		assert.Equal(t, "Producto", ev.Entidad)
+		assert.Equal(t, p.ID, ev.EntidadID)
+		assert.Equal(t, "req-1", ev.RequestID)
+		assert.Equal(t, "qa", ev.Env)
+		assert.Equal(t, "10.0.0.1", ev.IP)
+		require.NotNil(t, ev.UsuarioID)
+		assert.Equal(t, actor, *ev.UsuarioID)
+	}
+
+	assert.Equal(t, models.AuditoriaCrear, evs[0].Operacion)
+	assert.Equal(t, "Yerba", cambios(t, evs[0])["nombre"])
+
+	assert.Equal(t, models.AuditoriaActualizar, evs[1].Operacion)
+	c := cambios(t, evs[1])
+	assert.Equal(t, map[string]interface{}{"antes": 150.0, "despues": 180.0}, c["precio"])
+	assert.Equal(t, map[string]interface{}{"antes": 3.0, "despues": 5.0}, c["stock"])
+	assert.NotContains(t, c, "nombre", "solo lo que cambió")
+	assert.NotContains(t, c, "updated_at")
+
+	assert.Equal(t, models.AuditoriaActualizar, evs[2].Operacion)
+	assert.Equal(t, map[string]interface{}{"antes": 5.0, "despues": 4.0}, cambios(t, evs[2])["stock"])
+
+	assert.Equal(t, models.AuditoriaBorrar, evs[3].Operacion)
+	assert.Equal(t, 4.0, cambios(t, evs[3])["stock"])
+}
+
+func TestAuditoria_OcultaSecretos(t *testing.T) {
+	db := dbAuditada(t)
+
+	u := models.Usuario{Nombre: "ana", Clave: "hash-1", Rol: "vendedor"}
+	require.NoError(t, db.Create(&u).Error)
+	u.Clave = "hash-2"
+	require.NoError(t, db.Save(&u).Error)
+
+	evs := eventos(t, db)
+	require.Len(t, evs, 2)
+	assert.Equal(t, oculto, cambios(t, evs[0])["clave"])
+	assert.Equal(t, map[string]interface{}{"clave": oculto}, cambios(t, evs[1]))
+	assert.NotContains(t, string(evs[0].Cambios), "hash-1")
+	assert.NotContains(t, string(evs[1].Cambios), "hash-2")
+
+	assert.Nil(t, evs[0].UsuarioID, "sin InfoAuditoria no hay actor")
+	assert.Equal(t, CurrentEnv, evs[0].Env)
+}
+
+func TestAuditoria_IgnoraContadoresDeLogin(t *testing.T) {
+	db := dbAuditada(t)
+
+	u := models.Usuario{Nombre: "ana", Clave: "hash", Rol: "vendedor"}
+	require.NoError(t, db.Create(&u).Error)
+	u.IntentosFallidos = 1
+	require.NoError(t, db.Save(&u).Error)
+	u.TOTPUltimoPaso = 42
+	require.NoError(t, db.Save(&u).Error)
+	require.Len(t, eventos(t, db), 1, "un intento fallido no es un cambio auditable")
+
+	hasta := time.Now().Add(time.Minute)
+	u.IntentosFallidos = 0
+	u.BloqueadoHasta = &hasta
+	require.NoError(t, db.Save(&u).Error)
+	evs := eventos(t, db)
+	require.Len(t, evs, 2)
+	c := cambios(t, evs[1])
+	assert.Len(t, c, 1)
+	assert.Contains(t, c, "bloqueado_hasta")
+}
+
+func TestAuditoria_SinCambiosNoRegistra(t *testing.T) {
+	db := dbAuditada(t)
+
+	p := models.Producto{Nombre: "Mate", Precio: 10}
+	require.NoError(t, db.Create(&p).Error)
+	require.NoError(t, db.Save(&p).Error)
+	require.NoError(t, db.Create(&models.Venta{ProductoID: p.ID, Cantidad: 1}).Error)
+
+	evs := eventos(t, db)
+	require.Len(t, evs, 2)
+	assert.Equal(t, "Venta", evs[1].Entidad)
+}
+
+func TestAuditoria_RevierteConLaTransaccion(t *testing.T) {
+	db := dbAuditada(t)
+
+	_ = db.Transaction(func(tx *gorm.DB) error {
+		require.NoError(t, tx.Create(&models.Producto{Nombre: "Termo"}).Error)
+		return assert.AnError
+	})
+
+	assert.Empty(t, eventos(t, db))
+}
@@ -6,34 +6,86 @@ import (
 	"fmt"
 	"log"
 	"os"
-	"ventas-app/models"
+	"strings"
+	"sync/atomic"
+	"ventas-app/config"
 
 	"github.com/go-sql-driver/mysql"
 	gormMysql "gorm.io/driver/mysql"
+	"gorm.io/driver/postgres"
+	"gorm.io/driver/sqlite"
 	"gorm.io/gorm"
 )
 
 var DB *gorm.DB
 
-func Connect() *gorm.DB {
-	usuario := os.Getenv("DB_USER")
-	clave := os.Getenv("DB_PASS")
-	host := os.Getenv("DB_HOST")
-	port := os.Getenv("DB_PORT")
-	nombre := os.Getenv("DB_NAME")
-	sslMode := os.Getenv("DB_SSL_MODE")
+// Drivers soportados en DB_DRIVER. MySQL sigue siendo el default (Aiven).
+const (
+	DriverMySQL    = "mysql"
+	DriverPostgres = "postgres"
+	DriverSQLite   = "sqlite"
+)
 
-	// Configurar SSL para Aiven con skip-verify como fallback (solo QA/PROD)
-	err := configurarSSLAiven()
+// memoriaSeq numera las bases SQLite en memoria para que cada Open tenga la suya.
+var memoriaSeq int64
+
+// Connect abre la base de cfg, aplica las migraciones y la registra en DBs
+// bajo env, que pasa a ser el entorno por defecto de GetDB.
+func Connect(env string, cfg config.DatabaseConfig) *gorm.DB {
+	db, err := Open(cfg)
 	if err != nil {
-		log.Printf("Warning: No se pudo configurar SSL con certificado CA: %v", err)
-		err = configurarSSLAivenSimple()
-		if err != nil {
-			log.Printf("Warning: No se pudo configurar SSL simple: %v", err)
-		}
+		log.Fatal("Error al conectar con la base de datos:", err)
+	}
+
+	// Migraciones comunes
+	if err := Migrate(db); err != nil {
+		log.Fatal("Error al migrar la base de datos:", err)
+	}
+
+	// 🔥 REGISTRAR CONEXIÓN EN EL MAPA DBs
+	DBs[env] = db // <-- esta línea hace que GetDB funcione perfecto en CI/QA/PROD
+	CurrentEnv = env
+
+	if cfg.QueryTimeout > 0 {
+		QueryTimeout = cfg.QueryTimeout
 	}
 
+	DB = db
+	return db
+}
+
+// Open abre una conexión con el driver de cfg (mysql, postgres o sqlite).
+// No aplica migraciones; sí registra los callbacks de auditoría.
+func Open(cfg config.DatabaseConfig) (*gorm.DB, error) {
 	var db *gorm.DB
+	var err error
+	switch strings.ToLower(cfg.Driver) {
+	case "", DriverMySQL:
+		db, err = openMySQL(cfg)
+	case DriverPostgres, "postgresql":
+		db, err = openPostgres(cfg)
+	case DriverSQLite, "sqlite3":
+		db, err = openSQLite(cfg)
+	default:
+		return nil, fmt.Errorf("DB_DRIVER no soportado: %q", cfg.Driver)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if err := registrarAuditoria(db); err != nil {
+		return nil, err
+	}
+	return db, nil
+}
+
+func openMySQL(cfg config.DatabaseConfig) (*gorm.DB, error) {
+	usuario := cfg.User
+	clave := cfg.Password
+	host := cfg.Host
+	port := cfg.Port
+	nombre := cfg.Name
+	sslMode := cfg.SSLMode
 
 	// CI/local → sin TLS
 	if sslMode == "disable" {
@@ -41,56 +93,92 @@ func Connect() *gorm.DB {
 			"%s:%s@tcp(%s:%s)/%s?parseTime=true",
 			usuario, clave, host, port, nombre,
 		)
-		db, err = gorm.Open(gormMysql.Open(dsn), &gorm.Config{})
+		db, err := gorm.Open(gormMysql.Open(dsn), gormConfig())
 		if err != nil {
-			log.Fatal("Error al conectar con MySQL (sin TLS):", err)
+			return nil, fmt.Errorf("MySQL (sin TLS): %w", err)
 		}
-	} else {
-		// QA/PROD (Aiven con TLS)
-		dsn := fmt.Sprintf(
-			"%s:%s@tcp(%s:%s)/%s?parseTime=true&tls=custom",
+		return db, nil
+	}
+
+	// Configurar SSL para Aiven con skip-verify como fallback (solo QA/PROD)
+	err := configurarSSLAiven(cfg.CACertFile)
+	if err != nil {
+		log.Printf("Warning: No se pudo configurar SSL con certificado CA: %v", err)
+		err = configurarSSLAivenSimple()
+		if err != nil {
+			log.Printf("Warning: No se pudo configurar SSL simple: %v", err)
+		}
+	}
+
+	// QA/PROD (Aiven con TLS)
+	dsn := fmt.Sprintf(
+		"%s:%s@tcp(%s:%s)/%s?parseTime=true&tls=custom",
+		usuario, clave, host, port, nombre,
+	)
+
+	db, err := gorm.Open(gormMysql.Open(dsn), gormConfig())
+	if err != nil {
+		log.Printf("Error con SSL personalizado, intentando SSL básico: %v", err)
+
+		dsn = fmt.Sprintf(
+			"%s:%s@tcp(%s:%s)/%s?parseTime=true&tls=skip-verify",
 			usuario, clave, host, port, nombre,
 		)
 
-		db, err = gorm.Open(gormMysql.Open(dsn), &gorm.Config{})
+		db, err = gorm.Open(gormMysql.Open(dsn), gormConfig())
 		if err != nil {
-			log.Printf("Error con SSL personalizado, intentando SSL básico: %v", err)
+			return nil, fmt.Errorf("MySQL (Aiven): %w", err)
+		}
+	}
 
-			dsn = fmt.Sprintf(
-				"%s:%s@tcp(%s:%s)/%s?parseTime=true&tls=skip-verify",
-				usuario, clave, host, port, nombre,
-			)
+	return db, nil
+}
 
-			db, err = gorm.Open(gormMysql.Open(dsn), &gorm.Config{})
-			if err != nil {
-				log.Fatal("Error al conectar con MySQL (Aiven):", err)
-			}
-		}
+func openPostgres(cfg config.DatabaseConfig) (*gorm.DB, error) {
+	sslMode := cfg.SSLMode
+	if sslMode == "" {
+		sslMode = "require"
+	}
+	port := cfg.Port
+	if port == "" {
+		port = "5432"
 	}
 
-	// Migraciones comunes
-	db.AutoMigrate(
-		&models.Usuario{},
-		&models.Producto{},
-		&models.Compra{},
-		&models.Venta{},
+	dsn := fmt.Sprintf(
+		"host=%s user=%s password=%s dbname=%s port=%s sslmode=%s",
+		cfg.Host, cfg.User, cfg.Password, cfg.Name, port, sslMode,
 	)
 
-	// 🔥 REGISTRAR CONEXIÓN EN EL MAPA DBs
-	// SI ESTAMOS EN CI → usar clave "ci"
-	env := os.Getenv("APP_ENV")
-	if os.Getenv("CI") == "true" {
-		env = "ci"
+	db, err := gorm.Open(postgres.Open(dsn), gormConfig())
+	if err != nil {
+		return nil, fmt.Errorf("PostgreSQL: %w", err)
 	}
+	return db, nil
+}
 
-	DBs[env] = db // <-- esta línea hace que GetDB funcione perfecto en CI/QA/PROD
+// openSQLite usa Name (DB_NAME) como ruta del archivo. Vacío o ":memory:" abre
+// una base en memoria compartida entre las conexiones del pool (útil en tests).
+func openSQLite(cfg config.DatabaseConfig) (*gorm.DB, error) {
+	dsn := cfg.Name
+	if dsn == "" || dsn == ":memory:" {
+		n := atomic.AddInt64(&memoriaSeq, 1)
+		dsn = fmt.Sprintf("file:ventas_mem_%d?mode=memory&cache=shared", n)
+	}
 
-	DB = db
-	return db
+	db, err := gorm.Open(sqlite.Open(dsn), gormConfig())
+	if err != nil {
+		return nil, fmt.Errorf("SQLite: %w", err)
+	}
+	return db, nil
+}
+
+// gormConfig traduce los errores del driver (p. ej. clave duplicada) a los
+// errores de gorm, para que los controllers no dependan del motor.
+func gormConfig() *gorm.Config {
+	return &gorm.Config{TranslateError: true}
 }
 
-func configurarSSLAiven() error {
-	caCertPath := "BaltimoreCyberTrustRoot.crt.pem"
+func configurarSSLAiven(caCertPath string) error {
 	caCert, err := os.ReadFile(caCertPath)
 	if err != nil {
 		return fmt.Errorf("error leyendo certificado CA: %v", err)
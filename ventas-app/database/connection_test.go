@@ -0,0 +1,182 @@
+package database
+
+import (
+	"context"
+	"testing"
+	"time"
+	"ventas-app/config"
+	"ventas-app/models"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestOpen_SQLiteEnMemoria(t *testing.T) {
+	db, err := Open(config.DatabaseConfig{Driver: DriverSQLite, Name: ":memory:"})
+	assert.NoError(t, err)
+	assert.NoError(t, Migrate(db))
+
+	p := models.Producto{Nombre: "Yerba", Costo: 100, Precio: 150, Stock: 3}
+	assert.NoError(t, db.Create(&p).Error)
+	assert.NotZero(t, p.ID)
+
+	var leido models.Producto
+	assert.NoError(t, db.First(&leido, p.ID).Error)
+	assert.Equal(t, "Yerba", leido.Nombre)
+}
+
+func TestOpen_SQLiteBasesIndependientes(t *testing.T) {
+	db1, err := Open(config.DatabaseConfig{Driver: DriverSQLite})
+	assert.NoError(t, err)
+	db2, err := Open(config.DatabaseConfig{Driver: DriverSQLite})
+	assert.NoError(t, err)
+	assert.NoError(t, Migrate(db1))
+	assert.NoError(t, Migrate(db2))
+
+	assert.NoError(t, db1.Create(&models.Producto{Nombre: "Solo en db1"}).Error)
+
+	var count int64
+	db2.Model(&models.Producto{}).Count(&count)
+	assert.Zero(t, count)
+}
+
+func TestOpen_DriverDesconocido(t *testing.T) {
+	db, err := Open(config.DatabaseConfig{Driver: "oracle"})
+
+	assert.Error(t, err)
+	assert.Nil(t, db)
+}
+
+func TestConnect_RegistraSQLiteEnDBs(t *testing.T) {
+	defer delete(DBs, "test")
+
+	db := Connect("test", config.DatabaseConfig{Driver: DriverSQLite, Name: ":memory:"})
+
+	assert.NotNil(t, db)
+	assert.Same(t, db, DBs["test"])
+	assert.True(t, db.Migrator().HasTable(&models.Venta{}))
+}
+
+func TestMigrate_RegistraVersion(t *testing.T) {
+	db, err := Open(config.DatabaseConfig{Driver: DriverSQLite, Name: ":memory:"})
+	assert.NoError(t, err)
+
+	version, err := AppliedVersion(db)
+	assert.Error(t, err, "sin migrar la tabla no existe")
+	assert.Zero(t, version)
+
+	assert.NoError(t, Migrate(db))
+	assert.NoError(t, Migrate(db), "migrar dos veces no debe fallar")
+
+	version, err = AppliedVersion(db)
+	assert.NoError(t, err)
+	assert.Equal(t, SchemaVersion, version)
+}
+
+func TestClose_CierraLosPools(t *testing.T) {
+	defer delete(DBs, "test")
+
+	db := Connect("test", config.DatabaseConfig{Driver: DriverSQLite, Name: ":memory:"})
+	sqlDB, err := db.DB()
+	assert.NoError(t, err)
+
+	assert.NoError(t, Close(context.Background()))
+	assert.Error(t, sqlDB.Ping())
+}
+
+func TestMigrate_CompletaDatosDeVentasAnteriores(t *testing.T) {
+	db, err := Open(config.DatabaseConfig{Driver: DriverSQLite})
+	assert.NoError(t, err)
+	assert.NoError(t, Migrate(db))
+
+	// Base en la versión 7: ventas sin IVA desglosado ni costo
+	sinAuditoria := SinAuditoria(db)
+	assert.NoError(t, sinAuditoria.Create(&models.Producto{Nombre: "Mate", Costo: 60}).Error)
+	assert.NoError(t, sinAuditoria.Create(&models.Venta{ProductoID: 1, Cantidad: 1, PrecioFinal: 121}).Error)
+	assert.NoError(t, db.Where("version > ?", 7).Delete(&SchemaMigration{}).Error)
+	assert.NoError(t, Migrate(db))
+
+	var v models.Venta
+	assert.NoError(t, db.First(&v).Error)
+	assert.InDelta(t, 100, v.Neto, 0.001)
+	assert.InDelta(t, 21, v.IVA, 0.001)
+	assert.Equal(t, 60.0, v.CostoUnit)
+
+	// Ya migrada, una venta de costo 0 no toma el costo actual
+	assert.NoError(t, sinAuditoria.Create(&models.Venta{ProductoID: 1, Cantidad: 1, PrecioFinal: 121, Neto: 100, IVA: 21}).Error)
+	assert.NoError(t, Migrate(db))
+	var nueva models.Venta
+	assert.NoError(t, db.Last(&nueva).Error)
+	assert.Zero(t, nueva.CostoUnit)
+
+	var eventos int64
+	db.Model(&models.AuditoriaEvento{}).Count(&eventos)
+	assert.Zero(t, eventos, "la migración de datos no se audita")
+}
+
+func TestMigrate_CompletaCategoriaDeSnapshots(t *testing.T) {
+	db, err := Open(config.DatabaseConfig{Driver: DriverSQLite})
+	assert.NoError(t, err)
+	assert.NoError(t, Migrate(db))
+
+	// Base en la versión 17: snapshots sin categoría
+	sinAuditoria := SinAuditoria(db)
+	assert.NoError(t, sinAuditoria.Create(&models.Categoria{Nombre: "Bazar"}).Error)
+	categoria := uint(1)
+	assert.NoError(t, sinAuditoria.Create(&models.Producto{Nombre: "Mate", CategoriaID: &categoria}).Error)
+	assert.NoError(t, sinAuditoria.Create(&models.Producto{Nombre: "Yerba"}).Error)
+	assert.NoError(t, sinAuditoria.Create(&models.InventarioSnapshot{Fecha: time.Now(), ProductoID: 1}).Error)
+	assert.NoError(t, sinAuditoria.Create(&models.InventarioSnapshot{Fecha: time.Now(), ProductoID: 2}).Error)
+	assert.NoError(t, db.Where("version > ?", 17).Delete(&SchemaMigration{}).Error)
+	assert.NoError(t, Migrate(db))
+
+	var snapshots []models.InventarioSnapshot
+	assert.NoError(t, db.Order("producto_id").Find(&snapshots).Error)
+	assert.Len(t, snapshots, 2)
+	assert.Equal(t, &categoria, snapshots[0].CategoriaID)
+	assert.Nil(t, snapshots[1].CategoriaID)
+}
+
+func TestMigrate_VersionSesionDeRevocados(t *testing.T) {
+	db, err := Open(config.DatabaseConfig{Driver: DriverSQLite})
+	assert.NoError(t, err)
+	assert.NoError(t, Migrate(db))
+
+	// Base en la versión 18: revocaciones solo por fecha
+	sinAuditoria := SinAuditoria(db)
+	ahora := time.Now()
+	assert.NoError(t, sinAuditoria.Create(&models.Usuario{Nombre: "ana", Clave: "x", Rol: "vendedor", SesionesRevocadasEn: &ahora}).Error)
+	assert.NoError(t, sinAuditoria.Create(&models.Usuario{Nombre: "beto", Clave: "x", Rol: "vendedor"}).Error)
+	assert.NoError(t, db.Where("version > ?", 18).Delete(&SchemaMigration{}).Error)
+	assert.NoError(t, Migrate(db))
+
+	var usuarios []models.Usuario
+	assert.NoError(t, db.Order("id").Find(&usuarios).Error)
+	assert.Len(t, usuarios, 2)
+	assert.Equal(t, uint(1), usuarios[0].VersionSesion)
+	assert.Equal(t, uint(0), usuarios[1].VersionSesion)
+}
+
+func TestMigrate_NormalizaCodigosBarras(t *testing.T) {
+	db, err := Open(config.DatabaseConfig{Driver: DriverSQLite})
+	assert.NoError(t, err)
+	assert.NoError(t, Migrate(db))
+
+	// Base en la versión 19: códigos con su largo original, el mismo GTIN
+	// cargado como UPC-A y como EAN-13
+	sinAuditoria := SinAuditoria(db)
+	assert.NoError(t, sinAuditoria.Create(&models.Producto{Nombre: "Mate"}).Error)
+	for _, codigo := range []string{"012345678905", "96385074", "0012345678905", "07790070410122"} {
+		assert.NoError(t, sinAuditoria.Create(&models.CodigoBarras{ProductoID: 1, Codigo: codigo}).Error)
+	}
+	assert.NoError(t, db.Where("version > ?", 19).Delete(&SchemaMigration{}).Error)
+	assert.NoError(t, Migrate(db))
+
+	var codigos []models.CodigoBarras
+	assert.NoError(t, db.Order("id").Find(&codigos).Error)
+	var valores []string
+	for _, cb := range codigos {
+		valores = append(valores, cb.Codigo)
+	}
+	assert.Equal(t, []string{"00012345678905", "00000096385074", "07790070410122"}, valores)
+	assert.Equal(t, uint(1), codigos[0].ID, "se conserva el primero que se registró")
+}
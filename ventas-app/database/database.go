@@ -1,6 +1,9 @@
 package database
 
 import (
+	"context"
+	"fmt"
+
 	"gorm.io/gorm"
 )
 
@@ -54,3 +57,18 @@ func connectFromMap(env map[string]string) *gorm.DB {
 	return db
 }
 */
+
+// Close cierra los pools de todas las conexiones registradas en DBs.
+func Close(ctx context.Context) error {
+	var primero error
+	for env, db := range DBs {
+		sqlDB, err := db.DB()
+		if err == nil {
+			err = sqlDB.Close()
+		}
+		if err != nil && primero == nil {
+			primero = fmt.Errorf("cerrando base %s: %w", env, err)
+		}
+	}
+	return primero
+}
@@ -1,12 +1,48 @@
 package database
 
+import "context"
+
 // DBHandler define una interfaz mínima que abstrae GORM para facilitar tests.
 type DBHandler interface {
+	// WithContext devuelve un handler cuyas consultas se cancelan junto con ctx
+	// (típicamente el contexto del request HTTP).
+	WithContext(ctx context.Context) DBHandler
 	// Where devuelve otra DBHandler para permitir encadenamiento en controllers.
 	Where(query interface{}, args ...interface{}) DBHandler
+	// Order ("col DESC") y Limit también se encadenan; Limit(-1) lo quita.
+	Order(value string) DBHandler
+	Limit(n int) DBHandler
+	// Model, Select y Group arman consultas de agregación que se leen con
+	// Scan (ej. reportes); Driver devuelve DriverSQLite, DriverPostgres, ...
+	// para las expresiones que dependen del motor.
+	Model(value interface{}) DBHandler
+	Select(query interface{}, args ...interface{}) DBHandler
+	Group(name string) DBHandler
+	Scan(dest interface{}) error
+	Driver() string
+	// Joins agrega un JOIN a una consulta de agregación; Tabla da el nombre
+	// de la tabla de un modelo para armarlo (y calificar columnas) sin
+	// escribirlo a mano.
+	Joins(query string, args ...interface{}) DBHandler
+	Tabla(modelo interface{}) string
+	// ParaActualizar bloquea las filas que lean First/Find (SELECT ... FOR
+	// UPDATE) hasta que termine la transacción, para que otra no las lea y
+	// modifique en el medio. SQLite lo ignora: ahí las escrituras ya se
+	// serializan y la segunda transacción falla en vez de esperar.
+	ParaActualizar() DBHandler
 	// First/Find/Create/Save ejecutan la operación y retornan un error si falla.
 	First(dest interface{}, conds ...interface{}) error
 	Create(value interface{}) error
 	Save(value interface{}) error
 	Find(dest interface{}, conds ...interface{}) error
+	// UpdateColumns actualiza solo esas columnas en las filas del Model que
+	// cumplen los Where, sin tocar UpdatedAt, y devuelve cuántas cambió. Un
+	// valor gorm.Expr("stock - ?", n) se calcula en la base, así que sirve para
+	// contadores y descuentos sin carreras; con una condición en el Where (ej.
+	// "stock >= ?") 0 filas indica que no se cumplió.
+	UpdateColumns(columnas map[string]interface{}) (int64, error)
+	// Delete hace soft delete si el modelo tiene DeletedAt (gorm.Model).
+	Delete(value interface{}, conds ...interface{}) error
+	// Transaction ejecuta fn en una transacción: si fn devuelve error se revierte todo.
+	Transaction(fn func(tx DBHandler) error) error
 }
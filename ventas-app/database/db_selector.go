@@ -1,33 +1,49 @@
 package database
 
 import (
-	"os"
+	"time"
 
 	"github.com/gin-gonic/gin"
 )
 
-var GetDB func(c *gin.Context) DBHandler = func(c *gin.Context) DBHandler {
+// QueryTimeout es el límite por consulta que GetDB aplica a cada handler.
+// Se inicializa desde la configuración en Connect.
+var QueryTimeout = 10 * time.Second
+
+// CurrentEnv es el entorno del servidor (el último registrado por Connect).
+var CurrentEnv string
+
+// EnvSeleccionado devuelve el entorno cuya base usa el request: el del
+// servidor, o el del header X-Env si ese entorno está inicializado.
+func EnvSeleccionado(c *gin.Context) string {
 	// 1) Determinar entorno actual del servidor
-	current := os.Getenv("APP_ENV")
-	if os.Getenv("CI") == "true" {
-		current = "ci"
-	}
+	current := CurrentEnv
 	if current == "" {
 		current = "qa"
 	}
 
 	// 2) Permitir override opcional vía header si existe y está inicializado
 	headerEnv := c.GetHeader("X-Env")
-	selected := current
 	if headerEnv != "" {
 		if _, ok := DBs[headerEnv]; ok {
-			selected = headerEnv
+			return headerEnv
 		}
 	}
+	return current
+}
+
+var GetDB func(c *gin.Context) DBHandler = func(c *gin.Context) DBHandler {
+	selected := EnvSeleccionado(c)
 
 	db := DBs[selected]
 	if db == nil {
 		panic("Base de datos no inicializada para entorno: " + selected)
 	}
-	return &GormDB{DB: db}
+
+	// 3) Las consultas se cortan si el cliente se va o vence el request
+	handler := &GormDB{DB: db, QueryTimeout: QueryTimeout}
+	if c.Request != nil {
+		return handler.WithContext(c.Request.Context())
+	}
+	return handler
 }
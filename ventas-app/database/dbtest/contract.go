@@ -0,0 +1,315 @@
+// Package dbtest contiene la suite de contrato que debe cumplir cualquier
+// implementación de database.DBHandler (GormDB sobre SQLite, MemoryDB, ...).
+package dbtest
+
+import (
+	"errors"
+	"testing"
+	"time"
+	"ventas-app/database"
+	"ventas-app/models"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+)
+
+// RunContract ejecuta la suite. nueva debe devolver un handler vacío y con
+// las tablas de models ya creadas en cada llamada.
+func RunContract(t *testing.T, nueva func(t *testing.T) database.DBHandler) {
+	t.Run("Create asigna ID y timestamps", func(t *testing.T) {
+		db := nueva(t)
+		p := models.Producto{Nombre: "Mate", Precio: 10, Stock: 1}
+
+		require.NoError(t, db.Create(&p))
+
+		assert.NotZero(t, p.ID)
+		assert.False(t, p.CreatedAt.IsZero())
+		assert.False(t, p.UpdatedAt.IsZero())
+
+		otro := models.Producto{Nombre: "Bombilla"}
+		require.NoError(t, db.Create(&otro))
+		assert.Greater(t, otro.ID, p.ID)
+	})
+
+	t.Run("First por clave primaria", func(t *testing.T) {
+		db := nueva(t)
+		seed(t, db)
+
+		var p models.Producto
+		require.NoError(t, db.First(&p, uint(2)))
+		assert.Equal(t, "Termo", p.Nombre)
+
+		err := db.First(&p, uint(999))
+		assert.True(t, errors.Is(err, gorm.ErrRecordNotFound), "se esperaba ErrRecordNotFound, fue %v", err)
+	})
+
+	t.Run("Where filtra por el valor buscado", func(t *testing.T) {
+		db := nueva(t)
+		require.NoError(t, db.Create(&models.Usuario{Nombre: "ana", Clave: "x", Rol: "vendedor"}))
+		require.NoError(t, db.Create(&models.Usuario{Nombre: "beto", Clave: "y", Rol: "comprador"}))
+
+		var u models.Usuario
+		require.NoError(t, db.Where("nombre = ?", "beto").First(&u))
+		assert.Equal(t, "beto", u.Nombre)
+		assert.Equal(t, "comprador", u.Rol)
+
+		err := db.Where("nombre = ?", "carla").First(&u)
+		assert.True(t, errors.Is(err, gorm.ErrRecordNotFound))
+	})
+
+	t.Run("Where con AND y comparaciones", func(t *testing.T) {
+		db := nueva(t)
+		seed(t, db)
+
+		var productos []models.Producto
+		require.NoError(t, db.Where("precio >= ? AND stock < ?", 20.0, 10).Find(&productos))
+		require.Len(t, productos, 1)
+		assert.Equal(t, "Termo", productos[0].Nombre)
+
+		require.NoError(t, db.Where("stock > ?", 0).Where("nombre <> ?", "Mate").Find(&productos))
+		require.Len(t, productos, 1)
+		assert.Equal(t, "Termo", productos[0].Nombre)
+
+		require.NoError(t, db.Where("id IN ?", []uint{1, 3}).Find(&productos))
+		assert.Len(t, productos, 2)
+
+		require.NoError(t, db.Where("nombre LIKE ?", "%a%").Find(&productos))
+		assert.Len(t, productos, 2)
+	})
+
+	t.Run("Where con map y struct", func(t *testing.T) {
+		db := nueva(t)
+		seed(t, db)
+
+		var p models.Producto
+		require.NoError(t, db.Where(map[string]interface{}{"nombre": "Yerba"}).First(&p))
+		assert.Equal(t, uint(3), p.ID)
+
+		var q models.Producto
+		require.NoError(t, db.Where(&models.Producto{Stock: 5}).First(&q))
+		assert.Equal(t, "Termo", q.Nombre)
+	})
+
+	t.Run("Find sin resultados devuelve slice vacío", func(t *testing.T) {
+		db := nueva(t)
+
+		var productos []models.Producto
+		require.NoError(t, db.Find(&productos))
+		assert.Empty(t, productos)
+
+		seed(t, db)
+		require.NoError(t, db.Find(&productos))
+		assert.Len(t, productos, 3)
+	})
+
+	t.Run("Save actualiza la fila existente", func(t *testing.T) {
+		db := nueva(t)
+		seed(t, db)
+
+		var p models.Producto
+		require.NoError(t, db.First(&p, uint(1)))
+		creado := p.CreatedAt
+		time.Sleep(5 * time.Millisecond)
+
+		p.Stock = 42
+		require.NoError(t, db.Save(&p))
+
+		var leido models.Producto
+		require.NoError(t, db.First(&leido, uint(1)))
+		assert.Equal(t, 42, leido.Stock)
+		assert.True(t, leido.UpdatedAt.After(creado))
+		assert.WithinDuration(t, creado, leido.CreatedAt, time.Millisecond)
+
+		var todos []models.Producto
+		require.NoError(t, db.Find(&todos))
+		assert.Len(t, todos, 3)
+	})
+
+	t.Run("Save sin ID inserta", func(t *testing.T) {
+		db := nueva(t)
+
+		p := models.Producto{Nombre: "Nuevo"}
+		require.NoError(t, db.Save(&p))
+		assert.NotZero(t, p.ID)
+	})
+
+	t.Run("UpdateColumns calcula en la base y respeta el Where", func(t *testing.T) {
+		db := nueva(t)
+		seed(t, db)
+
+		n, err := db.Model(&models.Producto{}).Where("id = ? AND stock >= ?", 2, 3).
+			UpdateColumns(map[string]interface{}{"stock": gorm.Expr("stock - ?", 3)})
+		require.NoError(t, err)
+		assert.Equal(t, int64(1), n)
+
+		// La condición ya no se cumple: no cambia nada
+		n, err = db.Model(&models.Producto{}).Where("id = ? AND stock >= ?", 2, 3).
+			UpdateColumns(map[string]interface{}{"stock": gorm.Expr("stock - ?", 3)})
+		require.NoError(t, err)
+		assert.Zero(t, n)
+
+		var p models.Producto
+		require.NoError(t, db.First(&p, uint(2)))
+		assert.Equal(t, 2, p.Stock)
+
+		// Con el ID en el Model filtra por él; nil escribe NULL
+		u := models.Usuario{Nombre: "ana", Clave: "x", Rol: "vendedor"}
+		require.NoError(t, db.Create(&u))
+		hasta := time.Now().Add(time.Hour)
+		n, err = db.Model(&models.Usuario{Model: gorm.Model{ID: u.ID}}).
+			UpdateColumns(map[string]interface{}{"bloqueado_hasta": hasta, "intentos_fallidos": gorm.Expr("intentos_fallidos + ?", 2)})
+		require.NoError(t, err)
+		assert.Equal(t, int64(1), n)
+		require.NoError(t, db.First(&u, u.ID))
+		require.NotNil(t, u.BloqueadoHasta)
+		assert.Equal(t, 2, u.IntentosFallidos)
+
+		_, err = db.Model(&models.Usuario{}).Where("id = ?", u.ID).
+			UpdateColumns(map[string]interface{}{"bloqueado_hasta": nil})
+		require.NoError(t, err)
+		var leido models.Usuario
+		require.NoError(t, db.First(&leido, u.ID))
+		assert.Nil(t, leido.BloqueadoHasta)
+	})
+
+	t.Run("Delete es soft delete", func(t *testing.T) {
+		db := nueva(t)
+		seed(t, db)
+
+		require.NoError(t, db.Delete(&models.Producto{}, uint(2)))
+
+		var p models.Producto
+		err := db.First(&p, uint(2))
+		assert.True(t, errors.Is(err, gorm.ErrRecordNotFound))
+
+		var productos []models.Producto
+		require.NoError(t, db.Find(&productos))
+		assert.Len(t, productos, 2)
+
+		err = db.Delete(&models.Producto{})
+		assert.True(t, errors.Is(err, gorm.ErrMissingWhereClause), "borrar sin condiciones debe fallar, fue %v", err)
+	})
+
+	t.Run("Create respeta índices únicos", func(t *testing.T) {
+		db := nueva(t)
+		require.NoError(t, db.Create(&models.Usuario{Nombre: "ana", Clave: "x", Rol: "vendedor"}))
+
+		err := db.Create(&models.Usuario{Nombre: "ana", Clave: "y", Rol: "comprador"})
+		assert.True(t, errors.Is(err, gorm.ErrDuplicatedKey), "se esperaba ErrDuplicatedKey, fue %v", err)
+	})
+
+	t.Run("Order y Limit", func(t *testing.T) {
+		db := nueva(t)
+		seed(t, db)
+
+		var productos []models.Producto
+		require.NoError(t, db.Order("precio DESC").Limit(2).Find(&productos))
+		require.Len(t, productos, 2)
+		assert.Equal(t, "Termo", productos[0].Nombre)
+		assert.Equal(t, "Mate", productos[1].Nombre)
+
+		require.NoError(t, db.Where("stock > ?", 0).Order("nombre ASC").Find(&productos))
+		require.Len(t, productos, 2)
+		assert.Equal(t, "Mate", productos[0].Nombre)
+	})
+
+	t.Run("Transaction confirma si fn no falla", func(t *testing.T) {
+		db := nueva(t)
+
+		err := db.Transaction(func(tx database.DBHandler) error {
+			return tx.Create(&models.Producto{Nombre: "En tx"})
+		})
+		require.NoError(t, err)
+
+		var productos []models.Producto
+		require.NoError(t, db.Find(&productos))
+		assert.Len(t, productos, 1)
+	})
+
+	t.Run("ParaActualizar lee dentro de una transacción", func(t *testing.T) {
+		db := nueva(t)
+		seed(t, db)
+
+		err := db.Transaction(func(tx database.DBHandler) error {
+			var p models.Producto
+			if err := tx.ParaActualizar().First(&p, uint(2)); err != nil {
+				return err
+			}
+			assert.Equal(t, "Termo", p.Nombre)
+
+			var productos []models.Producto
+			if err := tx.ParaActualizar().Where("stock > ?", 0).Find(&productos); err != nil {
+				return err
+			}
+			assert.Len(t, productos, 2)
+			return nil
+		})
+		require.NoError(t, err)
+	})
+
+	t.Run("Tabla usa los nombres de la migración", func(t *testing.T) {
+		db := nueva(t)
+		assert.Equal(t, "venta", db.Tabla(&models.Venta{}))
+		assert.Equal(t, "productos", db.Tabla(&models.Producto{}))
+	})
+
+	t.Run("Joins no cambia las filas de la tabla principal", func(t *testing.T) {
+		db := nueva(t)
+		seed(t, db)
+		require.NoError(t, db.Create(&models.Venta{ProductoID: 1, Cantidad: 2}))
+		require.NoError(t, db.Create(&models.Venta{ProductoID: 2, Cantidad: 3}))
+
+		venta, productos := db.Tabla(&models.Venta{}), db.Tabla(&models.Producto{})
+		var ventas []models.Venta
+		err := db.Model(&models.Venta{}).
+			Joins("LEFT JOIN "+productos+" ON "+productos+".id = "+venta+".producto_id").
+			Where("cantidad = ?", 3).Find(&ventas)
+		require.NoError(t, err)
+		require.Len(t, ventas, 1)
+		assert.Equal(t, uint(2), ventas[0].ProductoID)
+	})
+
+	t.Run("Transaction revierte si fn falla", func(t *testing.T) {
+		db := nueva(t)
+		seed(t, db)
+		errFallo := errors.New("fallo")
+
+		err := db.Transaction(func(tx database.DBHandler) error {
+			var p models.Producto
+			if err := tx.First(&p, uint(1)); err != nil {
+				return err
+			}
+			p.Stock = 0
+			if err := tx.Save(&p); err != nil {
+				return err
+			}
+			if err := tx.Create(&models.Venta{ProductoID: 1, Cantidad: 2}); err != nil {
+				return err
+			}
+			return errFallo
+		})
+		assert.True(t, errors.Is(err, errFallo))
+
+		var p models.Producto
+		require.NoError(t, db.First(&p, uint(1)))
+		assert.Equal(t, 10, p.Stock)
+
+		var ventas []models.Venta
+		require.NoError(t, db.Find(&ventas))
+		assert.Empty(t, ventas)
+	})
+}
+
+// seed carga tres productos con IDs 1, 2 y 3.
+func seed(t *testing.T, db database.DBHandler) {
+	t.Helper()
+	for _, p := range []models.Producto{
+		{Nombre: "Mate", Precio: 15, Stock: 10},
+		{Nombre: "Termo", Precio: 30, Stock: 5},
+		{Nombre: "Yerba", Precio: 8, Stock: 0},
+	} {
+		p := p
+		require.NoError(t, db.Create(&p))
+	}
+}
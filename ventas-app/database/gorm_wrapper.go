@@ -1,30 +1,133 @@
 package database
 
 import (
-    "gorm.io/gorm"
+	"context"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
 // GormDB envuelve *gorm.DB para implementar database.DBHandler
-type GormDB struct{
-    DB *gorm.DB
+type GormDB struct {
+	DB *gorm.DB
+	// QueryTimeout limita cada consulta individual; 0 = solo el contexto del request.
+	QueryTimeout time.Duration
+}
+
+func (g *GormDB) WithContext(ctx context.Context) DBHandler {
+	return &GormDB{DB: g.DB.WithContext(ctx), QueryTimeout: g.QueryTimeout}
 }
 
 func (g *GormDB) Where(query interface{}, args ...interface{}) DBHandler {
-    return &GormDB{DB: g.DB.Where(query, args...)}
+	return &GormDB{DB: g.DB.Where(query, args...), QueryTimeout: g.QueryTimeout}
+}
+
+func (g *GormDB) Order(value string) DBHandler {
+	return &GormDB{DB: g.DB.Order(value), QueryTimeout: g.QueryTimeout}
+}
+
+func (g *GormDB) Limit(n int) DBHandler {
+	return &GormDB{DB: g.DB.Limit(n), QueryTimeout: g.QueryTimeout}
+}
+
+func (g *GormDB) Model(value interface{}) DBHandler {
+	return &GormDB{DB: g.DB.Model(value), QueryTimeout: g.QueryTimeout}
+}
+
+func (g *GormDB) Select(query interface{}, args ...interface{}) DBHandler {
+	return &GormDB{DB: g.DB.Select(query, args...), QueryTimeout: g.QueryTimeout}
+}
+
+func (g *GormDB) Group(name string) DBHandler {
+	return &GormDB{DB: g.DB.Group(name), QueryTimeout: g.QueryTimeout}
+}
+
+func (g *GormDB) Joins(query string, args ...interface{}) DBHandler {
+	return &GormDB{DB: g.DB.Joins(query, args...), QueryTimeout: g.QueryTimeout}
+}
+
+func (g *GormDB) ParaActualizar() DBHandler {
+	return &GormDB{DB: g.DB.Clauses(clause.Locking{Strength: "UPDATE"}), QueryTimeout: g.QueryTimeout}
+}
+
+// Tabla resuelve el nombre con la NamingStrategy de la conexión.
+func (g *GormDB) Tabla(modelo interface{}) string {
+	stmt := &gorm.Statement{DB: g.DB}
+	if err := stmt.Parse(modelo); err != nil {
+		return ""
+	}
+	return stmt.Schema.Table
+}
+
+func (g *GormDB) Scan(dest interface{}) error {
+	db, cancel := g.conTimeout()
+	defer cancel()
+	return db.Scan(dest).Error
+}
+
+// Driver devuelve el nombre del dialecto de gorm, que coincide con DriverSQLite,
+// DriverPostgres y DriverMySQL.
+func (g *GormDB) Driver() string {
+	return g.DB.Dialector.Name()
 }
 
 func (g *GormDB) First(dest interface{}, conds ...interface{}) error {
-    return g.DB.First(dest, conds...).Error
+	db, cancel := g.conTimeout()
+	defer cancel()
+	return db.First(dest, conds...).Error
 }
 
 func (g *GormDB) Create(value interface{}) error {
-    return g.DB.Create(value).Error
+	db, cancel := g.conTimeout()
+	defer cancel()
+	return db.Create(value).Error
 }
 
 func (g *GormDB) Save(value interface{}) error {
-    return g.DB.Save(value).Error
+	db, cancel := g.conTimeout()
+	defer cancel()
+	return db.Save(value).Error
+}
+
+// UpdateColumns devuelve RowsAffected; en MySQL solo cuenta las filas cuyo
+// valor cambió, no las que cumplieron el Where.
+func (g *GormDB) UpdateColumns(columnas map[string]interface{}) (int64, error) {
+	db, cancel := g.conTimeout()
+	defer cancel()
+	res := db.UpdateColumns(columnas)
+	return res.RowsAffected, res.Error
 }
 
 func (g *GormDB) Find(dest interface{}, conds ...interface{}) error {
-    return g.DB.Find(dest, conds...).Error
+	db, cancel := g.conTimeout()
+	defer cancel()
+	return db.Find(dest, conds...).Error
+}
+
+func (g *GormDB) Delete(value interface{}, conds ...interface{}) error {
+	db, cancel := g.conTimeout()
+	defer cancel()
+	return db.Delete(value, conds...).Error
+}
+
+// Transaction usa el contexto del request; el QueryTimeout se sigue aplicando
+// a cada consulta dentro de fn.
+func (g *GormDB) Transaction(fn func(tx DBHandler) error) error {
+	return g.DB.Transaction(func(tx *gorm.DB) error {
+		return fn(&GormDB{DB: tx, QueryTimeout: g.QueryTimeout})
+	})
+}
+
+// conTimeout deriva del contexto actual uno que vence a los QueryTimeout.
+func (g *GormDB) conTimeout() (*gorm.DB, context.CancelFunc) {
+	if g.QueryTimeout <= 0 {
+		return g.DB, func() {}
+	}
+	ctx := g.DB.Statement.Context
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	ctx, cancel := context.WithTimeout(ctx, g.QueryTimeout)
+	return g.DB.WithContext(ctx), cancel
 }
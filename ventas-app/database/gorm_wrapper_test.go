@@ -0,0 +1,50 @@
+package database_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+	"ventas-app/config"
+	"ventas-app/database"
+	"ventas-app/database/dbtest"
+	"ventas-app/models"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGormDB_Contrato(t *testing.T) {
+	dbtest.RunContract(t, func(t *testing.T) database.DBHandler {
+		db, err := database.Open(config.DatabaseConfig{Driver: database.DriverSQLite})
+		require.NoError(t, err)
+		require.NoError(t, database.Migrate(db))
+		return &database.GormDB{DB: db}
+	})
+}
+
+func TestGormDB_QueryTimeout(t *testing.T) {
+	db, err := database.Open(config.DatabaseConfig{Driver: database.DriverSQLite})
+	require.NoError(t, err)
+	require.NoError(t, database.Migrate(db))
+
+	handler := &database.GormDB{DB: db, QueryTimeout: time.Nanosecond}
+	time.Sleep(time.Millisecond)
+
+	var productos []models.Producto
+	err = handler.Find(&productos)
+	assert.True(t, errors.Is(err, context.DeadlineExceeded), "se esperaba DeadlineExceeded, fue %v", err)
+}
+
+func TestGormDB_WithContextCancelado(t *testing.T) {
+	db, err := database.Open(config.DatabaseConfig{Driver: database.DriverSQLite})
+	require.NoError(t, err)
+	require.NoError(t, database.Migrate(db))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	var p models.Producto
+	err = (&database.GormDB{DB: db}).WithContext(ctx).Where("nombre = ?", "x").First(&p)
+	assert.True(t, errors.Is(err, context.Canceled), "se esperaba Canceled, fue %v", err)
+}
@@ -0,0 +1,174 @@
+package database
+
+import (
+	"time"
+	"ventas-app/models"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// SchemaVersion es la versión del esquema que espera este binario. Hay que
+// incrementarla cada vez que se agrega o cambia un modelo en Migrate.
+const SchemaVersion = 20
+
+// SchemaMigration registra cada versión de esquema aplicada en la base.
+type SchemaMigration struct {
+	Version   int `gorm:"primaryKey;autoIncrement:false"`
+	AppliedAt time.Time
+}
+
+// Migrate crea o actualiza las tablas de todos los modelos, completa los
+// datos de las columnas nuevas según la versión que tenía la base y registra
+// SchemaVersion como aplicada.
+func Migrate(db *gorm.DB) error {
+	err := db.AutoMigrate(
+		&SchemaMigration{},
+		&models.Usuario{},
+		&models.Producto{},
+		&models.CodigoBarras{},
+		&models.Categoria{},
+		&models.Marca{},
+		&models.AtributoVariante{},
+		&models.Variante{},
+		&models.Compra{},
+		&models.Venta{},
+		&models.RefreshToken{},
+		&models.TokenRevocado{},
+		&models.LoginEvento{},
+		&models.CodigoRecuperacion{},
+		&models.APIKey{},
+		&models.AuditoriaEvento{},
+		&models.InventarioSnapshot{},
+		&models.Devolucion{},
+		&models.EsquemaComision{},
+		&models.CierreComision{},
+		&models.ComisionLiquidada{},
+	)
+	if err != nil {
+		return err
+	}
+
+	previa, err := AppliedVersion(db)
+	if err != nil {
+		return err
+	}
+	if err := migrarDatos(SinAuditoria(db), previa); err != nil {
+		return err
+	}
+
+	return db.Clauses(clause.OnConflict{DoNothing: true}).
+		Create(&SchemaMigration{Version: SchemaVersion, AppliedAt: time.Now()}).Error
+}
+
+// migrarDatos completa las columnas agregadas después de la versión previa.
+// No se audita: no son cambios de un usuario.
+func migrarDatos(db *gorm.DB, previa int) error {
+	ventas := func() *gorm.DB { return db.Model(&models.Venta{}).Unscoped() }
+
+	// 8: las ventas solo tenían el precio final con IVA
+	if previa < 8 {
+		err := ventas().Where("neto = 0 AND iva = 0 AND precio_final <> 0").
+			UpdateColumns(map[string]interface{}{
+				"neto": gorm.Expr("precio_final / ?", 1+models.AlicuotaIVA),
+				"iva":  gorm.Expr("precio_final - precio_final / ?", 1+models.AlicuotaIVA),
+			}).Error
+		if err != nil {
+			return err
+		}
+	}
+
+	// 9: sin el costo al momento de la venta, el mejor dato es el costo actual
+	if previa < 9 {
+		productos := clause.Table{Name: db.NamingStrategy.TableName("Producto")}
+		venta := clause.Table{Name: db.NamingStrategy.TableName("Venta")}
+		err := ventas().Where("costo_unit = 0").
+			UpdateColumn("costo_unit", gorm.Expr("COALESCE((SELECT costo FROM ? WHERE ?.id = ?.producto_id), 0)", productos, productos, venta)).Error
+		if err != nil {
+			return err
+		}
+	}
+
+	// 18: los snapshots no guardaban la categoría; la mejor estimación es la
+	// actual del producto
+	if previa < 18 {
+		productos := clause.Table{Name: db.NamingStrategy.TableName("Producto")}
+		snapshots := clause.Table{Name: db.NamingStrategy.TableName("InventarioSnapshot")}
+		err := db.Model(&models.InventarioSnapshot{}).Where("categoria_id IS NULL").
+			UpdateColumn("categoria_id", gorm.Expr("(SELECT categoria_id FROM ? WHERE ?.id = ?.producto_id)", productos, productos, snapshots)).Error
+		if err != nil {
+			return err
+		}
+	}
+
+	// 19: las sesiones se revocaban por fecha; los tokens emitidos antes no
+	// llevan versión, así que los de usuarios con revocaciones dejan de valer
+	// (a lo sumo vuelven a iniciar sesión)
+	if previa < 19 {
+		err := db.Model(&models.Usuario{}).Where("sesiones_revocadas_en IS NOT NULL AND version_sesion = 0").
+			UpdateColumn("version_sesion", 1).Error
+		if err != nil {
+			return err
+		}
+	}
+
+	// 20: los códigos de barras se guardan como GTIN-14
+	if previa < 20 {
+		if err := normalizarCodigosBarras(db); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// normalizarCodigosBarras completa con ceros los códigos guardados con su
+// largo original. Si dos quedan iguales (el mismo artículo cargado como UPC-A
+// y como EAN-13) se conserva el primero que se registró.
+func normalizarCodigosBarras(db *gorm.DB) error {
+	var codigos []models.CodigoBarras
+	if err := db.Order("id").Find(&codigos).Error; err != nil {
+		return err
+	}
+
+	vistos := map[string]bool{}
+	var repetidos []uint
+	cambios := map[uint]string{}
+	for _, cb := range codigos {
+		normalizado, err := models.NormalizarCodigoBarras(cb.Codigo)
+		if err != nil {
+			// Guardado antes de que se validara: se deja como está
+			continue
+		}
+		if vistos[normalizado] {
+			repetidos = append(repetidos, cb.ID)
+			continue
+		}
+		vistos[normalizado] = true
+		if normalizado != cb.Codigo {
+			cambios[cb.ID] = normalizado
+		}
+	}
+
+	// Primero los repetidos, para no chocar con el índice único
+	if len(repetidos) > 0 {
+		if err := db.Delete(&models.CodigoBarras{}, repetidos).Error; err != nil {
+			return err
+		}
+	}
+	for id, codigo := range cambios {
+		if err := db.Model(&models.CodigoBarras{}).Where("id = ?", id).UpdateColumn("codigo", codigo).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// AppliedVersion devuelve la última versión de esquema registrada (0 si ninguna).
+func AppliedVersion(db *gorm.DB) (int, error) {
+	var version *int
+	err := db.Model(&SchemaMigration{}).Select("MAX(version)").Scan(&version).Error
+	if err != nil || version == nil {
+		return 0, err
+	}
+	return *version, nil
+}
@@ -0,0 +1,73 @@
+// Package jobs corre tareas en segundo plano que se detienen junto con el servidor.
+package jobs
+
+import (
+	"context"
+	"log"
+	"sync"
+	"time"
+)
+
+// Runner lanza goroutines atadas a un contexto común y permite esperarlas al apagar.
+type Runner struct {
+	ctx    context.Context
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+}
+
+// NewRunner crea un Runner cuyos jobs se cancelan cuando ctx termina o al llamar Stop.
+func NewRunner(ctx context.Context) *Runner {
+	ctx, cancel := context.WithCancel(ctx)
+	return &Runner{ctx: ctx, cancel: cancel}
+}
+
+// Go corre fn en una goroutine. fn debe volver cuando su ctx se cancele.
+func (r *Runner) Go(nombre string, fn func(ctx context.Context)) {
+	r.wg.Add(1)
+	go func() {
+		defer r.wg.Done()
+		defer func() {
+			if p := recover(); p != nil {
+				log.Printf("Job %s terminó con panic: %v", nombre, p)
+			}
+		}()
+		fn(r.ctx)
+	}()
+}
+
+// Every ejecuta fn cada intervalo hasta que se detenga el Runner. Los errores
+// se registran en el log y no cortan el ciclo.
+func (r *Runner) Every(nombre string, intervalo time.Duration, fn func(ctx context.Context) error) {
+	r.Go(nombre, func(ctx context.Context) {
+		ticker := time.NewTicker(intervalo)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				if err := fn(ctx); err != nil {
+					log.Printf("Job %s falló: %v", nombre, err)
+				}
+			}
+		}
+	})
+}
+
+// Stop cancela los jobs y espera a que terminen, como máximo hasta que venza ctx.
+func (r *Runner) Stop(ctx context.Context) error {
+	r.cancel()
+
+	listo := make(chan struct{})
+	go func() {
+		r.wg.Wait()
+		close(listo)
+	}()
+
+	select {
+	case <-listo:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
@@ -0,0 +1,51 @@
+package jobs
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRunner_EveryHastaStop(t *testing.T) {
+	r := NewRunner(context.Background())
+	var ejecuciones int32
+
+	r.Every("contador", 10*time.Millisecond, func(ctx context.Context) error {
+		atomic.AddInt32(&ejecuciones, 1)
+		return errors.New("los errores no cortan el ciclo")
+	})
+
+	time.Sleep(55 * time.Millisecond)
+	assert.NoError(t, r.Stop(context.Background()))
+
+	hechas := atomic.LoadInt32(&ejecuciones)
+	assert.GreaterOrEqual(t, hechas, int32(2))
+
+	time.Sleep(30 * time.Millisecond)
+	assert.Equal(t, hechas, atomic.LoadInt32(&ejecuciones), "después de Stop no corre más")
+}
+
+func TestRunner_StopRespetaTimeout(t *testing.T) {
+	r := NewRunner(context.Background())
+	r.Go("terco", func(ctx context.Context) {
+		time.Sleep(time.Second)
+	})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+
+	assert.True(t, errors.Is(r.Stop(ctx), context.DeadlineExceeded))
+}
+
+func TestRunner_PanicNoTumbaElProceso(t *testing.T) {
+	r := NewRunner(context.Background())
+	r.Go("roto", func(ctx context.Context) {
+		panic("boom")
+	})
+
+	assert.NoError(t, r.Stop(context.Background()))
+}
@@ -0,0 +1,72 @@
+package middleware
+
+import (
+	"strings"
+	"ventas-app/database"
+	"ventas-app/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+// HeaderRequestID identifica el request en la auditoría y en la respuesta.
+const HeaderRequestID = "X-Request-ID"
+
+// Auditoria asigna un request ID (el que manda el cliente en X-Request-ID si
+// es válido, si no uno nuevo) y pone en el contexto del request la
+// database.InfoAuditoria con la que se registran los cambios.
+//
+// Como hay rutas que no pasan por AuthRequired, el actor se toma de un Bearer
+// token válido aunque no se verifique la sesión; AuthRequired lo reemplaza por
+// el usuario (o la API key) que autenticó.
+func Auditoria() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		requestID := c.GetHeader(HeaderRequestID)
+		if !requestIDValido(requestID) {
+			requestID = utils.RandomID()
+		}
+		c.Header(HeaderRequestID, requestID)
+		c.Set("request_id", requestID)
+
+		info := &database.InfoAuditoria{
+			RequestID: requestID,
+			Env:       database.EnvSeleccionado(c),
+			IP:        c.ClientIP(),
+		}
+		if auth := c.GetHeader("Authorization"); strings.HasPrefix(auth, "Bearer ") {
+			if claims, err := utils.ParseClaims(strings.TrimPrefix(auth, "Bearer ")); err == nil && claims.UserID != 0 {
+				id := claims.UserID
+				info.UsuarioID = &id
+			}
+		}
+
+		c.Request = c.Request.WithContext(database.ConInfoAuditoria(c.Request.Context(), info))
+		c.Next()
+	}
+}
+
+// requestIDValido acepta hasta 64 caracteres alfanuméricos, '-', '_' o '.',
+// para que el cliente no pueda meter cualquier cosa en la auditoría.
+func requestIDValido(id string) bool {
+	if id == "" || len(id) > 64 {
+		return false
+	}
+	for _, r := range id {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9':
+		case r == '-' || r == '_' || r == '.':
+		default:
+			return false
+		}
+	}
+	return true
+}
+
+// registrarActor anota en la auditoría del request quién se autenticó.
+func registrarActor(c *gin.Context, usuarioID uint, apiKeyID *uint) {
+	info := database.InfoAuditoriaDe(c.Request.Context())
+	if info == nil {
+		return
+	}
+	info.UsuarioID = &usuarioID
+	info.APIKeyID = apiKeyID
+}
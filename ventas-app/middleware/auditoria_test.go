@@ -0,0 +1,56 @@
+package middleware
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+	"ventas-app/database"
+	"ventas-app/utils"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAuditoria(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	utils.SetSecret("test-secret-key")
+
+	var info *database.InfoAuditoria
+	router := gin.New()
+	router.GET("/", Auditoria(), func(c *gin.Context) {
+		info = database.InfoAuditoriaDe(c.Request.Context())
+		c.Status(http.StatusOK)
+	})
+
+	t.Run("genera un request ID", func(t *testing.T) {
+		resp := pedir(router, "GET", "/", nil)
+
+		require.NotNil(t, info)
+		assert.Len(t, info.RequestID, 32)
+		assert.Equal(t, info.RequestID, resp.Header().Get(HeaderRequestID))
+		assert.Nil(t, info.UsuarioID)
+	})
+
+	t.Run("respeta el request ID del cliente si es válido", func(t *testing.T) {
+		resp := pedir(router, "GET", "/", map[string]string{HeaderRequestID: "abc-123_x.y", "X-Env": "qa"})
+		assert.Equal(t, "abc-123_x.y", resp.Header().Get(HeaderRequestID))
+		assert.Equal(t, "abc-123_x.y", info.RequestID)
+		assert.Equal(t, "qa", info.Env)
+
+		for _, invalido := range []string{"con espacios", "<script>", strings.Repeat("a", 65)} {
+			resp = pedir(router, "GET", "/", map[string]string{HeaderRequestID: invalido})
+			assert.NotEqual(t, invalido, resp.Header().Get(HeaderRequestID))
+		}
+	})
+
+	t.Run("toma el actor de un Bearer token válido", func(t *testing.T) {
+		token, _ := utils.GenerateToken(5, "vendedor")
+		pedir(router, "GET", "/", map[string]string{"Authorization": "Bearer " + token})
+		require.NotNil(t, info.UsuarioID)
+		assert.Equal(t, uint(5), *info.UsuarioID)
+
+		pedir(router, "GET", "/", map[string]string{"Authorization": "Bearer no-es-un-token"})
+		assert.Nil(t, info.UsuarioID)
+	})
+}
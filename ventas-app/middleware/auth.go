@@ -1,16 +1,41 @@
 package middleware
 
 import (
+	"errors"
 	"net/http"
 	"strings"
+	"ventas-app/database"
+	"ventas-app/models"
+	"ventas-app/services"
 	"ventas-app/utils"
 
 	"github.com/gin-gonic/gin"
-	"github.com/golang-jwt/jwt/v5"
 )
 
+const (
+	claveClaims  = "claims"
+	claveUsuario = "usuario"
+	claveAPIKey  = "api_key"
+)
+
+// HeaderAPIKey es el header con el que las integraciones envían su API key.
+const HeaderAPIKey = "X-API-Key"
+
+// AuthRequired exige un access token válido y no revocado, emitido para el
+// entorno del request y de un usuario existente y habilitado. Si se pasan
+// roles, el token debe tener alguno de ellos; sin roles alcanza con estar
+// autenticado. Cambiar el rol de un usuario revoca sus sesiones. Quien debe
+// cambiar la clave solo pasa por los endpoints que no exigen rol.
+//
+// También acepta una API key en X-API-Key: debe tener el scope de la ruta
+// (ver ScopeRequerido) y su usuario alguno de los roles.
 func AuthRequired(roles ...string) gin.HandlerFunc {
 	return func(c *gin.Context) {
+		if clave := c.GetHeader(HeaderAPIKey); clave != "" {
+			autenticarAPIKey(c, clave, roles)
+			return
+		}
+
 		authHeader := c.GetHeader("Authorization")
 		if authHeader == "" || !strings.HasPrefix(authHeader, "Bearer ") {
 			c.JSON(http.StatusUnauthorized, gin.H{"error": "Token faltante"})
@@ -19,26 +44,147 @@ func AuthRequired(roles ...string) gin.HandlerFunc {
 		}
 
 		tokenStr := strings.TrimPrefix(authHeader, "Bearer ")
-		token, err := utils.ParseToken(tokenStr)
-		if err != nil || !token.Valid {
+		claims, err := utils.ParseClaims(tokenStr)
+		if err != nil || claims.UserID == 0 {
 			c.JSON(http.StatusUnauthorized, gin.H{"error": "Token inválido"})
 			c.Abort()
 			return
 		}
 
-		claims := token.Claims.(jwt.MapClaims)
-		rol := claims["rol"].(string)
+		// Un token de QA no sirve contra PROD aunque se mande X-Env
+		if claims.Tenant != "" && claims.Tenant != database.EnvSeleccionado(c) {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "Token inválido"})
+			c.Abort()
+			return
+		}
+
+		// Logout, sesiones revocadas por un admin, usuario borrado o deshabilitado
+		usuario, err := services.ValidarSesion(database.GetDB(c), claims)
+		switch {
+		case errors.Is(err, services.ErrSesionRevocada):
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "Sesión revocada"})
+			c.Abort()
+			return
+		case errors.Is(err, services.ErrUsuarioInvalido):
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "Usuario inexistente o deshabilitado"})
+			c.Abort()
+			return
+		case err != nil:
+			c.JSON(http.StatusServiceUnavailable, gin.H{"error": "No se pudo validar la sesión"})
+			c.Abort()
+			return
+		}
 
 		// Validar rol
-		for _, r := range roles {
-			if r == rol {
-				c.Set("user_id", claims["user_id"])
-				c.Next()
-				return
-			}
+		if !rolPermitido(roles, claims.Rol) {
+			c.JSON(http.StatusForbidden, gin.H{"error": "Acceso denegado"})
+			c.Abort()
+			return
 		}
+		if len(roles) > 0 && usuario.DebeCambiarClave {
+			c.JSON(http.StatusForbidden, gin.H{"error": "Debe cambiar la clave"})
+			c.Abort()
+			return
+		}
+
+		registrarActor(c, usuario.ID, nil)
+		c.Set("user_id", usuario.ID)
+		c.Set(claveClaims, claims)
+		c.Set(claveUsuario, usuario)
+		c.Next()
+	}
+}
 
+// autenticarAPIKey es la rama de AuthRequired para las integraciones. El rol
+// que se controla es el del usuario dueño de la key.
+func autenticarAPIKey(c *gin.Context, clave string, roles []string) {
+	key, usuario, err := services.ValidarAPIKey(database.GetDB(c), clave)
+	if errors.Is(err, services.ErrAPIKeyInvalida) {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "API key inválida"})
+		c.Abort()
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "No se pudo validar la API key"})
+		c.Abort()
+		return
+	}
+
+	scope := ScopeRequerido(c)
+	if !key.Permite(scope) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "La API key no tiene el scope " + scope})
+		c.Abort()
+		return
+	}
+	if !rolPermitido(roles, usuario.Rol) {
 		c.JSON(http.StatusForbidden, gin.H{"error": "Acceso denegado"})
 		c.Abort()
+		return
+	}
+
+	registrarActor(c, usuario.ID, &key.ID)
+	c.Set("user_id", usuario.ID)
+	c.Set(claveUsuario, usuario)
+	c.Set(claveAPIKey, key)
+	c.Next()
+}
+
+// ScopeRequerido es el scope que necesita una API key para la ruta del
+// request: su primer segmento y "read" para GET/HEAD o "write" para el resto.
+// Por ejemplo GET /productos → "productos:read".
+func ScopeRequerido(c *gin.Context) string {
+	ruta := c.FullPath()
+	if ruta == "" {
+		ruta = c.Request.URL.Path
+	}
+	recurso, _, _ := strings.Cut(strings.TrimPrefix(ruta, "/"), "/")
+
+	accion := "write"
+	if c.Request.Method == http.MethodGet || c.Request.Method == http.MethodHead {
+		accion = "read"
+	}
+	return recurso + ":" + accion
+}
+
+func rolPermitido(roles []string, rol string) bool {
+	if len(roles) == 0 {
+		return true
+	}
+	for _, r := range roles {
+		if r == rol {
+			return true
+		}
+	}
+	return false
+}
+
+// CurrentUser devuelve el usuario autenticado por AuthRequired.
+func CurrentUser(c *gin.Context) (models.Usuario, bool) {
+	v, ok := c.Get(claveUsuario)
+	if !ok {
+		return models.Usuario{}, false
+	}
+	usuario, ok := v.(models.Usuario)
+	return usuario, ok
+}
+
+// CurrentClaims devuelve los claims del token validado por AuthRequired.
+func CurrentClaims(c *gin.Context) (*utils.Claims, bool) {
+	v, ok := c.Get(claveClaims)
+	if !ok {
+		return nil, false
+	}
+	claims, ok := v.(*utils.Claims)
+	return claims, ok
+}
+
+// CurrentAPIKey devuelve la API key con la que se autenticó el request, si
+// no fue con un token.
+func CurrentAPIKey(c *gin.Context) (models.APIKey, bool) {
+	v, ok := c.Get(claveAPIKey)
+	if !ok {
+		return models.APIKey{}, false
 	}
+	key, ok := v.(models.APIKey)
+	return key, ok
 }
@@ -5,9 +5,14 @@ import (
 	"net/http/httptest"
 	"os"
 	"testing"
+	"time"
+	"ventas-app/mocks"
+	"ventas-app/models"
+	"ventas-app/services"
 	"ventas-app/utils"
 
 	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt/v5"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -15,6 +20,12 @@ func TestAuthRequired(t *testing.T) {
 	// Setup
 	gin.SetMode(gin.TestMode)
 	os.Setenv("JWT_SECRET", "test-secret-key")
+	utils.SetSecret(os.Getenv("JWT_SECRET"))
+
+	// AuthRequired consulta la base para saber si el token fue revocado
+	mock := mocks.NewMemoryDB()
+	mock.Create(&models.Usuario{Nombre: "ana", Clave: "x", Rol: "vendedor"})
+	usarDB(t, mock)
 
 	t.Run("rechaza request sin token", func(t *testing.T) {
 		router := gin.New()
@@ -108,3 +119,190 @@ func TestAuthRequired(t *testing.T) {
 		assert.Equal(t, http.StatusOK, resp.Code)
 	})
 }
+
+func TestAuthRequired_Revocacion(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	utils.SetSecret("test-secret-key")
+
+	mock := mocks.NewMemoryDB()
+	usuario := models.Usuario{Nombre: "ana", Clave: "x", Rol: "vendedor"}
+	mock.Create(&usuario)
+	usarDB(t, mock)
+
+	router := gin.New()
+	router.GET("/protected", AuthRequired(), func(c *gin.Context) {
+		c.JSON(200, gin.H{"message": "ok"})
+	})
+
+	t.Run("sin roles acepta cualquier usuario autenticado", func(t *testing.T) {
+		token, _ := utils.GenerateToken(usuario.ID, "vendedor")
+		assert.Equal(t, http.StatusOK, pedir(router, "GET", "/protected", bearer(token)).Code)
+	})
+
+	t.Run("rechaza token revocado por logout", func(t *testing.T) {
+		token, _ := utils.GenerateToken(usuario.ID, "vendedor")
+		claims, _ := utils.ParseClaims(token)
+		assert.NoError(t, services.CerrarSesion(mock, claims, ""))
+
+		resp := pedir(router, "GET", "/protected", bearer(token))
+		assert.Equal(t, http.StatusUnauthorized, resp.Code)
+		assert.Contains(t, resp.Body.String(), "Sesión revocada")
+	})
+
+	t.Run("rechaza tokens emitidos antes de revocar las sesiones", func(t *testing.T) {
+		token, _ := utils.GenerateToken(usuario.ID, "vendedor")
+		assert.NoError(t, services.RevocarSesiones(mock, &usuario))
+		assert.Equal(t, http.StatusUnauthorized, pedir(router, "GET", "/protected", bearer(token)).Code)
+
+		// Emitido en el mismo segundo que la revocación, con la versión nueva
+		claims := utils.NewClaims(usuario.ID, "vendedor", "")
+		claims.Sesion = usuario.VersionSesion
+		nuevo, _ := utils.SignClaims(claims)
+		assert.Equal(t, http.StatusOK, pedir(router, "GET", "/protected", bearer(nuevo)).Code)
+	})
+
+	t.Run("rechaza token de usuario borrado", func(t *testing.T) {
+		token, _ := utils.GenerateToken(999, "vendedor")
+		assert.Equal(t, http.StatusUnauthorized, pedir(router, "GET", "/protected", bearer(token)).Code)
+	})
+
+	t.Run("rechaza token de usuario deshabilitado", func(t *testing.T) {
+		deshabilitado := models.Usuario{Nombre: "beto", Clave: "x", Rol: "vendedor"}
+		ahora := time.Now()
+		deshabilitado.DeshabilitadoEn = &ahora
+		mock.Create(&deshabilitado)
+
+		token, _ := utils.GenerateToken(deshabilitado.ID, "vendedor")
+		resp := pedir(router, "GET", "/protected", bearer(token))
+		assert.Equal(t, http.StatusUnauthorized, resp.Code)
+		assert.Contains(t, resp.Body.String(), "deshabilitado")
+	})
+
+	t.Run("quien debe cambiar la clave solo accede sin rol", func(t *testing.T) {
+		reseteado := models.Usuario{Nombre: "carla", Clave: "x", Rol: "vendedor", DebeCambiarClave: true}
+		mock.Create(&reseteado)
+
+		conRol := gin.New()
+		conRol.GET("/protected", AuthRequired("vendedor"), func(c *gin.Context) {
+			c.JSON(200, gin.H{"message": "ok"})
+		})
+
+		token, _ := utils.GenerateToken(reseteado.ID, "vendedor")
+		assert.Equal(t, http.StatusOK, pedir(router, "GET", "/protected", bearer(token)).Code)
+
+		req := httptest.NewRequest("GET", "/protected", nil)
+		req.Header.Set("Authorization", "Bearer "+token)
+		resp := httptest.NewRecorder()
+		conRol.ServeHTTP(resp, req)
+		assert.Equal(t, http.StatusForbidden, resp.Code)
+		assert.Contains(t, resp.Body.String(), "Debe cambiar la clave")
+	})
+}
+
+func TestAuthRequired_Claims(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	utils.SetSecret("test-secret-key")
+
+	mock := mocks.NewMemoryDB()
+	usuario := models.Usuario{Nombre: "ana", Clave: "x", Rol: "vendedor"}
+	mock.Create(&usuario)
+	usarDB(t, mock)
+
+	router := gin.New()
+	router.GET("/protected", AuthRequired(), func(c *gin.Context) {
+		u, ok := CurrentUser(c)
+		assert.True(t, ok)
+		claims, ok := CurrentClaims(c)
+		assert.True(t, ok)
+		c.JSON(200, gin.H{"nombre": u.Nombre, "user_id": claims.UserID, "tenant": claims.Tenant})
+	})
+	firmar := func(claims jwt.Claims) string {
+		token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
+		token.Header["kid"] = "default"
+		str, err := token.SignedString([]byte("test-secret-key"))
+		assert.NoError(t, err)
+		return str
+	}
+
+	t.Run("expone usuario y claims al handler", func(t *testing.T) {
+		token, _ := utils.SignClaims(utils.NewClaims(usuario.ID, "vendedor", "qa"))
+		resp := pedir(router, "GET", "/protected", bearer(token))
+		assert.Equal(t, http.StatusOK, resp.Code)
+		assert.JSONEq(t, `{"nombre": "ana", "user_id": 1, "tenant": "qa"}`, resp.Body.String())
+	})
+
+	t.Run("token sin rol no rompe el middleware", func(t *testing.T) {
+		claims := utils.NewClaims(usuario.ID, "", "")
+		resp := pedir(router, "GET", "/protected", bearer(firmar(claims)))
+		assert.Equal(t, http.StatusOK, resp.Code)
+
+		router := gin.New()
+		router.GET("/admin", AuthRequired("admin"), func(c *gin.Context) { c.Status(200) })
+		req := httptest.NewRequest("GET", "/admin", nil)
+		req.Header.Set("Authorization", "Bearer "+firmar(claims))
+		resp = httptest.NewRecorder()
+		router.ServeHTTP(resp, req)
+		assert.Equal(t, http.StatusForbidden, resp.Code)
+	})
+
+	t.Run("rechaza claims con tipos inesperados", func(t *testing.T) {
+		claims := jwt.MapClaims{"user_id": "uno", "rol": 3, "jti": "x", "iss": utils.Issuer, "aud": utils.Audience,
+			"iat": time.Now().Unix(), "exp": time.Now().Add(time.Minute).Unix()}
+		assert.Equal(t, http.StatusUnauthorized, pedir(router, "GET", "/protected", bearer(firmar(claims))).Code)
+	})
+
+	t.Run("rechaza otro issuer o audience", func(t *testing.T) {
+		claims := utils.NewClaims(usuario.ID, "vendedor", "")
+		claims.Issuer = "otro-servicio"
+		assert.Equal(t, http.StatusUnauthorized, pedir(router, "GET", "/protected", bearer(firmar(claims))).Code)
+
+		claims = utils.NewClaims(usuario.ID, "vendedor", "")
+		claims.Audience = jwt.ClaimStrings{"otra-api"}
+		assert.Equal(t, http.StatusUnauthorized, pedir(router, "GET", "/protected", bearer(firmar(claims))).Code)
+	})
+
+	t.Run("rechaza token de otro entorno", func(t *testing.T) {
+		token, _ := utils.SignClaims(utils.NewClaims(usuario.ID, "vendedor", "prod"))
+		assert.Equal(t, http.StatusUnauthorized, pedir(router, "GET", "/protected", bearer(token)).Code)
+	})
+}
+
+func TestAuthRequired_APIKey(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	mock := mocks.NewMemoryDB()
+	vendedor := models.Usuario{Nombre: "ecommerce", Clave: "x", Rol: "vendedor"}
+	mock.Create(&vendedor)
+	usarDB(t, mock)
+
+	_, lectura, _ := services.CrearAPIKey(mock, vendedor, "lector", []string{"productos:read"}, nil)
+	_, ventas, _ := services.CrearAPIKey(mock, vendedor, "sync", []string{"ventas:write"}, nil)
+
+	router := gin.New()
+	ok := func(c *gin.Context) {
+		key, _ := CurrentAPIKey(c)
+		usuario, _ := CurrentUser(c)
+		c.JSON(200, gin.H{"key": key.Nombre, "usuario": usuario.Nombre})
+	}
+	router.GET("/productos", AuthRequired(), ok)
+	router.POST("/productos", AuthRequired(), ok)
+	router.POST("/ventas", AuthRequired("vendedor"), ok)
+	router.POST("/compras", AuthRequired("comprador"), ok)
+
+	resp := pedir(router, "GET", "/productos", map[string]string{HeaderAPIKey: lectura})
+	assert.Equal(t, http.StatusOK, resp.Code)
+	assert.Contains(t, resp.Body.String(), `"key":"lector"`)
+	assert.Contains(t, resp.Body.String(), `"usuario":"ecommerce"`)
+
+	resp = pedir(router, "POST", "/productos", map[string]string{HeaderAPIKey: lectura})
+	assert.Equal(t, http.StatusForbidden, resp.Code)
+	assert.Contains(t, resp.Body.String(), "productos:write")
+
+	assert.Equal(t, http.StatusOK, pedir(router, "POST", "/ventas", map[string]string{HeaderAPIKey: ventas}).Code)
+	assert.Equal(t, http.StatusForbidden, pedir(router, "GET", "/productos", map[string]string{HeaderAPIKey: ventas}).Code)
+	assert.Equal(t, http.StatusUnauthorized, pedir(router, "GET", "/productos", map[string]string{HeaderAPIKey: "vk_inexistente"}).Code)
+
+	// El rol del dueño también cuenta
+	_, compras, _ := services.CrearAPIKey(mock, vendedor, "compras", []string{"compras:write"}, nil)
+	assert.Equal(t, http.StatusForbidden, pedir(router, "POST", "/compras", map[string]string{HeaderAPIKey: compras}).Code)
+}
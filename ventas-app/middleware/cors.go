@@ -0,0 +1,78 @@
+package middleware
+
+import (
+	"net/url"
+	"strings"
+	"ventas-app/config"
+
+	"github.com/gin-contrib/cors"
+	"github.com/gin-gonic/gin"
+)
+
+// CORS arma la política CORS a partir de la configuración del entorno.
+// Los orígenes exactos se comparan tal cual; los patrones "https://*.dominio"
+// aceptan cualquier subdominio de dominio con el mismo esquema y puerto.
+func CORS(cfg config.CORSConfig) gin.HandlerFunc {
+	c := cors.Config{
+		AllowMethods:     []string{"GET", "POST", "PUT", "DELETE", "OPTIONS"},
+		AllowHeaders:     cfg.AllowHeaders,
+		ExposeHeaders:    cfg.ExposeHeaders,
+		AllowCredentials: cfg.AllowCredentials,
+		MaxAge:           cfg.MaxAge,
+	}
+
+	var patrones []string
+	for _, origen := range cfg.AllowOrigins {
+		switch {
+		case origen == "*":
+			c.AllowAllOrigins = true
+		case strings.Contains(origen, "://*."):
+			patrones = append(patrones, origen)
+		default:
+			c.AllowOrigins = append(c.AllowOrigins, origen)
+		}
+	}
+	if c.AllowAllOrigins {
+		c.AllowOrigins = nil
+		patrones = nil
+	}
+	if len(patrones) > 0 {
+		c.AllowOriginFunc = func(origen string) bool {
+			for _, p := range patrones {
+				if coincideSubdominio(p, origen) {
+					return true
+				}
+			}
+			return false
+		}
+	}
+
+	return cors.New(c)
+}
+
+// coincideSubdominio indica si origen ("https://app.onrender.com") cumple el
+// patrón ("https://*.onrender.com"). El dominio base solo no coincide.
+func coincideSubdominio(patron, origen string) bool {
+	esquema, resto, _ := strings.Cut(patron, "://*.")
+	base, puerto := separarPuerto(resto)
+
+	u, err := url.Parse(origen)
+	if err != nil || u.Scheme != esquema || u.Path != "" || u.User != nil {
+		return false
+	}
+	host, puertoOrigen := separarPuerto(u.Host)
+	if puertoOrigen != puerto {
+		return false
+	}
+
+	host = strings.ToLower(host)
+	sub, ok := strings.CutSuffix(host, "."+strings.ToLower(base))
+	return ok && sub != "" && !strings.HasPrefix(sub, ".")
+}
+
+func separarPuerto(hostPuerto string) (string, string) {
+	if i := strings.LastIndex(hostPuerto, ":"); i >= 0 {
+		return hostPuerto[:i], hostPuerto[i+1:]
+	}
+	return hostPuerto, ""
+}
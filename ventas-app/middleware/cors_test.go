@@ -0,0 +1,104 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+	"ventas-app/config"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func corsRouter(cfg config.CORSConfig) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.Use(CORS(cfg))
+	router.GET("/productos", func(c *gin.Context) { c.Status(http.StatusOK) })
+	return router
+}
+
+func preflight(router *gin.Engine, origen string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest("OPTIONS", "/productos", nil)
+	req.Header.Set("Origin", origen)
+	req.Header.Set("Access-Control-Request-Method", "GET")
+	req.Header.Set("Access-Control-Request-Headers", "Content-Type, X-Env")
+	resp := httptest.NewRecorder()
+	router.ServeHTTP(resp, req)
+	return resp
+}
+
+func TestCORS_Preflight(t *testing.T) {
+	router := corsRouter(config.Default("qa").CORS)
+
+	resp := preflight(router, "https://frontqa-t0a9.onrender.com")
+
+	assert.Equal(t, http.StatusNoContent, resp.Code)
+	assert.Equal(t, "https://frontqa-t0a9.onrender.com", resp.Header().Get("Access-Control-Allow-Origin"))
+	assert.Equal(t, "true", resp.Header().Get("Access-Control-Allow-Credentials"))
+	assert.Contains(t, resp.Header().Get("Access-Control-Allow-Headers"), "X-Env")
+	assert.Equal(t, "43200", resp.Header().Get("Access-Control-Max-Age"))
+}
+
+func TestCORS_PreflightOrigenNoPermitido(t *testing.T) {
+	router := corsRouter(config.Default("prod").CORS)
+
+	resp := preflight(router, "http://localhost:5173")
+
+	assert.Equal(t, http.StatusForbidden, resp.Code)
+	assert.Empty(t, resp.Header().Get("Access-Control-Allow-Origin"))
+}
+
+func TestCORS_PatronDeSubdominio(t *testing.T) {
+	router := corsRouter(config.CORSConfig{
+		AllowOrigins:     []string{"https://*.onrender.com"},
+		AllowHeaders:     []string{"Content-Type", "X-Env"},
+		AllowCredentials: true,
+		MaxAge:           10 * time.Minute,
+	})
+
+	casos := []struct {
+		origen   string
+		esperado int
+	}{
+		{"https://front-nuevo.onrender.com", http.StatusNoContent},
+		{"https://a.b.onrender.com", http.StatusNoContent},
+		{"https://onrender.com", http.StatusForbidden},
+		{"http://front.onrender.com", http.StatusForbidden},
+		{"https://front.onrender.com.evil.com", http.StatusForbidden},
+		{"https://frontonrender.com", http.StatusForbidden},
+	}
+	for _, caso := range casos {
+		t.Run(caso.origen, func(t *testing.T) {
+			resp := preflight(router, caso.origen)
+			assert.Equal(t, caso.esperado, resp.Code)
+			if caso.esperado == http.StatusNoContent {
+				assert.Equal(t, caso.origen, resp.Header().Get("Access-Control-Allow-Origin"))
+				assert.Equal(t, "600", resp.Header().Get("Access-Control-Max-Age"))
+			}
+		})
+	}
+}
+
+func TestCORS_RequestNormal(t *testing.T) {
+	router := corsRouter(config.Default("dev").CORS)
+
+	req := httptest.NewRequest("GET", "/productos", nil)
+	req.Header.Set("Origin", "http://localhost:5173")
+	resp := httptest.NewRecorder()
+	router.ServeHTTP(resp, req)
+
+	assert.Equal(t, http.StatusOK, resp.Code)
+	assert.Equal(t, "http://localhost:5173", resp.Header().Get("Access-Control-Allow-Origin"))
+	assert.Equal(t, "Content-Length,X-Request-Id", resp.Header().Get("Access-Control-Expose-Headers"))
+}
+
+func TestCORS_CualquierOrigen(t *testing.T) {
+	router := corsRouter(config.CORSConfig{AllowOrigins: []string{"*"}, AllowHeaders: []string{"X-Env"}})
+
+	resp := preflight(router, "https://cualquiera.example.com")
+
+	assert.Equal(t, http.StatusNoContent, resp.Code)
+	assert.Equal(t, "*", resp.Header().Get("Access-Control-Allow-Origin"))
+}
@@ -0,0 +1,35 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"ventas-app/database"
+
+	"github.com/gin-gonic/gin"
+)
+
+// pedir hace el request contra router con los headers indicados.
+func pedir(router http.Handler, metodo, path string, headers map[string]string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(metodo, path, nil)
+	for k, v := range headers {
+		req.Header.Set(k, v)
+	}
+	resp := httptest.NewRecorder()
+	router.ServeHTTP(resp, req)
+	return resp
+}
+
+// bearer es el header Authorization con token.
+func bearer(token string) map[string]string {
+	return map[string]string{"Authorization": "Bearer " + token}
+}
+
+// usarDB hace que database.GetDB devuelva db hasta que termine el test.
+func usarDB(t *testing.T, db database.DBHandler) {
+	anterior := database.GetDB
+	database.GetDB = func(c *gin.Context) database.DBHandler {
+		return db
+	}
+	t.Cleanup(func() { database.GetDB = anterior })
+}
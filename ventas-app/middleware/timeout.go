@@ -0,0 +1,57 @@
+package middleware
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// claveContextoSinTimeout guarda el contexto del request previo a Timeout,
+// para que TimeoutPropio pueda reemplazar el límite en vez de acortarlo.
+const claveContextoSinTimeout = "contextoSinTimeout"
+
+// Timeout limita la duración de cada request: el contexto de c.Request vence
+// a los d y las consultas a la base en curso se cortan. d <= 0 lo desactiva.
+func Timeout(d time.Duration) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if d <= 0 {
+			c.Next()
+			return
+		}
+
+		c.Set(claveContextoSinTimeout, c.Request.Context())
+		ctx, cancel := context.WithTimeout(c.Request.Context(), d)
+		defer cancel()
+
+		c.Request = c.Request.WithContext(ctx)
+		c.Next()
+	}
+}
+
+// TimeoutPropio reemplaza el límite de Timeout por d en rutas que por
+// naturaleza tardan más (las exportaciones), y corre a d desde ahora el
+// WriteTimeout del servidor para que no corte la respuesta a mitad. d <= 0
+// quita ambos límites. Se sigue cortando si el cliente se desconecta.
+func TimeoutPropio(d time.Duration) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ctx := c.Request.Context()
+		if original, ok := c.Get(claveContextoSinTimeout); ok {
+			ctx = original.(context.Context)
+		}
+
+		var limite time.Time
+		if d > 0 {
+			limite = time.Now().Add(d)
+			var cancel context.CancelFunc
+			ctx, cancel = context.WithDeadline(ctx, limite)
+			defer cancel()
+		}
+		// Si el writer no lo soporta (p. ej. en tests) queda el del servidor
+		_ = http.NewResponseController(c.Writer).SetWriteDeadline(limite)
+
+		c.Request = c.Request.WithContext(ctx)
+		c.Next()
+	}
+}
@@ -0,0 +1,111 @@
+package middleware
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTimeout(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	t.Run("agrega deadline al contexto del request", func(t *testing.T) {
+		router := gin.New()
+		router.GET("/lento", Timeout(50*time.Millisecond), func(c *gin.Context) {
+			deadline, ok := c.Request.Context().Deadline()
+			assert.True(t, ok)
+			assert.WithinDuration(t, time.Now().Add(50*time.Millisecond), deadline, 50*time.Millisecond)
+
+			<-c.Request.Context().Done()
+			c.Status(http.StatusNoContent)
+		})
+
+		req := httptest.NewRequest("GET", "/lento", nil)
+		resp := httptest.NewRecorder()
+		router.ServeHTTP(resp, req)
+
+		assert.Equal(t, http.StatusNoContent, resp.Code)
+	})
+
+	t.Run("cero lo desactiva", func(t *testing.T) {
+		router := gin.New()
+		router.GET("/", Timeout(0), func(c *gin.Context) {
+			_, ok := c.Request.Context().Deadline()
+			assert.False(t, ok)
+			c.Status(http.StatusOK)
+		})
+
+		req := httptest.NewRequest("GET", "/", nil)
+		resp := httptest.NewRecorder()
+		router.ServeHTTP(resp, req)
+
+		assert.Equal(t, http.StatusOK, resp.Code)
+	})
+}
+
+func TestTimeoutPropio(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	t.Run("reemplaza el límite global", func(t *testing.T) {
+		router := gin.New()
+		router.Use(Timeout(50 * time.Millisecond))
+		router.GET("/exportar", TimeoutPropio(time.Minute), func(c *gin.Context) {
+			deadline, ok := c.Request.Context().Deadline()
+			assert.True(t, ok)
+			assert.WithinDuration(t, time.Now().Add(time.Minute), deadline, time.Second)
+
+			time.Sleep(100 * time.Millisecond)
+			assert.NoError(t, c.Request.Context().Err(), "el timeout global ya no aplica")
+			c.Status(http.StatusOK)
+		})
+
+		req := httptest.NewRequest("GET", "/exportar", nil)
+		resp := httptest.NewRecorder()
+		router.ServeHTTP(resp, req)
+
+		assert.Equal(t, http.StatusOK, resp.Code)
+	})
+
+	t.Run("cero quita el límite", func(t *testing.T) {
+		router := gin.New()
+		router.Use(Timeout(50 * time.Millisecond))
+		router.GET("/exportar", TimeoutPropio(0), func(c *gin.Context) {
+			_, ok := c.Request.Context().Deadline()
+			assert.False(t, ok)
+			c.Status(http.StatusOK)
+		})
+
+		req := httptest.NewRequest("GET", "/exportar", nil)
+		resp := httptest.NewRecorder()
+		router.ServeHTTP(resp, req)
+
+		assert.Equal(t, http.StatusOK, resp.Code)
+	})
+
+	t.Run("corre el write deadline del servidor", func(t *testing.T) {
+		router := gin.New()
+		router.Use(Timeout(50 * time.Millisecond))
+		router.GET("/exportar", TimeoutPropio(time.Minute), func(c *gin.Context) {
+			time.Sleep(200 * time.Millisecond)
+			c.String(http.StatusOK, "completo")
+		})
+
+		srv := httptest.NewUnstartedServer(router)
+		srv.Config.WriteTimeout = 100 * time.Millisecond
+		srv.Start()
+		defer srv.Close()
+
+		resp, err := http.Get(srv.URL + "/exportar")
+		if assert.NoError(t, err) {
+			defer resp.Body.Close()
+			cuerpo, err := io.ReadAll(resp.Body)
+			assert.NoError(t, err)
+			assert.Equal(t, "completo", string(cuerpo))
+		}
+	})
+}
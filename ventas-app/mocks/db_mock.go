@@ -1,6 +1,7 @@
 package mocks
 
 import (
+	"context"
 	"errors"
 	"ventas-app/database"
 	"ventas-app/models"
@@ -8,6 +9,8 @@ import (
 	"gorm.io/gorm"
 )
 
+// MockDB simula la base con slices y flags de error. Where ignora la consulta,
+// así que los tests que dependen del filtro deben usar MemoryDB.
 type MockDB struct {
 	Usuarios  []models.Usuario
 	Productos []models.Producto
@@ -22,6 +25,10 @@ type MockDB struct {
 	Ventas  []models.Venta
 }
 
+func (m *MockDB) WithContext(ctx context.Context) database.DBHandler {
+	return m
+}
+
 func (m *MockDB) Where(query interface{}, args ...interface{}) database.DBHandler {
 	// Mantener compatibilidad: devolveremos el mismo mock para permitir encadenamiento
 	// Guardar el filtro para que First pueda utilizarlo
@@ -29,6 +36,51 @@ func (m *MockDB) Where(query interface{}, args ...interface{}) database.DBHandle
 	return m
 }
 
+// Order y Limit se ignoran, igual que Where.
+func (m *MockDB) Order(value string) database.DBHandler {
+	return m
+}
+
+func (m *MockDB) Limit(n int) database.DBHandler {
+	return m
+}
+
+// Model, Select, Group y Joins se ignoran; Scan no devuelve filas.
+func (m *MockDB) Model(value interface{}) database.DBHandler {
+	return m
+}
+
+func (m *MockDB) Select(query interface{}, args ...interface{}) database.DBHandler {
+	return m
+}
+
+func (m *MockDB) Group(name string) database.DBHandler {
+	return m
+}
+
+func (m *MockDB) Joins(query string, args ...interface{}) database.DBHandler {
+	return m
+}
+
+func (m *MockDB) ParaActualizar() database.DBHandler {
+	return m
+}
+
+func (m *MockDB) Tabla(modelo interface{}) string {
+	return nombreTabla(modelo)
+}
+
+func (m *MockDB) Scan(dest interface{}) error {
+	if m.ShouldErr || m.FailFind {
+		return errors.New("scan error")
+	}
+	return nil
+}
+
+func (m *MockDB) Driver() string {
+	return "mock"
+}
+
 // Implementación simplificada: los métodos devuelven error en lugar de *gorm.DB
 func (m *MockDB) First(dest interface{}, conds ...interface{}) error {
 	if m.ShouldErr || m.FailFirst {
@@ -130,6 +182,15 @@ func (m *MockDB) Save(value interface{}) error {
 	return nil
 }
 
+// UpdateColumns no sabe a qué filas apunta (Model y Where se ignoran): solo
+// respeta los flags de error y reporta una fila afectada.
+func (m *MockDB) UpdateColumns(columnas map[string]interface{}) (int64, error) {
+	if m.ShouldErr || m.FailSave {
+		return 0, errors.New("error al actualizar")
+	}
+	return 1, nil
+}
+
 func (m *MockDB) Find(dest interface{}, conds ...interface{}) error {
 	if m.ShouldErr || m.FailFind {
 		return errors.New("error al listar")
@@ -147,3 +208,34 @@ func (m *MockDB) Find(dest interface{}, conds ...interface{}) error {
 
 	return nil
 }
+
+func (m *MockDB) Delete(value interface{}, conds ...interface{}) error {
+	if m.ShouldErr {
+		return errors.New("error al borrar")
+	}
+
+	switch v := value.(type) {
+	case *models.Producto:
+		for i, p := range m.Productos {
+			if p.ID == v.ID {
+				m.Productos = append(m.Productos[:i], m.Productos[i+1:]...)
+				return nil
+			}
+		}
+	case *models.Usuario:
+		for i, u := range m.Usuarios {
+			if u.ID == v.ID {
+				m.Usuarios = append(m.Usuarios[:i], m.Usuarios[i+1:]...)
+				return nil
+			}
+		}
+	}
+
+	return nil
+}
+
+// Transaction no revierte nada: los flags de error del mock se siguen aplicando
+// dentro de fn, que es lo que necesitan los tests de controllers.
+func (m *MockDB) Transaction(fn func(tx database.DBHandler) error) error {
+	return fn(m)
+}
@@ -0,0 +1,973 @@
+package mocks
+
+import (
+	"context"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"reflect"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+	"ventas-app/database"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"gorm.io/gorm/schema"
+)
+
+// ErrConsultaNoSoportada se devuelve cuando MemoryDB no sabe evaluar una condición.
+// Es preferible fallar el test a devolver un resultado que no daría la base real.
+var ErrConsultaNoSoportada = errors.New("memorydb: consulta no soportada")
+
+// MemoryDB es una implementación en memoria de database.DBHandler que evalúa
+// de verdad las condiciones simples de Where ("col = ?", "col > ?", IN, LIKE,
+// IS NULL, unidas con AND, mapas y structs), asigna IDs, completa CreatedAt/
+// UpdatedAt, respeta índices únicos y soft deletes, y soporta transacciones.
+// Con WithContext las operaciones fallan si el contexto ya venció, igual que gorm.
+// Pasa la misma suite de contrato (database/dbtest) que GormDB.
+type MemoryDB struct {
+	store   *memoriaStore
+	filtros []filtro
+	orden   []string
+	limite  int
+	modelo  interface{}
+	ctx     context.Context
+}
+
+type memoriaStore struct {
+	mu      sync.Mutex
+	tablas  map[reflect.Type]*memoriaTabla
+	schemas sync.Map
+}
+
+type memoriaTabla struct {
+	schema *schema.Schema
+	filas  []reflect.Value // punteros a copias de los structs guardados
+	nextID uint
+}
+
+type filtro struct {
+	query interface{}
+	args  []interface{}
+}
+
+// NewMemoryDB devuelve una base en memoria vacía.
+func NewMemoryDB() *MemoryDB {
+	return &MemoryDB{store: &memoriaStore{tablas: map[reflect.Type]*memoriaTabla{}}}
+}
+
+func (m *MemoryDB) WithContext(ctx context.Context) database.DBHandler {
+	copia := *m
+	copia.ctx = ctx
+	return &copia
+}
+
+func (m *MemoryDB) Where(query interface{}, args ...interface{}) database.DBHandler {
+	copia := *m
+	copia.filtros = append(append([]filtro{}, m.filtros...), filtro{query: query, args: args})
+	return &copia
+}
+
+// Order acepta "col", "col ASC" o "col DESC", separados por comas.
+func (m *MemoryDB) Order(value string) database.DBHandler {
+	copia := *m
+	copia.orden = append(append([]string{}, m.orden...), value)
+	return &copia
+}
+
+// Limit con n < 0 quita el límite, igual que gorm.
+func (m *MemoryDB) Limit(n int) database.DBHandler {
+	copia := *m
+	copia.limite = n
+	return &copia
+}
+
+// Model indica la tabla de UpdateColumns. Select, Group y Joins se aceptan
+// para encadenar, pero Scan no evalúa SQL: las consultas de agregación se
+// prueban contra SQLite.
+func (m *MemoryDB) Model(value interface{}) database.DBHandler {
+	copia := *m
+	copia.modelo = value
+	return &copia
+}
+
+func (m *MemoryDB) Select(query interface{}, args ...interface{}) database.DBHandler {
+	return m
+}
+
+func (m *MemoryDB) Group(name string) database.DBHandler {
+	return m
+}
+
+func (m *MemoryDB) Joins(query string, args ...interface{}) database.DBHandler {
+	return m
+}
+
+// ParaActualizar no bloquea nada: MemoryDB no aísla transacciones concurrentes.
+func (m *MemoryDB) ParaActualizar() database.DBHandler {
+	return m
+}
+
+func (m *MemoryDB) Tabla(modelo interface{}) string {
+	return nombreTabla(modelo)
+}
+
+func (m *MemoryDB) Scan(dest interface{}) error {
+	return fmt.Errorf("%w: Scan", ErrConsultaNoSoportada)
+}
+
+// Driver es "memoria": ninguna expresión específica de un motor aplica.
+func (m *MemoryDB) Driver() string {
+	return "memoria"
+}
+
+// errContexto devuelve el error del contexto si fue cancelado o venció.
+func (m *MemoryDB) errContexto() error {
+	if m.ctx == nil {
+		return nil
+	}
+	return m.ctx.Err()
+}
+
+func (m *MemoryDB) First(dest interface{}, conds ...interface{}) error {
+	if err := m.errContexto(); err != nil {
+		return err
+	}
+
+	rv := reflect.ValueOf(dest)
+	if rv.Kind() != reflect.Ptr || rv.Elem().Kind() != reflect.Struct {
+		return fmt.Errorf("%w: First espera un puntero a struct", ErrConsultaNoSoportada)
+	}
+
+	m.store.mu.Lock()
+	defer m.store.mu.Unlock()
+
+	tabla, err := m.store.tabla(rv.Elem().Type())
+	if err != nil {
+		return err
+	}
+	filas, err := m.seleccionar(tabla, conds)
+	if err != nil {
+		return err
+	}
+	if len(filas) == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	rv.Elem().Set(filas[0].Elem())
+	return nil
+}
+
+func (m *MemoryDB) Find(dest interface{}, conds ...interface{}) error {
+	if err := m.errContexto(); err != nil {
+		return err
+	}
+
+	rv := reflect.ValueOf(dest)
+	if rv.Kind() != reflect.Ptr || rv.Elem().Kind() != reflect.Slice {
+		return fmt.Errorf("%w: Find espera un puntero a slice", ErrConsultaNoSoportada)
+	}
+	slice := rv.Elem()
+	elemType := slice.Type().Elem()
+	esPuntero := elemType.Kind() == reflect.Ptr
+	structType := elemType
+	if esPuntero {
+		structType = elemType.Elem()
+	}
+
+	m.store.mu.Lock()
+	defer m.store.mu.Unlock()
+
+	tabla, err := m.store.tabla(structType)
+	if err != nil {
+		return err
+	}
+	filas, err := m.seleccionar(tabla, conds)
+	if err != nil {
+		return err
+	}
+
+	resultado := reflect.MakeSlice(slice.Type(), 0, len(filas))
+	for _, f := range filas {
+		if esPuntero {
+			resultado = reflect.Append(resultado, copiar(f))
+		} else {
+			resultado = reflect.Append(resultado, f.Elem())
+		}
+	}
+	slice.Set(resultado)
+	return nil
+}
+
+func (m *MemoryDB) Create(value interface{}) error {
+	if err := m.errContexto(); err != nil {
+		return err
+	}
+
+	rv := reflect.ValueOf(value)
+	if rv.Kind() != reflect.Ptr {
+		return fmt.Errorf("%w: Create espera un puntero", ErrConsultaNoSoportada)
+	}
+
+	m.store.mu.Lock()
+	defer m.store.mu.Unlock()
+
+	if rv.Elem().Kind() == reflect.Slice {
+		for i := 0; i < rv.Elem().Len(); i++ {
+			item := rv.Elem().Index(i)
+			if item.Kind() != reflect.Ptr {
+				item = item.Addr()
+			}
+			if err := m.store.insertar(item); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	return m.store.insertar(rv)
+}
+
+func (m *MemoryDB) Save(value interface{}) error {
+	if err := m.errContexto(); err != nil {
+		return err
+	}
+
+	rv := reflect.ValueOf(value)
+	if rv.Kind() != reflect.Ptr || rv.Elem().Kind() != reflect.Struct {
+		return fmt.Errorf("%w: Save espera un puntero a struct", ErrConsultaNoSoportada)
+	}
+
+	m.store.mu.Lock()
+	defer m.store.mu.Unlock()
+
+	tabla, err := m.store.tabla(rv.Elem().Type())
+	if err != nil {
+		return err
+	}
+	pk := tabla.schema.PrioritizedPrimaryField
+	if pk == nil {
+		return fmt.Errorf("%w: %s no tiene clave primaria", ErrConsultaNoSoportada, tabla.schema.Name)
+	}
+	if _, zero := pk.ValueOf(context.Background(), rv.Elem()); zero {
+		return m.store.insertar(rv)
+	}
+
+	idx := tabla.indicePorPK(rv.Elem())
+	if idx < 0 {
+		return m.store.insertar(rv)
+	}
+	if err := tabla.validarUnicos(rv.Elem(), idx); err != nil {
+		return err
+	}
+
+	ahora := time.Now()
+	anterior := tabla.filas[idx].Elem()
+	for _, f := range tabla.schema.Fields {
+		fv := f.ReflectValueOf(context.Background(), rv.Elem())
+		if f.AutoCreateTime > 0 && fv.IsZero() {
+			fv.Set(f.ReflectValueOf(context.Background(), anterior))
+		}
+		if f.AutoUpdateTime > 0 {
+			asignarTiempo(fv, ahora)
+		}
+	}
+	tabla.filas[idx] = copiar(rv)
+	return nil
+}
+
+// UpdateColumns acepta valores simples y gorm.Expr("col + ?", n) o
+// gorm.Expr("col - ?", n). Si el Model tiene clave primaria, filtra por ella
+// igual que gorm.
+func (m *MemoryDB) UpdateColumns(columnas map[string]interface{}) (int64, error) {
+	if err := m.errContexto(); err != nil {
+		return 0, err
+	}
+
+	rv := reflect.ValueOf(m.modelo)
+	if rv.Kind() != reflect.Ptr || rv.Elem().Kind() != reflect.Struct {
+		return 0, fmt.Errorf("%w: UpdateColumns espera Model con un puntero a struct", ErrConsultaNoSoportada)
+	}
+
+	m.store.mu.Lock()
+	defer m.store.mu.Unlock()
+
+	tabla, err := m.store.tabla(rv.Elem().Type())
+	if err != nil {
+		return 0, err
+	}
+
+	filtros := m.filtros
+	if pk := tabla.schema.PrioritizedPrimaryField; pk != nil {
+		if id, zero := pk.ValueOf(context.Background(), rv.Elem()); !zero {
+			filtros = append(append([]filtro{}, filtros...), filtro{query: pk.DBName + " = ?", args: []interface{}{id}})
+		}
+	}
+	if len(filtros) == 0 {
+		return 0, gorm.ErrMissingWhereClause
+	}
+
+	filas, err := (&MemoryDB{store: m.store, filtros: filtros, ctx: m.ctx}).seleccionar(tabla, nil)
+	if err != nil {
+		return 0, err
+	}
+
+	// Se calculan todas las filas antes de tocar ninguna: si una falla
+	// (columna inexistente, índice único) no queda nada a medias
+	nuevas := make([]reflect.Value, len(filas))
+	for i, fila := range filas {
+		nueva := copiar(fila)
+		for col, valor := range columnas {
+			if err := asignarColumna(tabla.schema, fila.Elem(), nueva.Elem(), col, valor); err != nil {
+				return 0, err
+			}
+		}
+		idx := tabla.indicePorPK(fila.Elem())
+		if err := tabla.validarUnicos(nueva.Elem(), idx); err != nil {
+			return 0, err
+		}
+		nuevas[i] = nueva
+	}
+	for i, fila := range filas {
+		fila.Elem().Set(nuevas[i].Elem())
+	}
+	return int64(len(filas)), nil
+}
+
+func (m *MemoryDB) Delete(value interface{}, conds ...interface{}) error {
+	if err := m.errContexto(); err != nil {
+		return err
+	}
+
+	rv := reflect.ValueOf(value)
+	if rv.Kind() != reflect.Ptr || rv.Elem().Kind() != reflect.Struct {
+		return fmt.Errorf("%w: Delete espera un puntero a struct", ErrConsultaNoSoportada)
+	}
+
+	m.store.mu.Lock()
+	defer m.store.mu.Unlock()
+
+	tabla, err := m.store.tabla(rv.Elem().Type())
+	if err != nil {
+		return err
+	}
+
+	filtros := m.filtros
+	if pk := tabla.schema.PrioritizedPrimaryField; pk != nil {
+		if id, zero := pk.ValueOf(context.Background(), rv.Elem()); !zero {
+			filtros = append(append([]filtro{}, filtros...), filtro{query: pk.DBName + " = ?", args: []interface{}{id}})
+		}
+	}
+	if len(filtros) == 0 && len(conds) == 0 {
+		return gorm.ErrMissingWhereClause
+	}
+
+	filas, err := (&MemoryDB{store: m.store, filtros: filtros, ctx: m.ctx}).seleccionar(tabla, conds)
+	if err != nil {
+		return err
+	}
+
+	deletedAt := tabla.schema.LookUpField("DeletedAt")
+	ahora := time.Now()
+	for _, f := range filas {
+		if deletedAt != nil {
+			if err := deletedAt.Set(context.Background(), f.Elem(), ahora); err != nil {
+				return err
+			}
+			continue
+		}
+		tabla.quitar(f)
+	}
+	return nil
+}
+
+// Transaction toma una foto del estado y la restaura si fn falla o hace panic.
+// No aísla transacciones concurrentes entre sí: alcanza para tests.
+func (m *MemoryDB) Transaction(fn func(tx database.DBHandler) error) (err error) {
+	if err := m.errContexto(); err != nil {
+		return err
+	}
+
+	m.store.mu.Lock()
+	foto := m.store.foto()
+	m.store.mu.Unlock()
+
+	restaurar := func() {
+		m.store.mu.Lock()
+		m.store.tablas = foto
+		m.store.mu.Unlock()
+	}
+
+	defer func() {
+		if r := recover(); r != nil {
+			restaurar()
+			panic(r)
+		}
+	}()
+
+	if err = fn(&MemoryDB{store: m.store, filtros: m.filtros, orden: m.orden, limite: m.limite, ctx: m.ctx}); err != nil {
+		restaurar()
+	}
+	return err
+}
+
+// seleccionar devuelve las filas vivas que cumplen los filtros y conds,
+// ordenadas por clave primaria. Debe llamarse con el mutex tomado.
+func (m *MemoryDB) seleccionar(tabla *memoriaTabla, conds []interface{}) ([]reflect.Value, error) {
+	filtros := m.filtros
+	if len(conds) > 0 {
+		f, err := filtroDesdeConds(tabla.schema, conds)
+		if err != nil {
+			return nil, err
+		}
+		filtros = append(append([]filtro{}, filtros...), f)
+	}
+
+	deletedAt := tabla.schema.LookUpField("DeletedAt")
+	var resultado []reflect.Value
+	for _, fila := range tabla.filas {
+		if deletedAt != nil {
+			if v, zero := deletedAt.ValueOf(context.Background(), fila.Elem()); !zero && !esNulo(v) {
+				continue
+			}
+		}
+		ok := true
+		for _, f := range filtros {
+			coincide, err := f.coincide(tabla.schema, fila.Elem())
+			if err != nil {
+				return nil, err
+			}
+			if !coincide {
+				ok = false
+				break
+			}
+		}
+		if ok {
+			resultado = append(resultado, fila)
+		}
+	}
+
+	if pk := tabla.schema.PrioritizedPrimaryField; pk != nil {
+		sort.SliceStable(resultado, func(i, j int) bool {
+			a, _ := pk.ValueOf(context.Background(), resultado[i].Elem())
+			b, _ := pk.ValueOf(context.Background(), resultado[j].Elem())
+			cmp, _ := comparar(a, b)
+			return cmp < 0
+		})
+	}
+
+	if err := ordenar(tabla.schema, resultado, m.orden); err != nil {
+		return nil, err
+	}
+	if m.limite > 0 && len(resultado) > m.limite {
+		resultado = resultado[:m.limite]
+	}
+	return resultado, nil
+}
+
+// ordenar aplica las cláusulas de Order en orden de prioridad; los empates
+// quedan por clave primaria.
+func ordenar(s *schema.Schema, filas []reflect.Value, orden []string) error {
+	type criterio struct {
+		campo *schema.Field
+		desc  bool
+	}
+	var criterios []criterio
+	for _, o := range orden {
+		for _, parte := range strings.Split(o, ",") {
+			palabras := strings.Fields(parte)
+			if len(palabras) == 0 || len(palabras) > 2 {
+				return fmt.Errorf("%w: orden %q", ErrConsultaNoSoportada, o)
+			}
+			campo := s.LookUpField(palabras[0])
+			if campo == nil {
+				return fmt.Errorf("%w: columna %q", ErrConsultaNoSoportada, palabras[0])
+			}
+			c := criterio{campo: campo}
+			if len(palabras) == 2 {
+				switch strings.ToUpper(palabras[1]) {
+				case "ASC":
+				case "DESC":
+					c.desc = true
+				default:
+					return fmt.Errorf("%w: orden %q", ErrConsultaNoSoportada, o)
+				}
+			}
+			criterios = append(criterios, c)
+		}
+	}
+	if len(criterios) == 0 {
+		return nil
+	}
+
+	sort.SliceStable(filas, func(i, j int) bool {
+		for _, c := range criterios {
+			a, _ := c.campo.ValueOf(context.Background(), filas[i].Elem())
+			b, _ := c.campo.ValueOf(context.Background(), filas[j].Elem())
+			cmp, _ := comparar(a, b)
+			if cmp != 0 {
+				return (cmp < 0) != c.desc
+			}
+		}
+		return false
+	})
+	return nil
+}
+
+func filtroDesdeConds(s *schema.Schema, conds []interface{}) (filtro, error) {
+	switch v := conds[0].(type) {
+	case string:
+		if len(conds) == 1 && !strings.ContainsAny(v, "?=<> ") {
+			break
+		}
+		return filtro{query: v, args: conds[1:]}, nil
+	case map[string]interface{}:
+		return filtro{query: v}, nil
+	}
+	if s.PrioritizedPrimaryField == nil {
+		return filtro{}, fmt.Errorf("%w: %s no tiene clave primaria", ErrConsultaNoSoportada, s.Name)
+	}
+	if reflect.ValueOf(conds[0]).Kind() == reflect.Slice {
+		return filtro{query: s.PrioritizedPrimaryField.DBName + " IN ?", args: conds[:1]}, nil
+	}
+	return filtro{query: s.PrioritizedPrimaryField.DBName + " = ?", args: conds[:1]}, nil
+}
+
+var (
+	separadorAND = regexp.MustCompile(`(?i)\s+AND\s+`)
+	condicionRe  = regexp.MustCompile(`(?i)^\s*[\x60"]?([\w.]+?)[\x60"]?\s*(=|<>|!=|>=|<=|>|<|NOT\s+LIKE|LIKE|NOT\s+IN|IN|IS\s+NOT\s+NULL|IS\s+NULL)\s*(\(?\s*\?\s*\)?)?\s*$`)
+)
+
+func (f filtro) coincide(s *schema.Schema, fila reflect.Value) (bool, error) {
+	switch q := f.query.(type) {
+	case string:
+		return coincideTexto(s, fila, q, f.args)
+	case map[string]interface{}:
+		for col, esperado := range q {
+			ok, err := coincideCondicion(s, fila, col, "=", esperado)
+			if err != nil || !ok {
+				return false, err
+			}
+		}
+		return true, nil
+	}
+
+	// Struct o puntero a struct: gorm filtra por los campos no-cero.
+	rv := reflect.Indirect(reflect.ValueOf(f.query))
+	if rv.Kind() != reflect.Struct || rv.Type() != fila.Type() {
+		return false, fmt.Errorf("%w: %T", ErrConsultaNoSoportada, f.query)
+	}
+	for _, campo := range s.Fields {
+		if campo.DBName == "" {
+			continue
+		}
+		v, zero := campo.ValueOf(context.Background(), rv)
+		if zero {
+			continue
+		}
+		ok, err := coincideCondicion(s, fila, campo.DBName, "=", v)
+		if err != nil || !ok {
+			return false, err
+		}
+	}
+	return true, nil
+}
+
+func coincideTexto(s *schema.Schema, fila reflect.Value, query string, args []interface{}) (bool, error) {
+	restantes := args
+	for _, parte := range separadorAND.Split(strings.TrimSpace(query), -1) {
+		parte = strings.TrimSpace(strings.TrimSuffix(strings.TrimPrefix(strings.TrimSpace(parte), "("), ")"))
+		match := condicionRe.FindStringSubmatch(parte)
+		if match == nil {
+			return false, fmt.Errorf("%w: %q", ErrConsultaNoSoportada, parte)
+		}
+		col := match[1]
+		if i := strings.LastIndex(col, "."); i >= 0 {
+			col = col[i+1:]
+		}
+		op := strings.ToUpper(strings.Join(strings.Fields(match[2]), " "))
+
+		var arg interface{}
+		if match[3] != "" {
+			if len(restantes) == 0 {
+				return false, fmt.Errorf("%w: faltan argumentos para %q", ErrConsultaNoSoportada, query)
+			}
+			arg, restantes = restantes[0], restantes[1:]
+		}
+
+		ok, err := coincideCondicion(s, fila, col, op, arg)
+		if err != nil || !ok {
+			return false, err
+		}
+	}
+	return true, nil
+}
+
+func coincideCondicion(s *schema.Schema, fila reflect.Value, col, op string, arg interface{}) (bool, error) {
+	campo := s.LookUpField(col)
+	if campo == nil {
+		return false, fmt.Errorf("%w: columna %q inexistente en %s", ErrConsultaNoSoportada, col, s.Name)
+	}
+	valor, _ := campo.ValueOf(context.Background(), fila)
+	valor = normalizar(valor)
+
+	switch op {
+	case "IS NULL":
+		return valor == nil, nil
+	case "IS NOT NULL":
+		return valor != nil, nil
+	case "IN", "NOT IN":
+		lista := reflect.ValueOf(arg)
+		if lista.Kind() != reflect.Slice {
+			return false, fmt.Errorf("%w: IN espera un slice", ErrConsultaNoSoportada)
+		}
+		encontrado := false
+		for i := 0; i < lista.Len(); i++ {
+			if cmp, ok := comparar(valor, normalizar(lista.Index(i).Interface())); ok && cmp == 0 {
+				encontrado = true
+				break
+			}
+		}
+		return encontrado == (op == "IN"), nil
+	case "LIKE", "NOT LIKE":
+		texto, ok1 := valor.(string)
+		patron, ok2 := normalizar(arg).(string)
+		if !ok1 || !ok2 {
+			return false, nil
+		}
+		return likeRegexp(patron).MatchString(texto) == (op == "LIKE"), nil
+	}
+
+	// En SQL cualquier comparación contra NULL es falsa.
+	esperado := normalizar(arg)
+	if valor == nil || esperado == nil {
+		return false, nil
+	}
+	cmp, ok := comparar(valor, esperado)
+	if !ok {
+		return false, fmt.Errorf("%w: no se puede comparar %T con %T", ErrConsultaNoSoportada, valor, esperado)
+	}
+	switch op {
+	case "=":
+		return cmp == 0, nil
+	case "<>", "!=":
+		return cmp != 0, nil
+	case ">":
+		return cmp > 0, nil
+	case ">=":
+		return cmp >= 0, nil
+	case "<":
+		return cmp < 0, nil
+	case "<=":
+		return cmp <= 0, nil
+	}
+	return false, fmt.Errorf("%w: operador %q", ErrConsultaNoSoportada, op)
+}
+
+// likeRegexp traduce un patrón LIKE a regexp (sin distinguir mayúsculas,
+// como la collation por defecto de MySQL).
+func likeRegexp(patron string) *regexp.Regexp {
+	var b strings.Builder
+	b.WriteString("(?is)^")
+	for _, r := range patron {
+		switch r {
+		case '%':
+			b.WriteString(".*")
+		case '_':
+			b.WriteString(".")
+		default:
+			b.WriteString(regexp.QuoteMeta(string(r)))
+		}
+	}
+	b.WriteString("$")
+	return regexp.MustCompile(b.String())
+}
+
+// normalizar lleva los valores a float64, string, bool, time.Time o nil para
+// poder compararlos sin importar el tipo Go concreto.
+func normalizar(v interface{}) interface{} {
+	if v == nil {
+		return nil
+	}
+	if valuer, ok := v.(driver.Valuer); ok {
+		rv := reflect.ValueOf(v)
+		if rv.Kind() == reflect.Ptr && rv.IsNil() {
+			return nil
+		}
+		dv, err := valuer.Value()
+		if err != nil {
+			return v
+		}
+		return normalizar(dv)
+	}
+
+	rv := reflect.ValueOf(v)
+	switch rv.Kind() {
+	case reflect.Ptr:
+		if rv.IsNil() {
+			return nil
+		}
+		return normalizar(rv.Elem().Interface())
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return float64(rv.Int())
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return float64(rv.Uint())
+	case reflect.Float32, reflect.Float64:
+		return rv.Float()
+	case reflect.String:
+		return rv.String()
+	case reflect.Bool:
+		return rv.Bool()
+	}
+	if b, ok := v.([]byte); ok {
+		return string(b)
+	}
+	return v
+}
+
+func comparar(a, b interface{}) (int, bool) {
+	a, b = normalizar(a), normalizar(b)
+	switch x := a.(type) {
+	case float64:
+		y, ok := b.(float64)
+		if !ok {
+			return 0, false
+		}
+		switch {
+		case x < y:
+			return -1, true
+		case x > y:
+			return 1, true
+		}
+		return 0, true
+	case string:
+		y, ok := b.(string)
+		if !ok {
+			return 0, false
+		}
+		return strings.Compare(x, y), true
+	case bool:
+		y, ok := b.(bool)
+		if !ok {
+			return 0, false
+		}
+		if x == y {
+			return 0, true
+		}
+		if !x {
+			return -1, true
+		}
+		return 1, true
+	case time.Time:
+		y, ok := b.(time.Time)
+		if !ok {
+			return 0, false
+		}
+		return x.Compare(y), true
+	}
+	return 0, false
+}
+
+var expresionAritmetica = regexp.MustCompile(`^\s*[\x60"]?(\w+)[\x60"]?\s*([+-])\s*\?\s*$`)
+
+// asignarColumna pone en destino el valor de col; las expresiones se evalúan
+// con los valores de la fila original, como en un UPDATE.
+func asignarColumna(s *schema.Schema, original, destino reflect.Value, col string, valor interface{}) error {
+	campo := s.LookUpField(col)
+	if campo == nil || campo.DBName == "" {
+		return fmt.Errorf("%w: columna %q inexistente en %s", ErrConsultaNoSoportada, col, s.Name)
+	}
+
+	if expr, ok := valor.(clause.Expr); ok {
+		match := expresionAritmetica.FindStringSubmatch(expr.SQL)
+		if match == nil || len(expr.Vars) != 1 {
+			return fmt.Errorf("%w: expresión %q", ErrConsultaNoSoportada, expr.SQL)
+		}
+		origen := s.LookUpField(match[1])
+		if origen == nil {
+			return fmt.Errorf("%w: columna %q inexistente en %s", ErrConsultaNoSoportada, match[1], s.Name)
+		}
+		actual, _ := origen.ValueOf(context.Background(), original)
+		a, ok1 := normalizar(actual).(float64)
+		b, ok2 := normalizar(expr.Vars[0]).(float64)
+		if !ok1 || !ok2 {
+			return fmt.Errorf("%w: expresión %q sobre valores no numéricos", ErrConsultaNoSoportada, expr.SQL)
+		}
+		if match[2] == "-" {
+			b = -b
+		}
+		valor = a + b
+	}
+
+	if valor == nil {
+		campo.ReflectValueOf(context.Background(), destino).Set(reflect.Zero(campo.FieldType))
+		return nil
+	}
+	return campo.Set(context.Background(), destino, valor)
+}
+
+func esNulo(v interface{}) bool {
+	return normalizar(v) == nil
+}
+
+func copiar(v reflect.Value) reflect.Value {
+	cp := reflect.New(v.Elem().Type())
+	cp.Elem().Set(v.Elem())
+	return cp
+}
+
+func asignarTiempo(fv reflect.Value, t time.Time) {
+	switch fv.Interface().(type) {
+	case time.Time:
+		fv.Set(reflect.ValueOf(t))
+	case *time.Time:
+		fv.Set(reflect.ValueOf(&t))
+	}
+}
+
+// nombreTabla es el nombre que le da gorm a la tabla de modelo con la
+// NamingStrategy por defecto, la misma que usa la base real.
+func nombreTabla(modelo interface{}) string {
+	sch, err := schema.Parse(modelo, &sync.Map{}, schema.NamingStrategy{})
+	if err != nil {
+		return ""
+	}
+	return sch.Table
+}
+
+// tabla devuelve (creando si hace falta) la tabla de un tipo de modelo.
+func (s *memoriaStore) tabla(t reflect.Type) (*memoriaTabla, error) {
+	if tabla, ok := s.tablas[t]; ok {
+		return tabla, nil
+	}
+	sch, err := schema.Parse(reflect.New(t).Interface(), &s.schemas, schema.NamingStrategy{})
+	if err != nil {
+		return nil, err
+	}
+	tabla := &memoriaTabla{schema: sch}
+	s.tablas[t] = tabla
+	return tabla, nil
+}
+
+// insertar guarda una copia de rv (puntero a struct), completando ID y timestamps.
+func (s *memoriaStore) insertar(rv reflect.Value) error {
+	tabla, err := s.tabla(rv.Elem().Type())
+	if err != nil {
+		return err
+	}
+
+	ctx := context.Background()
+	if pk := tabla.schema.PrioritizedPrimaryField; pk != nil {
+		id, zero := pk.ValueOf(ctx, rv.Elem())
+		if zero {
+			tabla.nextID++
+			if err := pk.Set(ctx, rv.Elem(), tabla.nextID); err != nil {
+				return err
+			}
+		} else {
+			if tabla.indicePorPK(rv.Elem()) >= 0 {
+				return gorm.ErrDuplicatedKey
+			}
+			if n, ok := normalizar(id).(float64); ok && uint(n) > tabla.nextID {
+				tabla.nextID = uint(n)
+			}
+		}
+	}
+
+	if err := tabla.validarUnicos(rv.Elem(), -1); err != nil {
+		return err
+	}
+
+	ahora := time.Now()
+	for _, f := range tabla.schema.Fields {
+		fv := f.ReflectValueOf(ctx, rv.Elem())
+		if (f.AutoCreateTime > 0 || f.AutoUpdateTime > 0) && fv.IsZero() {
+			asignarTiempo(fv, ahora)
+		}
+	}
+
+	tabla.filas = append(tabla.filas, copiar(rv))
+	return nil
+}
+
+// foto copia el estado de todas las tablas para poder restaurarlo.
+func (s *memoriaStore) foto() map[reflect.Type]*memoriaTabla {
+	foto := make(map[reflect.Type]*memoriaTabla, len(s.tablas))
+	for t, tabla := range s.tablas {
+		cp := &memoriaTabla{schema: tabla.schema, nextID: tabla.nextID}
+		for _, f := range tabla.filas {
+			cp.filas = append(cp.filas, copiar(f))
+		}
+		foto[t] = cp
+	}
+	return foto
+}
+
+func (t *memoriaTabla) indicePorPK(v reflect.Value) int {
+	pk := t.schema.PrioritizedPrimaryField
+	if pk == nil {
+		return -1
+	}
+	id, _ := pk.ValueOf(context.Background(), v)
+	for i, f := range t.filas {
+		otro, _ := pk.ValueOf(context.Background(), f.Elem())
+		if cmp, ok := comparar(id, otro); ok && cmp == 0 {
+			return i
+		}
+	}
+	return -1
+}
+
+func (t *memoriaTabla) quitar(fila reflect.Value) {
+	for i, f := range t.filas {
+		if f == fila {
+			t.filas = append(t.filas[:i], t.filas[i+1:]...)
+			return
+		}
+	}
+}
+
+// validarUnicos emula los índices UNIQUE: ignora la fila "excepto" (la que se
+// está actualizando) y los valores NULL, igual que MySQL/PostgreSQL/SQLite.
+func (t *memoriaTabla) validarUnicos(v reflect.Value, excepto int) error {
+	var grupos [][]*schema.Field
+	for _, f := range t.schema.Fields {
+		if f.Unique {
+			grupos = append(grupos, []*schema.Field{f})
+		}
+	}
+	for _, idx := range t.schema.ParseIndexes() {
+		if idx.Class != "UNIQUE" {
+			continue
+		}
+		var campos []*schema.Field
+		for _, opt := range idx.Fields {
+			campos = append(campos, opt.Field)
+		}
+		grupos = append(grupos, campos)
+	}
+
+	ctx := context.Background()
+	for _, campos := range grupos {
+		for i, f := range t.filas {
+			if i == excepto {
+				continue
+			}
+			iguales := true
+			for _, campo := range campos {
+				a, _ := campo.ValueOf(ctx, v)
+				b, _ := campo.ValueOf(ctx, f.Elem())
+				cmp, ok := comparar(a, b)
+				if esNulo(a) || !ok || cmp != 0 {
+					iguales = false
+					break
+				}
+			}
+			if iguales {
+				return gorm.ErrDuplicatedKey
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,39 @@
+package mocks
+
+import (
+	"errors"
+	"testing"
+	"ventas-app/database"
+	"ventas-app/database/dbtest"
+	"ventas-app/models"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMemoryDB_Contrato(t *testing.T) {
+	dbtest.RunContract(t, func(t *testing.T) database.DBHandler {
+		return NewMemoryDB()
+	})
+}
+
+func TestMemoryDB_ConsultaNoSoportada(t *testing.T) {
+	db := NewMemoryDB()
+	_ = db.Create(&models.Producto{Nombre: "Mate"})
+
+	var p models.Producto
+	err := db.Where("nombre = ? OR stock > ?", "Mate", 1).First(&p)
+
+	assert.True(t, errors.Is(err, ErrConsultaNoSoportada))
+}
+
+func TestMemoryDB_DevuelveCopias(t *testing.T) {
+	db := NewMemoryDB()
+	p := models.Producto{Nombre: "Mate", Stock: 3}
+	_ = db.Create(&p)
+
+	p.Stock = 100
+
+	var leido models.Producto
+	_ = db.First(&leido, p.ID)
+	assert.Equal(t, 3, leido.Stock)
+}
@@ -1,6 +1,7 @@
 package mocks
 
 import (
+	"context"
 	"errors"
 	"ventas-app/database"
 )
@@ -11,10 +12,57 @@ type FakeDB struct {
 	shouldFail bool
 }
 
+func (f *FakeDB) WithContext(ctx context.Context) database.DBHandler {
+	return f
+}
+
 func (f *FakeDB) Where(query interface{}, args ...interface{}) database.DBHandler {
 	return f
 }
 
+func (f *FakeDB) Order(value string) database.DBHandler {
+	return f
+}
+
+func (f *FakeDB) Limit(n int) database.DBHandler {
+	return f
+}
+
+func (f *FakeDB) Model(value interface{}) database.DBHandler {
+	return f
+}
+
+func (f *FakeDB) Select(query interface{}, args ...interface{}) database.DBHandler {
+	return f
+}
+
+func (f *FakeDB) Group(name string) database.DBHandler {
+	return f
+}
+
+func (f *FakeDB) Joins(query string, args ...interface{}) database.DBHandler {
+	return f
+}
+
+func (f *FakeDB) ParaActualizar() database.DBHandler {
+	return f
+}
+
+func (f *FakeDB) Tabla(modelo interface{}) string {
+	return nombreTabla(modelo)
+}
+
+func (f *FakeDB) Scan(dest interface{}) error {
+	if f.shouldFail {
+		return errors.New("fake scan error")
+	}
+	return nil
+}
+
+func (f *FakeDB) Driver() string {
+	return "fake"
+}
+
 func (f *FakeDB) First(dest interface{}, conds ...interface{}) error {
 	if f.shouldFail {
 		return errors.New("record not found")
@@ -36,6 +84,13 @@ func (f *FakeDB) Save(value interface{}) error {
 	return nil
 }
 
+func (f *FakeDB) UpdateColumns(columnas map[string]interface{}) (int64, error) {
+	if f.shouldFail {
+		return 0, errors.New("error al actualizar")
+	}
+	return 1, nil
+}
+
 func (f *FakeDB) Find(dest interface{}, conds ...interface{}) error {
 	if f.shouldFail {
 		return errors.New("error al listar")
@@ -43,6 +98,17 @@ func (f *FakeDB) Find(dest interface{}, conds ...interface{}) error {
 	return nil
 }
 
+func (f *FakeDB) Delete(value interface{}, conds ...interface{}) error {
+	if f.shouldFail {
+		return errors.New("error al borrar")
+	}
+	return nil
+}
+
+func (f *FakeDB) Transaction(fn func(tx database.DBHandler) error) error {
+	return fn(f)
+}
+
 // NewFakeDB devuelve un database.DBHandler simple
 func NewFakeDB(shouldFail bool) database.DBHandler {
 	return &FakeDB{shouldFail: shouldFail}
@@ -0,0 +1,39 @@
+package models
+
+import (
+	"strings"
+	"time"
+)
+
+// APIKey es una clave para integraciones (sincronización del e-commerce,
+// lectores de código de barras) que no pasan por el login. Actúa en nombre
+// de UsuarioID, limitada a Scopes ("productos:read ventas:write", separados
+// por espacios como en OAuth). Solo se guarda el hash SHA-256; Prefijo
+// permite reconocerla en el listado.
+type APIKey struct {
+	ID          uint       `gorm:"primaryKey" json:"id"`
+	CreatedAt   time.Time  `json:"creada_en"`
+	Nombre      string     `gorm:"size:100;not null" json:"nombre"`
+	Prefijo     string     `gorm:"size:16;index;not null" json:"prefijo"`
+	Hash        string     `gorm:"size:64;uniqueIndex;not null" json:"-"`
+	Scopes      string     `gorm:"size:255;not null" json:"scopes"`
+	UsuarioID   uint       `gorm:"index;not null" json:"usuario_id"`
+	ExpiraEn    *time.Time `json:"expira_en,omitempty"`
+	UltimoUsoEn *time.Time `json:"ultimo_uso_en,omitempty"`
+	RevocadaEn  *time.Time `json:"revocada_en,omitempty"`
+}
+
+// Vigente indica si la clave no fue revocada ni venció en el instante ahora.
+func (k APIKey) Vigente(ahora time.Time) bool {
+	return k.RevocadaEn == nil && (k.ExpiraEn == nil || ahora.Before(*k.ExpiraEn))
+}
+
+// Permite indica si la clave tiene el scope pedido.
+func (k APIKey) Permite(scope string) bool {
+	for _, s := range strings.Fields(k.Scopes) {
+		if s == scope {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,46 @@
+package models
+
+import "time"
+
+// Operaciones de AuditoriaEvento.
+const (
+	AuditoriaCrear      = "crear"
+	AuditoriaActualizar = "actualizar"
+	AuditoriaBorrar     = "borrar"
+)
+
+// AuditoriaEvento registra un alta, modificación o baja de una entidad
+// auditada. Cambios es un JSON: en un alta los valores creados, en una
+// modificación {"campo": {"antes": x, "despues": y}} solo con lo que cambió,
+// y en una baja los valores borrados. Los campos secretos (json:"-") se
+// registran como "[oculto]". UsuarioID es nil si no hubo actor identificado
+// (jobs, requests sin token).
+type AuditoriaEvento struct {
+	ID        uint      `gorm:"primaryKey" json:"id"`
+	CreatedAt time.Time `gorm:"index" json:"fecha"`
+	Env       string    `gorm:"size:32;index" json:"env"`
+	RequestID string    `gorm:"size:64;index" json:"request_id,omitempty"`
+	UsuarioID *uint     `gorm:"index" json:"usuario_id,omitempty"`
+	APIKeyID  *uint     `json:"api_key_id,omitempty"`
+	IP        string    `gorm:"size:64" json:"ip,omitempty"`
+	Entidad   string    `gorm:"size:64;index" json:"entidad"`
+	EntidadID uint      `gorm:"index" json:"entidad_id"`
+	Operacion string    `gorm:"size:16;index" json:"operacion"`
+	Cambios   JSONCrudo `gorm:"type:text" json:"cambios"`
+}
+
+// JSONCrudo es un JSON guardado como texto que se serializa tal cual, sin
+// escaparlo como string.
+type JSONCrudo string
+
+func (j JSONCrudo) MarshalJSON() ([]byte, error) {
+	if j == "" {
+		return []byte("null"), nil
+	}
+	return []byte(j), nil
+}
+
+func (j *JSONCrudo) UnmarshalJSON(b []byte) error {
+	*j = JSONCrudo(b)
+	return nil
+}
@@ -0,0 +1,22 @@
+package models
+
+import "time"
+
+// Categoria clasifica productos en un árbol: PadreID nil es una categoría
+// raíz. Los productos apuntan a una sola categoría; las de arriba se
+// deducen del árbol, así que mover una rama no toca los productos.
+type Categoria struct {
+	ID        uint      `gorm:"primaryKey" json:"id"`
+	CreatedAt time.Time `json:"creado_en"`
+	UpdatedAt time.Time `json:"actualizado_en"`
+	Nombre    string    `gorm:"not null" json:"nombre"`
+	PadreID   *uint     `gorm:"index" json:"padre_id"`
+}
+
+// Marca es el fabricante o marca comercial de un producto.
+type Marca struct {
+	ID        uint      `gorm:"primaryKey" json:"id"`
+	CreatedAt time.Time `json:"creado_en"`
+	UpdatedAt time.Time `json:"actualizado_en"`
+	Nombre    string    `gorm:"size:128;not null;uniqueIndex" json:"nombre"`
+}
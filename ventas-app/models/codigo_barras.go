@@ -0,0 +1,60 @@
+package models
+
+import (
+	"errors"
+	"strings"
+	"time"
+)
+
+// ErrCodigoBarrasInvalido: el código no es un GTIN (EAN-8, UPC-A, EAN-13 o
+// GTIN-14) o su dígito verificador no coincide.
+var ErrCodigoBarrasInvalido = errors.New("código de barras inválido")
+
+// CodigoBarras es uno de los códigos (GTIN) de un producto. Un producto puede
+// tener varios, pero cada código es de un solo producto. Codigo se guarda
+// como GTIN-14 (ver NormalizarCodigoBarras).
+type CodigoBarras struct {
+	ID         uint      `gorm:"primaryKey" json:"id"`
+	CreatedAt  time.Time `json:"created_at"`
+	ProductoID uint      `gorm:"not null;index" json:"producto_id"`
+	Codigo     string    `gorm:"size:14;not null;uniqueIndex" json:"codigo"`
+}
+
+// ValidarCodigoBarras controla largo y dígito verificador (módulo 10 de GS1,
+// el mismo para EAN-8, UPC-A, EAN-13 y GTIN-14).
+func ValidarCodigoBarras(codigo string) error {
+	switch len(codigo) {
+	case 8, 12, 13, 14:
+	default:
+		return ErrCodigoBarrasInvalido
+	}
+	suma := 0
+	for i := len(codigo) - 2; i >= 0; i-- {
+		d := codigo[i]
+		if d < '0' || d > '9' {
+			return ErrCodigoBarrasInvalido
+		}
+		// Desde la derecha (sin el verificador) los pesos son 3, 1, 3, ...
+		peso := 1
+		if (len(codigo)-2-i)%2 == 0 {
+			peso = 3
+		}
+		suma += int(d-'0') * peso
+	}
+	verificador := codigo[len(codigo)-1]
+	if verificador < '0' || verificador > '9' || int(verificador-'0') != (10-suma%10)%10 {
+		return ErrCodigoBarrasInvalido
+	}
+	return nil
+}
+
+// NormalizarCodigoBarras valida el código y lo devuelve como GTIN-14,
+// completando con ceros a la izquierda (no cambian el dígito verificador).
+// Así el mismo artículo leído como UPC-A (012345678905) o como EAN-13
+// (0012345678905) es un único código.
+func NormalizarCodigoBarras(codigo string) (string, error) {
+	if err := ValidarCodigoBarras(codigo); err != nil {
+		return "", err
+	}
+	return strings.Repeat("0", 14-len(codigo)) + codigo, nil
+}
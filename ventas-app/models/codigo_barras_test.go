@@ -0,0 +1,33 @@
+package models
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestValidarCodigoBarras(t *testing.T) {
+	for _, codigo := range []string{"7790070410122", "4006381333931", "96385074", "036000291452", "10012345678902"} {
+		assert.NoError(t, ValidarCodigoBarras(codigo), codigo)
+	}
+	for _, codigo := range []string{"", "7790070410123", "400638133393", "12345", "40063813339x1", "400638133393a"} {
+		assert.ErrorIs(t, ValidarCodigoBarras(codigo), ErrCodigoBarrasInvalido, codigo)
+	}
+}
+
+func TestNormalizarCodigoBarras(t *testing.T) {
+	upc, err := NormalizarCodigoBarras("012345678905")
+	assert.NoError(t, err)
+	ean, err := NormalizarCodigoBarras("0012345678905")
+	assert.NoError(t, err)
+	assert.Equal(t, "00012345678905", upc)
+	assert.Equal(t, upc, ean, "UPC-A y su EAN-13 son el mismo GTIN")
+
+	ean8, err := NormalizarCodigoBarras("96385074")
+	assert.NoError(t, err)
+	assert.Equal(t, "00000096385074", ean8)
+	assert.NoError(t, ValidarCodigoBarras(ean8))
+
+	_, err = NormalizarCodigoBarras("7790070410123")
+	assert.ErrorIs(t, err, ErrCodigoBarrasInvalido)
+}
@@ -0,0 +1,14 @@
+package models
+
+import "time"
+
+// CodigoRecuperacion reemplaza al código TOTP una única vez, por si se pierde
+// el dispositivo. Solo se guarda el hash SHA-256; el código en claro se
+// muestra al usuario al generarlos.
+type CodigoRecuperacion struct {
+	ID        uint `gorm:"primaryKey"`
+	CreatedAt time.Time
+	UsuarioID uint   `gorm:"index;not null"`
+	Hash      string `gorm:"size:64;uniqueIndex;not null"`
+	UsadoEn   *time.Time
+}
@@ -0,0 +1,76 @@
+package models
+
+import "time"
+
+// EsquemaComision define cuánto cobra un vendedor sobre el neto vendido
+// menos devoluciones. Con Tramos vacío es un porcentaje fijo; si no, se
+// aplica a todo el neto el porcentaje del mayor tramo alcanzado (Desde <=
+// neto). PorCategoria (categoría → porcentaje) reemplaza ese porcentaje para
+// lo vendido de productos de la categoría o sus subcategorías. UsuarioID nil
+// es el esquema por defecto de quienes no tienen uno.
+type EsquemaComision struct {
+	ID           uint             `gorm:"primaryKey" json:"id"`
+	CreatedAt    time.Time        `json:"creado_en"`
+	UpdatedAt    time.Time        `json:"actualizado_en"`
+	Nombre       string           `gorm:"not null" json:"nombre"`
+	UsuarioID    *uint            `gorm:"index" json:"usuario_id"`
+	Porcentaje   float64          `json:"porcentaje"`
+	Tramos       []TramoComision  `gorm:"serializer:json" json:"tramos"`
+	PorCategoria map[uint]float64 `gorm:"serializer:json" json:"por_categoria,omitempty"`
+}
+
+// TramoComision: desde Desde de neto en el período se cobra Porcentaje.
+type TramoComision struct {
+	Desde      float64 `json:"desde"`
+	Porcentaje float64 `json:"porcentaje"`
+}
+
+// PorcentajePara devuelve el porcentaje que corresponde a ese neto.
+func (e EsquemaComision) PorcentajePara(neto float64) float64 {
+	p := e.Porcentaje
+	for _, t := range e.Tramos {
+		if neto >= t.Desde {
+			p = t.Porcentaje
+		}
+	}
+	return p
+}
+
+// CierreComision congela las comisiones de un período [Desde, Hasta): sus
+// ComisionLiquidada ya no cambian aunque después se editen ventas o esquemas.
+type CierreComision struct {
+	ID         uint                `gorm:"primaryKey" json:"id"`
+	CreatedAt  time.Time           `json:"cerrado_en"`
+	Desde      time.Time           `gorm:"index;not null" json:"desde"`
+	Hasta      time.Time           `gorm:"index;not null" json:"hasta"`
+	CerradoPor uint                `json:"cerrado_por"`
+	Comisiones []ComisionLiquidada `gorm:"-" json:"comisiones,omitempty"`
+}
+
+// ComisionLiquidada es la comisión de un vendedor en un período. CierreID es
+// 0 mientras el período está abierto (cálculo provisorio). Porcentaje es el
+// general del esquema; Categorias detalla la parte de Base que se pagó con
+// un porcentaje por categoría.
+type ComisionLiquidada struct {
+	ID           uint                `gorm:"primaryKey" json:"-"`
+	CierreID     uint                `gorm:"index" json:"cierre_id,omitempty"`
+	UsuarioID    uint                `json:"usuario_id"`
+	Nombre       string              `json:"nombre"`
+	Ventas       int64               `json:"ventas"`
+	Neto         float64             `json:"neto"`
+	Devoluciones float64             `json:"devoluciones"`
+	Base         float64             `json:"base"`
+	Esquema      string              `json:"esquema"`
+	Porcentaje   float64             `json:"porcentaje"`
+	Comision     float64             `json:"comision"`
+	Categorias   []ComisionCategoria `gorm:"serializer:json" json:"categorias,omitempty"`
+}
+
+// ComisionCategoria es la parte de una comisión que corresponde a una
+// categoría con porcentaje propio.
+type ComisionCategoria struct {
+	CategoriaID uint    `json:"categoria_id"`
+	Base        float64 `json:"base"`
+	Porcentaje  float64 `json:"porcentaje"`
+	Comision    float64 `json:"comision"`
+}
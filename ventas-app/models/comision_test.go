@@ -0,0 +1,22 @@
+package models
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestEsquemaComision_PorcentajePara(t *testing.T) {
+	fijo := EsquemaComision{Porcentaje: 3}
+	assert.Equal(t, 3.0, fijo.PorcentajePara(0))
+	assert.Equal(t, 3.0, fijo.PorcentajePara(1e6))
+
+	escalonado := EsquemaComision{Porcentaje: 1, Tramos: []TramoComision{
+		{Desde: 1000, Porcentaje: 2},
+		{Desde: 5000, Porcentaje: 4},
+	}}
+	assert.Equal(t, 1.0, escalonado.PorcentajePara(999.99))
+	assert.Equal(t, 2.0, escalonado.PorcentajePara(1000))
+	assert.Equal(t, 2.0, escalonado.PorcentajePara(4999))
+	assert.Equal(t, 4.0, escalonado.PorcentajePara(5000))
+}
@@ -2,10 +2,12 @@ package models
 
 import "gorm.io/gorm"
 
+// Compra suma stock al producto o, si tiene variantes, a la VarianteID.
 type Compra struct {
 	gorm.Model
 	UsuarioID  uint    `json:"usuario_id"`
 	ProductoID uint    `json:"producto_id"`
 	Cantidad   int     `json:"cantidad"`
 	CostoUnit  float64 `json:"costo_unit"`
+	VarianteID *uint   `gorm:"index" json:"variante_id,omitempty"`
 }
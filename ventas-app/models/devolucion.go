@@ -0,0 +1,16 @@
+package models
+
+import "gorm.io/gorm"
+
+// Devolucion registra unidades devueltas de una venta. Neto e IVA son la
+// parte proporcional de la venta; VendedorID es el UsuarioID de la venta,
+// para descontarla de su comisión en el período de la devolución.
+type Devolucion struct {
+	gorm.Model
+	VentaID    uint    `gorm:"index;not null" json:"venta_id"`
+	VendedorID uint    `gorm:"index" json:"vendedor_id"`
+	Cantidad   int     `json:"cantidad"`
+	Neto       float64 `json:"neto"`
+	IVA        float64 `gorm:"column:iva" json:"iva"`
+	Motivo     string  `json:"motivo"`
+}
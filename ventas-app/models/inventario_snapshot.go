@@ -0,0 +1,19 @@
+package models
+
+import "time"
+
+// InventarioSnapshot es la foto del stock de un producto al cierre de una
+// fecha (el día, a las 00:00 UTC), con el costo y la categoría de ese
+// momento. Las valuaciones a fechas pasadas salen de acá, así no cambian si
+// después se edita o reclasifica el Producto.
+type InventarioSnapshot struct {
+	ID          uint      `gorm:"primaryKey" json:"id"`
+	CreatedAt   time.Time `json:"tomado_en"`
+	Fecha       time.Time `gorm:"uniqueIndex:idx_snapshot_fecha_producto;not null" json:"fecha"`
+	ProductoID  uint      `gorm:"uniqueIndex:idx_snapshot_fecha_producto;not null" json:"producto_id"`
+	Nombre      string    `json:"nombre"`
+	CategoriaID *uint     `json:"categoria_id,omitempty"`
+	Cantidad    int       `json:"cantidad"`
+	CostoUnit   float64   `json:"costo_unit"`
+	Valor       float64   `json:"valor"`
+}
@@ -0,0 +1,25 @@
+package models
+
+import "time"
+
+// Motivos de LoginEvento.
+const (
+	LoginOK                 = "ok"
+	LoginUsuarioInexistente = "usuario_inexistente"
+	LoginClaveIncorrecta    = "clave_incorrecta"
+	LoginBloqueado          = "bloqueado"
+	LoginDeshabilitado      = "deshabilitado"
+	LoginCodigoIncorrecto   = "codigo_incorrecto" // segundo factor (TOTP o recuperación)
+)
+
+// LoginEvento registra cada intento de login (exitoso o no) para auditoría.
+// UsuarioID es nil si el nombre no corresponde a ningún usuario.
+type LoginEvento struct {
+	ID        uint      `gorm:"primaryKey" json:"id"`
+	CreatedAt time.Time `gorm:"index" json:"fecha"`
+	UsuarioID *uint     `gorm:"index" json:"usuario_id,omitempty"`
+	Nombre    string    `gorm:"size:191;index" json:"nombre"`
+	IP        string    `gorm:"size:64;index" json:"ip"`
+	Exito     bool      `json:"exito"`
+	Motivo    string    `gorm:"size:32" json:"motivo"`
+}
@@ -1,11 +1,38 @@
 package models
 
-import "gorm.io/gorm"
+import (
+	"errors"
+	"regexp"
 
+	"gorm.io/gorm"
+)
+
+// ErrSKUInvalido: el SKU está vacío, pasa de 64 caracteres o tiene algo que
+// no sea letras, dígitos, '-', '_', '.' o '/'.
+var ErrSKUInvalido = errors.New("SKU inválido")
+
+var skuRe = regexp.MustCompile(`^[A-Za-z0-9._/-]{1,64}$`)
+
+// Producto es un artículo a la venta. SKU es opcional pero único: lo usa la
+// importación masiva para actualizar productos existentes. CodigosBarras se
+// guardan aparte (CodigoBarras) y se cargan al consultar. CategoriaID y
+// MarcaID son opcionales.
 type Producto struct {
 	gorm.Model
-	Nombre string  `json:"nombre"`
-	Costo  float64 `json:"costo"`
-	Precio float64 `json:"precio"`
-	Stock  int     `json:"stock"`
+	Nombre        string   `json:"nombre"`
+	SKU           *string  `gorm:"column:sku;size:64;uniqueIndex" json:"sku,omitempty"`
+	Costo         float64  `json:"costo"`
+	Precio        float64  `json:"precio"`
+	Stock         int      `json:"stock"`
+	CategoriaID   *uint    `gorm:"index" json:"categoria_id,omitempty"`
+	MarcaID       *uint    `gorm:"index" json:"marca_id,omitempty"`
+	CodigosBarras []string `gorm:"-" json:"codigos_barras,omitempty"`
+}
+
+// ValidarSKU controla el formato de un SKU.
+func ValidarSKU(sku string) error {
+	if !skuRe.MatchString(sku) {
+		return ErrSKUInvalido
+	}
+	return nil
 }
@@ -0,0 +1,25 @@
+package models
+
+import "time"
+
+// RefreshToken es un refresh token emitido. Solo se guarda el hash SHA-256:
+// el token en claro lo tiene únicamente el cliente. Cada uso lo rota por uno
+// nuevo de la misma Familia; si se reusa uno ya rotado se revoca la familia.
+type RefreshToken struct {
+	ID         uint `gorm:"primaryKey"`
+	CreatedAt  time.Time
+	UsuarioID  uint      `gorm:"index;not null"`
+	Familia    string    `gorm:"size:64;index;not null"`
+	Hash       string    `gorm:"size:64;uniqueIndex;not null"`
+	ExpiraEn   time.Time `gorm:"not null"`
+	RevocadoEn *time.Time
+}
+
+// TokenRevocado es un access token (por su jti) invalidado antes de vencer,
+// por ejemplo al hacer logout. Se puede borrar una vez pasado ExpiraEn.
+type TokenRevocado struct {
+	ID        uint `gorm:"primaryKey"`
+	CreatedAt time.Time
+	JTI       string    `gorm:"size:64;uniqueIndex;not null"`
+	ExpiraEn  time.Time `gorm:"index;not null"`
+}
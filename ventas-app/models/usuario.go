@@ -1,12 +1,52 @@
 package models
 
 import (
+	"time"
+
 	"gorm.io/gorm"
 )
 
 type Usuario struct {
 	gorm.Model
 	Nombre string `json:"nombre" gorm:"unique;not null"`
-	Clave  string `json:"clave" gorm:"not null"` // Hasheada con bcrypt
-	Rol    string `json:"rol" gorm:"not null"`   // Validado en el controlador
+	Clave  string `json:"-" gorm:"not null"`   // Hasheada con bcrypt, nunca se serializa
+	Rol    string `json:"rol" gorm:"not null"` // Validado en el controlador
+
+	// DebeCambiarClave se activa al resetear la clave: hasta cambiarla solo
+	// puede usar los endpoints que no exigen rol (PUT /me/clave, /logout)
+	DebeCambiarClave bool `json:"debe_cambiar_clave" gorm:"not null;default:false"`
+
+	// DeshabilitadoEn != nil impide iniciar sesión y usar tokens ya emitidos
+	DeshabilitadoEn *time.Time `json:"deshabilitado_en,omitempty"`
+
+	// Bloqueo temporal por intentos fallidos de login
+	IntentosFallidos int        `json:"-" gorm:"not null;default:0"`
+	BloqueadoHasta   *time.Time `json:"bloqueado_hasta,omitempty"`
+
+	// Revocar las sesiones sube VersionSesion: los access tokens llevan la
+	// vigente al emitirse y dejan de valer los de versiones anteriores.
+	// SesionesRevocadasEn registra cuándo fue la última revocación.
+	VersionSesion       uint       `json:"-" gorm:"not null;default:0"`
+	SesionesRevocadasEn *time.Time `json:"-"`
+
+	// Segundo factor TOTP. TOTPSecreto queda pendiente hasta que se confirma
+	// con un código (TOTPActivoEn != nil). TOTPUltimoPaso evita reusar un código.
+	TOTPSecreto    string     `json:"-"`
+	TOTPActivoEn   *time.Time `json:"totp_activo_en,omitempty"`
+	TOTPUltimoPaso int64      `json:"-" gorm:"not null;default:0"`
+}
+
+// Habilitado indica si el usuario puede operar.
+func (u Usuario) Habilitado() bool {
+	return u.DeshabilitadoEn == nil
+}
+
+// TOTPActivo indica si el usuario tiene el segundo factor confirmado.
+func (u Usuario) TOTPActivo() bool {
+	return u.TOTPActivoEn != nil
+}
+
+// Bloqueado indica si el usuario está bloqueado temporalmente en el instante ahora.
+func (u Usuario) Bloqueado(ahora time.Time) bool {
+	return u.BloqueadoHasta != nil && ahora.Before(*u.BloqueadoHasta)
 }
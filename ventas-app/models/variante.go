@@ -0,0 +1,33 @@
+package models
+
+import "gorm.io/gorm"
+
+// AtributoVariante define un eje de variación de un producto (ej. "Talle"
+// con S, M y L) y los valores que puede tomar.
+type AtributoVariante struct {
+	ID         uint     `gorm:"primaryKey" json:"id"`
+	ProductoID uint     `gorm:"not null;index" json:"producto_id"`
+	Nombre     string   `gorm:"not null" json:"nombre"`
+	Valores    []string `gorm:"serializer:json" json:"valores"`
+}
+
+// Variante es una combinación de atributos de un producto (ej. talle M,
+// color rojo) con su propio stock. Precio nil usa el del producto. El stock
+// del producto es la suma del de sus variantes.
+type Variante struct {
+	gorm.Model
+	ProductoID uint              `gorm:"not null;index" json:"producto_id"`
+	SKU        *string           `gorm:"column:sku;size:64;uniqueIndex" json:"sku,omitempty"`
+	Atributos  map[string]string `gorm:"serializer:json" json:"atributos"`
+	Precio     *float64          `json:"precio,omitempty"`
+	Stock      int               `json:"stock"`
+}
+
+// PrecioPara devuelve el precio de la variante, o el del producto si no lo
+// redefine.
+func (v Variante) PrecioPara(p Producto) float64 {
+	if v.Precio != nil {
+		return *v.Precio
+	}
+	return p.Precio
+}
@@ -2,11 +2,24 @@ package models
 
 import "gorm.io/gorm"
 
+// AlicuotaIVA es la tasa de IVA que se aplica a las ventas.
+const AlicuotaIVA = 0.21
+
+// Venta guarda el importe desglosado al momento de vender: Neto (con el
+// descuento aplicado), IVA y PrecioFinal = Neto + IVA. CostoUnit es el costo
+// del producto en ese momento, para calcular el margen aunque después cambie.
+// VarianteID es obligatoria si el producto tiene variantes. CodigoBarras
+// permite indicar el producto escaneado en lugar de ProductoID; no se guarda.
 type Venta struct {
 	gorm.Model
-	UsuarioID   uint    `json:"usuario_id"`
-	ProductoID  uint    `json:"producto_id"`
-	Cantidad    int     `json:"cantidad"`
-	Descuento   float64 `json:"descuento"`
-	PrecioFinal float64 `json:"precio_final"`
+	UsuarioID    uint    `json:"usuario_id"`
+	ProductoID   uint    `json:"producto_id"`
+	Cantidad     int     `json:"cantidad"`
+	Descuento    float64 `json:"descuento"`
+	Neto         float64 `json:"neto" gorm:"not null;default:0"`
+	IVA          float64 `json:"iva" gorm:"column:iva;not null;default:0"`
+	PrecioFinal  float64 `json:"precio_final"`
+	CostoUnit    float64 `json:"costo_unit" gorm:"not null;default:0"`
+	VarianteID   *uint   `gorm:"index" json:"variante_id,omitempty"`
+	CodigoBarras string  `json:"codigo_barras,omitempty" gorm:"-"`
 }
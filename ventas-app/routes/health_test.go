@@ -0,0 +1,84 @@
+package routes
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"ventas-app/database"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+)
+
+func TestSetup_LivezEndpoint(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	router := gin.Default()
+	Setup(router)
+
+	req, _ := http.NewRequest("GET", "/livez", nil)
+	resp := httptest.NewRecorder()
+	router.ServeHTTP(resp, req)
+
+	assert.Equal(t, http.StatusOK, resp.Code)
+	assert.JSONEq(t, `{"estado": "ok"}`, resp.Body.String())
+}
+
+func TestSetup_ReadyzConBaseDisponible(t *testing.T) {
+	router := newSQLiteRouter(t)
+
+	req, _ := http.NewRequest("GET", "/readyz", nil)
+	resp := httptest.NewRecorder()
+	router.ServeHTTP(resp, req)
+
+	require.Equal(t, http.StatusOK, resp.Code, resp.Body.String())
+
+	var body struct {
+		Estado           string `json:"estado"`
+		VersionMigracion int    `json:"version_migracion"`
+		Dependencias     map[string]struct {
+			Estado           string   `json:"estado"`
+			LatenciaMs       *float64 `json:"latencia_ms"`
+			VersionMigracion int      `json:"version_migracion"`
+		} `json:"dependencias"`
+	}
+	require.NoError(t, json.Unmarshal(resp.Body.Bytes(), &body))
+	assert.Equal(t, "ok", body.Estado)
+	assert.Equal(t, database.SchemaVersion, body.VersionMigracion)
+	require.Contains(t, body.Dependencias, "db:test")
+	assert.Equal(t, "ok", body.Dependencias["db:test"].Estado)
+	assert.NotNil(t, body.Dependencias["db:test"].LatenciaMs)
+	assert.Equal(t, database.SchemaVersion, body.Dependencias["db:test"].VersionMigracion)
+}
+
+func TestSetup_ReadyzConBaseCaida(t *testing.T) {
+	router := newSQLiteRouter(t)
+	sqlDB, err := database.DBs["test"].DB()
+	require.NoError(t, err)
+	require.NoError(t, sqlDB.Close())
+
+	req, _ := http.NewRequest("GET", "/readyz", nil)
+	resp := httptest.NewRecorder()
+	router.ServeHTTP(resp, req)
+
+	assert.Equal(t, http.StatusServiceUnavailable, resp.Code)
+	assert.Contains(t, resp.Body.String(), `"estado":"error"`)
+}
+
+func TestSetup_ReadyzSinBases(t *testing.T) {
+	anteriores := database.DBs
+	database.DBs = map[string]*gorm.DB{}
+	defer func() { database.DBs = anteriores }()
+
+	gin.SetMode(gin.TestMode)
+	router := gin.Default()
+	Setup(router)
+
+	req, _ := http.NewRequest("GET", "/readyz", nil)
+	resp := httptest.NewRecorder()
+	router.ServeHTTP(resp, req)
+
+	assert.Equal(t, http.StatusServiceUnavailable, resp.Code)
+}
@@ -0,0 +1,235 @@
+package routes
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"ventas-app/config"
+	"ventas-app/database"
+	"ventas-app/models"
+	"ventas-app/services"
+	"ventas-app/utils"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newSQLiteRouter levanta las rutas reales contra una base SQLite en memoria,
+// sin servicios externos.
+func newSQLiteRouter(t *testing.T) *gin.Engine {
+	t.Helper()
+	utils.SetSecret("secreto-de-test")
+
+	database.Connect("test", config.DatabaseConfig{Driver: database.DriverSQLite})
+	t.Cleanup(func() {
+		delete(database.DBs, "test")
+		database.CurrentEnv = ""
+	})
+
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	Setup(router)
+	return router
+}
+
+func doJSON(router *gin.Engine, method, path, body string) *httptest.ResponseRecorder {
+	return doJSONToken(router, "", method, path, body)
+}
+
+// doJSONToken es doJSON con un access token en Authorization.
+func doJSONToken(router *gin.Engine, token, method, path, body string) *httptest.ResponseRecorder {
+	req, _ := http.NewRequest(method, path, bytes.NewBufferString(body))
+	req.Header.Set("Content-Type", "application/json")
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	resp := httptest.NewRecorder()
+	router.ServeHTTP(resp, req)
+	return resp
+}
+
+// loginAdmin crea un admin directo en la base (como `server crear-admin`) y
+// devuelve su access token.
+func loginAdmin(t *testing.T, router *gin.Engine) string {
+	t.Helper()
+	_, err := services.CrearUsuario(&database.GormDB{DB: database.DBs["test"]}, "root", "Admin-Clave-2024", "admin")
+	require.NoError(t, err)
+	return login(t, router, "root", "Admin-Clave-2024")
+}
+
+func login(t *testing.T, router *gin.Engine, nombre, clave string) string {
+	t.Helper()
+	resp := doJSON(router, "POST", "/login", `{"nombre": "`+nombre+`", "clave": "`+clave+`"}`)
+	require.Equal(t, http.StatusOK, resp.Code, resp.Body.String())
+	var body struct {
+		Token string `json:"token"`
+	}
+	require.NoError(t, json.Unmarshal(resp.Body.Bytes(), &body))
+	return body.Token
+}
+
+func TestIntegracionSQLite_FlujoCompleto(t *testing.T) {
+	router := newSQLiteRouter(t)
+	admin := loginAdmin(t, router)
+
+	// Sin token no se pueden crear usuarios (ni admins)
+	resp := doJSON(router, "POST", "/usuarios", `{"nombre": "intruso", "clave": "Secreta-2024", "rol": "admin"}`)
+	assert.Equal(t, http.StatusUnauthorized, resp.Code)
+
+	resp = doJSONToken(router, admin, "POST", "/usuarios", `{"nombre": "ana", "clave": "Secreta-2024", "rol": "vendedor"}`)
+	require.Equal(t, http.StatusCreated, resp.Code, resp.Body.String())
+
+	resp = doJSONToken(router, admin, "POST", "/usuarios", `{"nombre": "ana", "clave": "Otra-Clave-99", "rol": "vendedor"}`)
+	assert.Equal(t, http.StatusConflict, resp.Code)
+
+	ana := login(t, router, "ana", "Secreta-2024")
+
+	resp = doJSON(router, "POST", "/login", `{"nombre": "ana", "clave": "incorrecta"}`)
+	assert.Equal(t, http.StatusUnauthorized, resp.Code)
+
+	resp = doJSON(router, "POST", "/productos", `{"nombre": "Mate", "costo": 50, "precio": 100, "stock": 2}`)
+	assert.Equal(t, http.StatusUnauthorized, resp.Code)
+
+	resp = doJSONToken(router, admin, "POST", "/productos", `{"nombre": "Mate", "costo": 50, "precio": 100, "stock": 2}`)
+	require.Equal(t, http.StatusCreated, resp.Code, resp.Body.String())
+	var producto models.Producto
+	require.NoError(t, json.Unmarshal(resp.Body.Bytes(), &producto))
+
+	// Los códigos de barras solo los administra un admin
+	resp = doJSON(router, "POST", "/productos/1/codigos-barras", `{"codigo": "96385074"}`)
+	assert.Equal(t, http.StatusUnauthorized, resp.Code)
+	resp = doJSONToken(router, ana, "POST", "/productos/1/codigos-barras", `{"codigo": "96385074"}`)
+	assert.Equal(t, http.StatusForbidden, resp.Code)
+	resp = doJSONToken(router, ana, "DELETE", "/productos/1/codigos-barras/96385074", "")
+	assert.Equal(t, http.StatusForbidden, resp.Code)
+	resp = doJSONToken(router, admin, "POST", "/productos/1/codigos-barras", `{"codigo": "96385074"}`)
+	assert.Equal(t, http.StatusCreated, resp.Code, resp.Body.String())
+
+	resp = doJSONToken(router, admin, "POST", "/compras", `{"producto_id": 1, "cantidad": 8, "costo_unit": 50}`)
+	require.Equal(t, http.StatusCreated, resp.Code, resp.Body.String())
+
+	resp = doJSONToken(router, ana, "POST", "/ventas", `{"producto_id": 1, "cantidad": 4, "descuento": 0}`)
+	require.Equal(t, http.StatusCreated, resp.Code, resp.Body.String())
+	var venta models.Venta
+	require.NoError(t, json.Unmarshal(resp.Body.Bytes(), &venta))
+	assert.InDelta(t, 484.0, venta.PrecioFinal, 0.001)
+
+	resp = doJSONToken(router, ana, "POST", "/ventas", `{"producto_id": 1, "cantidad": 100}`)
+	assert.Equal(t, http.StatusBadRequest, resp.Code)
+
+	resp = doJSON(router, "POST", "/ventas/1/devoluciones", `{"cantidad": 1}`)
+	assert.Equal(t, http.StatusUnauthorized, resp.Code)
+	resp = doJSONToken(router, ana, "POST", "/ventas/1/devoluciones", `{"cantidad": 1}`)
+	require.Equal(t, http.StatusCreated, resp.Code, resp.Body.String())
+
+	resp = doJSONToken(router, ana, "GET", "/productos", "")
+	require.Equal(t, http.StatusOK, resp.Code)
+	var productos []models.Producto
+	require.NoError(t, json.Unmarshal(resp.Body.Bytes(), &productos))
+	require.Len(t, productos, 1)
+	assert.Equal(t, producto.ID, productos[0].ID)
+	assert.Equal(t, 7, productos[0].Stock)
+
+	for _, ruta := range []string{"/categorias", "/marcas"} {
+		resp = doJSON(router, "GET", ruta, "")
+		assert.Equal(t, http.StatusUnauthorized, resp.Code, ruta)
+		resp = doJSONToken(router, ana, "GET", ruta, "")
+		assert.Equal(t, http.StatusOK, resp.Code, ruta)
+	}
+}
+
+func TestIntegracionSQLite_Sesiones(t *testing.T) {
+	router := newSQLiteRouter(t)
+	admin := loginAdmin(t, router)
+
+	resp := doJSONToken(router, admin, "POST", "/usuarios", `{"nombre": "ana", "clave": "Secreta-2024", "rol": "vendedor"}`)
+	require.Equal(t, http.StatusCreated, resp.Code, resp.Body.String())
+
+	resp = doJSON(router, "POST", "/login", `{"nombre": "ana", "clave": "Secreta-2024"}`)
+	require.Equal(t, http.StatusOK, resp.Code, resp.Body.String())
+	var login struct {
+		Token        string `json:"token"`
+		RefreshToken string `json:"refresh_token"`
+	}
+	require.NoError(t, json.Unmarshal(resp.Body.Bytes(), &login))
+	require.NotEmpty(t, login.RefreshToken)
+
+	resp = doJSON(router, "POST", "/refresh", `{"refresh_token": "`+login.RefreshToken+`"}`)
+	require.Equal(t, http.StatusOK, resp.Code, resp.Body.String())
+	var renovada struct {
+		Token        string `json:"token"`
+		RefreshToken string `json:"refresh_token"`
+	}
+	require.NoError(t, json.Unmarshal(resp.Body.Bytes(), &renovada))
+
+	resp = doJSON(router, "POST", "/refresh", `{"refresh_token": "`+login.RefreshToken+`"}`)
+	assert.Equal(t, http.StatusUnauthorized, resp.Code, "un refresh token no se puede usar dos veces")
+
+	req, _ := http.NewRequest("POST", "/logout", bytes.NewBufferString(`{"refresh_token": "`+renovada.RefreshToken+`"}`))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", "Bearer "+renovada.Token)
+	resp = httptest.NewRecorder()
+	router.ServeHTTP(resp, req)
+	require.Equal(t, http.StatusOK, resp.Code, resp.Body.String())
+
+	// El mismo access token ya no sirve
+	resp = httptest.NewRecorder()
+	router.ServeHTTP(resp, req)
+	assert.Equal(t, http.StatusUnauthorized, resp.Code)
+
+	resp = doJSON(router, "POST", "/usuarios/1/revocar-sesiones", "")
+	assert.Equal(t, http.StatusUnauthorized, resp.Code)
+
+	// Con HMAC no hay claves públicas que publicar
+	resp = doJSON(router, "GET", "/.well-known/jwks.json", "")
+	require.Equal(t, http.StatusOK, resp.Code)
+	assert.JSONEq(t, `{"keys": []}`, resp.Body.String())
+}
+
+func TestIntegracionSQLite_APIKeyScopes(t *testing.T) {
+	router := newSQLiteRouter(t)
+	admin := loginAdmin(t, router)
+
+	resp := doJSONToken(router, admin, "POST", "/productos", `{"nombre": "Mate", "costo": 50, "precio": 100, "stock": 2}`)
+	require.Equal(t, http.StatusCreated, resp.Code, resp.Body.String())
+
+	resp = doJSONToken(router, admin, "POST", "/api-keys", `{"nombre": "tienda online", "scopes": ["productos:read"]}`)
+	require.Equal(t, http.StatusCreated, resp.Code, resp.Body.String())
+	var creada struct {
+		Clave string `json:"clave"`
+	}
+	require.NoError(t, json.Unmarshal(resp.Body.Bytes(), &creada))
+	require.NotEmpty(t, creada.Clave)
+
+	conKey := func(method, path, body string) *httptest.ResponseRecorder {
+		req, _ := http.NewRequest(method, path, bytes.NewBufferString(body))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("X-API-Key", creada.Clave)
+		resp := httptest.NewRecorder()
+		router.ServeHTTP(resp, req)
+		return resp
+	}
+
+	resp = conKey("GET", "/productos", "")
+	assert.Equal(t, http.StatusOK, resp.Code, resp.Body.String())
+
+	// Sin ventas:write ni compras:write / productos:write
+	resp = conKey("POST", "/ventas", `{"producto_id": 1, "cantidad": 1}`)
+	assert.Equal(t, http.StatusForbidden, resp.Code)
+	assert.Contains(t, resp.Body.String(), "ventas:write")
+	resp = conKey("POST", "/compras", `{"producto_id": 1, "cantidad": 1, "costo_unit": 50}`)
+	assert.Equal(t, http.StatusForbidden, resp.Code)
+	resp = conKey("POST", "/productos", `{"nombre": "Yerba", "costo": 1, "precio": 2}`)
+	assert.Equal(t, http.StatusForbidden, resp.Code)
+
+	var producto models.Producto
+	require.NoError(t, database.DBs["test"].First(&producto, 1).Error)
+	assert.Equal(t, 2, producto.Stock, "no se registró ninguna venta ni compra")
+
+	var key models.APIKey
+	require.NoError(t, database.DBs["test"].First(&key).Error)
+	assert.NotNil(t, key.UltimoUsoEn)
+}
@@ -2,6 +2,7 @@ package routes
 
 import (
 	"ventas-app/controllers"
+	"ventas-app/middleware"
 
 	"github.com/gin-gonic/gin"
 )
@@ -15,18 +16,96 @@ func Setup(r *gin.Engine) {
 	r.GET("/healthz", healthHandler)
 	r.HEAD("/healthz", healthHandler)
 
+	// Liveness (proceso vivo) y readiness (bases de datos accesibles y migradas)
+	r.GET("/livez", controllers.Livez)
+	r.HEAD("/livez", controllers.Livez)
+	r.GET("/readyz", controllers.Readyz)
+	r.HEAD("/readyz", controllers.Readyz)
+
+	// Claves públicas (RS256/EdDSA) para que otros servicios validen nuestros tokens
+	r.GET("/.well-known/jwks.json", controllers.JWKS)
+
 	r.POST("/login", controllers.Login)
-	//r.POST("/usuarios", middleware.AuthRequired("precio", "comprador"), controllers.CrearUsuario)
-	r.POST("/usuarios", controllers.CrearUsuario)
+	r.POST("/login/mfa", controllers.LoginMFA)
+	r.POST("/login/mfa/enrolar", controllers.EnrolarLoginMFA)
+	r.POST("/refresh", controllers.Refresh)
+	r.POST("/logout", middleware.AuthRequired(), controllers.Logout)
+	r.POST("/usuarios/:id/revocar-sesiones", middleware.AuthRequired("admin"), controllers.RevocarSesiones)
+	r.POST("/usuarios/:id/desbloquear", middleware.AuthRequired("admin"), controllers.DesbloquearUsuario)
+	r.GET("/usuarios", middleware.AuthRequired("admin"), controllers.ListarUsuarios)
+	r.PUT("/usuarios/:id/rol", middleware.AuthRequired("admin"), controllers.CambiarRol)
+	r.POST("/usuarios/:id/deshabilitar", middleware.AuthRequired("admin"), controllers.DeshabilitarUsuario)
+	r.POST("/usuarios/:id/habilitar", middleware.AuthRequired("admin"), controllers.HabilitarUsuario)
+	r.POST("/usuarios/:id/resetear-clave", middleware.AuthRequired("admin"), controllers.ResetearClave)
+	r.POST("/usuarios/:id/desactivar-totp", middleware.AuthRequired("admin"), controllers.ResetearTOTP)
+	r.PUT("/me/clave", middleware.AuthRequired(), controllers.CambiarMiClave)
+	r.POST("/me/totp", middleware.AuthRequired(), controllers.IniciarTOTP)
+	r.POST("/me/totp/confirmar", middleware.AuthRequired(), controllers.ConfirmarTOTP)
+	r.POST("/me/totp/desactivar", middleware.AuthRequired(), controllers.DesactivarTOTP)
+	r.POST("/me/totp/recuperacion", middleware.AuthRequired(), controllers.RegenerarCodigosRecuperacion)
+	// Solo un admin da de alta usuarios; el primero se crea con `server crear-admin`
+	r.POST("/usuarios", middleware.AuthRequired("admin"), controllers.CrearUsuario)
+
+	// API keys para integraciones (X-API-Key); AuthRequired las acepta
+	r.POST("/api-keys", middleware.AuthRequired("admin"), controllers.CrearAPIKey)
+	r.GET("/api-keys", middleware.AuthRequired("admin"), controllers.ListarAPIKeys)
+	r.DELETE("/api-keys/:id", middleware.AuthRequired("admin"), controllers.RevocarAPIKey)
+
+	// Auditoría de altas, modificaciones y bajas
+	r.GET("/auditoria", middleware.AuthRequired("admin"), controllers.ListarAuditoria)
+
+	// Reportes (agregados en la base)
+	r.GET("/reportes/ventas", middleware.AuthRequired("admin"), controllers.ReporteVentas)
+	r.GET("/reportes/margenes", middleware.AuthRequired("admin"), controllers.ReporteMargen)
+	r.GET("/reportes/inventario", middleware.AuthRequired("admin"), controllers.ValuacionInventario)
+	r.POST("/inventario/snapshots", middleware.AuthRequired("admin"), controllers.TomarSnapshotInventario)
+	r.GET("/reportes/comisiones", middleware.AuthRequired("admin"), controllers.ReporteComisiones)
+
+	// Comisiones de vendedores: esquemas y cierres de período
+	r.GET("/comisiones/esquemas", middleware.AuthRequired("admin"), controllers.ListarEsquemasComision)
+	r.POST("/comisiones/esquemas", middleware.AuthRequired("admin"), controllers.CrearEsquemaComision)
+	r.PUT("/comisiones/esquemas/:id", middleware.AuthRequired("admin"), controllers.ActualizarEsquemaComision)
+	r.DELETE("/comisiones/esquemas/:id", middleware.AuthRequired("admin"), controllers.BorrarEsquemaComision)
+	r.GET("/comisiones/cierres", middleware.AuthRequired("admin"), controllers.ListarCierresComision)
+	r.POST("/comisiones/cierres", middleware.AuthRequired("admin"), controllers.CerrarComisiones)
+	r.GET("/comisiones/cierres/:id", middleware.AuthRequired("admin"), controllers.ObtenerCierreComision)
+
+	// Exportaciones para contabilidad (csv, xlsx o json-lines), con su propio
+	// timeout en lugar del general
+	exportacion := middleware.TimeoutPropio(controllers.ExportacionTimeout)
+	r.GET("/productos/exportar", middleware.AuthRequired("admin"), exportacion, controllers.ExportarProductos)
+	r.GET("/ventas/exportar", middleware.AuthRequired("admin"), exportacion, controllers.ExportarVentas)
+	r.GET("/compras/exportar", middleware.AuthRequired("admin"), exportacion, controllers.ExportarCompras)
+
+	// Productos, compras y ventas: con token o con una API key que tenga el
+	// scope de la ruta (productos:read, ventas:write, ...)
+	r.GET("/productos", middleware.AuthRequired(), controllers.ListarProductos)
+	r.HEAD("/productos", middleware.AuthRequired(), controllers.ListarProductos)
+	r.POST("/productos", middleware.AuthRequired("admin", "vendedor", "comprador"), controllers.CrearProducto)
+	r.POST("/productos/importar", middleware.AuthRequired("admin"), controllers.ImportarProductos)
+	r.GET("/productos/barcode/:code", middleware.AuthRequired(), controllers.BuscarProductoPorCodigo)
+	r.POST("/productos/:id/codigos-barras", middleware.AuthRequired("admin"), controllers.AgregarCodigoBarras)
+	r.DELETE("/productos/:id/codigos-barras/:codigo", middleware.AuthRequired("admin"), controllers.QuitarCodigoBarras)
+	r.PUT("/productos/:id/clasificacion", middleware.AuthRequired("admin"), controllers.ClasificarProducto)
 
-	r.GET("/productos", controllers.ListarProductos)
-	r.HEAD("/productos", controllers.ListarProductos)
-	//r.POST("/productos", middleware.AuthRequired("vendedor", "comprador"), controllers.CrearProducto)
-	r.POST("/productos", controllers.CrearProducto)
+	// Variantes (talle, color, ...) con stock propio
+	r.GET("/productos/:id/variantes", middleware.AuthRequired(), controllers.ListarVariantes)
+	r.PUT("/productos/:id/atributos", middleware.AuthRequired("admin"), controllers.DefinirAtributos)
+	r.POST("/productos/:id/variantes", middleware.AuthRequired("admin"), controllers.CrearVariante)
+	r.PUT("/productos/:id/variantes/:variante", middleware.AuthRequired("admin"), controllers.ActualizarVariante)
+	r.DELETE("/productos/:id/variantes/:variante", middleware.AuthRequired("admin"), controllers.BorrarVariante)
 
-	//r.POST("/compras", middleware.AuthRequired("comprador", "vendedor"), controllers.RegistrarCompra)
-	//r.POST("/ventas", middleware.AuthRequired("vendedor"), controllers.RegistrarVenta)
+	// Categorías (árbol) y marcas; leerlas también exige token, como los productos
+	r.GET("/categorias", middleware.AuthRequired(), controllers.ListarCategorias)
+	r.POST("/categorias", middleware.AuthRequired("admin"), controllers.CrearCategoria)
+	r.PUT("/categorias/:id", middleware.AuthRequired("admin"), controllers.ActualizarCategoria)
+	r.DELETE("/categorias/:id", middleware.AuthRequired("admin"), controllers.BorrarCategoria)
+	r.GET("/marcas", middleware.AuthRequired(), controllers.ListarMarcas)
+	r.POST("/marcas", middleware.AuthRequired("admin"), controllers.CrearMarca)
+	r.PUT("/marcas/:id", middleware.AuthRequired("admin"), controllers.ActualizarMarca)
+	r.DELETE("/marcas/:id", middleware.AuthRequired("admin"), controllers.BorrarMarca)
 
-	r.POST("/compras", controllers.RegistrarCompra)
-	r.POST("/ventas", controllers.RegistrarVenta)
+	r.POST("/compras", middleware.AuthRequired("admin", "comprador", "vendedor"), controllers.RegistrarCompra)
+	r.POST("/ventas", middleware.AuthRequired("admin", "vendedor"), controllers.RegistrarVenta)
+	r.POST("/ventas/:id/devoluciones", middleware.AuthRequired("admin", "vendedor"), controllers.RegistrarDevolucion)
 }
@@ -0,0 +1,105 @@
+// Package server arma el http.Server de la API y maneja el apagado ordenado.
+package server
+
+import (
+	"context"
+	"errors"
+	"log"
+	"net"
+	"net/http"
+	"time"
+)
+
+// Config agrupa los límites del http.Server.
+type Config struct {
+	Addr              string
+	ReadTimeout       time.Duration
+	ReadHeaderTimeout time.Duration
+	WriteTimeout      time.Duration
+	IdleTimeout       time.Duration
+	MaxHeaderBytes    int
+	// ShutdownTimeout es cuánto se espera a los requests en curso al apagar.
+	ShutdownTimeout time.Duration
+}
+
+// New crea el http.Server con los timeouts y el límite de headers de cfg.
+func New(handler http.Handler, cfg Config) *http.Server {
+	return &http.Server{
+		Addr:              cfg.Addr,
+		Handler:           handler,
+		ReadTimeout:       cfg.ReadTimeout,
+		ReadHeaderTimeout: cfg.ReadHeaderTimeout,
+		WriteTimeout:      cfg.WriteTimeout,
+		IdleTimeout:       cfg.IdleTimeout,
+		MaxHeaderBytes:    cfg.MaxHeaderBytes,
+	}
+}
+
+// Closer libera un recurso al apagar (jobs en segundo plano, pools de la BD...).
+type Closer func(ctx context.Context) error
+
+// CloseTimeout es cuánto tienen los closers para liberar los recursos. Es un
+// plazo aparte: el de los requests en curso puede haberse agotado.
+var CloseTimeout = 5 * time.Second
+
+// Run escucha en srv.Addr y bloquea hasta que ctx se cancele (SIGTERM/SIGINT).
+// Ver Serve.
+func Run(ctx context.Context, srv *http.Server, shutdownTimeout time.Duration, closers ...Closer) error {
+	ln, err := net.Listen("tcp", srv.Addr)
+	if err != nil {
+		return err
+	}
+	return Serve(ctx, srv, ln, shutdownTimeout, closers...)
+}
+
+// Serve atiende en ln hasta que ctx se cancele. Entonces deja de aceptar
+// conexiones, espera hasta shutdownTimeout a que terminen los requests en
+// curso (p. ej. una venta a medio registrar) y después ejecuta los closers en
+// orden, con CloseTimeout. Devuelve el primer error encontrado.
+func Serve(ctx context.Context, srv *http.Server, ln net.Listener, shutdownTimeout time.Duration, closers ...Closer) error {
+	errServe := make(chan error, 1)
+	go func() {
+		errServe <- srv.Serve(ln)
+	}()
+
+	select {
+	case err := <-errServe:
+		// El servidor se cayó solo: igual liberamos los recursos
+		cerrar(closers)
+		return err
+	case <-ctx.Done():
+	}
+
+	log.Printf("Apagando servidor: esperando requests en curso (máx. %s)", shutdownTimeout)
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+	defer cancel()
+
+	err := srv.Shutdown(shutdownCtx)
+	if errors.Is(err, context.DeadlineExceeded) {
+		log.Printf("Warning: quedaron requests sin terminar al vencer SHUTDOWN_TIMEOUT")
+		srv.Close()
+	}
+	if errCerrar := cerrar(closers); err == nil {
+		err = errCerrar
+	}
+	if errS := <-errServe; err == nil && !errors.Is(errS, http.ErrServerClosed) {
+		err = errS
+	}
+	return err
+}
+
+func cerrar(closers []Closer) error {
+	ctx, cancel := context.WithTimeout(context.Background(), CloseTimeout)
+	defer cancel()
+
+	var primero error
+	for _, c := range closers {
+		if err := c(ctx); err != nil {
+			log.Printf("Warning: error al liberar recursos: %v", err)
+			if primero == nil {
+				primero = err
+			}
+		}
+	}
+	return primero
+}
@@ -0,0 +1,129 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNew_AplicaConfig(t *testing.T) {
+	srv := New(http.NotFoundHandler(), Config{
+		Addr:              ":9999",
+		ReadTimeout:       time.Second,
+		ReadHeaderTimeout: 2 * time.Second,
+		WriteTimeout:      3 * time.Second,
+		IdleTimeout:       4 * time.Second,
+		MaxHeaderBytes:    2048,
+	})
+
+	assert.Equal(t, ":9999", srv.Addr)
+	assert.Equal(t, time.Second, srv.ReadTimeout)
+	assert.Equal(t, 2*time.Second, srv.ReadHeaderTimeout)
+	assert.Equal(t, 3*time.Second, srv.WriteTimeout)
+	assert.Equal(t, 4*time.Second, srv.IdleTimeout)
+	assert.Equal(t, 2048, srv.MaxHeaderBytes)
+}
+
+func TestServe_TerminaRequestsEnCursoAlApagar(t *testing.T) {
+	empezo := make(chan struct{})
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		close(empezo)
+		time.Sleep(200 * time.Millisecond)
+		w.Write([]byte("venta registrada"))
+	})
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+
+	var orden []string
+	closer := func(nombre string) Closer {
+		return func(ctx context.Context) error {
+			orden = append(orden, nombre)
+			return nil
+		}
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	terminado := make(chan error, 1)
+	go func() {
+		terminado <- Serve(ctx, New(handler, Config{}), ln, 5*time.Second, closer("jobs"), closer("db"))
+	}()
+
+	respuesta := make(chan string, 1)
+	go func() {
+		resp, err := http.Get("http://" + ln.Addr().String())
+		if err != nil {
+			respuesta <- err.Error()
+			return
+		}
+		defer resp.Body.Close()
+		body, _ := io.ReadAll(resp.Body)
+		respuesta <- string(body)
+	}()
+
+	<-empezo
+	cancel()
+
+	assert.Equal(t, "venta registrada", <-respuesta)
+	assert.NoError(t, <-terminado)
+	assert.Equal(t, []string{"jobs", "db"}, orden)
+
+	_, err = http.Get("http://" + ln.Addr().String())
+	assert.Error(t, err, "después del apagado no se aceptan conexiones")
+}
+
+func TestServe_DevuelveErrorDeCloser(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+
+	errCierre := errors.New("no se pudo cerrar")
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	err = Serve(ctx, New(http.NotFoundHandler(), Config{}), ln, time.Second,
+		func(ctx context.Context) error { return errCierre },
+	)
+
+	assert.True(t, errors.Is(err, errCierre))
+}
+
+func TestServe_ClosersConPlazoPropio(t *testing.T) {
+	empezo := make(chan struct{})
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		close(empezo)
+		time.Sleep(300 * time.Millisecond)
+	})
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+
+	var errCtx error
+	var plazo time.Duration
+	closer := func(ctx context.Context) error {
+		errCtx = ctx.Err()
+		deadline, _ := ctx.Deadline()
+		plazo = time.Until(deadline)
+		return nil
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	terminado := make(chan error, 1)
+	go func() {
+		terminado <- Serve(ctx, New(handler, Config{}), ln, 50*time.Millisecond, closer)
+	}()
+
+	go http.Get("http://" + ln.Addr().String())
+	<-empezo
+	cancel()
+
+	<-terminado
+	assert.NoError(t, errCtx, "el request lento agotó el plazo del Shutdown, no el de los closers")
+	assert.Greater(t, plazo, time.Second)
+}
@@ -0,0 +1,136 @@
+package services
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+	"ventas-app/database"
+	"ventas-app/models"
+
+	"gorm.io/gorm"
+)
+
+var (
+	// ErrAPIKeyInvalida: la clave no existe, fue revocada, venció o su
+	// usuario ya no está habilitado.
+	ErrAPIKeyInvalida = errors.New("API key inválida")
+
+	// ErrScopeInvalido: se pidió un scope que no existe.
+	ErrScopeInvalido = errors.New("scope inválido")
+)
+
+// PrefijoAPIKey distingue las API keys de otros tokens a simple vista.
+const PrefijoAPIKey = "vk_"
+
+// RecursosAPIKey son los recursos a los que puede acceder una API key. Cada
+// uno admite los scopes "<recurso>:read" (GET/HEAD) y "<recurso>:write" (el
+// resto de los métodos). Nada fuera de esta lista es accesible con una key.
+var RecursosAPIKey = []string{"productos", "ventas", "compras"}
+
+// usoMinimo evita escribir UltimoUsoEn en cada request de un lector que
+// consulta varias veces por segundo.
+const usoMinimo = time.Minute
+
+// NormalizarScopes valida los scopes y los devuelve ordenados y sin repetir,
+// separados por espacios.
+func NormalizarScopes(scopes []string) (string, error) {
+	validos := map[string]bool{}
+	for _, r := range RecursosAPIKey {
+		validos[r+":read"] = true
+		validos[r+":write"] = true
+	}
+
+	vistos := map[string]bool{}
+	var lista []string
+	for _, s := range scopes {
+		s = strings.ToLower(strings.TrimSpace(s))
+		if !validos[s] {
+			return "", fmt.Errorf("%w: %q", ErrScopeInvalido, s)
+		}
+		if !vistos[s] {
+			vistos[s] = true
+			lista = append(lista, s)
+		}
+	}
+	if len(lista) == 0 {
+		return "", fmt.Errorf("%w: se necesita al menos uno", ErrScopeInvalido)
+	}
+	sort.Strings(lista)
+	return strings.Join(lista, " "), nil
+}
+
+// CrearAPIKey genera una API key para usuario. La clave en claro se devuelve
+// una única vez; solo se guarda su hash.
+func CrearAPIKey(db database.DBHandler, usuario models.Usuario, nombre string, scopes []string, expira *time.Time) (models.APIKey, string, error) {
+	normalizados, err := NormalizarScopes(scopes)
+	if err != nil {
+		return models.APIKey{}, "", err
+	}
+
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return models.APIKey{}, "", err
+	}
+	clave := PrefijoAPIKey + base64.RawURLEncoding.EncodeToString(b)
+
+	key := models.APIKey{
+		Nombre:    nombre,
+		Prefijo:   clave[:len(PrefijoAPIKey)+8],
+		Hash:      hashToken(clave),
+		Scopes:    normalizados,
+		UsuarioID: usuario.ID,
+		ExpiraEn:  expira,
+	}
+	if err := db.Create(&key); err != nil {
+		return models.APIKey{}, "", err
+	}
+	return key, clave, nil
+}
+
+// ValidarAPIKey busca la key vigente y su usuario habilitado, y registra el uso.
+func ValidarAPIKey(db database.DBHandler, clave string) (models.APIKey, models.Usuario, error) {
+	var key models.APIKey
+	if err := db.Where("hash = ?", hashToken(clave)).First(&key); err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return models.APIKey{}, models.Usuario{}, ErrAPIKeyInvalida
+		}
+		return models.APIKey{}, models.Usuario{}, err
+	}
+	ahora := time.Now()
+	if !key.Vigente(ahora) {
+		return models.APIKey{}, models.Usuario{}, ErrAPIKeyInvalida
+	}
+
+	var usuario models.Usuario
+	if err := db.First(&usuario, key.UsuarioID); err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return models.APIKey{}, models.Usuario{}, ErrAPIKeyInvalida
+		}
+		return models.APIKey{}, models.Usuario{}, err
+	}
+	if !usuario.Habilitado() {
+		return models.APIKey{}, models.Usuario{}, ErrAPIKeyInvalida
+	}
+
+	if key.UltimoUsoEn == nil || ahora.Sub(*key.UltimoUsoEn) >= usoMinimo {
+		key.UltimoUsoEn = &ahora
+		if err := db.Save(&key); err != nil {
+			return models.APIKey{}, models.Usuario{}, err
+		}
+	}
+	return key, usuario, nil
+}
+
+// RevocarAPIKey deja la key inutilizable de inmediato.
+func RevocarAPIKey(db database.DBHandler, key *models.APIKey) error {
+	if key.RevocadaEn != nil {
+		return nil
+	}
+	ahora := time.Now()
+	key.RevocadaEn = &ahora
+	return db.Save(key)
+}
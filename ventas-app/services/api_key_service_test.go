@@ -0,0 +1,69 @@
+package services
+
+import (
+	"strings"
+	"testing"
+	"time"
+	"ventas-app/mocks"
+	"ventas-app/models"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNormalizarScopes(t *testing.T) {
+	scopes, err := NormalizarScopes([]string{"Ventas:write", "productos:read", "ventas:write"})
+	require.NoError(t, err)
+	assert.Equal(t, "productos:read ventas:write", scopes)
+
+	for _, invalidos := range [][]string{nil, {"usuarios:read"}, {"productos:borrar"}, {"api-keys:write"}} {
+		_, err := NormalizarScopes(invalidos)
+		assert.ErrorIs(t, err, ErrScopeInvalido, invalidos)
+	}
+}
+
+func TestAPIKey_CicloDeVida(t *testing.T) {
+	db := mocks.NewMemoryDB()
+	usuario := nuevoUsuario(t, db)
+
+	key, clave, err := CrearAPIKey(db, usuario, "lector caja 1", []string{"productos:read"}, nil)
+	require.NoError(t, err)
+	assert.True(t, strings.HasPrefix(clave, PrefijoAPIKey))
+	assert.True(t, strings.HasPrefix(clave, key.Prefijo))
+	assert.NotContains(t, key.Hash, clave)
+
+	leida, dueno, err := ValidarAPIKey(db, clave)
+	require.NoError(t, err)
+	assert.Equal(t, usuario.ID, dueno.ID)
+	assert.True(t, leida.Permite("productos:read"))
+	assert.False(t, leida.Permite("productos:write"))
+	require.NotNil(t, leida.UltimoUsoEn)
+
+	_, _, err = ValidarAPIKey(db, clave+"x")
+	assert.ErrorIs(t, err, ErrAPIKeyInvalida)
+
+	require.NoError(t, RevocarAPIKey(db, &leida))
+	_, _, err = ValidarAPIKey(db, clave)
+	assert.ErrorIs(t, err, ErrAPIKeyInvalida)
+}
+
+func TestAPIKey_VencidaOUsuarioDeshabilitado(t *testing.T) {
+	db := mocks.NewMemoryDB()
+	usuario := nuevoUsuario(t, db)
+
+	ayer := time.Now().Add(-24 * time.Hour)
+	_, vencida, err := CrearAPIKey(db, usuario, "vieja", []string{"ventas:write"}, &ayer)
+	require.NoError(t, err)
+	_, _, err = ValidarAPIKey(db, vencida)
+	assert.ErrorIs(t, err, ErrAPIKeyInvalida)
+
+	_, clave, err := CrearAPIKey(db, usuario, "sync", []string{"ventas:write"}, nil)
+	require.NoError(t, err)
+	require.NoError(t, DeshabilitarUsuario(db, &usuario))
+	_, _, err = ValidarAPIKey(db, clave)
+	assert.ErrorIs(t, err, ErrAPIKeyInvalida)
+
+	var keys []models.APIKey
+	require.NoError(t, db.Find(&keys))
+	assert.Len(t, keys, 2)
+}
@@ -1 +1,227 @@
 package services
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"time"
+	"ventas-app/database"
+	"ventas-app/models"
+	"ventas-app/utils"
+
+	"gorm.io/gorm"
+)
+
+var (
+	// ErrRefreshInvalido se devuelve si el refresh token no existe, venció,
+	// fue revocado o su usuario ya no existe o está deshabilitado.
+	ErrRefreshInvalido = errors.New("refresh token inválido")
+
+	// ErrSesionRevocada: el access token fue revocado (logout o revocación
+	// de todas las sesiones del usuario).
+	ErrSesionRevocada = errors.New("sesión revocada")
+
+	// ErrUsuarioInvalido: el usuario del token fue borrado o deshabilitado.
+	ErrUsuarioInvalido = errors.New("usuario inexistente o deshabilitado")
+)
+
+// RefreshTTL es la vida de cada refresh token. Se renueva en cada rotación.
+var RefreshTTL = 7 * 24 * time.Hour
+
+// Sesion es el par de tokens que recibe el cliente.
+type Sesion struct {
+	AccessToken  string
+	RefreshToken string
+	Usuario      models.Usuario
+}
+
+// IniciarSesion emite un access token y un refresh token de una familia
+// nueva. tenant es el entorno cuya base guarda al usuario.
+func IniciarSesion(db database.DBHandler, usuario models.Usuario, tenant string) (Sesion, error) {
+	return emitir(db, usuario, utils.RandomID(), tenant)
+}
+
+// RenovarSesion rota el refresh token: lo marca como usado y emite un par nuevo
+// de la misma familia. Si el token ya había sido rotado (alguien lo está
+// reusando, posiblemente robado) se revoca la familia entera. La marca es
+// condicional (revocado_en IS NULL): de dos requests con el mismo token solo
+// uno lo rota y el otro cuenta como reuso.
+func RenovarSesion(db database.DBHandler, refresh, tenant string) (Sesion, error) {
+	var sesion Sesion
+	familia := ""
+
+	err := db.Transaction(func(tx database.DBHandler) error {
+		var rt models.RefreshToken
+		if err := tx.Where("hash = ?", hashToken(refresh)).First(&rt); err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return ErrRefreshInvalido
+			}
+			return err
+		}
+		if rt.RevocadoEn != nil {
+			familia = rt.Familia
+			return ErrRefreshInvalido
+		}
+		if time.Now().After(rt.ExpiraEn) {
+			return ErrRefreshInvalido
+		}
+
+		var usuario models.Usuario
+		if err := tx.First(&usuario, rt.UsuarioID); err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return ErrRefreshInvalido
+			}
+			return err
+		}
+		if !usuario.Habilitado() {
+			return ErrRefreshInvalido
+		}
+
+		rotados, err := tx.Model(&models.RefreshToken{}).Where("id = ? AND revocado_en IS NULL", rt.ID).
+			UpdateColumns(map[string]interface{}{"revocado_en": time.Now()})
+		if err != nil {
+			return err
+		}
+		if rotados == 0 {
+			// Otro request lo rotó después de leerlo acá
+			familia = rt.Familia
+			return ErrRefreshInvalido
+		}
+
+		sesion, err = emitir(tx, usuario, rt.Familia, tenant)
+		return err
+	})
+
+	if familia != "" {
+		if errRevocar := revocarRefresh(db, "familia = ?", familia); errRevocar != nil {
+			return Sesion{}, errRevocar
+		}
+	}
+	return sesion, err
+}
+
+// CerrarSesion revoca el access token actual (por su jti) y, si viene, la
+// familia del refresh token del mismo usuario.
+func CerrarSesion(db database.DBHandler, claims *utils.Claims, refresh string) error {
+	if claims == nil || claims.ID == "" || claims.ExpiresAt == nil {
+		return errors.New("token sin jti o exp")
+	}
+
+	return db.Transaction(func(tx database.DBHandler) error {
+		revocado := models.TokenRevocado{JTI: claims.ID, ExpiraEn: claims.ExpiresAt.Time}
+		if err := tx.Create(&revocado); err != nil && !errors.Is(err, gorm.ErrDuplicatedKey) {
+			return err
+		}
+		if refresh == "" {
+			return nil
+		}
+
+		var rt models.RefreshToken
+		err := tx.Where("hash = ?", hashToken(refresh)).First(&rt)
+		if errors.Is(err, gorm.ErrRecordNotFound) || (err == nil && rt.UsuarioID != claims.UserID) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		return revocarRefresh(tx, "familia = ?", rt.Familia)
+	})
+}
+
+// RevocarSesiones invalida todos los tokens emitidos hasta ahora para el
+// usuario: los access tokens subiendo su versión de sesiones (no por fecha:
+// iat tiene resolución de segundos y no distingue un token emitido en el
+// mismo segundo) y los refresh tokens uno a uno.
+func RevocarSesiones(db database.DBHandler, usuario *models.Usuario) error {
+	return db.Transaction(func(tx database.DBHandler) error {
+		ahora := time.Now()
+		usuario.VersionSesion++
+		usuario.SesionesRevocadasEn = &ahora
+		if err := tx.Save(usuario); err != nil {
+			return err
+		}
+		return revocarRefresh(tx, "usuario_id = ?", usuario.ID)
+	})
+}
+
+// ValidarSesion comprueba que un access token con firma válida siga vigente:
+// que no se haya revocado por logout (jti) ni por revocación de todas las
+// sesiones, y que su usuario exista y esté habilitado. Devuelve el usuario.
+func ValidarSesion(db database.DBHandler, claims *utils.Claims) (models.Usuario, error) {
+	if claims.ID == "" || claims.IssuedAt == nil {
+		return models.Usuario{}, ErrSesionRevocada
+	}
+
+	var revocado models.TokenRevocado
+	err := db.Where("jti = ?", claims.ID).First(&revocado)
+	if err == nil {
+		return models.Usuario{}, ErrSesionRevocada
+	}
+	if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return models.Usuario{}, err
+	}
+
+	var usuario models.Usuario
+	if err := db.First(&usuario, claims.UserID); err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return models.Usuario{}, ErrUsuarioInvalido
+		}
+		return models.Usuario{}, err
+	}
+	if !usuario.Habilitado() {
+		return models.Usuario{}, ErrUsuarioInvalido
+	}
+	if claims.Sesion != usuario.VersionSesion {
+		return models.Usuario{}, ErrSesionRevocada
+	}
+	return usuario, nil
+}
+
+// PurgarVencidos borra los tokens revocados y refresh tokens ya vencidos,
+// que no hace falta seguir guardando.
+func PurgarVencidos(db database.DBHandler) error {
+	ahora := time.Now()
+	if err := db.Where("expira_en < ?", ahora).Delete(&models.TokenRevocado{}); err != nil {
+		return err
+	}
+	return db.Where("expira_en < ?", ahora).Delete(&models.RefreshToken{})
+}
+
+func emitir(db database.DBHandler, usuario models.Usuario, familia, tenant string) (Sesion, error) {
+	claims := utils.NewClaims(usuario.ID, usuario.Rol, tenant)
+	claims.Sesion = usuario.VersionSesion
+	access, err := utils.SignClaims(claims)
+	if err != nil {
+		return Sesion{}, err
+	}
+
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return Sesion{}, err
+	}
+	refresh := base64.RawURLEncoding.EncodeToString(b)
+
+	rt := models.RefreshToken{
+		UsuarioID: usuario.ID,
+		Familia:   familia,
+		Hash:      hashToken(refresh),
+		ExpiraEn:  time.Now().Add(RefreshTTL),
+	}
+	if err := db.Create(&rt); err != nil {
+		return Sesion{}, err
+	}
+	return Sesion{AccessToken: access, RefreshToken: refresh, Usuario: usuario}, nil
+}
+
+func revocarRefresh(db database.DBHandler, query string, arg interface{}) error {
+	_, err := db.Model(&models.RefreshToken{}).Where(query, arg).Where("revocado_en IS NULL").
+		UpdateColumns(map[string]interface{}{"revocado_en": time.Now()})
+	return err
+}
+
+func hashToken(token string) string {
+	h := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(h[:])
+}
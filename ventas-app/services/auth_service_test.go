@@ -0,0 +1,199 @@
+package services
+
+import (
+	"errors"
+	"testing"
+	"time"
+	"ventas-app/database"
+	"ventas-app/mocks"
+	"ventas-app/models"
+	"ventas-app/utils"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func nuevoUsuario(t *testing.T, db *mocks.MemoryDB) models.Usuario {
+	t.Helper()
+	utils.SetSecret("test-secret-key")
+	usuario := models.Usuario{Nombre: "ana", Clave: "x", Rol: "vendedor"}
+	require.NoError(t, db.Create(&usuario))
+	return usuario
+}
+
+func claimsDe(t *testing.T, token string) *utils.Claims {
+	t.Helper()
+	claims, err := utils.ParseClaims(token)
+	require.NoError(t, err)
+	return claims
+}
+
+func TestRenovarSesion_RotaElRefreshToken(t *testing.T) {
+	db := mocks.NewMemoryDB()
+	usuario := nuevoUsuario(t, db)
+
+	sesion, err := IniciarSesion(db, usuario, "qa")
+	require.NoError(t, err)
+	assert.NotEmpty(t, sesion.AccessToken)
+	assert.NotEmpty(t, sesion.RefreshToken)
+
+	nueva, err := RenovarSesion(db, sesion.RefreshToken, "qa")
+	require.NoError(t, err)
+	assert.NotEqual(t, sesion.RefreshToken, nueva.RefreshToken)
+	assert.Equal(t, usuario.ID, nueva.Usuario.ID)
+
+	// Solo se guarda el hash
+	var tokens []models.RefreshToken
+	require.NoError(t, db.Find(&tokens))
+	require.Len(t, tokens, 2)
+	assert.NotEqual(t, sesion.RefreshToken, tokens[0].Hash)
+	assert.Equal(t, tokens[0].Familia, tokens[1].Familia)
+}
+
+func TestRenovarSesion_ReusoRevocaLaFamilia(t *testing.T) {
+	db := mocks.NewMemoryDB()
+	usuario := nuevoUsuario(t, db)
+
+	sesion, err := IniciarSesion(db, usuario, "qa")
+	require.NoError(t, err)
+	otra, err := IniciarSesion(db, usuario, "qa")
+	require.NoError(t, err)
+	nueva, err := RenovarSesion(db, sesion.RefreshToken, "qa")
+	require.NoError(t, err)
+
+	_, err = RenovarSesion(db, sesion.RefreshToken, "qa")
+	assert.True(t, errors.Is(err, ErrRefreshInvalido))
+
+	_, err = RenovarSesion(db, nueva.RefreshToken, "qa")
+	assert.True(t, errors.Is(err, ErrRefreshInvalido), "el token rotado también queda revocado")
+
+	_, err = RenovarSesion(db, otra.RefreshToken, "qa")
+	assert.NoError(t, err, "otras sesiones del usuario no se tocan")
+}
+
+// rotacionEnCarrera ejecuta antes una sola vez, justo cuando la transacción
+// va a marcar el refresh token: simula otro request que lo rotó en el medio.
+type rotacionEnCarrera struct {
+	database.DBHandler
+	antes func()
+}
+
+func (r *rotacionEnCarrera) Transaction(fn func(tx database.DBHandler) error) error {
+	return r.DBHandler.Transaction(func(tx database.DBHandler) error {
+		return fn(&rotacionEnCarrera{DBHandler: tx, antes: r.antes})
+	})
+}
+
+func (r *rotacionEnCarrera) Model(value interface{}) database.DBHandler {
+	if _, ok := value.(*models.RefreshToken); ok && r.antes != nil {
+		antes := r.antes
+		r.antes = nil
+		antes()
+	}
+	return r.DBHandler.Model(value)
+}
+
+func TestRenovarSesion_RotacionesSimultaneas(t *testing.T) {
+	db := mocks.NewMemoryDB()
+	usuario := nuevoUsuario(t, db)
+
+	sesion, err := IniciarSesion(db, usuario, "qa")
+	require.NoError(t, err)
+
+	carrera := &rotacionEnCarrera{DBHandler: db, antes: func() {
+		_, err := RenovarSesion(db, sesion.RefreshToken, "qa")
+		require.NoError(t, err)
+	}}
+
+	nueva, err := RenovarSesion(carrera, sesion.RefreshToken, "qa")
+	assert.True(t, errors.Is(err, ErrRefreshInvalido), "el segundo en rotar el mismo token es un reuso")
+	assert.Empty(t, nueva.RefreshToken)
+}
+
+func TestRenovarSesion_Invalidos(t *testing.T) {
+	db := mocks.NewMemoryDB()
+	usuario := nuevoUsuario(t, db)
+
+	_, err := RenovarSesion(db, "no-existe", "qa")
+	assert.True(t, errors.Is(err, ErrRefreshInvalido))
+
+	RefreshTTL = -time.Minute
+	defer func() { RefreshTTL = 7 * 24 * time.Hour }()
+	vencida, err := IniciarSesion(db, usuario, "qa")
+	require.NoError(t, err)
+
+	_, err = RenovarSesion(db, vencida.RefreshToken, "qa")
+	assert.True(t, errors.Is(err, ErrRefreshInvalido))
+}
+
+func TestCerrarSesion(t *testing.T) {
+	db := mocks.NewMemoryDB()
+	usuario := nuevoUsuario(t, db)
+	sesion, err := IniciarSesion(db, usuario, "qa")
+	require.NoError(t, err)
+	claims := claimsDe(t, sesion.AccessToken)
+
+	_, err = ValidarSesion(db, claims)
+	require.NoError(t, err)
+
+	require.NoError(t, CerrarSesion(db, claims, sesion.RefreshToken))
+	require.NoError(t, CerrarSesion(db, claims, ""), "cerrar dos veces no falla")
+
+	_, err = ValidarSesion(db, claims)
+	assert.True(t, errors.Is(err, ErrSesionRevocada))
+
+	_, err = RenovarSesion(db, sesion.RefreshToken, "qa")
+	assert.True(t, errors.Is(err, ErrRefreshInvalido))
+}
+
+func TestRevocarSesiones(t *testing.T) {
+	db := mocks.NewMemoryDB()
+	usuario := nuevoUsuario(t, db)
+	sesion, err := IniciarSesion(db, usuario, "qa")
+	require.NoError(t, err)
+
+	require.NoError(t, RevocarSesiones(db, &usuario))
+
+	_, err = ValidarSesion(db, claimsDe(t, sesion.AccessToken))
+	assert.True(t, errors.Is(err, ErrSesionRevocada))
+
+	_, err = RenovarSesion(db, sesion.RefreshToken, "qa")
+	assert.True(t, errors.Is(err, ErrRefreshInvalido))
+
+	// Un login en el mismo segundo que la revocación no queda revocado
+	nueva, err := IniciarSesion(db, usuario, "qa")
+	require.NoError(t, err)
+	_, err = ValidarSesion(db, claimsDe(t, nueva.AccessToken))
+	assert.NoError(t, err)
+}
+
+func TestSesion_UsuarioDeshabilitado(t *testing.T) {
+	db := mocks.NewMemoryDB()
+	usuario := nuevoUsuario(t, db)
+	sesion, err := IniciarSesion(db, usuario, "qa")
+	require.NoError(t, err)
+	assert.Equal(t, "qa", claimsDe(t, sesion.AccessToken).Tenant)
+
+	ahora := time.Now()
+	usuario.DeshabilitadoEn = &ahora
+	require.NoError(t, db.Save(&usuario))
+
+	_, err = ValidarSesion(db, claimsDe(t, sesion.AccessToken))
+	assert.True(t, errors.Is(err, ErrUsuarioInvalido))
+
+	_, err = RenovarSesion(db, sesion.RefreshToken, "qa")
+	assert.True(t, errors.Is(err, ErrRefreshInvalido))
+}
+
+func TestPurgarVencidos(t *testing.T) {
+	db := mocks.NewMemoryDB()
+	require.NoError(t, db.Create(&models.TokenRevocado{JTI: "viejo", ExpiraEn: time.Now().Add(-time.Hour)}))
+	require.NoError(t, db.Create(&models.TokenRevocado{JTI: "vigente", ExpiraEn: time.Now().Add(time.Hour)}))
+
+	require.NoError(t, PurgarVencidos(db))
+
+	var quedan []models.TokenRevocado
+	require.NoError(t, db.Find(&quedan))
+	require.Len(t, quedan, 1)
+	assert.Equal(t, "vigente", quedan[0].JTI)
+}
@@ -0,0 +1,224 @@
+package services
+
+import (
+	"errors"
+	"sort"
+	"strings"
+	"ventas-app/database"
+	"ventas-app/models"
+
+	"gorm.io/gorm"
+)
+
+var (
+	// ErrCategoriaInvalida: nombre vacío o padre inexistente.
+	ErrCategoriaInvalida = errors.New("categoría inválida")
+	// ErrCategoriaCiclo: el padre elegido está debajo de la propia categoría.
+	ErrCategoriaCiclo = errors.New("la categoría no puede quedar debajo de sí misma")
+	// ErrCategoriaDuplicada: ya hay una hermana con ese nombre.
+	ErrCategoriaDuplicada = errors.New("ya existe una categoría con ese nombre en el mismo nivel")
+	// ErrCategoriaEnUso: tiene subcategorías o productos.
+	ErrCategoriaEnUso = errors.New("la categoría tiene subcategorías o productos")
+	// ErrMarcaInvalida: nombre vacío.
+	ErrMarcaInvalida = errors.New("marca inválida")
+	// ErrMarcaDuplicada: ya existe una marca con ese nombre.
+	ErrMarcaDuplicada = errors.New("ya existe una marca con ese nombre")
+	// ErrMarcaEnUso: hay productos de esa marca.
+	ErrMarcaEnUso = errors.New("la marca tiene productos")
+	// ErrClasificacionInvalida: la categoría o la marca del producto no existe.
+	ErrClasificacionInvalida = errors.New("la categoría o la marca no existe")
+)
+
+// NodoCategoria es una categoría con sus hijas, para recorrer el árbol.
+type NodoCategoria struct {
+	models.Categoria
+	Hijas []*NodoCategoria `json:"hijas"`
+}
+
+// GuardarCategoria valida y crea o actualiza la categoría. Cambiar PadreID
+// mueve la rama entera sin tocar productos.
+func GuardarCategoria(db database.DBHandler, cat *models.Categoria) error {
+	cat.Nombre = strings.TrimSpace(cat.Nombre)
+	if cat.Nombre == "" {
+		return ErrCategoriaInvalida
+	}
+
+	categorias, err := cargarCategorias(db)
+	if err != nil {
+		return err
+	}
+	if cat.PadreID != nil {
+		if _, ok := categorias[*cat.PadreID]; !ok {
+			return ErrCategoriaInvalida
+		}
+		// Subiendo desde el padre no se puede llegar a la propia categoría
+		for id := cat.PadreID; id != nil; id = categorias[*id].PadreID {
+			if cat.ID != 0 && *id == cat.ID {
+				return ErrCategoriaCiclo
+			}
+		}
+	}
+	for _, otra := range categorias {
+		if otra.ID != cat.ID && mismoPadre(otra.PadreID, cat.PadreID) && strings.EqualFold(otra.Nombre, cat.Nombre) {
+			return ErrCategoriaDuplicada
+		}
+	}
+
+	if cat.ID == 0 {
+		return db.Create(cat)
+	}
+	return db.Save(cat)
+}
+
+// BorrarCategoria elimina una categoría sin subcategorías ni productos.
+func BorrarCategoria(db database.DBHandler, cat models.Categoria) error {
+	var hija models.Categoria
+	err := db.Where("padre_id = ?", cat.ID).First(&hija)
+	if err == nil {
+		return ErrCategoriaEnUso
+	}
+	if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return err
+	}
+	var producto models.Producto
+	err = db.Where("categoria_id = ?", cat.ID).First(&producto)
+	if err == nil {
+		return ErrCategoriaEnUso
+	}
+	if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return err
+	}
+	return db.Delete(&cat)
+}
+
+// ArbolCategorias devuelve las categorías raíz con sus descendientes,
+// ordenadas por nombre en cada nivel.
+func ArbolCategorias(db database.DBHandler) ([]*NodoCategoria, error) {
+	categorias, err := cargarCategorias(db)
+	if err != nil {
+		return nil, err
+	}
+	nodos := make(map[uint]*NodoCategoria, len(categorias))
+	for id, cat := range categorias {
+		nodos[id] = &NodoCategoria{Categoria: cat, Hijas: []*NodoCategoria{}}
+	}
+	raices := []*NodoCategoria{}
+	for _, n := range nodos {
+		if n.PadreID == nil {
+			raices = append(raices, n)
+			continue
+		}
+		padre := nodos[*n.PadreID]
+		padre.Hijas = append(padre.Hijas, n)
+	}
+	ordenarNodos(raices)
+	return raices, nil
+}
+
+// SubarbolCategoria devuelve el ID de la categoría y los de todas sus
+// descendientes; gorm.ErrRecordNotFound si no existe.
+func SubarbolCategoria(db database.DBHandler, id uint) ([]uint, error) {
+	categorias, err := cargarCategorias(db)
+	if err != nil {
+		return nil, err
+	}
+	if _, ok := categorias[id]; !ok {
+		return nil, gorm.ErrRecordNotFound
+	}
+	hijas := map[uint][]uint{}
+	for _, cat := range categorias {
+		if cat.PadreID != nil {
+			hijas[*cat.PadreID] = append(hijas[*cat.PadreID], cat.ID)
+		}
+	}
+	ids := []uint{id}
+	for i := 0; i < len(ids); i++ {
+		ids = append(ids, hijas[ids[i]]...)
+	}
+	return ids, nil
+}
+
+// GuardarMarca valida y crea o actualiza la marca.
+func GuardarMarca(db database.DBHandler, marca *models.Marca) error {
+	marca.Nombre = strings.TrimSpace(marca.Nombre)
+	if marca.Nombre == "" {
+		return ErrMarcaInvalida
+	}
+	var err error
+	if marca.ID == 0 {
+		err = db.Create(marca)
+	} else {
+		err = db.Save(marca)
+	}
+	if errors.Is(err, gorm.ErrDuplicatedKey) {
+		return ErrMarcaDuplicada
+	}
+	return err
+}
+
+// BorrarMarca elimina una marca sin productos.
+func BorrarMarca(db database.DBHandler, marca models.Marca) error {
+	var producto models.Producto
+	err := db.Where("marca_id = ?", marca.ID).First(&producto)
+	if err == nil {
+		return ErrMarcaEnUso
+	}
+	if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return err
+	}
+	return db.Delete(&marca)
+}
+
+// ClasificarProducto asigna (o con nil quita) categoría y marca.
+func ClasificarProducto(db database.DBHandler, producto *models.Producto, categoriaID, marcaID *uint) error {
+	if err := validarClasificacion(db, categoriaID, marcaID); err != nil {
+		return err
+	}
+	producto.CategoriaID = categoriaID
+	producto.MarcaID = marcaID
+	return db.Save(producto)
+}
+
+// validarClasificacion controla que existan la categoría y la marca (si no
+// son nil).
+func validarClasificacion(db database.DBHandler, categoriaID, marcaID *uint) error {
+	var err error
+	if categoriaID != nil {
+		err = db.First(&models.Categoria{}, *categoriaID)
+	}
+	if err == nil && marcaID != nil {
+		err = db.First(&models.Marca{}, *marcaID)
+	}
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return ErrClasificacionInvalida
+	}
+	return err
+}
+
+// cargarCategorias trae todas las categorías: son pocas y así el árbol se
+// arma en memoria sin una consulta por nivel.
+func cargarCategorias(db database.DBHandler) (map[uint]models.Categoria, error) {
+	var lista []models.Categoria
+	if err := db.Find(&lista); err != nil {
+		return nil, err
+	}
+	categorias := make(map[uint]models.Categoria, len(lista))
+	for _, cat := range lista {
+		categorias[cat.ID] = cat
+	}
+	return categorias, nil
+}
+
+func mismoPadre(a, b *uint) bool {
+	if a == nil || b == nil {
+		return a == nil && b == nil
+	}
+	return *a == *b
+}
+
+func ordenarNodos(nodos []*NodoCategoria) {
+	sort.Slice(nodos, func(i, j int) bool { return nodos[i].Nombre < nodos[j].Nombre })
+	for _, n := range nodos {
+		ordenarNodos(n.Hijas)
+	}
+}
@@ -0,0 +1,75 @@
+package services
+
+import (
+	"testing"
+	"ventas-app/mocks"
+	"ventas-app/models"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+)
+
+func TestCategorias(t *testing.T) {
+	db := mocks.NewMemoryDB()
+	nueva := func(nombre string, padre *uint) *models.Categoria {
+		cat := &models.Categoria{Nombre: nombre, PadreID: padre}
+		require.NoError(t, GuardarCategoria(db, cat))
+		return cat
+	}
+	bebidas := nueva("Bebidas", nil)
+	infusiones := nueva("Infusiones", &bebidas.ID)
+	yerbas := nueva("Yerbas", &infusiones.ID)
+	almacen := nueva("Almacén", nil)
+
+	assert.ErrorIs(t, GuardarCategoria(db, &models.Categoria{Nombre: "yerbas", PadreID: &infusiones.ID}), ErrCategoriaDuplicada)
+	assert.NoError(t, GuardarCategoria(db, &models.Categoria{Nombre: "Yerbas"}), "mismo nombre en otro nivel")
+	padreInexistente := uint(99)
+	assert.ErrorIs(t, GuardarCategoria(db, &models.Categoria{Nombre: "X", PadreID: &padreInexistente}), ErrCategoriaInvalida)
+	bebidas.PadreID = &yerbas.ID
+	assert.ErrorIs(t, GuardarCategoria(db, bebidas), ErrCategoriaCiclo)
+	bebidas.PadreID = nil
+
+	ids, err := SubarbolCategoria(db, bebidas.ID)
+	require.NoError(t, err)
+	assert.ElementsMatch(t, []uint{bebidas.ID, infusiones.ID, yerbas.ID}, ids)
+	_, err = SubarbolCategoria(db, 99)
+	assert.ErrorIs(t, err, gorm.ErrRecordNotFound)
+
+	// Mover la rama cambia el subárbol sin tocar productos
+	infusiones.PadreID = &almacen.ID
+	require.NoError(t, GuardarCategoria(db, infusiones))
+	ids, err = SubarbolCategoria(db, almacen.ID)
+	require.NoError(t, err)
+	assert.ElementsMatch(t, []uint{almacen.ID, infusiones.ID, yerbas.ID}, ids)
+
+	arbol, err := ArbolCategorias(db)
+	require.NoError(t, err)
+	require.Len(t, arbol, 3)
+	assert.Equal(t, "Almacén", arbol[0].Nombre)
+	require.Len(t, arbol[0].Hijas, 1)
+	assert.Equal(t, "Yerbas", arbol[0].Hijas[0].Hijas[0].Nombre)
+
+	producto := models.Producto{Nombre: "Yerba"}
+	require.NoError(t, db.Create(&producto))
+	require.NoError(t, ClasificarProducto(db, &producto, &yerbas.ID, nil))
+	assert.ErrorIs(t, ClasificarProducto(db, &producto, &padreInexistente, nil), ErrClasificacionInvalida)
+	assert.ErrorIs(t, BorrarCategoria(db, *yerbas), ErrCategoriaEnUso)
+	assert.ErrorIs(t, BorrarCategoria(db, *infusiones), ErrCategoriaEnUso)
+	require.NoError(t, BorrarCategoria(db, *bebidas))
+}
+
+func TestMarcas(t *testing.T) {
+	db := mocks.NewMemoryDB()
+	marca := models.Marca{Nombre: " Taragüí "}
+	require.NoError(t, GuardarMarca(db, &marca))
+	assert.Equal(t, "Taragüí", marca.Nombre)
+	assert.ErrorIs(t, GuardarMarca(db, &models.Marca{Nombre: "Taragüí"}), ErrMarcaDuplicada)
+	assert.ErrorIs(t, GuardarMarca(db, &models.Marca{Nombre: " "}), ErrMarcaInvalida)
+
+	producto := models.Producto{Nombre: "Yerba", MarcaID: &marca.ID}
+	require.NoError(t, db.Create(&producto))
+	assert.ErrorIs(t, BorrarMarca(db, marca), ErrMarcaEnUso)
+	require.NoError(t, db.Delete(&producto))
+	require.NoError(t, BorrarMarca(db, marca))
+}
@@ -0,0 +1,170 @@
+package services
+
+import (
+	"bufio"
+	_ "embed"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"unicode"
+	"ventas-app/database"
+	"ventas-app/models"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+// PoliticaClave son las reglas que debe cumplir una clave nueva. main la
+// reemplaza con los valores de config.ClaveConfig.
+type PoliticaClave struct {
+	MinLongitud       int
+	RequerirMayuscula bool
+	RequerirMinuscula bool
+	RequerirDigito    bool
+	RequerirSimbolo   bool
+}
+
+var Politica = PoliticaClave{
+	MinLongitud:       10,
+	RequerirMayuscula: true,
+	RequerirMinuscula: true,
+	RequerirDigito:    true,
+}
+
+// CostoBcrypt es el costo con el que se hashean las claves. Los hashes con
+// un costo menor se actualizan en el siguiente login correcto.
+var CostoBcrypt = bcrypt.DefaultCost
+
+// maxBytesClave es el límite de bcrypt: lo que pasa de 72 bytes se ignora.
+const maxBytesClave = 72
+
+//go:embed claves_comunes.txt
+var clavesComunesTxt string
+
+// listaNegra son las claves prohibidas, en minúsculas.
+var listaNegra = leerListaNegra(strings.NewReader(clavesComunesTxt), map[string]bool{})
+
+// ErrClaveDebil indica que la clave no cumple la política. Problemas lista
+// cada regla incumplida, para mostrárselas al usuario.
+type ErrClaveDebil struct {
+	Problemas []string
+}
+
+func (e *ErrClaveDebil) Error() string {
+	return "clave débil: " + strings.Join(e.Problemas, "; ")
+}
+
+// CargarListaNegra suma a las claves prohibidas las del archivo, una por
+// línea. Se llama al arrancar, antes de atender requests.
+func CargarListaNegra(ruta string) error {
+	f, err := os.Open(ruta)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	leerListaNegra(f, listaNegra)
+	return nil
+}
+
+func leerListaNegra(r io.Reader, lista map[string]bool) map[string]bool {
+	sc := bufio.NewScanner(r)
+	for sc.Scan() {
+		if linea := strings.TrimSpace(sc.Text()); linea != "" {
+			lista[strings.ToLower(linea)] = true
+		}
+	}
+	return lista
+}
+
+// ValidarClave comprueba la clave contra Politica, la lista de claves
+// comunes y el nombre de usuario. Devuelve *ErrClaveDebil si no la cumple.
+func ValidarClave(clave, nombre string) error {
+	var problemas []string
+
+	if n := len([]rune(clave)); n < Politica.MinLongitud {
+		problemas = append(problemas, fmt.Sprintf("debe tener al menos %d caracteres", Politica.MinLongitud))
+	}
+	if len(clave) > maxBytesClave {
+		problemas = append(problemas, fmt.Sprintf("no puede superar los %d bytes", maxBytesClave))
+	}
+
+	var mayuscula, minuscula, digito, simbolo bool
+	for _, r := range clave {
+		switch {
+		case unicode.IsUpper(r):
+			mayuscula = true
+		case unicode.IsLower(r):
+			minuscula = true
+		case unicode.IsDigit(r):
+			digito = true
+		case unicode.IsPunct(r) || unicode.IsSymbol(r) || unicode.IsSpace(r):
+			simbolo = true
+		}
+	}
+	if Politica.RequerirMayuscula && !mayuscula {
+		problemas = append(problemas, "debe incluir una mayúscula")
+	}
+	if Politica.RequerirMinuscula && !minuscula {
+		problemas = append(problemas, "debe incluir una minúscula")
+	}
+	if Politica.RequerirDigito && !digito {
+		problemas = append(problemas, "debe incluir un número")
+	}
+	if Politica.RequerirSimbolo && !simbolo {
+		problemas = append(problemas, "debe incluir un símbolo")
+	}
+
+	minusculas := strings.ToLower(clave)
+	if listaNegra[minusculas] {
+		problemas = append(problemas, "es una clave demasiado común")
+	}
+	if len(nombre) >= 3 && strings.Contains(minusculas, strings.ToLower(nombre)) {
+		problemas = append(problemas, "no puede contener el nombre de usuario")
+	}
+
+	if len(problemas) > 0 {
+		return &ErrClaveDebil{Problemas: problemas}
+	}
+	return nil
+}
+
+// ProblemasClave devuelve las reglas incumplidas si err es *ErrClaveDebil.
+func ProblemasClave(err error) ([]string, bool) {
+	var debil *ErrClaveDebil
+	if errors.As(err, &debil) {
+		return debil.Problemas, true
+	}
+	return nil, false
+}
+
+// HashClave hashea una clave con bcrypt para guardarla en Usuario.Clave.
+func HashClave(clave string) (string, error) {
+	hash, err := bcrypt.GenerateFromPassword([]byte(clave), CostoBcrypt)
+	if err != nil {
+		return "", err
+	}
+	return string(hash), nil
+}
+
+// NecesitaRehash indica si el hash se generó con un costo menor a CostoBcrypt.
+func NecesitaRehash(hash string) bool {
+	costo, err := bcrypt.Cost([]byte(hash))
+	return err == nil && costo < CostoBcrypt
+}
+
+// ActualizarHash vuelve a hashear la clave (ya verificada) si su hash quedó
+// por debajo de CostoBcrypt. Se llama tras un login correcto, que es el
+// único momento en que se tiene la clave en claro.
+func ActualizarHash(db database.DBHandler, usuario *models.Usuario, clave string) error {
+	if !NecesitaRehash(usuario.Clave) {
+		return nil
+	}
+	hash, err := HashClave(clave)
+	if err != nil {
+		return err
+	}
+	usuario.Clave = hash
+	return db.Save(usuario)
+}
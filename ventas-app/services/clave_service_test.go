@@ -0,0 +1,86 @@
+package services
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"ventas-app/mocks"
+	"ventas-app/models"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/crypto/bcrypt"
+)
+
+func TestValidarClave(t *testing.T) {
+	casos := []struct {
+		clave    string
+		problema string
+	}{
+		{"Corta1", "al menos 10 caracteres"},
+		{"sinmayuscula1", "una mayúscula"},
+		{"SINMINUSCULA1", "una minúscula"},
+		{"SinNumeroAlguno", "un número"},
+		{"Password123", "demasiado común"},
+		{"Ana-Clave-2024", "nombre de usuario"},
+		{"Ñandú-Clave-1" + string(make([]byte, 70)), "72 bytes"},
+	}
+	for _, caso := range casos {
+		err := ValidarClave(caso.clave, "ana")
+		problemas, ok := ProblemasClave(err)
+		require.True(t, ok, caso.clave)
+		assert.Contains(t, problemas[0], caso.problema, caso.clave)
+	}
+
+	assert.NoError(t, ValidarClave("Mostrador-2024", "ana"))
+	assert.NoError(t, ValidarClave("Ñandú-Cañón-7", "ana"), "cuenta caracteres, no bytes")
+}
+
+func TestValidarClave_Politica(t *testing.T) {
+	original := Politica
+	defer func() { Politica = original }()
+
+	Politica = PoliticaClave{MinLongitud: 4, RequerirSimbolo: true}
+	assert.NoError(t, ValidarClave("a b c", "ana"))
+	assert.Error(t, ValidarClave("abcd", "ana"))
+}
+
+func TestCargarListaNegra(t *testing.T) {
+	ruta := filepath.Join(t.TempDir(), "prohibidas.txt")
+	require.NoError(t, os.WriteFile(ruta, []byte("Empresa-2024\n\n  Sucursal-Centro1  \n"), 0o600))
+	defer func() {
+		delete(listaNegra, "empresa-2024")
+		delete(listaNegra, "sucursal-centro1")
+	}()
+
+	require.NoError(t, CargarListaNegra(ruta))
+	assert.NoError(t, ValidarClave("Empresa-2024X", "ana"), "solo coincide completa")
+	assert.Error(t, ValidarClave("EMPRESA-2024", "ana"))
+	assert.Error(t, ValidarClave("Sucursal-Centro1", "ana"))
+	assert.Error(t, CargarListaNegra(filepath.Join(t.TempDir(), "no-existe.txt")))
+}
+
+func TestActualizarHash_SubeElCosto(t *testing.T) {
+	original := CostoBcrypt
+	defer func() { CostoBcrypt = original }()
+
+	db := mocks.NewMemoryDB()
+	viejo, _ := bcrypt.GenerateFromPassword([]byte("Mostrador-2024"), bcrypt.MinCost)
+	usuario := models.Usuario{Nombre: "ana", Clave: string(viejo), Rol: "vendedor"}
+	require.NoError(t, db.Create(&usuario))
+
+	CostoBcrypt = bcrypt.MinCost + 1
+	assert.True(t, NecesitaRehash(usuario.Clave))
+	require.NoError(t, ActualizarHash(db, &usuario, "Mostrador-2024"))
+
+	var leido models.Usuario
+	require.NoError(t, db.First(&leido, usuario.ID))
+	costo, err := bcrypt.Cost([]byte(leido.Clave))
+	require.NoError(t, err)
+	assert.Equal(t, bcrypt.MinCost+1, costo)
+	assert.NoError(t, bcrypt.CompareHashAndPassword([]byte(leido.Clave), []byte("Mostrador-2024")))
+
+	// Bajar el costo no degrada los hashes existentes
+	CostoBcrypt = bcrypt.MinCost
+	assert.False(t, NecesitaRehash(leido.Clave))
+}
@@ -0,0 +1,308 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+	"ventas-app/database"
+	"ventas-app/models"
+
+	"gorm.io/gorm"
+)
+
+var (
+	// ErrEsquemaInvalido: porcentajes fuera de 0-100 o tramos desordenados.
+	ErrEsquemaInvalido = errors.New("esquema de comisión inválido")
+	// ErrEsquemaDuplicado: el vendedor (o el esquema por defecto) ya tiene uno.
+	ErrEsquemaDuplicado = errors.New("ya existe un esquema para ese vendedor")
+	// ErrPeriodoCerrado: el período se superpone con un cierre existente.
+	ErrPeriodoCerrado = errors.New("el período se superpone con uno cerrado")
+	// ErrPeriodoInvalido: desde no es anterior a hasta o hasta es futuro.
+	ErrPeriodoInvalido = errors.New("período inválido")
+)
+
+// ValidarEsquema controla los porcentajes y que los tramos estén en orden
+// creciente de Desde, sin repetir.
+func ValidarEsquema(e models.EsquemaComision) error {
+	if e.Porcentaje < 0 || e.Porcentaje > 100 {
+		return ErrEsquemaInvalido
+	}
+	for _, p := range e.PorCategoria {
+		if p < 0 || p > 100 {
+			return ErrEsquemaInvalido
+		}
+	}
+	for i, t := range e.Tramos {
+		if t.Porcentaje < 0 || t.Porcentaje > 100 || t.Desde < 0 {
+			return ErrEsquemaInvalido
+		}
+		if i > 0 && t.Desde <= e.Tramos[i-1].Desde {
+			return ErrEsquemaInvalido
+		}
+	}
+	return nil
+}
+
+// GuardarEsquema valida y crea o actualiza el esquema. Cada vendedor tiene a
+// lo sumo uno, y hay un solo esquema por defecto (UsuarioID nil). Las
+// categorías de PorCategoria tienen que existir (ErrClasificacionInvalida).
+func GuardarEsquema(db database.DBHandler, e *models.EsquemaComision) error {
+	if err := ValidarEsquema(*e); err != nil {
+		return err
+	}
+	for id := range e.PorCategoria {
+		if err := validarClasificacion(db, &id, nil); err != nil {
+			return err
+		}
+	}
+
+	q := db.Where("usuario_id IS NULL")
+	if e.UsuarioID != nil {
+		q = db.Where("usuario_id = ?", *e.UsuarioID)
+	}
+	var existente models.EsquemaComision
+	err := q.First(&existente)
+	if err == nil && existente.ID != e.ID {
+		return ErrEsquemaDuplicado
+	}
+	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		return err
+	}
+
+	if e.ID == 0 {
+		return db.Create(e)
+	}
+	return db.Save(e)
+}
+
+// CalcularComisiones liquida, sin guardar, el período [desde, hasta): por
+// vendedor, el neto de sus ventas menos el de las devoluciones registradas
+// en el período, por el porcentaje de su esquema (o el por defecto), salvo
+// lo de categorías con porcentaje propio. Las sumas se hacen en la base, por
+// vendedor y categoría del producto.
+func CalcularComisiones(db database.DBHandler, desde, hasta time.Time) ([]models.ComisionLiquidada, error) {
+	venta := db.Tabla(&models.Venta{})
+	var ventas []struct {
+		UsuarioID   uint
+		CategoriaID *uint
+		Ventas      int64
+		Neto        float64
+	}
+	q, categoria := conCategoria(db, ventasEnRango(db, FiltroReporte{Desde: desde, Hasta: hasta}))
+	err := q.Select(venta + ".usuario_id, " + categoria + " AS categoria_id, COUNT(*) AS ventas, COALESCE(SUM(" + venta + ".neto), 0) AS neto").
+		Where(venta + ".usuario_id <> 0").Group(venta + ".usuario_id, " + categoria).Scan(&ventas)
+	if err != nil {
+		return nil, err
+	}
+
+	// La devolución se descuenta en la categoría del producto de su venta
+	devolucion := db.Tabla(&models.Devolucion{})
+	var devoluciones []struct {
+		VendedorID  uint
+		CategoriaID *uint
+		Neto        float64
+	}
+	q, categoria = conCategoria(db, db.Model(&models.Devolucion{}).
+		Joins(fmt.Sprintf("JOIN %[1]s ON %[1]s.id = %[2]s.venta_id", venta, devolucion)))
+	err = q.Select(devolucion+".vendedor_id, "+categoria+" AS categoria_id, COALESCE(SUM("+devolucion+".neto), 0) AS neto").
+		Where(devolucion+".created_at >= ? AND "+devolucion+".created_at < ? AND "+devolucion+".vendedor_id <> 0", desde, hasta).
+		Group(devolucion + ".vendedor_id, " + categoria).Scan(&devoluciones)
+	if err != nil {
+		return nil, err
+	}
+
+	// Base por vendedor y categoría (0: producto sin categoría)
+	porVendedor := map[uint]*models.ComisionLiquidada{}
+	bases := map[uint]map[uint]float64{}
+	liquidacion := func(id uint) *models.ComisionLiquidada {
+		c, ok := porVendedor[id]
+		if !ok {
+			c = &models.ComisionLiquidada{UsuarioID: id}
+			porVendedor[id] = c
+			bases[id] = map[uint]float64{}
+		}
+		return c
+	}
+	for _, v := range ventas {
+		c := liquidacion(v.UsuarioID)
+		c.Ventas += v.Ventas
+		c.Neto += v.Neto
+		bases[v.UsuarioID][idCategoria(v.CategoriaID)] += v.Neto
+	}
+	for _, d := range devoluciones {
+		c := liquidacion(d.VendedorID)
+		c.Devoluciones += d.Neto
+		bases[d.VendedorID][idCategoria(d.CategoriaID)] -= d.Neto
+	}
+	if len(porVendedor) == 0 {
+		return []models.ComisionLiquidada{}, nil
+	}
+
+	var esquemas []models.EsquemaComision
+	if err := db.Find(&esquemas); err != nil {
+		return nil, err
+	}
+	var porDefecto *models.EsquemaComision
+	esquemaDe := map[uint]models.EsquemaComision{}
+	conCategorias := false
+	for i, e := range esquemas {
+		conCategorias = conCategorias || len(e.PorCategoria) > 0
+		if e.UsuarioID == nil {
+			porDefecto = &esquemas[i]
+			continue
+		}
+		esquemaDe[*e.UsuarioID] = e
+	}
+	var categorias map[uint]models.Categoria
+	if conCategorias {
+		if categorias, err = cargarCategorias(db); err != nil {
+			return nil, err
+		}
+	}
+
+	ids := make([]uint, 0, len(porVendedor))
+	for id := range porVendedor {
+		ids = append(ids, id)
+	}
+	var usuarios []models.Usuario
+	if err := db.Where("id IN ?", ids).Find(&usuarios); err != nil {
+		return nil, err
+	}
+	for _, u := range usuarios {
+		porVendedor[u.ID].Nombre = u.Nombre
+	}
+
+	liquidadas := make([]models.ComisionLiquidada, 0, len(porVendedor))
+	for id, c := range porVendedor {
+		c.Neto = centavos(c.Neto)
+		c.Devoluciones = centavos(c.Devoluciones)
+		c.Base = centavos(c.Neto - c.Devoluciones)
+
+		esquema, ok := esquemaDe[id]
+		if !ok && porDefecto != nil {
+			esquema, ok = *porDefecto, true
+		}
+		if ok {
+			c.Esquema = esquema.Nombre
+			if c.Base > 0 {
+				c.Porcentaje = esquema.PorcentajePara(c.Base)
+				liquidarCategorias(c, esquema, bases[id], categorias)
+			}
+		}
+		liquidadas = append(liquidadas, *c)
+	}
+	sort.Slice(liquidadas, func(i, j int) bool { return liquidadas[i].UsuarioID < liquidadas[j].UsuarioID })
+	return liquidadas, nil
+}
+
+// liquidarCategorias calcula la comisión de c: lo vendido en categorías
+// (o subcategorías) con porcentaje en el esquema se paga con ese porcentaje,
+// agrupado por la categoría del esquema, y el resto con c.Porcentaje.
+func liquidarCategorias(c *models.ComisionLiquidada, esquema models.EsquemaComision, bases map[uint]float64, categorias map[uint]models.Categoria) {
+	porTasa := map[uint]float64{}
+	resto := c.Base
+	for id, base := range bases {
+		tasa, ok := categoriaConPorcentaje(esquema.PorCategoria, categorias, id)
+		if !ok {
+			continue
+		}
+		porTasa[tasa] += base
+		resto -= base
+	}
+
+	comision := resto * c.Porcentaje / 100
+	for id, base := range porTasa {
+		p := esquema.PorCategoria[id]
+		comision += base * p / 100
+		c.Categorias = append(c.Categorias, models.ComisionCategoria{
+			CategoriaID: id,
+			Base:        centavos(base),
+			Porcentaje:  p,
+			Comision:    centavos(base * p / 100),
+		})
+	}
+	sort.Slice(c.Categorias, func(i, j int) bool { return c.Categorias[i].CategoriaID < c.Categorias[j].CategoriaID })
+	c.Comision = centavos(comision)
+}
+
+// categoriaConPorcentaje busca, desde id hacia la raíz, la primera categoría
+// con porcentaje propio en porCategoria.
+func categoriaConPorcentaje(porCategoria map[uint]float64, categorias map[uint]models.Categoria, id uint) (uint, bool) {
+	// El límite de pasos evita un ciclo si el árbol estuviera roto
+	for pasos := 0; id != 0 && pasos <= len(categorias); pasos++ {
+		if _, ok := porCategoria[id]; ok {
+			return id, true
+		}
+		cat, ok := categorias[id]
+		if !ok || cat.PadreID == nil {
+			break
+		}
+		id = *cat.PadreID
+	}
+	return 0, false
+}
+
+func idCategoria(id *uint) uint {
+	if id == nil {
+		return 0
+	}
+	return *id
+}
+
+// BuscarCierre devuelve el cierre de exactamente [desde, hasta) con sus
+// comisiones, o gorm.ErrRecordNotFound.
+func BuscarCierre(db database.DBHandler, desde, hasta time.Time) (models.CierreComision, error) {
+	var cierre models.CierreComision
+	if err := db.Where("desde = ? AND hasta = ?", desde, hasta).First(&cierre); err != nil {
+		return cierre, err
+	}
+	return cierre, CargarComisiones(db, &cierre)
+}
+
+// CargarComisiones completa las comisiones congeladas de un cierre.
+func CargarComisiones(db database.DBHandler, cierre *models.CierreComision) error {
+	cierre.Comisiones = []models.ComisionLiquidada{}
+	return db.Where("cierre_id = ?", cierre.ID).Order("usuario_id").Find(&cierre.Comisiones)
+}
+
+// CerrarPeriodo calcula y guarda las comisiones de [desde, hasta). El
+// período tiene que haber terminado y no superponerse con otro cierre.
+func CerrarPeriodo(db database.DBHandler, desde, hasta time.Time, cerradoPor uint, ahora time.Time) (models.CierreComision, error) {
+	if !desde.Before(hasta) || hasta.After(ahora) {
+		return models.CierreComision{}, ErrPeriodoInvalido
+	}
+
+	var cierre models.CierreComision
+	err := db.Transaction(func(tx database.DBHandler) error {
+		var superpuesto models.CierreComision
+		err := tx.Where("desde < ? AND hasta > ?", hasta, desde).First(&superpuesto)
+		if err == nil {
+			return ErrPeriodoCerrado
+		}
+		if !errors.Is(err, gorm.ErrRecordNotFound) {
+			return err
+		}
+
+		comisiones, err := CalcularComisiones(tx, desde, hasta)
+		if err != nil {
+			return err
+		}
+		cierre = models.CierreComision{Desde: desde, Hasta: hasta, CerradoPor: cerradoPor}
+		if err := tx.Create(&cierre); err != nil {
+			return err
+		}
+		for i := range comisiones {
+			comisiones[i].CierreID = cierre.ID
+			if err := tx.Create(&comisiones[i]); err != nil {
+				return err
+			}
+		}
+		cierre.Comisiones = comisiones
+		return nil
+	})
+	if err != nil {
+		return models.CierreComision{}, err
+	}
+	return cierre, nil
+}
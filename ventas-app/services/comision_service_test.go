@@ -0,0 +1,136 @@
+package services
+
+import (
+	"testing"
+	"time"
+	"ventas-app/config"
+	"ventas-app/database"
+	"ventas-app/models"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+)
+
+func TestValidarEsquema(t *testing.T) {
+	assert.NoError(t, ValidarEsquema(models.EsquemaComision{Porcentaje: 5}))
+	assert.NoError(t, ValidarEsquema(models.EsquemaComision{Tramos: []models.TramoComision{{Desde: 0, Porcentaje: 1}, {Desde: 10, Porcentaje: 2}}}))
+
+	for _, e := range []models.EsquemaComision{
+		{Porcentaje: -1},
+		{Porcentaje: 101},
+		{Tramos: []models.TramoComision{{Desde: 10, Porcentaje: 1}, {Desde: 10, Porcentaje: 2}}},
+		{Tramos: []models.TramoComision{{Desde: 10, Porcentaje: 1}, {Desde: 5, Porcentaje: 2}}},
+		{Tramos: []models.TramoComision{{Desde: -1, Porcentaje: 1}}},
+	} {
+		assert.ErrorIs(t, ValidarEsquema(e), ErrEsquemaInvalido, "%+v", e)
+	}
+}
+
+func TestComisiones(t *testing.T) {
+	gdb, err := database.Open(config.DatabaseConfig{Driver: database.DriverSQLite})
+	require.NoError(t, err)
+	require.NoError(t, database.Migrate(gdb))
+	db := &database.GormDB{DB: gdb}
+
+	ana := models.Usuario{Nombre: "ana", Clave: "x", Rol: "vendedor"}
+	beto := models.Usuario{Nombre: "beto", Clave: "x", Rol: "vendedor"}
+	require.NoError(t, db.Create(&ana))
+	require.NoError(t, db.Create(&beto))
+
+	require.NoError(t, GuardarEsquema(db, &models.EsquemaComision{Nombre: "General", Porcentaje: 2}))
+	escalonado := models.EsquemaComision{Nombre: "Senior", UsuarioID: &beto.ID, Porcentaje: 3,
+		Tramos: []models.TramoComision{{Desde: 1000, Porcentaje: 5}}}
+	require.NoError(t, GuardarEsquema(db, &escalonado))
+	assert.ErrorIs(t, GuardarEsquema(db, &models.EsquemaComision{Nombre: "Otro", Porcentaje: 1}), ErrEsquemaDuplicado)
+
+	marzo := func(d int) time.Time { return time.Date(2026, time.March, d, 12, 0, 0, 0, time.UTC) }
+	for _, v := range []models.Venta{
+		{Model: gorm.Model{CreatedAt: marzo(2)}, UsuarioID: ana.ID, Cantidad: 1, Neto: 500},
+		{Model: gorm.Model{CreatedAt: marzo(5)}, UsuarioID: beto.ID, Cantidad: 2, Neto: 800},
+		{Model: gorm.Model{CreatedAt: marzo(9)}, UsuarioID: beto.ID, Cantidad: 1, Neto: 400},
+		{Model: gorm.Model{CreatedAt: marzo(10)}, Cantidad: 1, Neto: 999}, // sin vendedor
+		{Model: gorm.Model{CreatedAt: time.Date(2026, time.April, 1, 12, 0, 0, 0, time.UTC)}, UsuarioID: ana.ID, Cantidad: 1, Neto: 700},
+	} {
+		v := v
+		require.NoError(t, db.Create(&v))
+	}
+	// Devolución de beto en marzo: baja su base a 1000
+	require.NoError(t, db.Create(&models.Devolucion{Model: gorm.Model{CreatedAt: marzo(20)}, VentaID: 3, VendedorID: beto.ID, Cantidad: 1, Neto: 200}))
+
+	desde := time.Date(2026, time.March, 1, 0, 0, 0, 0, time.UTC)
+	hasta := time.Date(2026, time.April, 1, 0, 0, 0, 0, time.UTC)
+
+	comisiones, err := CalcularComisiones(db, desde, hasta)
+	require.NoError(t, err)
+	assert.Equal(t, []models.ComisionLiquidada{
+		{UsuarioID: ana.ID, Nombre: "ana", Ventas: 1, Neto: 500, Base: 500, Esquema: "General", Porcentaje: 2, Comision: 10},
+		{UsuarioID: beto.ID, Nombre: "beto", Ventas: 2, Neto: 1200, Devoluciones: 200, Base: 1000, Esquema: "Senior", Porcentaje: 5, Comision: 50},
+	}, comisiones)
+
+	t.Run("el cierre congela el resultado", func(t *testing.T) {
+		ahora := time.Date(2026, time.April, 2, 0, 0, 0, 0, time.UTC)
+		cierre, err := CerrarPeriodo(db, desde, hasta, ana.ID, ahora)
+		require.NoError(t, err)
+		assert.Len(t, cierre.Comisiones, 2)
+
+		escalonado.Tramos = nil
+		require.NoError(t, GuardarEsquema(db, &escalonado))
+
+		guardado, err := BuscarCierre(db, desde, hasta)
+		require.NoError(t, err)
+		require.Len(t, guardado.Comisiones, 2)
+		assert.Equal(t, 50.0, guardado.Comisiones[1].Comision)
+
+		provisorio, err := CalcularComisiones(db, desde, hasta)
+		require.NoError(t, err)
+		assert.Equal(t, 30.0, provisorio[1].Comision)
+
+		_, err = CerrarPeriodo(db, time.Date(2026, time.March, 15, 0, 0, 0, 0, time.UTC), ahora, ana.ID, ahora)
+		assert.ErrorIs(t, err, ErrPeriodoCerrado)
+	})
+
+	t.Run("porcentaje por categoría", func(t *testing.T) {
+		bebidas := models.Categoria{Nombre: "Bebidas"}
+		require.NoError(t, db.Create(&bebidas))
+		vinos := models.Categoria{Nombre: "Vinos", PadreID: &bebidas.ID}
+		require.NoError(t, db.Create(&vinos))
+		otra := models.Categoria{Nombre: "Almacén"}
+		require.NoError(t, db.Create(&otra))
+
+		vino := models.Producto{Nombre: "Malbec", CategoriaID: &vinos.ID}
+		yerba := models.Producto{Nombre: "Yerba", CategoriaID: &otra.ID}
+		require.NoError(t, db.Create(&vino))
+		require.NoError(t, db.Create(&yerba))
+
+		carla := models.Usuario{Nombre: "carla", Clave: "x", Rol: "vendedor"}
+		require.NoError(t, db.Create(&carla))
+		assert.ErrorIs(t, GuardarEsquema(db, &models.EsquemaComision{Nombre: "X", UsuarioID: &carla.ID, PorCategoria: map[uint]float64{999: 1}}), ErrClasificacionInvalida)
+		assert.ErrorIs(t, GuardarEsquema(db, &models.EsquemaComision{Nombre: "X", UsuarioID: &carla.ID, PorCategoria: map[uint]float64{bebidas.ID: 101}}), ErrEsquemaInvalido)
+		require.NoError(t, GuardarEsquema(db, &models.EsquemaComision{Nombre: "Bebidas al 10", UsuarioID: &carla.ID, Porcentaje: 2,
+			PorCategoria: map[uint]float64{bebidas.ID: 10}}))
+
+		mayo := func(d int) time.Time { return time.Date(2026, time.May, d, 12, 0, 0, 0, time.UTC) }
+		ventaVino := models.Venta{Model: gorm.Model{CreatedAt: mayo(2)}, UsuarioID: carla.ID, ProductoID: vino.ID, Cantidad: 2, Neto: 600}
+		require.NoError(t, db.Create(&ventaVino))
+		require.NoError(t, db.Create(&models.Venta{Model: gorm.Model{CreatedAt: mayo(3)}, UsuarioID: carla.ID, ProductoID: yerba.ID, Cantidad: 1, Neto: 500}))
+		// Devuelve un vino: baja la base de Bebidas, no la general
+		require.NoError(t, db.Create(&models.Devolucion{Model: gorm.Model{CreatedAt: mayo(4)}, VentaID: ventaVino.ID, VendedorID: carla.ID, Cantidad: 1, Neto: 300}))
+
+		comisiones, err := CalcularComisiones(db, time.Date(2026, time.May, 1, 0, 0, 0, 0, time.UTC), time.Date(2026, time.June, 1, 0, 0, 0, 0, time.UTC))
+		require.NoError(t, err)
+		require.Len(t, comisiones, 1)
+		assert.Equal(t, models.ComisionLiquidada{
+			UsuarioID: carla.ID, Nombre: "carla", Ventas: 2, Neto: 1100, Devoluciones: 300, Base: 800,
+			Esquema: "Bebidas al 10", Porcentaje: 2, Comision: 40, // 500 * 2% + 300 * 10%
+			Categorias: []models.ComisionCategoria{{CategoriaID: bebidas.ID, Base: 300, Porcentaje: 10, Comision: 30}},
+		}, comisiones[0])
+	})
+
+	t.Run("solo períodos terminados", func(t *testing.T) {
+		_, err := CerrarPeriodo(db, hasta, hasta.AddDate(0, 1, 0), ana.ID, hasta.AddDate(0, 0, 10))
+		assert.ErrorIs(t, err, ErrPeriodoInvalido)
+		_, err = CerrarPeriodo(db, hasta, desde, ana.ID, hasta.AddDate(1, 0, 0))
+		assert.ErrorIs(t, err, ErrPeriodoInvalido)
+	})
+}
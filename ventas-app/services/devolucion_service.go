@@ -0,0 +1,62 @@
+package services
+
+import (
+	"errors"
+	"ventas-app/database"
+	"ventas-app/models"
+)
+
+// ErrCantidadDevolucion: se quiere devolver más de lo vendido (descontando
+// devoluciones anteriores) o una cantidad no positiva.
+var ErrCantidadDevolucion = errors.New("cantidad a devolver inválida")
+
+// RegistrarDevolucion devuelve cantidad unidades de la venta: repone el stock
+// (del producto y, si la venta fue de una variante, el de ella) y guarda la
+// parte proporcional del neto y el IVA, en una transacción. La transacción
+// empieza bloqueando la fila de la venta, así otra devolución de la misma
+// venta espera a que esta termine antes de sumar lo ya devuelto. El stock se
+// repone con un incremento en la base, sin pisar ventas o compras del
+// producto que ocurran mientras tanto.
+func RegistrarDevolucion(db database.DBHandler, venta models.Venta, cantidad int, motivo string) (models.Devolucion, error) {
+	if cantidad <= 0 {
+		return models.Devolucion{}, ErrCantidadDevolucion
+	}
+
+	proporcion := float64(cantidad) / float64(venta.Cantidad)
+	devolucion := models.Devolucion{
+		VentaID:    venta.ID,
+		VendedorID: venta.UsuarioID,
+		Cantidad:   cantidad,
+		Neto:       centavos(venta.Neto * proporcion),
+		IVA:        centavos(venta.IVA * proporcion),
+		Motivo:     motivo,
+	}
+
+	err := db.Transaction(func(tx database.DBHandler) error {
+		var bloqueada models.Venta
+		if err := tx.ParaActualizar().First(&bloqueada, venta.ID); err != nil {
+			return err
+		}
+
+		var anteriores []models.Devolucion
+		if err := tx.Where("venta_id = ?", venta.ID).Find(&anteriores); err != nil {
+			return err
+		}
+		disponible := venta.Cantidad
+		for _, d := range anteriores {
+			disponible -= d.Cantidad
+		}
+		if cantidad > disponible {
+			return ErrCantidadDevolucion
+		}
+
+		if err := MoverStock(tx, venta.ProductoID, venta.VarianteID, cantidad); err != nil {
+			return err
+		}
+		return tx.Create(&devolucion)
+	})
+	if err != nil {
+		return models.Devolucion{}, err
+	}
+	return devolucion, nil
+}
@@ -0,0 +1,35 @@
+package services
+
+import (
+	"testing"
+	"ventas-app/mocks"
+	"ventas-app/models"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRegistrarDevolucion(t *testing.T) {
+	db := mocks.NewMemoryDB()
+	require.NoError(t, db.Create(&models.Producto{Nombre: "Mate", Stock: 7}))
+	venta := models.Venta{UsuarioID: 4, ProductoID: 1, Cantidad: 3, Neto: 300, IVA: 63, PrecioFinal: 363}
+	require.NoError(t, db.Create(&venta))
+
+	d, err := RegistrarDevolucion(db, venta, 2, "falla")
+	require.NoError(t, err)
+	assert.Equal(t, uint(4), d.VendedorID)
+	assert.Equal(t, 200.0, d.Neto)
+	assert.Equal(t, 42.0, d.IVA)
+
+	var p models.Producto
+	require.NoError(t, db.First(&p, uint(1)))
+	assert.Equal(t, 9, p.Stock)
+
+	_, err = RegistrarDevolucion(db, venta, 2, "")
+	assert.ErrorIs(t, err, ErrCantidadDevolucion, "ya se devolvieron 2 de 3")
+	_, err = RegistrarDevolucion(db, venta, 0, "")
+	assert.ErrorIs(t, err, ErrCantidadDevolucion)
+
+	_, err = RegistrarDevolucion(db, venta, 1, "")
+	assert.NoError(t, err)
+}
@@ -0,0 +1,243 @@
+package services
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"time"
+	"ventas-app/database"
+	"ventas-app/models"
+
+	"github.com/xuri/excelize/v2"
+)
+
+// FormatoJSONLines es un objeto JSON por línea, como los que devuelven los
+// listados.
+const FormatoJSONLines = "json-lines"
+
+// LoteExportacion es cuántas filas se leen de la base por consulta.
+var LoteExportacion = 500
+
+// ErrExportacionInvalida: entidad o formato que Exportar no conoce.
+var ErrExportacionInvalida = errors.New("exportación inválida")
+
+// Exportacion describe una entidad exportable: sus columnas para CSV/XLSX
+// y cómo recorrerla.
+type Exportacion struct {
+	Columnas []string
+	recorrer func(db database.DBHandler, filtro FiltroReporte, fn func(registro interface{}, valores []interface{}) error) error
+}
+
+// Exportaciones son las entidades que se pueden exportar, por nombre.
+var Exportaciones = map[string]Exportacion{
+	"productos": {
+		Columnas: []string{"id", "creado", "nombre", "sku", "costo", "precio", "stock", "categoria_id", "marca_id"},
+		recorrer: func(db database.DBHandler, filtro FiltroReporte, fn func(interface{}, []interface{}) error) error {
+			return recorrerLotes(db, filtro, func(p models.Producto) uint { return p.ID }, func(p models.Producto) error {
+				sku := ""
+				if p.SKU != nil {
+					sku = *p.SKU
+				}
+				return fn(p, []interface{}{p.ID, p.CreatedAt, p.Nombre, sku, p.Costo, p.Precio, p.Stock, idOpcional(p.CategoriaID), idOpcional(p.MarcaID)})
+			})
+		},
+	},
+	"ventas": {
+		Columnas: []string{"id", "fecha", "usuario_id", "producto_id", "cantidad", "descuento", "neto", "iva", "precio_final", "costo_unit", "variante_id"},
+		recorrer: func(db database.DBHandler, filtro FiltroReporte, fn func(interface{}, []interface{}) error) error {
+			return recorrerLotes(db, filtro, func(v models.Venta) uint { return v.ID }, func(v models.Venta) error {
+				return fn(v, []interface{}{v.ID, v.CreatedAt, v.UsuarioID, v.ProductoID, v.Cantidad, v.Descuento, v.Neto, v.IVA, v.PrecioFinal, v.CostoUnit, idOpcional(v.VarianteID)})
+			})
+		},
+	},
+	"compras": {
+		Columnas: []string{"id", "fecha", "usuario_id", "producto_id", "cantidad", "costo_unit", "variante_id"},
+		recorrer: func(db database.DBHandler, filtro FiltroReporte, fn func(interface{}, []interface{}) error) error {
+			return recorrerLotes(db, filtro, func(c models.Compra) uint { return c.ID }, func(c models.Compra) error {
+				return fn(c, []interface{}{c.ID, c.CreatedAt, c.UsuarioID, c.ProductoID, c.Cantidad, c.CostoUnit, idOpcional(c.VarianteID)})
+			})
+		},
+	},
+}
+
+// recorrerLotes lee la tabla de T por id en lotes de LoteExportacion (id >
+// último visto), así nunca hay más de un lote en memoria.
+func recorrerLotes[T any](db database.DBHandler, filtro FiltroReporte, id func(T) uint, fn func(T) error) error {
+	var ultimo uint
+	for {
+		q := db.Where("id > ?", ultimo)
+		if !filtro.Desde.IsZero() {
+			q = q.Where("created_at >= ?", filtro.Desde)
+		}
+		if !filtro.Hasta.IsZero() {
+			q = q.Where("created_at < ?", filtro.Hasta)
+		}
+		var lote []T
+		if err := q.Order("id").Limit(LoteExportacion).Find(&lote); err != nil {
+			return err
+		}
+		for _, r := range lote {
+			if err := fn(r); err != nil {
+				return err
+			}
+		}
+		if len(lote) < LoteExportacion {
+			return nil
+		}
+		ultimo = id(lote[len(lote)-1])
+	}
+}
+
+// EscritorExportacion escribe las filas en un formato. Cerrar termina el
+// archivo (el XLSX se arma recién ahí).
+type EscritorExportacion interface {
+	Encabezado(columnas []string) error
+	Fila(registro interface{}, valores []interface{}) error
+	Cerrar() error
+}
+
+// NuevoEscritor devuelve el escritor de formato (csv, xlsx o json-lines)
+// sobre w.
+func NuevoEscritor(formato string, w io.Writer) (EscritorExportacion, error) {
+	switch formato {
+	case FormatoCSV:
+		return &escritorCSV{w: csv.NewWriter(w)}, nil
+	case FormatoXLSX:
+		return &escritorXLSX{w: w}, nil
+	case FormatoJSONLines:
+		return &escritorJSONLines{enc: json.NewEncoder(w)}, nil
+	}
+	return nil, fmt.Errorf("%w: formato %q", ErrExportacionInvalida, formato)
+}
+
+// Exportar escribe en escritor las filas de entidad dentro del rango de
+// fechas de filtro (Agrupar no se usa), sin cargar la tabla en memoria.
+func Exportar(db database.DBHandler, entidad string, filtro FiltroReporte, escritor EscritorExportacion) error {
+	exp, ok := Exportaciones[entidad]
+	if !ok {
+		return fmt.Errorf("%w: entidad %q", ErrExportacionInvalida, entidad)
+	}
+	if err := escritor.Encabezado(exp.Columnas); err != nil {
+		return err
+	}
+	if err := exp.recorrer(db, filtro, escritor.Fila); err != nil {
+		return err
+	}
+	return escritor.Cerrar()
+}
+
+type escritorCSV struct {
+	w     *csv.Writer
+	filas int
+}
+
+func (e *escritorCSV) Encabezado(columnas []string) error {
+	return e.w.Write(columnas)
+}
+
+func (e *escritorCSV) Fila(_ interface{}, valores []interface{}) error {
+	celdas := make([]string, len(valores))
+	for i, v := range valores {
+		celdas[i] = textoCelda(v)
+	}
+	if err := e.w.Write(celdas); err != nil {
+		return err
+	}
+	// Se vacía cada tanto para que la respuesta salga mientras se lee
+	if e.filas++; e.filas%LoteExportacion == 0 {
+		e.w.Flush()
+		return e.w.Error()
+	}
+	return nil
+}
+
+func (e *escritorCSV) Cerrar() error {
+	e.w.Flush()
+	return e.w.Error()
+}
+
+type escritorJSONLines struct {
+	enc *json.Encoder
+}
+
+func (e *escritorJSONLines) Encabezado([]string) error { return nil }
+
+func (e *escritorJSONLines) Fila(registro interface{}, _ []interface{}) error {
+	return e.enc.Encode(registro)
+}
+
+func (e *escritorJSONLines) Cerrar() error { return nil }
+
+// escritorXLSX usa el StreamWriter de excelize, que pasa a un archivo
+// temporal las filas que no entran en su buffer; el zip se escribe en w al
+// cerrar.
+type escritorXLSX struct {
+	w      io.Writer
+	libro  *excelize.File
+	stream *excelize.StreamWriter
+	fila   int
+}
+
+func (e *escritorXLSX) Encabezado(columnas []string) error {
+	e.libro = excelize.NewFile()
+	stream, err := e.libro.NewStreamWriter("Sheet1")
+	if err != nil {
+		return err
+	}
+	e.stream = stream
+	celdas := make([]interface{}, len(columnas))
+	for i, c := range columnas {
+		celdas[i] = c
+	}
+	return e.agregar(celdas)
+}
+
+func (e *escritorXLSX) Fila(_ interface{}, valores []interface{}) error {
+	celdas := make([]interface{}, len(valores))
+	for i, v := range valores {
+		if t, ok := v.(time.Time); ok {
+			v = t.UTC().Format(time.RFC3339)
+		}
+		celdas[i] = v
+	}
+	return e.agregar(celdas)
+}
+
+func (e *escritorXLSX) agregar(celdas []interface{}) error {
+	e.fila++
+	celda, err := excelize.CoordinatesToCellName(1, e.fila)
+	if err != nil {
+		return err
+	}
+	return e.stream.SetRow(celda, celdas)
+}
+
+func (e *escritorXLSX) Cerrar() error {
+	defer e.libro.Close()
+	if err := e.stream.Flush(); err != nil {
+		return err
+	}
+	return e.libro.Write(e.w)
+}
+
+// idOpcional deja vacía la celda de una referencia nil.
+func idOpcional(id *uint) interface{} {
+	if id == nil {
+		return ""
+	}
+	return *id
+}
+
+func textoCelda(v interface{}) string {
+	switch x := v.(type) {
+	case time.Time:
+		return x.UTC().Format(time.RFC3339)
+	case float64:
+		return strconv.FormatFloat(x, 'f', -1, 64)
+	default:
+		return fmt.Sprint(x)
+	}
+}
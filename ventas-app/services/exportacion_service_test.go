@@ -0,0 +1,70 @@
+package services
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+	"ventas-app/mocks"
+	"ventas-app/models"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/xuri/excelize/v2"
+	"gorm.io/gorm"
+)
+
+func TestExportar(t *testing.T) {
+	db := mocks.NewMemoryDB()
+	dia := func(d int) gorm.Model {
+		return gorm.Model{CreatedAt: time.Date(2026, time.March, d, 10, 0, 0, 0, time.UTC)}
+	}
+	for d := 1; d <= 5; d++ {
+		require.NoError(t, db.Create(&models.Compra{Model: dia(d), UsuarioID: 1, ProductoID: 1, Cantidad: d, CostoUnit: 10.5}))
+	}
+	anterior := LoteExportacion
+	LoteExportacion = 2
+	defer func() { LoteExportacion = anterior }()
+	filtro := FiltroReporte{Desde: time.Date(2026, time.March, 2, 0, 0, 0, 0, time.UTC), Hasta: time.Date(2026, time.March, 5, 0, 0, 0, 0, time.UTC)}
+
+	t.Run("csv en lotes con filtro de fecha", func(t *testing.T) {
+		var buf bytes.Buffer
+		escritor, err := NuevoEscritor(FormatoCSV, &buf)
+		require.NoError(t, err)
+		require.NoError(t, Exportar(db, "compras", filtro, escritor))
+		assert.Equal(t, "id,fecha,usuario_id,producto_id,cantidad,costo_unit,variante_id\n"+
+			"2,2026-03-02T10:00:00Z,1,1,2,10.5,\n"+
+			"3,2026-03-03T10:00:00Z,1,1,3,10.5,\n"+
+			"4,2026-03-04T10:00:00Z,1,1,4,10.5,\n", buf.String())
+	})
+
+	t.Run("json lines", func(t *testing.T) {
+		var buf bytes.Buffer
+		escritor, err := NuevoEscritor(FormatoJSONLines, &buf)
+		require.NoError(t, err)
+		require.NoError(t, Exportar(db, "compras", FiltroReporte{}, escritor))
+		lineas := strings.Split(strings.TrimSpace(buf.String()), "\n")
+		require.Len(t, lineas, 5)
+		assert.Contains(t, lineas[4], `"cantidad":5`)
+	})
+
+	t.Run("xlsx", func(t *testing.T) {
+		var buf bytes.Buffer
+		escritor, err := NuevoEscritor(FormatoXLSX, &buf)
+		require.NoError(t, err)
+		require.NoError(t, Exportar(db, "compras", filtro, escritor))
+		libro, err := excelize.OpenReader(&buf)
+		require.NoError(t, err)
+		filas, err := libro.GetRows("Sheet1")
+		require.NoError(t, err)
+		require.Len(t, filas, 4)
+		assert.Equal(t, []string{"4", "2026-03-04T10:00:00Z", "1", "1", "4", "10.5"}, filas[3])
+	})
+
+	t.Run("entidad o formato desconocidos", func(t *testing.T) {
+		_, err := NuevoEscritor("pdf", &bytes.Buffer{})
+		assert.ErrorIs(t, err, ErrExportacionInvalida)
+		escritor, _ := NuevoEscritor(FormatoCSV, &bytes.Buffer{})
+		assert.ErrorIs(t, Exportar(db, "usuarios", FiltroReporte{}, escritor), ErrExportacionInvalida)
+	})
+}
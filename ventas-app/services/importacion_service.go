@@ -0,0 +1,412 @@
+package services
+
+import (
+	"bytes"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"ventas-app/database"
+	"ventas-app/models"
+
+	"github.com/xuri/excelize/v2"
+	"gorm.io/gorm"
+)
+
+// Formatos de archivo que acepta LeerArchivo.
+const (
+	FormatoCSV  = "csv"
+	FormatoXLSX = "xlsx"
+)
+
+// CamposImportacion son los campos de Producto que se pueden importar.
+var CamposImportacion = []string{"nombre", "sku", "costo", "precio", "stock"}
+
+var (
+	// ErrFormatoImportacion: el archivo no es CSV/XLSX o no se puede leer.
+	ErrFormatoImportacion = errors.New("formato de archivo no soportado")
+	// ErrMapeoImportacion: el mapeo nombra un campo desconocido, una columna
+	// que no está en el encabezado o falta la columna de la clave.
+	ErrMapeoImportacion = errors.New("mapeo de columnas inválido")
+)
+
+// OpcionesImportacion configuran ImportarProductos. Mapeo va de campo
+// (CamposImportacion) a encabezado del archivo; los campos sin mapeo se
+// buscan por su propio nombre, sin distinguir mayúsculas. Clave es "nombre"
+// o "sku": por ese campo se decide si la fila crea o actualiza un producto.
+// Con DryRun se valida todo pero no se escribe nada.
+type OpcionesImportacion struct {
+	Mapeo  map[string]string
+	Clave  string
+	DryRun bool
+}
+
+// ErrorFila es un problema de una fila. Fila es el número en la planilla
+// (el encabezado es la 1).
+type ErrorFila struct {
+	Fila    int    `json:"fila"`
+	Columna string `json:"columna,omitempty"`
+	Error   string `json:"error"`
+}
+
+// ResultadoImportacion resume la importación. Si hay Errores no se aplicó
+// ninguna fila.
+type ResultadoImportacion struct {
+	Filas        int         `json:"filas"`
+	Creados      int         `json:"creados"`
+	Actualizados int         `json:"actualizados"`
+	DryRun       bool        `json:"dry_run"`
+	Aplicado     bool        `json:"aplicado"`
+	Errores      []ErrorFila `json:"errores"`
+}
+
+// LeerArchivo devuelve las filas (con el encabezado) de un CSV, separado por
+// comas o punto y coma, o de la primera hoja de un XLSX.
+func LeerArchivo(r io.Reader, formato string) ([][]string, error) {
+	switch strings.ToLower(formato) {
+	case FormatoCSV:
+		datos, err := io.ReadAll(r)
+		if err != nil {
+			return nil, err
+		}
+		datos = bytes.TrimPrefix(datos, []byte("\xef\xbb\xbf")) // BOM de Excel
+		lector := csv.NewReader(bytes.NewReader(datos))
+		primera, _, _ := bytes.Cut(datos, []byte("\n"))
+		if bytes.Count(primera, []byte(";")) > bytes.Count(primera, []byte(",")) {
+			lector.Comma = ';'
+		}
+		lector.FieldsPerRecord = -1
+		filas, err := lector.ReadAll()
+		if err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrFormatoImportacion, err)
+		}
+		return filas, nil
+	case FormatoXLSX:
+		libro, err := excelize.OpenReader(r, excelize.Options{RawCellValue: true})
+		if err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrFormatoImportacion, err)
+		}
+		defer libro.Close()
+		hojas := libro.GetSheetList()
+		if len(hojas) == 0 {
+			return nil, nil
+		}
+		filas, err := libro.GetRows(hojas[0], excelize.Options{RawCellValue: true})
+		if err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrFormatoImportacion, err)
+		}
+		return filas, nil
+	}
+	return nil, ErrFormatoImportacion
+}
+
+// ParsearMapeo lee un mapeo "campo=Encabezado,campo=Encabezado" (el formato
+// del parámetro mapeo y del flag -mapeo). Vacío es sin mapeo.
+func ParsearMapeo(s string) (map[string]string, error) {
+	mapeo := map[string]string{}
+	for _, par := range strings.Split(s, ",") {
+		if strings.TrimSpace(par) == "" {
+			continue
+		}
+		campo, titulo, ok := strings.Cut(par, "=")
+		campo = strings.ToLower(strings.TrimSpace(campo))
+		if !ok || campo == "" || strings.TrimSpace(titulo) == "" {
+			return nil, fmt.Errorf("%w: %q no es campo=columna", ErrMapeoImportacion, par)
+		}
+		if !campoImportable(campo) {
+			return nil, fmt.Errorf("%w: campo desconocido %q", ErrMapeoImportacion, campo)
+		}
+		mapeo[campo] = strings.TrimSpace(titulo)
+	}
+	return mapeo, nil
+}
+
+// filaImportada es una fila ya validada; los punteros nil son columnas que
+// el archivo no trae y no se tocan al actualizar.
+type filaImportada struct {
+	numero int
+	nombre *string
+	sku    *string
+	costo  *float64
+	precio *float64
+	stock  *int
+}
+
+// ImportarProductos valida todas las filas y, si no hay errores (y no es
+// DryRun), crea o actualiza los productos en una sola transacción: o se
+// aplican todas o ninguna. El stock importado reemplaza al actual, salvo en
+// productos con variantes, donde es un error de la fila.
+func ImportarProductos(db database.DBHandler, filas [][]string, opciones OpcionesImportacion) (ResultadoImportacion, error) {
+	res := ResultadoImportacion{DryRun: opciones.DryRun, Errores: []ErrorFila{}}
+	if opciones.Clave == "" {
+		opciones.Clave = "nombre"
+	}
+	if opciones.Clave != "nombre" && opciones.Clave != "sku" {
+		return res, fmt.Errorf("%w: la clave debe ser nombre o sku", ErrMapeoImportacion)
+	}
+	if len(filas) == 0 {
+		return res, fmt.Errorf("%w: el archivo está vacío", ErrMapeoImportacion)
+	}
+
+	columnas, err := resolverColumnas(filas[0], opciones)
+	if err != nil {
+		return res, err
+	}
+
+	var validas []filaImportada
+	vistas, skus := map[string]int{}, map[string]int{}
+	for i, celdas := range filas[1:] {
+		numero := i + 2
+		if filaVacia(celdas) {
+			continue
+		}
+		res.Filas++
+
+		fila, errs := parsearFila(numero, celdas, columnas)
+		clave := valorClave(fila, opciones.Clave)
+		switch {
+		case clave == "":
+			errs = append(errs, ErrorFila{Fila: numero, Columna: opciones.Clave, Error: "obligatorio"})
+		case vistas[strings.ToLower(clave)] != 0:
+			errs = append(errs, ErrorFila{Fila: numero, Columna: opciones.Clave,
+				Error: fmt.Sprintf("repetido (ya está en la fila %d)", vistas[strings.ToLower(clave)])})
+		default:
+			vistas[strings.ToLower(clave)] = numero
+		}
+		if fila.sku != nil && opciones.Clave != "sku" {
+			if previa := skus[strings.ToLower(*fila.sku)]; previa != 0 {
+				errs = append(errs, ErrorFila{Fila: numero, Columna: "sku",
+					Error: fmt.Sprintf("repetido (ya está en la fila %d)", previa)})
+			} else {
+				skus[strings.ToLower(*fila.sku)] = numero
+			}
+		}
+		res.Errores = append(res.Errores, errs...)
+		if len(errs) == 0 {
+			validas = append(validas, fila)
+		}
+	}
+
+	// Se busca en la base aunque haya errores, para que el reporte diga
+	// cuántos productos se crearían y cuántos se actualizarían.
+	aplicar := func(tx database.DBHandler, escribir bool) error {
+		res.Creados, res.Actualizados = 0, 0
+		for _, fila := range validas {
+			var producto models.Producto
+			err := tx.Where(opciones.Clave+" = ?", valorClave(fila, opciones.Clave)).First(&producto)
+			nuevo := errors.Is(err, gorm.ErrRecordNotFound)
+			if err != nil && !nuevo {
+				return err
+			}
+			if nuevo && fila.nombre == nil {
+				res.Errores = append(res.Errores, ErrorFila{Fila: fila.numero, Columna: "nombre", Error: "obligatorio para crear un producto"})
+				continue
+			}
+			if fila.sku != nil && !nuevo && opciones.Clave == "nombre" {
+				var otro models.Producto
+				err := tx.Where("sku = ?", *fila.sku).First(&otro)
+				if err == nil && otro.ID != producto.ID {
+					res.Errores = append(res.Errores, ErrorFila{Fila: fila.numero, Columna: "sku", Error: "ya lo usa otro producto"})
+					continue
+				}
+			}
+			// Con variantes el stock del producto es la suma de las de ellas:
+			// solo se acepta si no cambia
+			if fila.stock != nil && !nuevo && *fila.stock != producto.Stock {
+				_, err := ResolverVariante(tx, producto, nil)
+				if errors.Is(err, ErrVarianteRequerida) {
+					res.Errores = append(res.Errores, ErrorFila{Fila: fila.numero, Columna: "stock", Error: "el producto tiene variantes: el stock se carga por variante"})
+					continue
+				}
+				if err != nil {
+					return err
+				}
+			}
+
+			aplicarFila(&producto, fila)
+			if nuevo {
+				res.Creados++
+			} else {
+				res.Actualizados++
+			}
+			if !escribir {
+				continue
+			}
+			if nuevo {
+				err = tx.Create(&producto)
+			} else {
+				err = tx.Save(&producto)
+			}
+			if errors.Is(err, gorm.ErrDuplicatedKey) {
+				res.Errores = append(res.Errores, ErrorFila{Fila: fila.numero, Error: "duplicado: el SKU ya existe"})
+				continue
+			}
+			if err != nil {
+				return err
+			}
+		}
+		if len(res.Errores) > 0 {
+			return errImportacionConErrores
+		}
+		return nil
+	}
+
+	if opciones.DryRun || len(res.Errores) > 0 {
+		if err := aplicar(db, false); err != nil && !errors.Is(err, errImportacionConErrores) {
+			return res, err
+		}
+		return res, nil
+	}
+
+	err = db.Transaction(func(tx database.DBHandler) error {
+		return aplicar(tx, true)
+	})
+	if errors.Is(err, errImportacionConErrores) {
+		return res, nil
+	}
+	if err != nil {
+		return res, err
+	}
+	res.Aplicado = true
+	return res, nil
+}
+
+// errImportacionConErrores revierte la transacción cuando alguna fila falla
+// al escribirla; los detalles quedan en ResultadoImportacion.Errores.
+var errImportacionConErrores = errors.New("importación con errores")
+
+// resolverColumnas devuelve el índice de columna de cada campo presente.
+func resolverColumnas(encabezado []string, opciones OpcionesImportacion) (map[string]int, error) {
+	indices := map[string]int{}
+	for i, titulo := range encabezado {
+		indices[strings.ToLower(strings.TrimSpace(titulo))] = i
+	}
+
+	columnas := map[string]int{}
+	for _, campo := range CamposImportacion {
+		titulo, mapeado := opciones.Mapeo[campo]
+		if !mapeado {
+			titulo = campo
+		}
+		i, ok := indices[strings.ToLower(strings.TrimSpace(titulo))]
+		if !ok {
+			if mapeado {
+				return nil, fmt.Errorf("%w: no hay columna %q para %s", ErrMapeoImportacion, titulo, campo)
+			}
+			continue
+		}
+		columnas[campo] = i
+	}
+	for campo := range opciones.Mapeo {
+		if !campoImportable(campo) {
+			return nil, fmt.Errorf("%w: campo desconocido %q", ErrMapeoImportacion, campo)
+		}
+	}
+	if _, ok := columnas[opciones.Clave]; !ok {
+		return nil, fmt.Errorf("%w: falta la columna de la clave (%s)", ErrMapeoImportacion, opciones.Clave)
+	}
+	return columnas, nil
+}
+
+func campoImportable(campo string) bool {
+	for _, c := range CamposImportacion {
+		if c == campo {
+			return true
+		}
+	}
+	return false
+}
+
+func parsearFila(numero int, celdas []string, columnas map[string]int) (filaImportada, []ErrorFila) {
+	fila := filaImportada{numero: numero}
+	var errs []ErrorFila
+	celda := func(campo string) (string, bool) {
+		i, ok := columnas[campo]
+		if !ok || i >= len(celdas) {
+			return "", ok
+		}
+		return strings.TrimSpace(celdas[i]), true
+	}
+	numeroDe := func(campo string) *float64 {
+		v, ok := celda(campo)
+		if !ok || v == "" {
+			return nil
+		}
+		n, err := parsearNumero(v)
+		if err != nil || n < 0 {
+			errs = append(errs, ErrorFila{Fila: numero, Columna: campo, Error: fmt.Sprintf("número inválido %q", v)})
+			return nil
+		}
+		return &n
+	}
+
+	if v, ok := celda("nombre"); ok && v != "" {
+		fila.nombre = &v
+	}
+	if v, ok := celda("sku"); ok && v != "" {
+		if err := models.ValidarSKU(v); err != nil {
+			errs = append(errs, ErrorFila{Fila: numero, Columna: "sku", Error: fmt.Sprintf("SKU inválido %q", v)})
+		} else {
+			fila.sku = &v
+		}
+	}
+	fila.costo = numeroDe("costo")
+	fila.precio = numeroDe("precio")
+	if v, ok := celda("stock"); ok && v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 0 {
+			errs = append(errs, ErrorFila{Fila: numero, Columna: "stock", Error: fmt.Sprintf("cantidad inválida %q", v)})
+		} else {
+			fila.stock = &n
+		}
+	}
+	return fila, errs
+}
+
+// parsearNumero acepta "1234.5" y, como exporta Excel en español, "1234,5".
+func parsearNumero(v string) (float64, error) {
+	if !strings.Contains(v, ".") {
+		v = strings.Replace(v, ",", ".", 1)
+	}
+	return strconv.ParseFloat(v, 64)
+}
+
+func valorClave(fila filaImportada, clave string) string {
+	p := fila.nombre
+	if clave == "sku" {
+		p = fila.sku
+	}
+	if p == nil {
+		return ""
+	}
+	return *p
+}
+
+func aplicarFila(p *models.Producto, fila filaImportada) {
+	if fila.nombre != nil {
+		p.Nombre = *fila.nombre
+	}
+	if fila.sku != nil {
+		p.SKU = fila.sku
+	}
+	if fila.costo != nil {
+		p.Costo = *fila.costo
+	}
+	if fila.precio != nil {
+		p.Precio = *fila.precio
+	}
+	if fila.stock != nil {
+		p.Stock = *fila.stock
+	}
+}
+
+func filaVacia(celdas []string) bool {
+	for _, c := range celdas {
+		if strings.TrimSpace(c) != "" {
+			return false
+		}
+	}
+	return true
+}
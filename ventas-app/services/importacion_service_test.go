@@ -0,0 +1,116 @@
+package services
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"ventas-app/mocks"
+	"ventas-app/models"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/xuri/excelize/v2"
+)
+
+func TestLeerArchivo(t *testing.T) {
+	filas, err := LeerArchivo(strings.NewReader("\xef\xbb\xbfNombre;Precio\nMate;100,5\n"), FormatoCSV)
+	require.NoError(t, err)
+	assert.Equal(t, [][]string{{"Nombre", "Precio"}, {"Mate", "100,5"}}, filas)
+
+	libro := excelize.NewFile()
+	require.NoError(t, libro.SetSheetRow("Sheet1", "A1", &[]interface{}{"Producto", "Código", "Precio"}))
+	require.NoError(t, libro.SetSheetRow("Sheet1", "A2", &[]interface{}{"Termo", "T-1", 300.25}))
+	var buf bytes.Buffer
+	require.NoError(t, libro.Write(&buf))
+	filas, err = LeerArchivo(&buf, FormatoXLSX)
+	require.NoError(t, err)
+	assert.Equal(t, [][]string{{"Producto", "Código", "Precio"}, {"Termo", "T-1", "300.25"}}, filas)
+
+	_, err = LeerArchivo(strings.NewReader("x"), "ods")
+	assert.ErrorIs(t, err, ErrFormatoImportacion)
+}
+
+func TestImportarProductos(t *testing.T) {
+	sku := "M-1"
+	db := mocks.NewMemoryDB()
+	require.NoError(t, db.Create(&models.Producto{Nombre: "Mate", SKU: &sku, Costo: 50, Precio: 90, Stock: 3}))
+
+	filas := [][]string{
+		{"Código", "Producto", "Precio", "Stock"},
+		{"M-1", "Mate calabaza", "120", "10"},
+		{"T-1", "Termo", "300", "4"},
+		{"", "", "", ""},
+	}
+	opciones := OpcionesImportacion{Mapeo: map[string]string{"sku": "Código", "nombre": "Producto"}, Clave: "sku", DryRun: true}
+
+	t.Run("dry run no escribe", func(t *testing.T) {
+		res, err := ImportarProductos(db, filas, opciones)
+		require.NoError(t, err)
+		assert.Equal(t, ResultadoImportacion{Filas: 2, Creados: 1, Actualizados: 1, DryRun: true, Errores: []ErrorFila{}}, res)
+		var productos []models.Producto
+		require.NoError(t, db.Find(&productos))
+		assert.Len(t, productos, 1)
+	})
+
+	t.Run("errores por fila no aplican nada", func(t *testing.T) {
+		conErrores := append(filas[:3:3], []string{"T-1", "Termo 2", "abc", "-1"})
+		res, err := ImportarProductos(db, conErrores, OpcionesImportacion{Mapeo: opciones.Mapeo, Clave: "sku"})
+		require.NoError(t, err)
+		assert.False(t, res.Aplicado)
+		assert.Equal(t, []ErrorFila{
+			{Fila: 4, Columna: "precio", Error: `número inválido "abc"`},
+			{Fila: 4, Columna: "stock", Error: `cantidad inválida "-1"`},
+			{Fila: 4, Columna: "sku", Error: "repetido (ya está en la fila 3)"},
+		}, res.Errores)
+		var productos []models.Producto
+		require.NoError(t, db.Find(&productos))
+		assert.Len(t, productos, 1)
+	})
+
+	t.Run("aplica por sku", func(t *testing.T) {
+		res, err := ImportarProductos(db, filas, OpcionesImportacion{Mapeo: opciones.Mapeo, Clave: "sku"})
+		require.NoError(t, err)
+		assert.True(t, res.Aplicado)
+		assert.Equal(t, 1, res.Creados)
+		assert.Equal(t, 1, res.Actualizados)
+
+		var mate models.Producto
+		require.NoError(t, db.First(&mate, uint(1)))
+		assert.Equal(t, "Mate calabaza", mate.Nombre)
+		assert.Equal(t, 50.0, mate.Costo, "las columnas que no trae el archivo no cambian")
+		assert.Equal(t, 120.0, mate.Precio)
+		assert.Equal(t, 10, mate.Stock)
+		var termo models.Producto
+		require.NoError(t, db.Where("sku = ?", "T-1").First(&termo))
+		assert.Equal(t, "Termo", termo.Nombre)
+	})
+
+	t.Run("stock de producto con variantes", func(t *testing.T) {
+		var mate models.Producto
+		require.NoError(t, db.First(&mate, uint(1)))
+		require.NoError(t, db.Create(&models.Variante{ProductoID: mate.ID, Stock: mate.Stock}))
+
+		sinCambio := [][]string{filas[0], {"M-1", "Mate", "120", "10"}}
+		res, err := ImportarProductos(db, sinCambio, OpcionesImportacion{Mapeo: opciones.Mapeo, Clave: "sku"})
+		require.NoError(t, err)
+		assert.True(t, res.Aplicado, "el mismo stock que ya tiene no es un error")
+
+		conStock := [][]string{filas[0], {"M-1", "Mate", "120", "25"}}
+		res, err = ImportarProductos(db, conStock, OpcionesImportacion{Mapeo: opciones.Mapeo, Clave: "sku"})
+		require.NoError(t, err)
+		assert.False(t, res.Aplicado)
+		assert.Equal(t, []ErrorFila{{Fila: 2, Columna: "stock", Error: "el producto tiene variantes: el stock se carga por variante"}}, res.Errores)
+		require.NoError(t, db.First(&mate, uint(1)))
+		assert.Equal(t, 10, mate.Stock)
+	})
+
+	t.Run("mapeo inválido", func(t *testing.T) {
+		_, err := ImportarProductos(db, filas, OpcionesImportacion{Mapeo: map[string]string{"precio": "PVP"}})
+		assert.ErrorIs(t, err, ErrMapeoImportacion)
+		_, err = ParsearMapeo("nombre=Producto,color=Rojo")
+		assert.ErrorIs(t, err, ErrMapeoImportacion)
+		mapeo, err := ParsearMapeo("sku=Código, nombre = Producto")
+		require.NoError(t, err)
+		assert.Equal(t, map[string]string{"sku": "Código", "nombre": "Producto"}, mapeo)
+	})
+}
@@ -0,0 +1,215 @@
+package services
+
+import (
+	"errors"
+	"sort"
+	"time"
+	"ventas-app/database"
+	"ventas-app/models"
+
+	"gorm.io/gorm"
+)
+
+// ErrSinSnapshot: no hay snapshot de inventario en o antes de la fecha pedida.
+var ErrSinSnapshot = errors.New("no hay snapshot de inventario para esa fecha")
+
+// Fuentes de una ValuacionInventario.
+const (
+	FuenteActual   = "actual"
+	FuenteSnapshot = "snapshot"
+)
+
+// ItemInventario es la valuación de un producto: Cantidad × CostoUnit.
+type ItemInventario struct {
+	ProductoID  uint    `json:"producto_id"`
+	Nombre      string  `json:"nombre"`
+	CategoriaID *uint   `json:"categoria_id,omitempty"`
+	Cantidad    int     `json:"cantidad"`
+	CostoUnit   float64 `json:"costo_unit"`
+	Valor       float64 `json:"valor"`
+}
+
+// SubtotalCategoria suma los productos de una categoría (CategoriaID nil:
+// sin categoría). En un snapshot es la categoría que tenían al tomarlo.
+type SubtotalCategoria struct {
+	CategoriaID *uint   `json:"categoria_id"`
+	Nombre      string  `json:"nombre"`
+	Unidades    int     `json:"unidades"`
+	Valor       float64 `json:"valor"`
+}
+
+// ValuacionInventario es el valor del stock, por producto y por categoría.
+// Fuente indica si sale de los productos (actual) o de un snapshot; en ese
+// caso Fecha es la del snapshot usado y TomadoEn cuándo se tomó.
+type ValuacionInventario struct {
+	Fuente     string              `json:"fuente"`
+	Fecha      *time.Time          `json:"fecha,omitempty"`
+	TomadoEn   *time.Time          `json:"tomado_en,omitempty"`
+	Productos  []ItemInventario    `json:"productos"`
+	Categorias []SubtotalCategoria `json:"categorias"`
+	Unidades   int                 `json:"unidades"`
+	Valor      float64             `json:"valor"`
+}
+
+// DiaSnapshot es la fecha (00:00 UTC) del día de t en su zona horaria.
+func DiaSnapshot(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+}
+
+// DiaCierre es el último día terminado a la hora ahora: el que guarda
+// TomarSnapshotInventario.
+func DiaCierre(ahora time.Time) time.Time {
+	return DiaSnapshot(ahora).AddDate(0, 0, -1)
+}
+
+// TomarSnapshotInventario guarda el stock y costo de cada producto como el
+// cierre del día anterior a ahora: el snapshot con fecha D es el stock al
+// terminar D, así que se toma en cuanto empieza D+1. Si ese día ya tiene
+// snapshot no hace nada, para que la foto no cambie; devuelve cuántas filas
+// guardó.
+func TomarSnapshotInventario(db database.DBHandler, ahora time.Time) (int, error) {
+	fecha := DiaCierre(ahora)
+
+	var existente models.InventarioSnapshot
+	err := db.Where("fecha = ?", fecha).First(&existente)
+	if err == nil {
+		return 0, nil
+	}
+	if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return 0, err
+	}
+
+	items, err := inventarioActual(db)
+	if err != nil || len(items) == 0 {
+		return 0, err
+	}
+	err = db.Transaction(func(tx database.DBHandler) error {
+		for _, it := range items {
+			snapshot := models.InventarioSnapshot{
+				Fecha:       fecha,
+				ProductoID:  it.ProductoID,
+				Nombre:      it.Nombre,
+				CategoriaID: it.CategoriaID,
+				Cantidad:    it.Cantidad,
+				CostoUnit:   it.CostoUnit,
+				Valor:       it.Valor,
+			}
+			if err := tx.Create(&snapshot); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+	return len(items), nil
+}
+
+// ValorarInventario valúa el stock al cierre del día de fecha. Con fecha
+// cero, hoy o posterior usa los productos tal como están; para días pasados
+// usa el último snapshot de ese día o anterior (ErrSinSnapshot si no hay).
+func ValorarInventario(db database.DBHandler, fecha, ahora time.Time) (ValuacionInventario, error) {
+	if fecha.IsZero() || !DiaSnapshot(fecha).Before(DiaSnapshot(ahora)) {
+		items, err := inventarioActual(db)
+		if err != nil {
+			return ValuacionInventario{}, err
+		}
+		return valuacion(db, FuenteActual, items)
+	}
+
+	var ultimo models.InventarioSnapshot
+	err := db.Where("fecha <= ?", DiaSnapshot(fecha)).Order("fecha DESC").First(&ultimo)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return ValuacionInventario{}, ErrSinSnapshot
+	}
+	if err != nil {
+		return ValuacionInventario{}, err
+	}
+
+	var snapshots []models.InventarioSnapshot
+	if err := db.Where("fecha = ?", ultimo.Fecha).Order("producto_id").Find(&snapshots); err != nil {
+		return ValuacionInventario{}, err
+	}
+	items := make([]ItemInventario, len(snapshots))
+	for i, s := range snapshots {
+		items[i] = ItemInventario{
+			ProductoID:  s.ProductoID,
+			Nombre:      s.Nombre,
+			CategoriaID: s.CategoriaID,
+			Cantidad:    s.Cantidad,
+			CostoUnit:   s.CostoUnit,
+			Valor:       s.Valor,
+		}
+	}
+
+	v, err := valuacion(db, FuenteSnapshot, items)
+	if err != nil {
+		return ValuacionInventario{}, err
+	}
+	v.Fecha = &ultimo.Fecha
+	v.TomadoEn = &ultimo.CreatedAt
+	return v, nil
+}
+
+func inventarioActual(db database.DBHandler) ([]ItemInventario, error) {
+	var productos []models.Producto
+	if err := db.Order("id").Find(&productos); err != nil {
+		return nil, err
+	}
+	items := make([]ItemInventario, len(productos))
+	for i, p := range productos {
+		items[i] = ItemInventario{
+			ProductoID:  p.ID,
+			Nombre:      p.Nombre,
+			CategoriaID: p.CategoriaID,
+			Cantidad:    p.Stock,
+			CostoUnit:   p.Costo,
+			Valor:       centavos(float64(p.Stock) * p.Costo),
+		}
+	}
+	return items, nil
+}
+
+// valuacion suma los items en total y por categoría; las categorías salen
+// primero sin categoría y después por ID.
+func valuacion(db database.DBHandler, fuente string, items []ItemInventario) (ValuacionInventario, error) {
+	v := ValuacionInventario{Fuente: fuente, Productos: items, Categorias: []SubtotalCategoria{}}
+	porCategoria := map[uint]*SubtotalCategoria{}
+	var ids []uint
+	for _, it := range items {
+		v.Unidades += it.Cantidad
+		v.Valor += it.Valor
+
+		id := idCategoria(it.CategoriaID)
+		sub, ok := porCategoria[id]
+		if !ok {
+			sub = &SubtotalCategoria{CategoriaID: it.CategoriaID, Nombre: "Sin categoría"}
+			porCategoria[id] = sub
+			if id != 0 {
+				ids = append(ids, id)
+			}
+		}
+		sub.Unidades += it.Cantidad
+		sub.Valor += it.Valor
+	}
+	v.Valor = centavos(v.Valor)
+
+	if len(ids) > 0 {
+		var categorias []models.Categoria
+		if err := db.Where("id IN ?", ids).Find(&categorias); err != nil {
+			return ValuacionInventario{}, err
+		}
+		for _, cat := range categorias {
+			porCategoria[cat.ID].Nombre = cat.Nombre
+		}
+	}
+	for _, sub := range porCategoria {
+		sub.Valor = centavos(sub.Valor)
+		v.Categorias = append(v.Categorias, *sub)
+	}
+	sort.Slice(v.Categorias, func(i, j int) bool {
+		return idCategoria(v.Categorias[i].CategoriaID) < idCategoria(v.Categorias[j].CategoriaID)
+	})
+	return v, nil
+}
@@ -0,0 +1,111 @@
+package services
+
+import (
+	"testing"
+	"time"
+	"ventas-app/mocks"
+	"ventas-app/models"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSnapshotsYValuacionInventario(t *testing.T) {
+	db := mocks.NewMemoryDB()
+	require.NoError(t, db.Create(&models.Producto{Nombre: "Mate", Costo: 60, Stock: 10}))
+	require.NoError(t, db.Create(&models.Producto{Nombre: "Termo", Costo: 200, Stock: 2}))
+
+	// En la primera hora del 1/10 se guarda el cierre del 30/9
+	cierre := time.Date(2026, time.October, 1, 0, 10, 0, 0, time.UTC)
+	creados, err := TomarSnapshotInventario(db, cierre)
+	require.NoError(t, err)
+	assert.Equal(t, 2, creados)
+
+	// El segundo del mismo día no pisa la foto
+	var mate models.Producto
+	require.NoError(t, db.First(&mate, uint(1)))
+	mate.Stock, mate.Costo = 3, 80
+	require.NoError(t, db.Save(&mate))
+	creados, err = TomarSnapshotInventario(db, cierre.Add(time.Hour))
+	require.NoError(t, err)
+	assert.Zero(t, creados)
+
+	hoy := time.Date(2026, time.October, 19, 12, 0, 0, 0, time.UTC)
+
+	t.Run("fecha pasada usa el último snapshot", func(t *testing.T) {
+		v, err := ValorarInventario(db, time.Date(2026, time.October, 5, 0, 0, 0, 0, time.UTC), hoy)
+		require.NoError(t, err)
+
+		assert.Equal(t, FuenteSnapshot, v.Fuente)
+		require.NotNil(t, v.Fecha)
+		assert.Equal(t, "2026-09-30", v.Fecha.Format(time.DateOnly))
+		assert.Equal(t, []ItemInventario{
+			{ProductoID: 1, Nombre: "Mate", Cantidad: 10, CostoUnit: 60, Valor: 600},
+			{ProductoID: 2, Nombre: "Termo", Cantidad: 2, CostoUnit: 200, Valor: 400},
+		}, v.Productos)
+		assert.Equal(t, 12, v.Unidades)
+		assert.Equal(t, 1000.0, v.Valor)
+	})
+
+	t.Run("hoy o sin fecha usa el stock actual", func(t *testing.T) {
+		for _, fecha := range []time.Time{{}, hoy} {
+			v, err := ValorarInventario(db, fecha, hoy)
+			require.NoError(t, err)
+			assert.Equal(t, FuenteActual, v.Fuente)
+			assert.Nil(t, v.Fecha)
+			assert.Equal(t, 640.0, v.Valor)
+		}
+	})
+
+	t.Run("la fecha D es el cierre de D", func(t *testing.T) {
+		v, err := ValorarInventario(db, time.Date(2026, time.September, 30, 0, 0, 0, 0, time.UTC), hoy)
+		require.NoError(t, err)
+		assert.Equal(t, "2026-09-30", v.Fecha.Format(time.DateOnly))
+		assert.Equal(t, 1000.0, v.Valor)
+
+		// El 29/9 no tiene snapshot: el cierre del 30/9 no sirve para antes
+		_, err = ValorarInventario(db, time.Date(2026, time.September, 29, 0, 0, 0, 0, time.UTC), hoy)
+		assert.ErrorIs(t, err, ErrSinSnapshot)
+	})
+
+	t.Run("antes del primer snapshot", func(t *testing.T) {
+		_, err := ValorarInventario(db, time.Date(2026, time.September, 1, 0, 0, 0, 0, time.UTC), hoy)
+		assert.ErrorIs(t, err, ErrSinSnapshot)
+	})
+}
+
+func TestValuacionInventario_PorCategoria(t *testing.T) {
+	db := mocks.NewMemoryDB()
+	require.NoError(t, db.Create(&models.Categoria{Nombre: "Bazar"}))
+	require.NoError(t, db.Create(&models.Categoria{Nombre: "Almacén"}))
+	bazar, almacen := uint(1), uint(2)
+	require.NoError(t, db.Create(&models.Producto{Nombre: "Mate", Costo: 60, Stock: 10, CategoriaID: &bazar}))
+	require.NoError(t, db.Create(&models.Producto{Nombre: "Termo", Costo: 200, Stock: 2, CategoriaID: &bazar}))
+	require.NoError(t, db.Create(&models.Producto{Nombre: "Yerba", Costo: 5, Stock: 4}))
+
+	_, err := TomarSnapshotInventario(db, time.Date(2026, time.October, 1, 0, 10, 0, 0, time.UTC))
+	require.NoError(t, err)
+
+	// Reclasificar el termo no cambia la foto anterior
+	var termo models.Producto
+	require.NoError(t, db.First(&termo, uint(2)))
+	termo.CategoriaID = &almacen
+	require.NoError(t, db.Save(&termo))
+
+	hoy := time.Date(2026, time.October, 19, 12, 0, 0, 0, time.UTC)
+	v, err := ValorarInventario(db, time.Date(2026, time.October, 5, 0, 0, 0, 0, time.UTC), hoy)
+	require.NoError(t, err)
+	assert.Equal(t, []SubtotalCategoria{
+		{Nombre: "Sin categoría", Unidades: 4, Valor: 20},
+		{CategoriaID: &bazar, Nombre: "Bazar", Unidades: 12, Valor: 1000},
+	}, v.Categorias)
+
+	v, err = ValorarInventario(db, time.Time{}, hoy)
+	require.NoError(t, err)
+	assert.Equal(t, []SubtotalCategoria{
+		{Nombre: "Sin categoría", Unidades: 4, Valor: 20},
+		{CategoriaID: &bazar, Nombre: "Bazar", Unidades: 10, Valor: 600},
+		{CategoriaID: &almacen, Nombre: "Almacén", Unidades: 2, Valor: 400},
+	}, v.Categorias)
+	assert.Equal(t, 1020.0, v.Valor)
+}
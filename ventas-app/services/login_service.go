@@ -0,0 +1,169 @@
+package services
+
+import (
+	"sync"
+	"time"
+	"ventas-app/database"
+	"ventas-app/models"
+
+	"gorm.io/gorm"
+)
+
+// Bloqueo de cuentas por intentos fallidos. Se configuran al arrancar
+// (config.LoginConfig).
+var (
+	MaxIntentosUsuario = 5
+	BloqueoUsuario     = 15 * time.Minute
+)
+
+// RegistrarLogin guarda el evento de auditoría y actualiza el contador de
+// intentos del usuario (si existe): una clave o código TOTP incorrecto lo
+// incrementa y al llegar a MaxIntentosUsuario bloquea la cuenta; un éxito lo
+// reinicia. Solo se escriben esas columnas y el incremento lo hace la base,
+// así dos intentos simultáneos no pisan la cuenta del otro (ni un login
+// exitoso pisa lo que el admin cambió mientras tanto).
+func RegistrarLogin(db database.DBHandler, usuario *models.Usuario, nombre, ip, motivo string) error {
+	exito := motivo == models.LoginOK
+	evento := models.LoginEvento{Nombre: nombre, IP: ip, Exito: exito, Motivo: motivo}
+
+	if usuario != nil {
+		evento.UsuarioID = &usuario.ID
+
+		switch {
+		case exito && (usuario.IntentosFallidos > 0 || usuario.BloqueadoHasta != nil):
+			if err := reiniciarIntentos(db, usuario); err != nil {
+				return err
+			}
+		case motivo == models.LoginClaveIncorrecta || motivo == models.LoginCodigoIncorrecto:
+			if err := sumarIntentoFallido(db, usuario); err != nil {
+				return err
+			}
+		}
+	}
+
+	return db.Create(&evento)
+}
+
+// sumarIntentoFallido incrementa el contador en la base y, si llegó al
+// máximo, bloquea la cuenta y lo reinicia. El bloqueo es condicional sobre
+// el valor ya incrementado, de modo que lo aplica un solo intento aunque
+// lleguen varios a la vez.
+func sumarIntentoFallido(db database.DBHandler, usuario *models.Usuario) error {
+	_, err := db.Model(&models.Usuario{}).Where("id = ?", usuario.ID).
+		UpdateColumns(map[string]interface{}{"intentos_fallidos": gorm.Expr("intentos_fallidos + ?", 1)})
+	if err != nil {
+		return err
+	}
+
+	hasta := time.Now().Add(BloqueoUsuario)
+	bloqueados, err := db.Model(&models.Usuario{}).
+		Where("id = ? AND intentos_fallidos >= ?", usuario.ID, MaxIntentosUsuario).
+		UpdateColumns(map[string]interface{}{"bloqueado_hasta": hasta, "intentos_fallidos": 0})
+	if err != nil {
+		return err
+	}
+
+	if bloqueados > 0 {
+		usuario.BloqueadoHasta = &hasta
+		usuario.IntentosFallidos = 0
+	} else {
+		usuario.IntentosFallidos++
+	}
+	return nil
+}
+
+// reiniciarIntentos pone en cero el contador y quita el bloqueo.
+func reiniciarIntentos(db database.DBHandler, usuario *models.Usuario) error {
+	_, err := db.Model(&models.Usuario{}).Where("id = ?", usuario.ID).
+		UpdateColumns(map[string]interface{}{"intentos_fallidos": 0, "bloqueado_hasta": nil})
+	if err != nil {
+		return err
+	}
+	usuario.IntentosFallidos = 0
+	usuario.BloqueadoHasta = nil
+	return nil
+}
+
+// DesbloquearUsuario quita el bloqueo temporal y reinicia los intentos.
+func DesbloquearUsuario(db database.DBHandler, usuario *models.Usuario) error {
+	return reiniciarIntentos(db, usuario)
+}
+
+// LimitadorIP cuenta los logins fallidos por IP en ventanas fijas y bloquea
+// la IP al llegar al máximo. Vive en memoria: cada instancia lleva su cuenta.
+type LimitadorIP struct {
+	mu      sync.Mutex
+	max     int
+	ventana time.Duration
+	bloqueo time.Duration
+	ips     map[string]*intentosIP
+	ahora   func() time.Time
+
+	ultimaPurga time.Time
+}
+
+type intentosIP struct {
+	fallos         int
+	desde          time.Time
+	bloqueadoHasta time.Time
+}
+
+// NuevoLimitadorIP permite max fallos por IP en cada ventana; al superarlos
+// la IP queda bloqueada durante bloqueo.
+func NuevoLimitadorIP(max int, ventana, bloqueo time.Duration) *LimitadorIP {
+	return &LimitadorIP{
+		max:     max,
+		ventana: ventana,
+		bloqueo: bloqueo,
+		ips:     map[string]*intentosIP{},
+		ahora:   time.Now,
+	}
+}
+
+// Bloqueado indica si la IP está bloqueada y cuánto falta para que se libere.
+func (l *LimitadorIP) Bloqueado(ip string) (time.Duration, bool) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	e, ok := l.ips[ip]
+	if !ok {
+		return 0, false
+	}
+	restante := e.bloqueadoHasta.Sub(l.ahora())
+	return restante, restante > 0
+}
+
+// Fallo suma un intento fallido para la IP.
+func (l *LimitadorIP) Fallo(ip string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	ahora := l.ahora()
+	e, ok := l.ips[ip]
+	if !ok || ahora.Sub(e.desde) > l.ventana {
+		e = &intentosIP{desde: ahora}
+		l.ips[ip] = e
+		l.purgar(ahora)
+	}
+	e.fallos++
+	if e.fallos >= l.max {
+		e.bloqueadoHasta = ahora.Add(l.bloqueo)
+		e.fallos = 0
+		e.desde = ahora
+	}
+}
+
+// purgar descarta las IPs sin actividad reciente ni bloqueo vigente, para que
+// el mapa no crezca sin límite. Recorre el mapa como mucho una vez por
+// ventana. Debe llamarse con el mutex tomado.
+func (l *LimitadorIP) purgar(ahora time.Time) {
+	if ahora.Sub(l.ultimaPurga) < l.ventana {
+		return
+	}
+	l.ultimaPurga = ahora
+	for ip, e := range l.ips {
+		if ahora.Sub(e.desde) > l.ventana && !ahora.Before(e.bloqueadoHasta) {
+			delete(l.ips, ip)
+		}
+	}
+}
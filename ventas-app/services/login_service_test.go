@@ -0,0 +1,119 @@
+package services
+
+import (
+	"testing"
+	"time"
+	"ventas-app/mocks"
+	"ventas-app/models"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRegistrarLogin_BloqueaTrasMaxIntentos(t *testing.T) {
+	db := mocks.NewMemoryDB()
+	usuario := nuevoUsuario(t, db)
+
+	for i := 1; i < MaxIntentosUsuario; i++ {
+		require.NoError(t, RegistrarLogin(db, &usuario, "ana", "10.0.0.1", models.LoginClaveIncorrecta))
+		assert.False(t, usuario.Bloqueado(time.Now()), "intento %d", i)
+	}
+	require.NoError(t, RegistrarLogin(db, &usuario, "ana", "10.0.0.1", models.LoginClaveIncorrecta))
+
+	var leido models.Usuario
+	require.NoError(t, db.First(&leido, usuario.ID))
+	assert.True(t, leido.Bloqueado(time.Now()))
+	assert.False(t, leido.Bloqueado(time.Now().Add(BloqueoUsuario+time.Second)))
+
+	require.NoError(t, DesbloquearUsuario(db, &leido))
+	require.NoError(t, db.First(&leido, usuario.ID))
+	assert.False(t, leido.Bloqueado(time.Now()))
+
+	var eventos []models.LoginEvento
+	require.NoError(t, db.Find(&eventos))
+	assert.Len(t, eventos, MaxIntentosUsuario)
+	assert.Equal(t, usuario.ID, *eventos[0].UsuarioID)
+	assert.False(t, eventos[0].Exito)
+}
+
+func TestRegistrarLogin_ExitoReiniciaIntentos(t *testing.T) {
+	db := mocks.NewMemoryDB()
+	usuario := nuevoUsuario(t, db)
+
+	require.NoError(t, RegistrarLogin(db, &usuario, "ana", "10.0.0.1", models.LoginClaveIncorrecta))
+	require.NoError(t, RegistrarLogin(db, &usuario, "ana", "10.0.0.1", models.LoginOK))
+
+	var leido models.Usuario
+	require.NoError(t, db.First(&leido, usuario.ID))
+	assert.Zero(t, leido.IntentosFallidos)
+
+	require.NoError(t, RegistrarLogin(db, nil, "nadie", "10.0.0.1", models.LoginUsuarioInexistente))
+	var eventos []models.LoginEvento
+	require.NoError(t, db.Where("exito = ?", true).Find(&eventos))
+	assert.Len(t, eventos, 1)
+	require.NoError(t, db.Where("usuario_id IS NULL").Find(&eventos))
+	require.Len(t, eventos, 1)
+	assert.Equal(t, "nadie", eventos[0].Nombre)
+}
+
+func TestRegistrarLogin_CopiasDesactualizadas(t *testing.T) {
+	db := mocks.NewMemoryDB()
+	usuario := nuevoUsuario(t, db)
+
+	// Cada request trabaja con el usuario que leyó al empezar: ninguno ve los
+	// intentos de los otros, pero la base los cuenta todos
+	for i := 0; i < MaxIntentosUsuario; i++ {
+		copia := usuario
+		require.NoError(t, RegistrarLogin(db, &copia, "ana", "10.0.0.1", models.LoginClaveIncorrecta))
+	}
+	var leido models.Usuario
+	require.NoError(t, db.First(&leido, usuario.ID))
+	assert.True(t, leido.Bloqueado(time.Now()))
+
+	// Un login exitoso no pisa lo que otro cambió mientras tanto
+	require.NoError(t, DesbloquearUsuario(db, &leido))
+	copia := usuario
+	copia.IntentosFallidos = 1
+	leido.Rol = "admin"
+	require.NoError(t, db.Save(&leido))
+	require.NoError(t, RegistrarLogin(db, &copia, "ana", "10.0.0.1", models.LoginOK))
+
+	require.NoError(t, db.First(&leido, usuario.ID))
+	assert.Equal(t, "admin", leido.Rol)
+	assert.Zero(t, leido.IntentosFallidos)
+}
+
+func TestLimitadorIP(t *testing.T) {
+	ahora := time.Date(2025, 1, 1, 10, 0, 0, 0, time.UTC)
+	l := NuevoLimitadorIP(3, time.Minute, 5*time.Minute)
+	l.ahora = func() time.Time { return ahora }
+
+	l.Fallo("1.1.1.1")
+	l.Fallo("1.1.1.1")
+	_, bloqueada := l.Bloqueado("1.1.1.1")
+	assert.False(t, bloqueada)
+
+	// La ventana vence y el contador vuelve a cero
+	ahora = ahora.Add(2 * time.Minute)
+	l.Fallo("1.1.1.1")
+	l.Fallo("1.1.1.1")
+	_, bloqueada = l.Bloqueado("1.1.1.1")
+	assert.False(t, bloqueada)
+
+	l.Fallo("1.1.1.1")
+	espera, bloqueada := l.Bloqueado("1.1.1.1")
+	assert.True(t, bloqueada)
+	assert.Equal(t, 5*time.Minute, espera)
+
+	_, bloqueada = l.Bloqueado("2.2.2.2")
+	assert.False(t, bloqueada, "otras IPs no se ven afectadas")
+
+	ahora = ahora.Add(5 * time.Minute)
+	_, bloqueada = l.Bloqueado("1.1.1.1")
+	assert.False(t, bloqueada)
+
+	// Las IPs inactivas se purgan
+	ahora = ahora.Add(time.Hour)
+	l.Fallo("3.3.3.3")
+	assert.Len(t, l.ips, 1)
+}
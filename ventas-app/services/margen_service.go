@@ -0,0 +1,103 @@
+package services
+
+import (
+	"sort"
+	"ventas-app/database"
+)
+
+// MargenMinimo es el margen (% sobre el neto) por debajo del cual
+// GenerarReporteMargen marca la fila con BajoMargen. Lo fija main desde la
+// configuración.
+var MargenMinimo = 15.0
+
+// FilaMargen es la rentabilidad de un grupo. Costo usa el costo registrado en
+// cada venta (Venta.CostoUnit), no el actual del producto. Margen es
+// Ganancia sobre Neto, en %.
+type FilaMargen struct {
+	Grupo      string  `json:"grupo,omitempty"`
+	Nombre     string  `json:"nombre,omitempty"`
+	Ventas     int64   `json:"ventas"`
+	Unidades   int64   `json:"unidades"`
+	Neto       float64 `json:"neto"`
+	Costo      float64 `json:"costo"`
+	Ganancia   float64 `json:"ganancia"`
+	Margen     float64 `json:"margen"`
+	BajoMargen bool    `json:"bajo_margen"`
+}
+
+// ReporteMargen tiene una fila por grupo, de menor a mayor margen, el total
+// y cuántas filas quedaron por debajo de MargenMinimo.
+type ReporteMargen struct {
+	Filas        []FilaMargen `json:"filas"`
+	Totales      FilaMargen   `json:"totales"`
+	MargenMinimo float64      `json:"margen_minimo"`
+	Alertas      int          `json:"alertas"`
+}
+
+const columnasMargen = "COUNT(*) AS ventas, COALESCE(SUM(cantidad), 0) AS unidades, " +
+	"COALESCE(SUM(neto), 0) AS neto, COALESCE(SUM(costo_unit * cantidad), 0) AS costo"
+
+// GenerarReporteMargen agrega en la base ventas, neto y costo por producto,
+// usuario o categoría del producto (filtro.Agrupar) y calcula ganancia y
+// margen de cada grupo. Las ventas de productos sin categoría van en el grupo
+// "". minimo < 0 usa MargenMinimo.
+func GenerarReporteMargen(db database.DBHandler, filtro FiltroReporte, minimo float64) (ReporteMargen, error) {
+	var grupo string
+	var err error
+	porGrupo := ventasEnRango(db, filtro)
+	switch filtro.Agrupar {
+	case AgruparProducto, AgruparUsuario:
+		grupo, err = expresionGrupo(db.Driver(), filtro.Agrupar)
+	case AgruparCategoria:
+		porGrupo, grupo = conCategoria(db, porGrupo)
+	default:
+		err = ErrAgrupacionInvalida
+	}
+	if err != nil {
+		return ReporteMargen{}, err
+	}
+	if minimo < 0 {
+		minimo = MargenMinimo
+	}
+
+	reporte := ReporteMargen{Filas: []FilaMargen{}, MargenMinimo: minimo}
+	if err := ventasEnRango(db, filtro).Select(columnasMargen).Scan(&reporte.Totales); err != nil {
+		return ReporteMargen{}, err
+	}
+	err = porGrupo.Select(grupo + " AS grupo, " + columnasMargen).Group(grupo).Scan(&reporte.Filas)
+	if err != nil {
+		return ReporteMargen{}, err
+	}
+
+	grupos := make([]string, len(reporte.Filas))
+	for i, f := range reporte.Filas {
+		grupos[i] = f.Grupo
+	}
+	nombres, err := nombresGrupos(db, filtro.Agrupar, grupos)
+	if err != nil {
+		return ReporteMargen{}, err
+	}
+
+	calcularMargen(&reporte.Totales, minimo)
+	for i := range reporte.Filas {
+		reporte.Filas[i].Nombre = nombres[reporte.Filas[i].Grupo]
+		calcularMargen(&reporte.Filas[i], minimo)
+		if reporte.Filas[i].BajoMargen {
+			reporte.Alertas++
+		}
+	}
+	sort.SliceStable(reporte.Filas, func(i, j int) bool {
+		return reporte.Filas[i].Margen < reporte.Filas[j].Margen
+	})
+	return reporte, nil
+}
+
+func calcularMargen(f *FilaMargen, minimo float64) {
+	f.Ganancia = centavos(f.Neto - f.Costo)
+	if f.Neto != 0 {
+		f.Margen = centavos(f.Ganancia / f.Neto * 100)
+	}
+	f.Neto = centavos(f.Neto)
+	f.Costo = centavos(f.Costo)
+	f.BajoMargen = f.Ventas > 0 && f.Margen < minimo
+}
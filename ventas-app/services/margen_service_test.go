@@ -0,0 +1,71 @@
+package services
+
+import (
+	"testing"
+	"time"
+	"ventas-app/models"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGenerarReporteMargen(t *testing.T) {
+	db := baseReportes(t)
+
+	t.Run("por producto con el costo de cada venta", func(t *testing.T) {
+		r, err := GenerarReporteMargen(db, FiltroReporte{Agrupar: AgruparProducto}, 31)
+		require.NoError(t, err)
+
+		require.Len(t, r.Filas, 2)
+		assert.Equal(t, FilaMargen{Grupo: "1", Nombre: "Mate", Ventas: 2, Unidades: 3, Neto: 300, Costo: 210, Ganancia: 90, Margen: 30, BajoMargen: true}, r.Filas[0])
+		assert.Equal(t, FilaMargen{Grupo: "2", Nombre: "Termo", Ventas: 2, Unidades: 4, Neto: 1200, Costo: 800, Ganancia: 400, Margen: 33.33}, r.Filas[1])
+		assert.Equal(t, FilaMargen{Ventas: 4, Unidades: 7, Neto: 1500, Costo: 1010, Ganancia: 490, Margen: 32.67}, r.Totales)
+		assert.Equal(t, 1, r.Alertas)
+		assert.Equal(t, 31.0, r.MargenMinimo)
+	})
+
+	t.Run("por usuario con el mínimo por defecto", func(t *testing.T) {
+		anterior := MargenMinimo
+		MargenMinimo = 32
+		defer func() { MargenMinimo = anterior }()
+
+		r, err := GenerarReporteMargen(db, FiltroReporte{Agrupar: AgruparUsuario}, -1)
+		require.NoError(t, err)
+
+		require.Len(t, r.Filas, 2)
+		assert.Equal(t, "2", r.Filas[0].Grupo)
+		assert.Equal(t, 31.0, r.Filas[0].Margen)
+		assert.True(t, r.Filas[0].BajoMargen)
+		assert.Equal(t, "ana", r.Filas[1].Nombre)
+		assert.Equal(t, 36.0, r.Filas[1].Margen)
+		assert.Equal(t, 1, r.Alertas)
+	})
+
+	t.Run("por categoría del producto", func(t *testing.T) {
+		bazar := models.Categoria{Nombre: "Bazar"}
+		require.NoError(t, db.Create(&bazar))
+		var termo models.Producto
+		require.NoError(t, db.First(&termo, 2))
+		termo.CategoriaID = &bazar.ID
+		require.NoError(t, db.Save(&termo))
+
+		r, err := GenerarReporteMargen(db, FiltroReporte{Agrupar: AgruparCategoria}, 31)
+		require.NoError(t, err)
+		require.Len(t, r.Filas, 2)
+		assert.Equal(t, FilaMargen{Nombre: "Sin categoría", Ventas: 2, Unidades: 3, Neto: 300, Costo: 210, Ganancia: 90, Margen: 30, BajoMargen: true}, r.Filas[0])
+		assert.Equal(t, FilaMargen{Grupo: "1", Nombre: "Bazar", Ventas: 2, Unidades: 4, Neto: 1200, Costo: 800, Ganancia: 400, Margen: 33.33}, r.Filas[1])
+		assert.Equal(t, int64(4), r.Totales.Ventas)
+
+		// El rango de fechas se aplica a las ventas, no a los productos
+		r, err = GenerarReporteMargen(db, FiltroReporte{Agrupar: AgruparCategoria, Desde: time.Date(2026, time.March, 5, 0, 0, 0, 0, time.UTC)}, 31)
+		require.NoError(t, err)
+		require.Len(t, r.Filas, 2)
+		assert.Equal(t, int64(1), r.Filas[0].Ventas)
+		assert.Equal(t, int64(1), r.Filas[1].Ventas)
+	})
+
+	t.Run("solo producto, usuario o categoría", func(t *testing.T) {
+		_, err := GenerarReporteMargen(db, FiltroReporte{Agrupar: AgruparMes}, 10)
+		assert.ErrorIs(t, err, ErrAgrupacionInvalida)
+	})
+}
@@ -0,0 +1,226 @@
+package services
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/hex"
+	"errors"
+	"strings"
+	"time"
+	"ventas-app/database"
+	"ventas-app/models"
+	"ventas-app/utils"
+
+	"github.com/pquerna/otp"
+	"github.com/pquerna/otp/totp"
+	"gorm.io/gorm"
+)
+
+var (
+	// ErrCodigoInvalido: el código TOTP o de recuperación no es válido.
+	ErrCodigoInvalido = errors.New("código inválido")
+
+	// ErrTOTPActivo: el usuario ya tiene el segundo factor confirmado.
+	ErrTOTPActivo = errors.New("el doble factor ya está activo")
+
+	// ErrTOTPNoIniciado: se quiso confirmar sin haber generado el secreto.
+	ErrTOTPNoIniciado = errors.New("no hay un alta de doble factor en curso")
+
+	// ErrTokenMFAInvalido: el token intermedio no es válido, venció o ya se usó.
+	ErrTokenMFAInvalido = errors.New("token MFA inválido")
+)
+
+// Doble factor. main los reemplaza con los valores de config.MFAConfig.
+var (
+	// RolesMFA son los roles que deben usar TOTP aunque no se hayan enrolado:
+	// al iniciar sesión se les pide el alta antes de entregarles la sesión.
+	RolesMFA = map[string]bool{}
+
+	// EmisorTOTP es el nombre que muestra la app autenticadora.
+	EmisorTOTP = "ventas-app"
+
+	// MFATTL es la vida del token intermedio entre la clave y el código.
+	MFATTL = 5 * time.Minute
+)
+
+// CantidadCodigosRecuperacion es cuántos códigos se entregan en cada generación.
+const CantidadCodigosRecuperacion = 10
+
+var opcionesTOTP = totp.ValidateOpts{Period: 30, Digits: otp.DigitsSix, Algorithm: otp.AlgorithmSHA1}
+
+// RequiereMFA indica si el login del usuario necesita el segundo factor: lo
+// tiene activo o su rol lo exige.
+func RequiereMFA(usuario models.Usuario) bool {
+	return usuario.TOTPActivo() || RolesMFA[usuario.Rol]
+}
+
+// IniciarMFA emite el token intermedio para quien ya validó la clave.
+func IniciarMFA(usuario models.Usuario, tenant string) (string, error) {
+	return utils.SignClaims(utils.NewClaimsMFA(usuario.ID, usuario.Rol, tenant, MFATTL))
+}
+
+// ValidarTokenMFA comprueba el token intermedio y devuelve sus claims y el
+// usuario, que debe seguir habilitado y sin bloqueo.
+func ValidarTokenMFA(db database.DBHandler, token string) (*utils.Claims, models.Usuario, error) {
+	claims, err := utils.ParseClaimsMFA(token)
+	if err != nil || claims.UserID == 0 {
+		return nil, models.Usuario{}, ErrTokenMFAInvalido
+	}
+
+	usuario, err := ValidarSesion(db, claims)
+	if errors.Is(err, ErrSesionRevocada) || errors.Is(err, ErrUsuarioInvalido) {
+		return nil, models.Usuario{}, ErrTokenMFAInvalido
+	}
+	if err != nil {
+		return nil, models.Usuario{}, err
+	}
+	if usuario.Bloqueado(time.Now()) {
+		return nil, models.Usuario{}, ErrTokenMFAInvalido
+	}
+	return claims, usuario, nil
+}
+
+// ConsumirTokenMFA revoca el token intermedio para que no se pueda reusar.
+func ConsumirTokenMFA(db database.DBHandler, claims *utils.Claims) error {
+	revocado := models.TokenRevocado{JTI: claims.ID, ExpiraEn: claims.ExpiresAt.Time}
+	if err := db.Create(&revocado); err != nil && !errors.Is(err, gorm.ErrDuplicatedKey) {
+		return err
+	}
+	return nil
+}
+
+// GenerarTOTP crea un secreto nuevo, pendiente hasta ConfirmarTOTP, y
+// devuelve el secreto y la URI otpauth:// para el código QR.
+func GenerarTOTP(db database.DBHandler, usuario *models.Usuario) (secreto, uri string, err error) {
+	if usuario.TOTPActivo() {
+		return "", "", ErrTOTPActivo
+	}
+
+	key, err := totp.Generate(totp.GenerateOpts{Issuer: EmisorTOTP, AccountName: usuario.Nombre})
+	if err != nil {
+		return "", "", err
+	}
+	usuario.TOTPSecreto = key.Secret()
+	usuario.TOTPUltimoPaso = 0
+	if err := db.Save(usuario); err != nil {
+		return "", "", err
+	}
+	return key.Secret(), key.URL(), nil
+}
+
+// ConfirmarTOTP activa el secreto pendiente si el código es correcto y
+// devuelve los códigos de recuperación.
+func ConfirmarTOTP(db database.DBHandler, usuario *models.Usuario, codigo string) ([]string, error) {
+	if usuario.TOTPActivo() {
+		return nil, ErrTOTPActivo
+	}
+	if usuario.TOTPSecreto == "" {
+		return nil, ErrTOTPNoIniciado
+	}
+
+	var codigos []string
+	err := db.Transaction(func(tx database.DBHandler) error {
+		if err := verificarTOTP(usuario, codigo, time.Now()); err != nil {
+			return err
+		}
+		ahora := time.Now()
+		usuario.TOTPActivoEn = &ahora
+		if err := tx.Save(usuario); err != nil {
+			return err
+		}
+		var err error
+		codigos, err = GenerarCodigosRecuperacion(tx, usuario)
+		return err
+	})
+	return codigos, err
+}
+
+// VerificarSegundoFactor acepta un código TOTP (una sola vez por período) o
+// un código de recuperación sin usar.
+func VerificarSegundoFactor(db database.DBHandler, usuario *models.Usuario, codigo string) error {
+	if !usuario.TOTPActivo() {
+		return ErrCodigoInvalido
+	}
+
+	codigo = strings.TrimSpace(codigo)
+	if len(codigo) != int(otp.DigitsSix) {
+		return usarCodigoRecuperacion(db, usuario, codigo)
+	}
+	if err := verificarTOTP(usuario, codigo, time.Now()); err != nil {
+		return err
+	}
+	return db.Save(usuario)
+}
+
+// DesactivarTOTP quita el segundo factor y sus códigos de recuperación.
+func DesactivarTOTP(db database.DBHandler, usuario *models.Usuario) error {
+	return db.Transaction(func(tx database.DBHandler) error {
+		usuario.TOTPSecreto = ""
+		usuario.TOTPActivoEn = nil
+		usuario.TOTPUltimoPaso = 0
+		if err := tx.Save(usuario); err != nil {
+			return err
+		}
+		return tx.Where("usuario_id = ?", usuario.ID).Delete(&models.CodigoRecuperacion{})
+	})
+}
+
+// GenerarCodigosRecuperacion reemplaza los códigos de recuperación del
+// usuario por CantidadCodigosRecuperacion nuevos, que se devuelven en claro.
+func GenerarCodigosRecuperacion(db database.DBHandler, usuario *models.Usuario) ([]string, error) {
+	if err := db.Where("usuario_id = ?", usuario.ID).Delete(&models.CodigoRecuperacion{}); err != nil {
+		return nil, err
+	}
+
+	codigos := make([]string, 0, CantidadCodigosRecuperacion)
+	for i := 0; i < CantidadCodigosRecuperacion; i++ {
+		b := make([]byte, 5)
+		if _, err := rand.Read(b); err != nil {
+			return nil, err
+		}
+		h := hex.EncodeToString(b)
+		codigo := h[:5] + "-" + h[5:]
+
+		if err := db.Create(&models.CodigoRecuperacion{UsuarioID: usuario.ID, Hash: hashToken(codigo)}); err != nil {
+			return nil, err
+		}
+		codigos = append(codigos, codigo)
+	}
+	return codigos, nil
+}
+
+// verificarTOTP acepta el código del período actual o de los vecinos (reloj
+// desfasado), siempre que sea posterior al último usado.
+func verificarTOTP(usuario *models.Usuario, codigo string, ahora time.Time) error {
+	paso := ahora.Unix() / int64(opcionesTOTP.Period)
+	for _, desvio := range []int64{-1, 0, 1} {
+		p := paso + desvio
+		if p <= usuario.TOTPUltimoPaso {
+			continue
+		}
+		esperado, err := totp.GenerateCodeCustom(usuario.TOTPSecreto, time.Unix(p*int64(opcionesTOTP.Period), 0), opcionesTOTP)
+		if err != nil {
+			return err
+		}
+		if subtle.ConstantTimeCompare([]byte(esperado), []byte(codigo)) == 1 {
+			usuario.TOTPUltimoPaso = p
+			return nil
+		}
+	}
+	return ErrCodigoInvalido
+}
+
+func usarCodigoRecuperacion(db database.DBHandler, usuario *models.Usuario, codigo string) error {
+	var rc models.CodigoRecuperacion
+	err := db.Where("usuario_id = ? AND hash = ?", usuario.ID, hashToken(strings.ToLower(codigo))).
+		Where("usado_en IS NULL").First(&rc)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return ErrCodigoInvalido
+	}
+	if err != nil {
+		return err
+	}
+	ahora := time.Now()
+	rc.UsadoEn = &ahora
+	return db.Save(&rc)
+}
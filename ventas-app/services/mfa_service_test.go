@@ -0,0 +1,108 @@
+package services
+
+import (
+	"testing"
+	"time"
+	"ventas-app/mocks"
+	"ventas-app/models"
+
+	"github.com/pquerna/otp/totp"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTOTP_AltaYVerificacion(t *testing.T) {
+	db := mocks.NewMemoryDB()
+	usuario := nuevoUsuario(t, db)
+
+	secreto, uri, err := GenerarTOTP(db, &usuario)
+	require.NoError(t, err)
+	assert.Contains(t, uri, "otpauth://totp/")
+	assert.Contains(t, uri, "secret="+secreto)
+	assert.False(t, usuario.TOTPActivo())
+
+	_, err = ConfirmarTOTP(db, &usuario, "000000")
+	assert.ErrorIs(t, err, ErrCodigoInvalido)
+
+	codigo, err := totp.GenerateCode(secreto, time.Now())
+	require.NoError(t, err)
+	codigos, err := ConfirmarTOTP(db, &usuario, codigo)
+	require.NoError(t, err)
+	assert.Len(t, codigos, CantidadCodigosRecuperacion)
+	assert.True(t, usuario.TOTPActivo())
+
+	_, _, err = GenerarTOTP(db, &usuario)
+	assert.ErrorIs(t, err, ErrTOTPActivo)
+
+	// El mismo código no se puede usar dos veces
+	assert.ErrorIs(t, VerificarSegundoFactor(db, &usuario, codigo), ErrCodigoInvalido)
+
+	// El código del período siguiente sí (reloj del teléfono adelantado)
+	siguiente, _ := totp.GenerateCode(secreto, time.Now().Add(30*time.Second))
+	assert.NoError(t, VerificarSegundoFactor(db, &usuario, siguiente))
+
+	var leido models.Usuario
+	require.NoError(t, db.First(&leido, usuario.ID))
+	assert.True(t, leido.TOTPActivo())
+	assert.Equal(t, usuario.TOTPUltimoPaso, leido.TOTPUltimoPaso)
+}
+
+func TestTOTP_CodigosRecuperacion(t *testing.T) {
+	db := mocks.NewMemoryDB()
+	usuario := nuevoUsuario(t, db)
+
+	secreto, _, err := GenerarTOTP(db, &usuario)
+	require.NoError(t, err)
+	codigo, _ := totp.GenerateCode(secreto, time.Now())
+	codigos, err := ConfirmarTOTP(db, &usuario, codigo)
+	require.NoError(t, err)
+
+	assert.NoError(t, VerificarSegundoFactor(db, &usuario, codigos[0]))
+	assert.ErrorIs(t, VerificarSegundoFactor(db, &usuario, codigos[0]), ErrCodigoInvalido, "un solo uso")
+	assert.ErrorIs(t, VerificarSegundoFactor(db, &usuario, "abcde-12345"), ErrCodigoInvalido)
+
+	nuevos, err := GenerarCodigosRecuperacion(db, &usuario)
+	require.NoError(t, err)
+	assert.ErrorIs(t, VerificarSegundoFactor(db, &usuario, codigos[1]), ErrCodigoInvalido, "los anteriores se invalidan")
+	assert.NoError(t, VerificarSegundoFactor(db, &usuario, nuevos[1]))
+
+	require.NoError(t, DesactivarTOTP(db, &usuario))
+	assert.False(t, usuario.TOTPActivo())
+	var restantes []models.CodigoRecuperacion
+	require.NoError(t, db.Find(&restantes))
+	assert.Empty(t, restantes)
+}
+
+func TestValidarTokenMFA(t *testing.T) {
+	db := mocks.NewMemoryDB()
+	usuario := nuevoUsuario(t, db)
+
+	token, err := IniciarMFA(usuario, "qa")
+	require.NoError(t, err)
+
+	claims, leido, err := ValidarTokenMFA(db, token)
+	require.NoError(t, err)
+	assert.Equal(t, usuario.ID, leido.ID)
+
+	require.NoError(t, ConsumirTokenMFA(db, claims))
+	_, _, err = ValidarTokenMFA(db, token)
+	assert.ErrorIs(t, err, ErrTokenMFAInvalido, "no se puede reusar")
+
+	sesion, err := IniciarSesion(db, usuario, "qa")
+	require.NoError(t, err)
+	_, _, err = ValidarTokenMFA(db, sesion.AccessToken)
+	assert.ErrorIs(t, err, ErrTokenMFAInvalido, "un access token no sirve como token MFA")
+}
+
+func TestRequiereMFA(t *testing.T) {
+	defer func() { RolesMFA = map[string]bool{} }()
+
+	usuario := models.Usuario{Rol: "comprador"}
+	assert.False(t, RequiereMFA(usuario))
+
+	RolesMFA = map[string]bool{"comprador": true}
+	assert.True(t, RequiereMFA(usuario))
+
+	ahora := time.Now()
+	assert.True(t, RequiereMFA(models.Usuario{Rol: "vendedor", TOTPActivoEn: &ahora}))
+}
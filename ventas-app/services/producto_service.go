@@ -1 +1,188 @@
 package services
+
+import (
+	"errors"
+	"ventas-app/database"
+	"ventas-app/models"
+
+	"gorm.io/gorm"
+)
+
+var (
+	// ErrSKUDuplicado: otro producto ya tiene ese SKU.
+	ErrSKUDuplicado = errors.New("el SKU ya existe")
+	// ErrCodigoBarrasDuplicado: el código ya es de un producto.
+	ErrCodigoBarrasDuplicado = errors.New("el código de barras ya existe")
+	// ErrStockInsuficiente: se quiere descontar más de lo que hay.
+	ErrStockInsuficiente = errors.New("stock insuficiente")
+)
+
+// MoverStock suma delta (negativo para descontar) al stock del producto y, si
+// varianteID no es nil, al de la variante. La suma la hace la base ("stock +
+// ?"), así no se pisan compras, ventas o devoluciones simultáneas. Para
+// descontar exige en la misma sentencia que alcance el stock: si otra venta
+// se lo llevó devuelve ErrStockInsuficiente. Debe correr en la transacción de
+// la operación, que se revierte si falla.
+func MoverStock(tx database.DBHandler, productoID uint, varianteID *uint, delta int) error {
+	if delta == 0 {
+		return nil
+	}
+	if err := moverStock(tx, &models.Producto{}, productoID, delta); err != nil {
+		return err
+	}
+	if varianteID != nil {
+		return moverStock(tx, &models.Variante{}, *varianteID, delta)
+	}
+	return nil
+}
+
+func moverStock(tx database.DBHandler, modelo interface{}, id uint, delta int) error {
+	q := tx.Model(modelo).Where("id = ?", id)
+	if delta < 0 {
+		q = q.Where("stock >= ?", -delta)
+	}
+	n, err := q.UpdateColumns(map[string]interface{}{"stock": gorm.Expr("stock + ?", delta)})
+	if err != nil {
+		return err
+	}
+	if n == 0 && delta < 0 {
+		return ErrStockInsuficiente
+	}
+	if n == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
+}
+
+// CrearProducto valida SKU, códigos de barras, categoría y marca y guarda el producto con sus
+// códigos en una transacción.
+func CrearProducto(db database.DBHandler, p *models.Producto) error {
+	if p.SKU != nil {
+		if err := models.ValidarSKU(*p.SKU); err != nil {
+			return err
+		}
+	}
+	codigos := map[string]bool{}
+	for i, codigo := range p.CodigosBarras {
+		normalizado, err := models.NormalizarCodigoBarras(codigo)
+		if err != nil {
+			return err
+		}
+		if codigos[normalizado] {
+			return ErrCodigoBarrasDuplicado
+		}
+		codigos[normalizado] = true
+		p.CodigosBarras[i] = normalizado
+	}
+	if err := validarClasificacion(db, p.CategoriaID, p.MarcaID); err != nil {
+		return err
+	}
+
+	return db.Transaction(func(tx database.DBHandler) error {
+		if len(p.CodigosBarras) > 0 {
+			var existentes []models.CodigoBarras
+			if err := tx.Where("codigo IN ?", p.CodigosBarras).Find(&existentes); err != nil {
+				return err
+			}
+			if len(existentes) > 0 {
+				return ErrCodigoBarrasDuplicado
+			}
+		}
+		if err := tx.Create(p); err != nil {
+			if errors.Is(err, gorm.ErrDuplicatedKey) {
+				return ErrSKUDuplicado
+			}
+			return err
+		}
+		for _, codigo := range p.CodigosBarras {
+			if err := tx.Create(&models.CodigoBarras{ProductoID: p.ID, Codigo: codigo}); err != nil {
+				if errors.Is(err, gorm.ErrDuplicatedKey) {
+					return ErrCodigoBarrasDuplicado
+				}
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// AgregarCodigoBarras suma un código al producto, guardado como GTIN-14.
+func AgregarCodigoBarras(db database.DBHandler, productoID uint, codigo string) (models.CodigoBarras, error) {
+	codigo, err := models.NormalizarCodigoBarras(codigo)
+	if err != nil {
+		return models.CodigoBarras{}, err
+	}
+	var producto models.Producto
+	if err := db.First(&producto, productoID); err != nil {
+		return models.CodigoBarras{}, err
+	}
+	cb := models.CodigoBarras{ProductoID: productoID, Codigo: codigo}
+	if err := db.Create(&cb); err != nil {
+		if errors.Is(err, gorm.ErrDuplicatedKey) {
+			return models.CodigoBarras{}, ErrCodigoBarrasDuplicado
+		}
+		return models.CodigoBarras{}, err
+	}
+	return cb, nil
+}
+
+// QuitarCodigoBarras borra el código del producto (en cualquiera de sus
+// largos); gorm.ErrRecordNotFound si el producto no lo tiene.
+func QuitarCodigoBarras(db database.DBHandler, productoID uint, codigo string) error {
+	if normalizado, err := models.NormalizarCodigoBarras(codigo); err == nil {
+		codigo = normalizado
+	}
+	var cb models.CodigoBarras
+	if err := db.Where("producto_id = ?", productoID).Where("codigo = ?", codigo).First(&cb); err != nil {
+		return err
+	}
+	return db.Delete(&cb)
+}
+
+// BuscarPorCodigoBarras devuelve el producto del código (con todos sus
+// códigos); se busca como GTIN-14, así da igual si el lector lo leyó como
+// UPC-A o EAN-13. Un código mal formado da models.ErrCodigoBarrasInvalido
+// sin ir a la base; uno que no es de ningún producto, gorm.ErrRecordNotFound.
+func BuscarPorCodigoBarras(db database.DBHandler, codigo string) (models.Producto, error) {
+	codigo, err := models.NormalizarCodigoBarras(codigo)
+	if err != nil {
+		return models.Producto{}, err
+	}
+	var cb models.CodigoBarras
+	if err := db.Where("codigo = ?", codigo).First(&cb); err != nil {
+		return models.Producto{}, err
+	}
+	var producto models.Producto
+	if err := db.First(&producto, cb.ProductoID); err != nil {
+		return models.Producto{}, err
+	}
+	productos := []models.Producto{producto}
+	if err := CargarCodigosBarras(db, productos); err != nil {
+		return models.Producto{}, err
+	}
+	return productos[0], nil
+}
+
+// CargarCodigosBarras completa CodigosBarras de los productos con una sola
+// consulta.
+func CargarCodigosBarras(db database.DBHandler, productos []models.Producto) error {
+	if len(productos) == 0 {
+		return nil
+	}
+	ids := make([]uint, len(productos))
+	for i, p := range productos {
+		ids[i] = p.ID
+	}
+	var codigos []models.CodigoBarras
+	if err := db.Where("producto_id IN ?", ids).Order("id").Find(&codigos); err != nil {
+		return err
+	}
+	porProducto := map[uint][]string{}
+	for _, cb := range codigos {
+		porProducto[cb.ProductoID] = append(porProducto[cb.ProductoID], cb.Codigo)
+	}
+	for i := range productos {
+		productos[i].CodigosBarras = porProducto[productos[i].ID]
+	}
+	return nil
+}
@@ -0,0 +1,121 @@
+package services
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"time"
+	"ventas-app/database"
+	"ventas-app/models"
+
+	"golang.org/x/crypto/bcrypt"
+	"gorm.io/gorm"
+)
+
+var (
+	// ErrClaveActualIncorrecta: al cambiar la clave propia no coincide la actual.
+	ErrClaveActualIncorrecta = errors.New("la clave actual no es correcta")
+	// ErrUsuarioExistente: ya hay un usuario con ese nombre.
+	ErrUsuarioExistente = errors.New("el usuario ya existe")
+)
+
+// CrearUsuario da de alta un usuario con la clave validada contra la política
+// (devuelve *ErrClaveDebil). El rol no se controla acá: lo hacen el endpoint
+// y `server crear-admin`.
+func CrearUsuario(db database.DBHandler, nombre, clave, rol string) (models.Usuario, error) {
+	if err := ValidarClave(clave, nombre); err != nil {
+		return models.Usuario{}, err
+	}
+
+	var existente models.Usuario
+	err := db.Where("nombre = ?", nombre).First(&existente)
+	if err == nil {
+		return models.Usuario{}, ErrUsuarioExistente
+	}
+	if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return models.Usuario{}, err
+	}
+
+	hash, err := HashClave(clave)
+	if err != nil {
+		return models.Usuario{}, err
+	}
+	usuario := models.Usuario{Nombre: nombre, Clave: hash, Rol: rol}
+	if err := db.Create(&usuario); err != nil {
+		if errors.Is(err, gorm.ErrDuplicatedKey) {
+			return models.Usuario{}, ErrUsuarioExistente
+		}
+		return models.Usuario{}, err
+	}
+	return usuario, nil
+}
+
+// CambiarRol asigna el rol y revoca las sesiones del usuario: los tokens
+// emitidos llevan el rol anterior.
+func CambiarRol(db database.DBHandler, usuario *models.Usuario, rol string) error {
+	usuario.Rol = rol
+	return RevocarSesiones(db, usuario)
+}
+
+// DeshabilitarUsuario impide que el usuario inicie sesión y revoca las
+// sesiones abiertas.
+func DeshabilitarUsuario(db database.DBHandler, usuario *models.Usuario) error {
+	if usuario.DeshabilitadoEn == nil {
+		ahora := time.Now()
+		usuario.DeshabilitadoEn = &ahora
+	}
+	return RevocarSesiones(db, usuario)
+}
+
+// HabilitarUsuario vuelve a permitir el acceso a un usuario deshabilitado.
+func HabilitarUsuario(db database.DBHandler, usuario *models.Usuario) error {
+	usuario.DeshabilitadoEn = nil
+	return db.Save(usuario)
+}
+
+// ResetearClave reemplaza la clave por una temporal aleatoria, que se
+// devuelve una única vez, y obliga a cambiarla en el próximo ingreso. También
+// quita el bloqueo y revoca las sesiones abiertas.
+func ResetearClave(db database.DBHandler, usuario *models.Usuario) (string, error) {
+	b := make([]byte, 12)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	temporal := base64.RawURLEncoding.EncodeToString(b)
+
+	hash, err := HashClave(temporal)
+	if err != nil {
+		return "", err
+	}
+	usuario.Clave = hash
+	usuario.DebeCambiarClave = true
+	usuario.IntentosFallidos = 0
+	usuario.BloqueadoHasta = nil
+	if err := RevocarSesiones(db, usuario); err != nil {
+		return "", err
+	}
+	return temporal, nil
+}
+
+// CambiarClave cambia la clave propia verificando la actual y la política
+// (devuelve *ErrClaveDebil). Revoca todas las sesiones, incluida la del
+// request: hay que volver a iniciar sesión.
+func CambiarClave(db database.DBHandler, usuario *models.Usuario, actual, nueva string) error {
+	if bcrypt.CompareHashAndPassword([]byte(usuario.Clave), []byte(actual)) != nil {
+		return ErrClaveActualIncorrecta
+	}
+	if err := ValidarClave(nueva, usuario.Nombre); err != nil {
+		return err
+	}
+	if nueva == actual {
+		return &ErrClaveDebil{Problemas: []string{"debe ser distinta de la actual"}}
+	}
+
+	hash, err := HashClave(nueva)
+	if err != nil {
+		return err
+	}
+	usuario.Clave = hash
+	usuario.DebeCambiarClave = false
+	return RevocarSesiones(db, usuario)
+}
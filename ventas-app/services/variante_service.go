@@ -0,0 +1,241 @@
+package services
+
+import (
+	"errors"
+	"strings"
+	"ventas-app/database"
+	"ventas-app/models"
+
+	"gorm.io/gorm"
+)
+
+var (
+	// ErrAtributosInvalidos: definiciones con nombres o valores vacíos o
+	// repetidos, o que dejan afuera a variantes existentes.
+	ErrAtributosInvalidos = errors.New("atributos de variante inválidos")
+	// ErrVarianteInvalida: la variante no tiene un valor permitido por cada
+	// atributo del producto, o tiene stock o precio negativos.
+	ErrVarianteInvalida = errors.New("variante inválida")
+	// ErrVarianteDuplicada: el producto ya tiene esa combinación.
+	ErrVarianteDuplicada = errors.New("ya existe una variante con esos atributos")
+	// ErrVarianteRequerida: el producto tiene variantes y no se indicó cuál.
+	ErrVarianteRequerida = errors.New("el producto tiene variantes: falta variante_id")
+	// ErrVarianteAjena: la variante no es del producto indicado.
+	ErrVarianteAjena = errors.New("la variante no es de ese producto")
+	// ErrStockSinVariante: el producto tiene stock propio y no se sabe de
+	// qué variante es; hay que llevarlo a 0 antes de crear la primera.
+	ErrStockSinVariante = errors.New("el producto tiene stock sin asignar a variantes")
+	// ErrVarianteConStock: solo se borran variantes sin stock.
+	ErrVarianteConStock = errors.New("la variante tiene stock")
+)
+
+// VariantesProducto son las definiciones de atributos y las variantes de un
+// producto.
+type VariantesProducto struct {
+	Atributos []models.AtributoVariante `json:"atributos"`
+	Variantes []models.Variante         `json:"variantes"`
+}
+
+// ListarVariantes devuelve atributos y variantes del producto.
+func ListarVariantes(db database.DBHandler, productoID uint) (VariantesProducto, error) {
+	res := VariantesProducto{Atributos: []models.AtributoVariante{}, Variantes: []models.Variante{}}
+	if err := db.Where("producto_id = ?", productoID).Order("id").Find(&res.Atributos); err != nil {
+		return res, err
+	}
+	if err := db.Where("producto_id = ?", productoID).Order("id").Find(&res.Variantes); err != nil {
+		return res, err
+	}
+	return res, nil
+}
+
+// DefinirAtributos reemplaza los atributos del producto. Las variantes que ya
+// existen tienen que seguir siendo válidas con las nuevas definiciones.
+func DefinirAtributos(db database.DBHandler, productoID uint, atributos []models.AtributoVariante) ([]models.AtributoVariante, error) {
+	nombres := map[string]bool{}
+	for i := range atributos {
+		a := &atributos[i]
+		a.ID, a.ProductoID = 0, productoID
+		a.Nombre = strings.TrimSpace(a.Nombre)
+		if a.Nombre == "" || nombres[strings.ToLower(a.Nombre)] || len(a.Valores) == 0 {
+			return nil, ErrAtributosInvalidos
+		}
+		nombres[strings.ToLower(a.Nombre)] = true
+		valores := map[string]bool{}
+		for j, v := range a.Valores {
+			v = strings.TrimSpace(v)
+			if v == "" || valores[v] {
+				return nil, ErrAtributosInvalidos
+			}
+			valores[v] = true
+			a.Valores[j] = v
+		}
+	}
+
+	err := db.Transaction(func(tx database.DBHandler) error {
+		var variantes []models.Variante
+		if err := tx.Where("producto_id = ?", productoID).Find(&variantes); err != nil {
+			return err
+		}
+		for _, v := range variantes {
+			if validarAtributos(atributos, v.Atributos) != nil {
+				return ErrAtributosInvalidos
+			}
+		}
+
+		var anteriores []models.AtributoVariante
+		if err := tx.Where("producto_id = ?", productoID).Find(&anteriores); err != nil {
+			return err
+		}
+		for i := range anteriores {
+			if err := tx.Delete(&anteriores[i]); err != nil {
+				return err
+			}
+		}
+		for i := range atributos {
+			if err := tx.Create(&atributos[i]); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return atributos, nil
+}
+
+// CrearVariante valida y da de alta la variante; su stock inicial se suma al
+// del producto en la misma transacción.
+func CrearVariante(db database.DBHandler, producto *models.Producto, v *models.Variante) error {
+	if v.SKU != nil {
+		if err := models.ValidarSKU(*v.SKU); err != nil {
+			return err
+		}
+	}
+	if v.Stock < 0 || (v.Precio != nil && *v.Precio < 0) {
+		return ErrVarianteInvalida
+	}
+	v.ProductoID = producto.ID
+
+	return db.Transaction(func(tx database.DBHandler) error {
+		if err := tx.First(producto, producto.ID); err != nil {
+			return err
+		}
+		existentes, err := ListarVariantes(tx, producto.ID)
+		if err != nil {
+			return err
+		}
+		if err := validarAtributos(existentes.Atributos, v.Atributos); err != nil {
+			return err
+		}
+		if len(existentes.Variantes) == 0 && producto.Stock != 0 {
+			return ErrStockSinVariante
+		}
+		for _, otra := range existentes.Variantes {
+			if mismosAtributos(otra.Atributos, v.Atributos) {
+				return ErrVarianteDuplicada
+			}
+		}
+
+		if err := tx.Create(v); err != nil {
+			if errors.Is(err, gorm.ErrDuplicatedKey) {
+				return ErrSKUDuplicado
+			}
+			return err
+		}
+		if err := MoverStock(tx, producto.ID, nil, v.Stock); err != nil {
+			return err
+		}
+		producto.Stock += v.Stock
+		return nil
+	})
+}
+
+// ActualizarVariante cambia SKU y precio (nil vuelve al del producto). El
+// stock solo cambia con compras, ventas y devoluciones.
+func ActualizarVariante(db database.DBHandler, v *models.Variante, sku *string, precio *float64) error {
+	if sku != nil {
+		if err := models.ValidarSKU(*sku); err != nil {
+			return err
+		}
+	}
+	if precio != nil && *precio < 0 {
+		return ErrVarianteInvalida
+	}
+	v.SKU, v.Precio = sku, precio
+	if err := db.Save(v); err != nil {
+		if errors.Is(err, gorm.ErrDuplicatedKey) {
+			return ErrSKUDuplicado
+		}
+		return err
+	}
+	return nil
+}
+
+// BorrarVariante da de baja una variante sin stock. Las ventas y compras
+// anteriores la siguen referenciando (borrado lógico).
+func BorrarVariante(db database.DBHandler, v models.Variante) error {
+	if v.Stock != 0 {
+		return ErrVarianteConStock
+	}
+	return db.Delete(&v)
+}
+
+// ResolverVariante devuelve la variante que corresponde a una venta, compra o
+// devolución del producto: nil si el producto no tiene variantes, y error si
+// las tiene y no se indicó una o se indicó una de otro producto.
+func ResolverVariante(db database.DBHandler, producto models.Producto, varianteID *uint) (*models.Variante, error) {
+	if varianteID == nil {
+		var variantes []models.Variante
+		if err := db.Where("producto_id = ?", producto.ID).Limit(1).Find(&variantes); err != nil {
+			return nil, err
+		}
+		if len(variantes) > 0 {
+			return nil, ErrVarianteRequerida
+		}
+		return nil, nil
+	}
+
+	var v models.Variante
+	if err := db.First(&v, *varianteID); err != nil {
+		return nil, err
+	}
+	if v.ProductoID != producto.ID {
+		return nil, ErrVarianteAjena
+	}
+	return &v, nil
+}
+
+// validarAtributos controla que valores tenga exactamente un valor permitido
+// por cada atributo definido.
+func validarAtributos(definiciones []models.AtributoVariante, valores map[string]string) error {
+	if len(valores) != len(definiciones) || len(definiciones) == 0 {
+		return ErrVarianteInvalida
+	}
+	for _, a := range definiciones {
+		valor, ok := valores[a.Nombre]
+		if !ok {
+			return ErrVarianteInvalida
+		}
+		permitido := false
+		for _, p := range a.Valores {
+			permitido = permitido || p == valor
+		}
+		if !permitido {
+			return ErrVarianteInvalida
+		}
+	}
+	return nil
+}
+
+func mismosAtributos(a, b map[string]string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for k, v := range a {
+		if b[k] != v {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,109 @@
+package services
+
+import (
+	"testing"
+	"ventas-app/database"
+	"ventas-app/mocks"
+	"ventas-app/models"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+)
+
+func TestVariantes(t *testing.T) {
+	db := mocks.NewMemoryDB()
+	remera := models.Producto{Nombre: "Remera", Precio: 100}
+	require.NoError(t, db.Create(&remera))
+	otro := models.Producto{Nombre: "Buzo", Precio: 200}
+	require.NoError(t, db.Create(&otro))
+
+	_, err := DefinirAtributos(db, remera.ID, []models.AtributoVariante{{Nombre: "Talle", Valores: []string{"S", "S"}}})
+	assert.ErrorIs(t, err, ErrAtributosInvalidos)
+	_, err = DefinirAtributos(db, remera.ID, []models.AtributoVariante{
+		{Nombre: "Talle", Valores: []string{"S", "M"}},
+		{Nombre: "Color", Valores: []string{"Rojo", "Azul"}},
+	})
+	require.NoError(t, err)
+
+	precio := 120.0
+	m := models.Variante{Atributos: map[string]string{"Talle": "M", "Color": "Rojo"}, Precio: &precio, Stock: 3}
+	require.NoError(t, CrearVariante(db, &remera, &m))
+	s := models.Variante{Atributos: map[string]string{"Talle": "S", "Color": "Rojo"}, Stock: 2}
+	require.NoError(t, CrearVariante(db, &remera, &s))
+	assert.Equal(t, 5, remera.Stock, "el stock del producto es la suma de sus variantes")
+
+	assert.ErrorIs(t, CrearVariante(db, &remera, &models.Variante{Atributos: map[string]string{"Talle": "M", "Color": "Rojo"}}), ErrVarianteDuplicada)
+	assert.ErrorIs(t, CrearVariante(db, &remera, &models.Variante{Atributos: map[string]string{"Talle": "XL", "Color": "Rojo"}}), ErrVarianteInvalida)
+	assert.ErrorIs(t, CrearVariante(db, &remera, &models.Variante{Atributos: map[string]string{"Talle": "M"}}), ErrVarianteInvalida)
+
+	assert.Equal(t, 120.0, m.PrecioPara(remera))
+	assert.Equal(t, 100.0, s.PrecioPara(remera))
+
+	// Quitar un valor que usa una variante no se permite
+	_, err = DefinirAtributos(db, remera.ID, []models.AtributoVariante{
+		{Nombre: "Talle", Valores: []string{"M"}},
+		{Nombre: "Color", Valores: []string{"Rojo"}},
+	})
+	assert.ErrorIs(t, err, ErrAtributosInvalidos)
+
+	_, err = ResolverVariante(db, remera, nil)
+	assert.ErrorIs(t, err, ErrVarianteRequerida)
+	v, err := ResolverVariante(db, remera, &m.ID)
+	require.NoError(t, err)
+	assert.Equal(t, m.ID, v.ID)
+	_, err = ResolverVariante(db, otro, &m.ID)
+	assert.ErrorIs(t, err, ErrVarianteAjena)
+	v, err = ResolverVariante(db, otro, nil)
+	require.NoError(t, err)
+	assert.Nil(t, v)
+
+	otro.Stock = 4
+	require.NoError(t, db.Save(&otro))
+	_, err = DefinirAtributos(db, otro.ID, []models.AtributoVariante{{Nombre: "Talle", Valores: []string{"S"}}})
+	require.NoError(t, err)
+	assert.ErrorIs(t, CrearVariante(db, &otro, &models.Variante{Atributos: map[string]string{"Talle": "S"}}), ErrStockSinVariante)
+
+	assert.ErrorIs(t, BorrarVariante(db, s), ErrVarianteConStock)
+	listado, err := ListarVariantes(db, remera.ID)
+	require.NoError(t, err)
+	assert.Len(t, listado.Atributos, 2)
+	assert.Len(t, listado.Variantes, 2)
+}
+
+func TestMoverStock(t *testing.T) {
+	db := mocks.NewMemoryDB()
+	remera := models.Producto{Nombre: "Remera", Precio: 100}
+	require.NoError(t, db.Create(&remera))
+	_, err := DefinirAtributos(db, remera.ID, []models.AtributoVariante{{Nombre: "Talle", Valores: []string{"S", "M"}}})
+	require.NoError(t, err)
+	m := models.Variante{Atributos: map[string]string{"Talle": "M"}, Stock: 3}
+	require.NoError(t, CrearVariante(db, &remera, &m))
+	s := models.Variante{Atributos: map[string]string{"Talle": "S"}, Stock: 1}
+	require.NoError(t, CrearVariante(db, &remera, &s))
+
+	stock := func() (int, int) {
+		var p models.Producto
+		require.NoError(t, db.First(&p, remera.ID))
+		var v models.Variante
+		require.NoError(t, db.First(&v, s.ID))
+		return p.Stock, v.Stock
+	}
+
+	// Al producto le alcanza pero a la variante no: no se descuenta nada
+	err = db.Transaction(func(tx database.DBHandler) error {
+		return MoverStock(tx, remera.ID, &s.ID, -2)
+	})
+	assert.ErrorIs(t, err, ErrStockInsuficiente)
+	producto, variante := stock()
+	assert.Equal(t, 4, producto)
+	assert.Equal(t, 1, variante)
+
+	require.NoError(t, MoverStock(db, remera.ID, &s.ID, -1))
+	require.NoError(t, MoverStock(db, remera.ID, &s.ID, 2))
+	producto, variante = stock()
+	assert.Equal(t, 5, producto)
+	assert.Equal(t, 2, variante)
+
+	assert.ErrorIs(t, MoverStock(db, 999, nil, 1), gorm.ErrRecordNotFound)
+}
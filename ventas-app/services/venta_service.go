@@ -1 +1,217 @@
 package services
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"time"
+	"ventas-app/database"
+	"ventas-app/models"
+)
+
+// Agrupaciones de ReporteVentas.
+const (
+	AgruparDia      = "dia"
+	AgruparSemana   = "semana"
+	AgruparMes      = "mes"
+	AgruparProducto = "producto"
+	AgruparUsuario  = "usuario"
+	// AgruparCategoria es por la categoría actual del producto (solo
+	// GenerarReporteMargen).
+	AgruparCategoria = "categoria"
+)
+
+// ErrAgrupacionInvalida: GenerarReporteVentas no conoce la agrupación pedida.
+var ErrAgrupacionInvalida = errors.New("agrupación inválida")
+
+// FiltroReporte limita el reporte a las ventas con Desde <= fecha < Hasta
+// (los ceros no limitan) y las agrupa según Agrupar.
+type FiltroReporte struct {
+	Desde   time.Time
+	Hasta   time.Time
+	Agrupar string
+}
+
+// FilaReporte son los totales de un grupo. Grupo es el período (día
+// "2006-01-02", semana por la fecha de su lunes, mes "2006-01") o el ID de
+// producto o usuario, con su Nombre.
+type FilaReporte struct {
+	Grupo    string  `json:"grupo,omitempty"`
+	Nombre   string  `json:"nombre,omitempty"`
+	Ventas   int64   `json:"ventas"`
+	Unidades int64   `json:"unidades"`
+	Neto     float64 `json:"neto"`
+	IVA      float64 `json:"iva"`
+	Bruto    float64 `json:"bruto"`
+}
+
+// ReporteVentas es el resultado: una fila por grupo y el total general.
+type ReporteVentas struct {
+	Filas   []FilaReporte `json:"filas"`
+	Totales FilaReporte   `json:"totales"`
+}
+
+const columnasReporte = "COUNT(*) AS ventas, COALESCE(SUM(cantidad), 0) AS unidades, " +
+	"COALESCE(SUM(neto), 0) AS neto, COALESCE(SUM(iva), 0) AS iva, COALESCE(SUM(precio_final), 0) AS bruto"
+
+// GenerarReporteVentas agrega las ventas en la base (GROUP BY), sin traer las
+// filas a memoria. Los períodos los calcula la base, en UTC como los límites
+// de FiltroReporte.
+func GenerarReporteVentas(db database.DBHandler, filtro FiltroReporte) (ReporteVentas, error) {
+	grupo, err := expresionGrupo(db.Driver(), filtro.Agrupar)
+	if err != nil {
+		return ReporteVentas{}, err
+	}
+
+	reporte := ReporteVentas{Filas: []FilaReporte{}}
+	if err := ventasEnRango(db, filtro).Select(columnasReporte).Scan(&reporte.Totales); err != nil {
+		return ReporteVentas{}, err
+	}
+
+	orden := "grupo"
+	if filtro.Agrupar == AgruparProducto || filtro.Agrupar == AgruparUsuario {
+		orden = "bruto DESC"
+	}
+	err = ventasEnRango(db, filtro).Select(grupo + " AS grupo, " + columnasReporte).Group(grupo).Order(orden).Scan(&reporte.Filas)
+	if err != nil {
+		return ReporteVentas{}, err
+	}
+
+	grupos := make([]string, len(reporte.Filas))
+	for i, f := range reporte.Filas {
+		grupos[i] = f.Grupo
+	}
+	nombres, err := nombresGrupos(db, filtro.Agrupar, grupos)
+	if err != nil {
+		return ReporteVentas{}, err
+	}
+
+	redondear(&reporte.Totales)
+	for i := range reporte.Filas {
+		reporte.Filas[i].Nombre = nombres[reporte.Filas[i].Grupo]
+		redondear(&reporte.Filas[i])
+	}
+	return reporte, nil
+}
+
+// ventasEnRango filtra las ventas por fecha. Cada consulta tiene que armar la
+// suya: gorm reutiliza el statement al encadenar.
+func ventasEnRango(db database.DBHandler, filtro FiltroReporte) database.DBHandler {
+	venta := db.Tabla(&models.Venta{})
+	q := db.Model(&models.Venta{})
+	if !filtro.Desde.IsZero() {
+		q = q.Where(venta+".created_at >= ?", filtro.Desde)
+	}
+	if !filtro.Hasta.IsZero() {
+		q = q.Where(venta+".created_at < ?", filtro.Hasta)
+	}
+	return q
+}
+
+// conCategoria suma a q, una consulta sobre ventas, el producto de cada una
+// para agrupar por su categoría. Devuelve la consulta y la columna de la
+// categoría (NULL si el producto no tiene).
+func conCategoria(db, q database.DBHandler) (database.DBHandler, string) {
+	venta, productos := db.Tabla(&models.Venta{}), db.Tabla(&models.Producto{})
+	q = q.Joins(fmt.Sprintf("LEFT JOIN %[1]s ON %[1]s.id = %[2]s.producto_id", productos, venta))
+	return q, productos + ".categoria_id"
+}
+
+// expresionGrupo devuelve la expresión SQL del GROUP BY para cada motor.
+func expresionGrupo(driver, agrupar string) (string, error) {
+	switch agrupar {
+	case AgruparProducto:
+		return "producto_id", nil
+	case AgruparUsuario:
+		return "usuario_id", nil
+	}
+
+	var expresiones map[string]string
+	switch driver {
+	case database.DriverPostgres:
+		expresiones = map[string]string{
+			AgruparDia:    "to_char(created_at AT TIME ZONE 'UTC', 'YYYY-MM-DD')",
+			AgruparSemana: "to_char(date_trunc('week', created_at AT TIME ZONE 'UTC'), 'YYYY-MM-DD')",
+			AgruparMes:    "to_char(created_at AT TIME ZONE 'UTC', 'YYYY-MM')",
+		}
+	case database.DriverMySQL:
+		// El driver guarda las fechas en UTC (loc por defecto)
+		expresiones = map[string]string{
+			AgruparDia:    "DATE_FORMAT(created_at, '%Y-%m-%d')",
+			AgruparSemana: "DATE_FORMAT(DATE_SUB(created_at, INTERVAL WEEKDAY(created_at) DAY), '%Y-%m-%d')",
+			AgruparMes:    "DATE_FORMAT(created_at, '%Y-%m')",
+		}
+	default:
+		// SQLite: strftime pasa las fechas a UTC; 'weekday 0' avanza al
+		// domingo y 6 días antes es el lunes
+		expresiones = map[string]string{
+			AgruparDia:    "strftime('%Y-%m-%d', created_at)",
+			AgruparSemana: "date(created_at, 'weekday 0', '-6 days')",
+			AgruparMes:    "strftime('%Y-%m', created_at)",
+		}
+	}
+
+	expr, ok := expresiones[agrupar]
+	if !ok {
+		return "", ErrAgrupacionInvalida
+	}
+	return expr, nil
+}
+
+// nombresGrupos devuelve el nombre del producto o usuario de cada grupo (su
+// ID); para las demás agrupaciones no hay nombres.
+func nombresGrupos(db database.DBHandler, agrupar string, grupos []string) (map[string]string, error) {
+	nombres := map[string]string{}
+	if len(grupos) == 0 || (agrupar != AgruparProducto && agrupar != AgruparUsuario && agrupar != AgruparCategoria) {
+		return nombres, nil
+	}
+	var ids []uint
+	for _, g := range grupos {
+		if id, err := strconv.ParseUint(g, 10, 64); err == nil {
+			ids = append(ids, uint(id))
+		}
+	}
+
+	if agrupar == AgruparProducto {
+		var productos []models.Producto
+		if err := db.Where("id IN ?", ids).Find(&productos); err != nil {
+			return nil, err
+		}
+		for _, p := range productos {
+			nombres[strconv.FormatUint(uint64(p.ID), 10)] = p.Nombre
+		}
+		return nombres, nil
+	}
+
+	if agrupar == AgruparCategoria {
+		var categorias []models.Categoria
+		if err := db.Where("id IN ?", ids).Find(&categorias); err != nil {
+			return nil, err
+		}
+		for _, cat := range categorias {
+			nombres[strconv.FormatUint(uint64(cat.ID), 10)] = cat.Nombre
+		}
+		nombres[""] = "Sin categoría"
+		return nombres, nil
+	}
+
+	var usuarios []models.Usuario
+	if err := db.Where("id IN ?", ids).Find(&usuarios); err != nil {
+		return nil, err
+	}
+	for _, u := range usuarios {
+		nombres[strconv.FormatUint(uint64(u.ID), 10)] = u.Nombre
+	}
+	return nombres, nil
+}
+
+func redondear(f *FilaReporte) {
+	f.Neto = centavos(f.Neto)
+	f.IVA = centavos(f.IVA)
+	f.Bruto = centavos(f.Bruto)
+}
+
+func centavos(x float64) float64 {
+	return math.Round(x*100) / 100
+}
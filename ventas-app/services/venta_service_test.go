@@ -0,0 +1,106 @@
+package services
+
+import (
+	"testing"
+	"time"
+	"ventas-app/config"
+	"ventas-app/database"
+	"ventas-app/models"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+)
+
+func baseReportes(t *testing.T) database.DBHandler {
+	t.Helper()
+	gdb, err := database.Open(config.DatabaseConfig{Driver: database.DriverSQLite})
+	require.NoError(t, err)
+	require.NoError(t, database.Migrate(gdb))
+	db := &database.GormDB{DB: gdb}
+
+	require.NoError(t, db.Create(&models.Producto{Nombre: "Mate", Costo: 95, Precio: 100}))
+	require.NoError(t, db.Create(&models.Producto{Nombre: "Termo", Costo: 200, Precio: 300}))
+	require.NoError(t, db.Create(&models.Usuario{Nombre: "ana", Clave: "x", Rol: "vendedor"}))
+
+	dia := func(d, h int) gorm.Model {
+		return gorm.Model{CreatedAt: time.Date(2026, time.March, d, h, 0, 0, 0, time.UTC)}
+	}
+	// Lunes 2, martes 3 y lunes 9 de marzo, y martes 31 (semana del 30). El
+	// Mate costaba 60 y después 90; hoy cuesta 95.
+	for _, v := range []models.Venta{
+		{Model: dia(2, 10), UsuarioID: 1, ProductoID: 1, Cantidad: 2, Neto: 200, IVA: 42, PrecioFinal: 242, CostoUnit: 60},
+		{Model: dia(3, 23), UsuarioID: 1, ProductoID: 2, Cantidad: 1, Neto: 300, IVA: 63, PrecioFinal: 363, CostoUnit: 200},
+		{Model: dia(9, 9), UsuarioID: 2, ProductoID: 1, Cantidad: 1, Neto: 100, IVA: 21, PrecioFinal: 121, CostoUnit: 90},
+		{Model: dia(31, 12), UsuarioID: 2, ProductoID: 2, Cantidad: 3, Neto: 900, IVA: 189, PrecioFinal: 1089, CostoUnit: 200},
+	} {
+		v := v
+		require.NoError(t, db.Create(&v))
+	}
+	return db
+}
+
+func TestGenerarReporteVentas(t *testing.T) {
+	db := baseReportes(t)
+
+	t.Run("por día con totales", func(t *testing.T) {
+		r, err := GenerarReporteVentas(db, FiltroReporte{Agrupar: AgruparDia})
+		require.NoError(t, err)
+
+		require.Len(t, r.Filas, 4)
+		assert.Equal(t, "2026-03-02", r.Filas[0].Grupo)
+		assert.Equal(t, "2026-03-31", r.Filas[3].Grupo)
+		assert.Equal(t, FilaReporte{Ventas: 4, Unidades: 7, Neto: 1500, IVA: 315, Bruto: 1815}, r.Totales)
+	})
+
+	t.Run("por semana desde el lunes", func(t *testing.T) {
+		r, err := GenerarReporteVentas(db, FiltroReporte{Agrupar: AgruparSemana})
+		require.NoError(t, err)
+
+		require.Len(t, r.Filas, 3)
+		assert.Equal(t, FilaReporte{Grupo: "2026-03-02", Ventas: 2, Unidades: 3, Neto: 500, IVA: 105, Bruto: 605}, r.Filas[0])
+		assert.Equal(t, "2026-03-09", r.Filas[1].Grupo)
+		assert.Equal(t, "2026-03-30", r.Filas[2].Grupo)
+	})
+
+	t.Run("por mes con rango de fechas", func(t *testing.T) {
+		r, err := GenerarReporteVentas(db, FiltroReporte{
+			Agrupar: AgruparMes,
+			Desde:   time.Date(2026, time.March, 3, 0, 0, 0, 0, time.UTC),
+			Hasta:   time.Date(2026, time.March, 31, 0, 0, 0, 0, time.UTC),
+		})
+		require.NoError(t, err)
+
+		require.Len(t, r.Filas, 1)
+		assert.Equal(t, "2026-03", r.Filas[0].Grupo)
+		assert.Equal(t, int64(2), r.Filas[0].Ventas)
+		assert.Equal(t, 484.0, r.Totales.Bruto)
+	})
+
+	t.Run("por producto y usuario con nombre", func(t *testing.T) {
+		r, err := GenerarReporteVentas(db, FiltroReporte{Agrupar: AgruparProducto})
+		require.NoError(t, err)
+		require.Len(t, r.Filas, 2)
+		assert.Equal(t, FilaReporte{Grupo: "2", Nombre: "Termo", Ventas: 2, Unidades: 4, Neto: 1200, IVA: 252, Bruto: 1452}, r.Filas[0])
+		assert.Equal(t, "Mate", r.Filas[1].Nombre)
+
+		r, err = GenerarReporteVentas(db, FiltroReporte{Agrupar: AgruparUsuario})
+		require.NoError(t, err)
+		require.Len(t, r.Filas, 2)
+		assert.Equal(t, "2", r.Filas[0].Grupo)
+		assert.Empty(t, r.Filas[0].Nombre, "usuario inexistente")
+		assert.Equal(t, "ana", r.Filas[1].Nombre)
+	})
+
+	t.Run("sin ventas en el rango", func(t *testing.T) {
+		r, err := GenerarReporteVentas(db, FiltroReporte{Agrupar: AgruparDia, Desde: time.Date(2030, 1, 1, 0, 0, 0, 0, time.UTC)})
+		require.NoError(t, err)
+		assert.Empty(t, r.Filas)
+		assert.Zero(t, r.Totales.Ventas)
+	})
+
+	t.Run("agrupación desconocida", func(t *testing.T) {
+		_, err := GenerarReporteVentas(db, FiltroReporte{Agrupar: "anio"})
+		assert.ErrorIs(t, err, ErrAgrupacionInvalida)
+	})
+}
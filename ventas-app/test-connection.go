@@ -3,32 +3,35 @@ package main
 import (
 	"fmt"
 	"log"
+	"ventas-app/config"
 	"ventas-app/database"
-
-	"github.com/joho/godotenv"
 )
 
 func main() {
-	// Cargar variables de entorno
-	err := godotenv.Load()
+	// Cargar configuración (.env, CONFIG_FILE y variables de entorno)
+	cfg, err := config.Load()
 	if err != nil {
-		log.Printf("Warning: No se pudo cargar el archivo .env: %v", err)
+		log.Fatal("Configuración inválida:\n", err)
 	}
 
-	fmt.Println("Intentando conectar a la base de datos Aiven...")
+	fmt.Printf("Intentando conectar a la base de datos (%s)...\n", cfg.Database.Driver)
 
 	// Intentar conectar
-	db := database.Connect()
+	db := database.Connect(cfg.Env, cfg.Database)
 	if db != nil {
-		fmt.Println("✅ Conexión exitosa a Aiven MySQL!")
+		fmt.Println("✅ Conexión exitosa!")
 
 		// Verificar que podemos hacer una consulta simple
+		consulta := "SELECT VERSION()"
+		if db.Dialector.Name() == database.DriverSQLite {
+			consulta = "SELECT sqlite_version()"
+		}
 		var version string
-		err := db.Raw("SELECT VERSION()").Scan(&version).Error
+		err := db.Raw(consulta).Scan(&version).Error
 		if err != nil {
 			log.Printf("Error ejecutando consulta de prueba: %v", err)
 		} else {
-			fmt.Printf("🎉 Versión de MySQL: %s\n", version)
+			fmt.Printf("🎉 Versión de la base: %s\n", version)
 		}
 	} else {
 		fmt.Println("❌ Error: no se pudo establecer la conexión")
@@ -0,0 +1,192 @@
+package utils
+
+import (
+	"crypto/ed25519"
+	"crypto/rsa"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"math/big"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// ErrSinClave se devuelve al firmar o validar si no hay ninguna clave configurada.
+var ErrSinClave = errors.New("no hay clave JWT configurada")
+
+// Clave es una clave de firma identificada por kid. Para HMAC Firma y
+// Verificacion son el mismo secreto; para RS256/EdDSA la privada y la pública.
+type Clave struct {
+	Kid          string
+	Metodo       jwt.SigningMethod
+	Firma        interface{}
+	Verificacion interface{}
+}
+
+// Llavero tiene la clave activa (con la que se firma) y las que solo se usan
+// para validar. Rotar sin cortar sesiones: 1) agregar la clave nueva como no
+// activa en todas las instancias, 2) activarla, 3) quitar la vieja cuando
+// venzan los tokens que firmó (AccessTTL).
+type Llavero struct {
+	activa *Clave
+	claves map[string]*Clave
+}
+
+// NuevoLlavero arma un llavero con las claves dadas; activa es el kid con el
+// que se firma (vacío = la primera).
+func NuevoLlavero(activa string, claves ...Clave) (*Llavero, error) {
+	if len(claves) == 0 {
+		return nil, ErrSinClave
+	}
+	l := &Llavero{claves: map[string]*Clave{}}
+	for i := range claves {
+		c := &claves[i]
+		if c.Kid == "" {
+			return nil, errors.New("clave JWT sin kid")
+		}
+		if _, repetida := l.claves[c.Kid]; repetida {
+			return nil, fmt.Errorf("kid JWT repetido: %s", c.Kid)
+		}
+		if b, ok := c.Firma.([]byte); ok && len(b) == 0 {
+			return nil, fmt.Errorf("clave JWT %s vacía", c.Kid)
+		}
+		l.claves[c.Kid] = c
+	}
+
+	if activa == "" {
+		activa = claves[0].Kid
+	}
+	l.activa = l.claves[activa]
+	if l.activa == nil {
+		return nil, fmt.Errorf("kid JWT activo desconocido: %s", activa)
+	}
+	return l, nil
+}
+
+// CargarLlavero arma el llavero desde la configuración. Cada entrada de
+// claves tiene la forma "kid:ALG:valor", donde valor es el secreto para
+// HS256/HS384/HS512 o la ruta a la clave privada PEM para RS256 y EdDSA.
+// Si no hay claves, secreto se usa como única clave HS256 con kid "default".
+func CargarLlavero(secreto string, claves []string, activa string) (*Llavero, error) {
+	if len(claves) == 0 {
+		if secreto == "" {
+			return nil, ErrSinClave
+		}
+		return NuevoLlavero("", ClaveHMAC("default", secreto))
+	}
+
+	var parseadas []Clave
+	for _, entrada := range claves {
+		partes := strings.SplitN(entrada, ":", 3)
+		if len(partes) != 3 || partes[2] == "" {
+			return nil, errors.New("clave JWT mal formada: se espera kid:ALG:valor")
+		}
+		c, err := parsearClave(partes[0], partes[1], partes[2])
+		if err != nil {
+			return nil, err
+		}
+		parseadas = append(parseadas, c)
+	}
+	return NuevoLlavero(activa, parseadas...)
+}
+
+// ClaveHMAC devuelve una clave HS256.
+func ClaveHMAC(kid, secreto string) Clave {
+	return Clave{Kid: kid, Metodo: jwt.SigningMethodHS256, Firma: []byte(secreto), Verificacion: []byte(secreto)}
+}
+
+func parsearClave(kid, alg, valor string) (Clave, error) {
+	c := Clave{Kid: kid, Metodo: jwt.GetSigningMethod(alg)}
+
+	switch alg {
+	case "HS256", "HS384", "HS512":
+		c.Firma, c.Verificacion = []byte(valor), []byte(valor)
+		return c, nil
+	case "RS256", "EdDSA":
+	default:
+		return Clave{}, fmt.Errorf("clave JWT %s: algoritmo no soportado %q", kid, alg)
+	}
+
+	pem, err := os.ReadFile(valor)
+	if err != nil {
+		return Clave{}, fmt.Errorf("clave JWT %s: %w", kid, err)
+	}
+	if alg == "RS256" {
+		privada, err := jwt.ParseRSAPrivateKeyFromPEM(pem)
+		if err != nil {
+			return Clave{}, fmt.Errorf("clave JWT %s: %w", kid, err)
+		}
+		c.Firma, c.Verificacion = privada, &privada.PublicKey
+		return c, nil
+	}
+	privada, err := jwt.ParseEdPrivateKeyFromPEM(pem)
+	if err != nil {
+		return Clave{}, fmt.Errorf("clave JWT %s: %w", kid, err)
+	}
+	c.Firma, c.Verificacion = privada, privada.(ed25519.PrivateKey).Public()
+	return c, nil
+}
+
+// claveVerificacion es el jwt.Keyfunc: busca la clave por kid y exige que el
+// algoritmo del token sea el de esa clave (evita la confusión HS256/RS256).
+func (l *Llavero) claveVerificacion(token *jwt.Token) (interface{}, error) {
+	kid, _ := token.Header["kid"].(string)
+	c, ok := l.claves[kid]
+	if !ok {
+		return nil, fmt.Errorf("kid desconocido: %q", kid)
+	}
+	if token.Method.Alg() != c.Metodo.Alg() {
+		return nil, fmt.Errorf("algoritmo %s no permitido para kid %s", token.Method.Alg(), kid)
+	}
+	return c.Verificacion, nil
+}
+
+// metodos devuelve los algoritmos aceptados: solo los de las claves cargadas.
+func (l *Llavero) metodos() []string {
+	var algs []string
+	vistos := map[string]bool{}
+	for _, c := range l.claves {
+		if alg := c.Metodo.Alg(); !vistos[alg] {
+			vistos[alg] = true
+			algs = append(algs, alg)
+		}
+	}
+	return algs
+}
+
+// JWK es una clave pública en formato JSON Web Key (RFC 7517).
+type JWK struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Alg string `json:"alg"`
+	Use string `json:"use"`
+	N   string `json:"n,omitempty"`
+	E   string `json:"e,omitempty"`
+	Crv string `json:"crv,omitempty"`
+	X   string `json:"x,omitempty"`
+}
+
+// JWKS devuelve las claves públicas del llavero. Las HMAC nunca se publican.
+func (l *Llavero) JWKS() []JWK {
+	claves := []JWK{}
+	for _, c := range l.claves {
+		switch pub := c.Verificacion.(type) {
+		case *rsa.PublicKey:
+			claves = append(claves, JWK{
+				Kty: "RSA", Kid: c.Kid, Alg: c.Metodo.Alg(), Use: "sig",
+				N: base64.RawURLEncoding.EncodeToString(pub.N.Bytes()),
+				E: base64.RawURLEncoding.EncodeToString(big.NewInt(int64(pub.E)).Bytes()),
+			})
+		case ed25519.PublicKey:
+			claves = append(claves, JWK{
+				Kty: "OKP", Kid: c.Kid, Alg: c.Metodo.Alg(), Use: "sig",
+				Crv: "Ed25519", X: base64.RawURLEncoding.EncodeToString(pub),
+			})
+		}
+	}
+	sort.Slice(claves, func(i, j int) bool { return claves[i].Kid < claves[j].Kid })
+	return claves
+}
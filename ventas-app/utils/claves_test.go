@@ -0,0 +1,179 @@
+package utils
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"errors"
+	"math/big"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// escribirPEM guarda la clave privada en PKCS#8 y devuelve la ruta.
+func escribirPEM(t *testing.T, nombre string, privada interface{}) string {
+	t.Helper()
+	der, err := x509.MarshalPKCS8PrivateKey(privada)
+	require.NoError(t, err)
+	ruta := filepath.Join(t.TempDir(), nombre)
+	require.NoError(t, os.WriteFile(ruta, pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der}), 0o600))
+	return ruta
+}
+
+func usarLlavero(t *testing.T, activa string, claves ...string) {
+	t.Helper()
+	l, err := CargarLlavero("", claves, activa)
+	require.NoError(t, err)
+	SetLlavero(l)
+}
+
+func TestSinClave(t *testing.T) {
+	SetSecret("")
+
+	_, err := GenerateToken(1, "vendedor")
+	assert.True(t, errors.Is(err, ErrSinClave))
+
+	_, err = ParseToken("a.b.c")
+	assert.True(t, errors.Is(err, ErrSinClave))
+
+	_, err = CargarLlavero("", nil, "")
+	assert.True(t, errors.Is(err, ErrSinClave))
+}
+
+func TestRotacionDeClaves(t *testing.T) {
+	usarLlavero(t, "", "v1:HS256:secreto-viejo")
+	viejo, err := GenerateToken(1, "vendedor")
+	require.NoError(t, err)
+
+	// Paso 1 y 2: la nueva firma, la vieja solo valida
+	usarLlavero(t, "v2", "v1:HS256:secreto-viejo", "v2:HS256:secreto-nuevo")
+	nuevo, err := GenerateToken(1, "vendedor")
+	require.NoError(t, err)
+
+	token, err := ParseToken(viejo)
+	require.NoError(t, err)
+	assert.Equal(t, "v1", token.Header["kid"])
+	token, err = ParseToken(nuevo)
+	require.NoError(t, err)
+	assert.Equal(t, "v2", token.Header["kid"])
+
+	// Paso 3: se quita la vieja
+	usarLlavero(t, "", "v2:HS256:secreto-nuevo")
+	_, err = ParseToken(viejo)
+	assert.Error(t, err)
+	_, err = ParseToken(nuevo)
+	assert.NoError(t, err)
+}
+
+func TestParseToken_AlgoritmosFijos(t *testing.T) {
+	privada, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+	usarLlavero(t, "", "rsa:RS256:"+escribirPEM(t, "rsa.pem", privada))
+
+	t.Run("rechaza alg none", func(t *testing.T) {
+		token := jwt.NewWithClaims(jwt.SigningMethodNone, jwt.MapClaims{"user_id": 1})
+		token.Header["kid"] = "rsa"
+		str, err := token.SignedString(jwt.UnsafeAllowNoneSignatureType)
+		require.NoError(t, err)
+
+		_, err = ParseToken(str)
+		assert.Error(t, err)
+	})
+
+	t.Run("rechaza HS256 firmado con la clave pública", func(t *testing.T) {
+		pub, err := x509.MarshalPKIXPublicKey(&privada.PublicKey)
+		require.NoError(t, err)
+		token := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{"user_id": 1})
+		token.Header["kid"] = "rsa"
+		str, err := token.SignedString(pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: pub}))
+		require.NoError(t, err)
+
+		_, err = ParseToken(str)
+		assert.Error(t, err)
+	})
+
+	t.Run("rechaza kid desconocido o ausente", func(t *testing.T) {
+		for _, kid := range []interface{}{"otro", nil} {
+			token := jwt.NewWithClaims(jwt.SigningMethodRS256, jwt.MapClaims{"user_id": 1})
+			if kid != nil {
+				token.Header["kid"] = kid
+			}
+			str, err := token.SignedString(privada)
+			require.NoError(t, err)
+
+			_, err = ParseToken(str)
+			assert.Error(t, err, kid)
+		}
+	})
+
+	t.Run("acepta RS256 propio", func(t *testing.T) {
+		str, err := GenerateToken(7, "comprador")
+		require.NoError(t, err)
+
+		token, err := ParseToken(str)
+		require.NoError(t, err)
+		assert.Equal(t, "RS256", token.Method.Alg())
+	})
+}
+
+func TestJWKS(t *testing.T) {
+	rsaPriv, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+	_, edPriv, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+
+	usarLlavero(t, "ed",
+		"hs:HS256:no-se-publica",
+		"rsa:RS256:"+escribirPEM(t, "rsa.pem", rsaPriv),
+		"ed:EdDSA:"+escribirPEM(t, "ed.pem", edPriv),
+	)
+
+	claves := JWKS()
+	require.Len(t, claves, 2)
+
+	assert.Equal(t, "ed", claves[0].Kid)
+	assert.Equal(t, "OKP", claves[0].Kty)
+	assert.Equal(t, "Ed25519", claves[0].Crv)
+	x, err := base64.RawURLEncoding.DecodeString(claves[0].X)
+	require.NoError(t, err)
+	assert.Equal(t, []byte(edPriv.Public().(ed25519.PublicKey)), x)
+
+	assert.Equal(t, "rsa", claves[1].Kid)
+	assert.Equal(t, "RS256", claves[1].Alg)
+	n, err := base64.RawURLEncoding.DecodeString(claves[1].N)
+	require.NoError(t, err)
+	assert.Equal(t, 0, new(big.Int).SetBytes(n).Cmp(rsaPriv.N))
+	assert.Equal(t, "AQAB", claves[1].E)
+
+	// Firma con la activa (EdDSA)
+	str, err := GenerateToken(1, "vendedor")
+	require.NoError(t, err)
+	token, err := ParseToken(str)
+	require.NoError(t, err)
+	assert.Equal(t, "EdDSA", token.Method.Alg())
+}
+
+func TestCargarLlavero_Errores(t *testing.T) {
+	casos := map[string][]string{
+		"mal formada":         {"solo-kid"},
+		"valor vacío":         {"k1:HS256:"},
+		"algoritmo inválido":  {"k1:none:x"},
+		"kid repetido":        {"k1:HS256:a", "k1:HS256:b"},
+		"archivo inexistente": {"k1:RS256:/no/existe.pem"},
+	}
+	for nombre, claves := range casos {
+		_, err := CargarLlavero("", claves, "")
+		assert.Error(t, err, nombre)
+	}
+
+	_, err := CargarLlavero("", []string{"k1:HS256:a"}, "k2")
+	assert.Error(t, err, "kid activo desconocido")
+}
@@ -1,26 +1,157 @@
 package utils
 
 import (
-	"os"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
 	"time"
 
 	"github.com/golang-jwt/jwt/v5"
 )
 
-var secret = []byte(os.Getenv("JWT_SECRET"))
+// llavero se configura al arrancar con SetLlavero (config.JWTConfig). Sin
+// llavero no se firma ni se valida ningún token.
+var llavero *Llavero
 
-func GenerateToken(userID uint, rol string) (string, error) {
-	claims := jwt.MapClaims{
-		"user_id": userID,
-		"rol":     rol,
-		"exp":     time.Now().Add(time.Hour * 24).Unix(),
+// AccessTTL es la vida de los access tokens. Son cortos: la sesión se
+// extiende con el refresh token (services.RenovarSesion).
+var AccessTTL = 15 * time.Minute
+
+// SetLlavero define las claves con las que se firman y validan los tokens.
+func SetLlavero(l *Llavero) {
+	llavero = l
+}
+
+// SetSecret usa s como única clave HS256. Un secreto vacío deja al servidor
+// sin clave.
+func SetSecret(s string) {
+	l, err := CargarLlavero(s, nil, "")
+	if err != nil {
+		l = nil
+	}
+	SetLlavero(l)
+}
+
+// JWKS devuelve las claves públicas para que otros servicios validen los tokens.
+func JWKS() []JWK {
+	if llavero == nil {
+		return []JWK{}
+	}
+	return llavero.JWKS()
+}
+
+// Issuer y Audience se incluyen en cada token y se exigen al validarlo.
+var (
+	Issuer   = "ventas-app"
+	Audience = "ventas-app"
+)
+
+// Claims son los datos que viajan en el access token. Tenant es el entorno
+// (base de datos) en el que se autenticó el usuario y Sesion la versión de
+// sesiones del usuario al emitirlo (ver services.RevocarSesiones).
+type Claims struct {
+	UserID uint   `json:"user_id"`
+	Rol    string `json:"rol"`
+	Tenant string `json:"tenant,omitempty"`
+	Sesion uint   `json:"ses,omitempty"`
+	jwt.RegisteredClaims
+}
+
+// NewClaims arma los claims de un access token nuevo con un jti único, que
+// es lo que se revoca al hacer logout.
+func NewClaims(userID uint, rol, tenant string) Claims {
+	ahora := time.Now()
+	return Claims{
+		UserID: userID,
+		Rol:    rol,
+		Tenant: tenant,
+		RegisteredClaims: jwt.RegisteredClaims{
+			ID:        RandomID(),
+			Issuer:    Issuer,
+			Audience:  jwt.ClaimStrings{Audience},
+			IssuedAt:  jwt.NewNumericDate(ahora),
+			ExpiresAt: jwt.NewNumericDate(ahora.Add(AccessTTL)),
+		},
 	}
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-	return token.SignedString(secret)
 }
 
+// NewClaimsMFA arma los claims del token intermedio que recibe quien pasó la
+// clave pero todavía debe ingresar el código TOTP. Lleva otra audiencia, así
+// que ParseToken (y AuthRequired) lo rechazan: solo sirve para ParseClaimsMFA.
+func NewClaimsMFA(userID uint, rol, tenant string, ttl time.Duration) Claims {
+	claims := NewClaims(userID, rol, tenant)
+	claims.Audience = jwt.ClaimStrings{audienciaMFA()}
+	claims.ExpiresAt = jwt.NewNumericDate(claims.IssuedAt.Add(ttl))
+	return claims
+}
+
+func audienciaMFA() string {
+	return Audience + "/mfa"
+}
+
+// SignClaims firma los claims con la clave activa.
+func SignClaims(claims Claims) (string, error) {
+	if llavero == nil {
+		return "", ErrSinClave
+	}
+
+	clave := llavero.activa
+	token := jwt.NewWithClaims(clave.Metodo, claims)
+	token.Header["kid"] = clave.Kid
+	return token.SignedString(clave.Firma)
+}
+
+// GenerateToken firma un access token sin tenant.
+func GenerateToken(userID uint, rol string) (string, error) {
+	return SignClaims(NewClaims(userID, rol, ""))
+}
+
+// ParseToken valida firma, vencimiento, emisor y audiencia, y devuelve el
+// token con sus Claims. Solo acepta los algoritmos de las claves
+// configuradas y cada kid únicamente con su propio algoritmo.
 func ParseToken(tokenStr string) (*jwt.Token, error) {
-	return jwt.Parse(tokenStr, func(token *jwt.Token) (interface{}, error) {
-		return secret, nil
-	})
+	return parsear(tokenStr, Audience)
+}
+
+// ParseClaims es ParseToken devolviendo directamente los claims.
+func ParseClaims(tokenStr string) (*Claims, error) {
+	return claimsDe(ParseToken(tokenStr))
+}
+
+// ParseClaimsMFA valida un token intermedio emitido con NewClaimsMFA.
+func ParseClaimsMFA(tokenStr string) (*Claims, error) {
+	return claimsDe(parsear(tokenStr, audienciaMFA()))
+}
+
+func parsear(tokenStr, audiencia string) (*jwt.Token, error) {
+	if llavero == nil {
+		return nil, ErrSinClave
+	}
+	return jwt.ParseWithClaims(tokenStr, &Claims{}, llavero.claveVerificacion,
+		jwt.WithValidMethods(llavero.metodos()),
+		jwt.WithIssuer(Issuer),
+		jwt.WithAudience(audiencia),
+		jwt.WithExpirationRequired(),
+		jwt.WithIssuedAt(),
+	)
+}
+
+func claimsDe(token *jwt.Token, err error) (*Claims, error) {
+	if err != nil {
+		return nil, err
+	}
+	claims, ok := token.Claims.(*Claims)
+	if !ok || !token.Valid {
+		return nil, errors.New("token inválido")
+	}
+	return claims, nil
+}
+
+// RandomID devuelve 16 bytes aleatorios en hexadecimal.
+func RandomID() string {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		panic(err) // crypto/rand no falla en las plataformas soportadas
+	}
+	return hex.EncodeToString(b)
 }
@@ -3,15 +3,15 @@ package utils
 import (
 	"os"
 	"testing"
+	"time"
 
-	"github.com/golang-jwt/jwt/v5"
 	"github.com/stretchr/testify/assert"
 )
 
 func TestGenerateToken(t *testing.T) {
 	// Setup
 	os.Setenv("JWT_SECRET", "test-secret-key")
-	secret = []byte(os.Getenv("JWT_SECRET"))
+	SetSecret(os.Getenv("JWT_SECRET"))
 
 	t.Run("genera token válido", func(t *testing.T) {
 		token, err := GenerateToken(1, "admin")
@@ -32,17 +32,17 @@ func TestGenerateToken(t *testing.T) {
 		assert.NoError(t, err)
 		assert.True(t, token.Valid)
 
-		claims, ok := token.Claims.(jwt.MapClaims)
+		claims, ok := token.Claims.(*Claims)
 		assert.True(t, ok)
-		assert.Equal(t, float64(userID), claims["user_id"])
-		assert.Equal(t, rol, claims["rol"])
-		assert.NotNil(t, claims["exp"])
+		assert.Equal(t, userID, claims.UserID)
+		assert.Equal(t, rol, claims.Rol)
+		assert.NotNil(t, claims.ExpiresAt)
 	})
 }
 
 func TestParseToken(t *testing.T) {
 	os.Setenv("JWT_SECRET", "test-secret-key")
-	secret = []byte(os.Getenv("JWT_SECRET"))
+	SetSecret(os.Getenv("JWT_SECRET"))
 
 	t.Run("parsea token válido correctamente", func(t *testing.T) {
 		// Generar token primero
@@ -68,4 +68,19 @@ func TestParseToken(t *testing.T) {
 		assert.Error(t, err)
 		assert.Nil(t, token)
 	})
+	t.Run("el token intermedio de MFA solo sirve para ParseClaimsMFA", func(t *testing.T) {
+		mfa, err := SignClaims(NewClaimsMFA(1, "comprador", "qa", time.Minute))
+		assert.NoError(t, err)
+		normal, _ := GenerateToken(1, "comprador")
+
+		_, err = ParseClaims(mfa)
+		assert.Error(t, err)
+		_, err = ParseClaimsMFA(normal)
+		assert.Error(t, err)
+
+		claims, err := ParseClaimsMFA(mfa)
+		assert.NoError(t, err)
+		assert.Equal(t, uint(1), claims.UserID)
+		assert.Equal(t, "qa", claims.Tenant)
+	})
 }